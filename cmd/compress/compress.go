@@ -2,6 +2,7 @@
 package compress
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -9,7 +10,6 @@ import (
 
 	"github.com/GoFurry/gf-file-tool/cmd"
 	"github.com/GoFurry/gf-file-tool/core/compress"
-	"github.com/GoFurry/gf-file-tool/utils"
 	uc "github.com/GoFurry/gf-file-tool/utils/compress"
 	"github.com/GoFurry/gf-file-tool/utils/log"
 	"github.com/spf13/cobra"
@@ -22,30 +22,107 @@ var compressCmd = &cobra.Command{
 	Short: "压缩文件/目录",
 	Long: `压缩文件/目录, 支持多格式、批量处理、分卷压缩:
   简易模式: gf-file-tool compress ./test.txt -o test.zip
-  高级模式: gf-file-tool compress ./docs -f zip -s 104857600 -e -k 123456 -l 32 -r -v`,
-	Args: cobra.MinimumNArgs(1), // 至少需要 1 个源文件/目录参数
-	Run: func(cmd *cobra.Command, args []string) {
+  高级模式: gf-file-tool compress ./docs -f zip -s 104857600 -e -k 123456 -l 32 -r -v
+  7z 格式: gf-file-tool compress ./docs -f 7z -e -k 123456 --encrypt-header
+  tar 系列: gf-file-tool compress ./docs -f tar.zst --level 19
+  zip 压缩方式: gf-file-tool compress ./docs -f zip --method zstd --level 19
+  管道模式: pg_dump db | gf-file-tool compress - -f tar.zst -o - | ssh host 'cat > db.tar.zst'
+  文件筛选: gf-file-tool compress ./project --exclude node_modules --exclude '**/*.log' --max-size 100M
+  增量备份: gf-file-tool compress ./docs --newer-than 7d -o docs-week.zip
+  文件列表: find . -name '*.go' -print0 | gf-file-tool compress --files-from - -o src.zip
+  路径布局: gf-file-tool compress www/html etc/nginx --base-dir /srv --prefix backup -f tar.zst
+  断点续传: gf-file-tool compress ./data -f tar.zst -o data.tar.zst --resume
+  恢复卷: gf-file-tool compress ./data -f tar.zst -s 104857600 --parity 4 -o data.tar.zst
+  独立分包: gf-file-tool compress ./data -s 4000000000 --split-mode entries -o data.zip
+目录内的符号链接与硬链接原样归档 (zip 无硬链接, 按普通文件存储), --follow-symlinks 改为归档链接目标
+源目录中的 .gfignore 按 .gitignore 语法排除文件, --no-ignore 关闭`,
+	Args: cobra.ArbitraryArgs, // 源路径可全部来自 --files-from
+	Run: func(c *cobra.Command, args []string) {
 		// 解析命令参数
-		outputPath, _ := cmd.Flags().GetString("output")
-		format, _ := cmd.Flags().GetString("format")
-		splitSize, _ := cmd.Flags().GetInt64("split")
-		encrypt, _ := cmd.Flags().GetBool("encrypt")
-		key, _ := cmd.Flags().GetString("key")
-		verify, _ := cmd.Flags().GetBool("verify")
-		keyLength, _ := cmd.Flags().GetInt("key-length")
+		outputPath, _ := c.Flags().GetString("output")
+		format, _ := c.Flags().GetString("format")
+		splitSize, _ := c.Flags().GetInt64("split")
+		encrypt, _ := c.Flags().GetBool("encrypt")
+		key, _ := c.Flags().GetString("key")
+		verify, _ := c.Flags().GetBool("verify")
+		keyLength, _ := c.Flags().GetInt("key-length")
+		encryptHeader, _ := c.Flags().GetBool("encrypt-header")
+		solidSize, _ := c.Flags().GetInt64("solid-size")
+		level, _ := c.Flags().GetInt("level")
+		stdinName, _ := c.Flags().GetString("stdin-name")
+		method, _ := c.Flags().GetString("method")
+		resume, _ := c.Flags().GetBool("resume")
+		parity, _ := c.Flags().GetInt("parity")
+		splitMode, _ := c.Flags().GetString("split-mode")
+
+		// 源路径与筛选条件
+		sources, err := cmd.SourceArgs(c, args)
+		if err != nil {
+			log.Error(err)
+			return
+		}
+		if len(sources) == 0 {
+			log.Error("至少需要指定 1 个源文件/目录 (或 --files-from)")
+			return
+		}
+		selector, err := cmd.NewSelector(c)
+		if err != nil {
+			log.Error(err)
+			return
+		}
+		baseDir, _ := c.Flags().GetString("base-dir")
+		prefix, _ := c.Flags().GetString("prefix")
+		layout, err := compress.NewEntryLayout(baseDir, prefix)
+		if err != nil {
+			log.Error(err)
+			return
+		}
+
+		// 输出到标准输出时, 日志与进度条改走标准错误, 保证标准输出只有压缩数据
+		if compress.IsStdio(outputPath) {
+			log.SetOutput(os.Stderr)
+		}
 
 		// 校验支持的格式
 		format = strings.ToLower(strings.TrimSpace(format))
-		supportedFormats := map[string]bool{"zip": true, "targz": true}
-		if !supportedFormats[format] {
-			log.Error("不支持的格式:", format, ", 仅支持 zip/targz")
+		if _, err := compress.NewCompressor(format); err != nil {
+			log.Error(err)
 			return
 		}
 
+		// 校验分卷方式
+		splitMode, err = compress.ParseSplitMode(strings.ToLower(strings.TrimSpace(splitMode)))
+		if err != nil {
+			log.Error(err)
+			return
+		}
+
+		// 校验 zip 压缩方式, 其他格式忽略
+		if format == "zip" {
+			if _, err := compress.ParseZipMethod(method); err != nil {
+				log.Error(err)
+				return
+			}
+			if compress.IsStdio(outputPath) {
+				if err := compress.CheckStdoutZipMethod(method); err != nil {
+					log.Error(err)
+					return
+				}
+			}
+		} else if c.Flags().Changed("method") {
+			log.Warn("--method 仅对 zip 格式生效, 已忽略")
+		}
+
 		// 自动补全输出路径
 		if outputPath == "" {
-			// 目录名或文件名
-			base := filepath.Base(args[0])
+			// 目录名或文件名, 标准输入使用 --stdin-name
+			base := filepath.Base(sources[0])
+			if absPath, err := filepath.Abs(layout.Resolve(sources[0])); err == nil {
+				base = filepath.Base(absPath) // 源为 . 时取目录名
+			}
+			if compress.IsStdio(sources[0]) {
+				base = stdinName
+			}
 			if !strings.HasSuffix(base, "."+format) {
 				outputPath = fmt.Sprintf("%s.%s", base, format) // 文件
 			} else {
@@ -53,24 +130,47 @@ var compressCmd = &cobra.Command{
 			}
 		}
 
-		// 获取所有待压缩文件
-		var sourcePaths []string
-		for _, src := range args {
-			files, err := uc.GetFileList(src)
+		// 获取所有待压缩文件与目录, 计算压缩包内路径
+		var entries []compress.SourceEntry
+		hasStdin := false
+		for _, src := range sources {
+			// - 表示标准输入, 只能出现一次
+			if compress.IsStdio(src) {
+				if hasStdin {
+					log.Warn("标准输入只能指定一次, 已忽略重复的 -")
+					continue
+				}
+				hasStdin = true
+				entries = append(entries, compress.SourceEntry{Path: src, Name: layout.Name("", stdinName)})
+				continue
+			}
+			root, err := layout.Root(src)
+			if err != nil {
+				log.Warn("跳过无效路径:", src, ", 错误:", err)
+				continue
+			}
+			tree, err := selector.CollectTree(layout.Resolve(src))
 			if err != nil {
 				log.Warn("跳过无效路径:", src, ", 错误:", err)
 				continue
 			}
-			sourcePaths = append(sourcePaths, files...)
+			for _, item := range tree {
+				name := layout.Name(root, item.Rel)
+				if name == "" {
+					continue // 源目录本身位于压缩包根部
+				}
+				entries = append(entries, compress.SourceEntry{Path: item.Path, Name: name, Dir: item.Dir, Mode: item.Mode.Type()})
+			}
 		}
-		if len(sourcePaths) == 0 {
+		if skipped := selector.Skipped(); skipped > 0 {
+			log.Info("按筛选条件跳过", skipped, "个文件/目录")
+		}
+		if len(entries) == 0 {
 			log.Error("无有效待压缩文件")
 			return
 		}
 
-		// 加密参数校验
-		var keyBytes []byte
-		var salt string
+		// 加密参数校验: zip/7z 均由原始密码按各自标准派生密钥, 盐值随机写入压缩包
 		if encrypt {
 			if key == "" {
 				log.Error("加密模式下必须指定密钥 (--key/-k)")
@@ -84,51 +184,44 @@ var compressCmd = &cobra.Command{
 				log.Error("无效的密钥长度:", keyLength, ", 仅支持 16/24/32")
 				return
 			}
-			// 补全密钥
-			paddedKey, err := uc.PadKey("aes", key)
-			if err != nil {
-				log.Error("密钥处理失败:", err)
-				return
-			}
-			if len(paddedKey) < keyLength {
-				paddedKey = append(paddedKey, make([]byte, keyLength-len(paddedKey))...)
-			}
-			keyBytes = paddedKey[:keyLength]
-
-			// 生成盐值
-			saltBytes, err := uc.GenerateSalt(16)
-			if err != nil {
-				log.Error("生成盐值失败:", err)
-				return
-			}
-			salt = saltBytes
-			if utils.VerboseMode() {
-				log.Success("盐值成功生成!")
-			}
 		}
 
 		// 构建压缩配置
 		opts := compress.CompressOptions{
-			SourcePaths: sourcePaths,
-			OutputPath:  outputPath,
-			Format:      format,
-			SplitSize:   splitSize,
-			Encrypt:     encrypt,
-			Key:         keyBytes,
-			Verify:      verify,
-			SplitSuffix: ".%03d", // 分卷后缀 .001/.002
-			EncryptSalt: salt,
-			KeyLength:   keyLength,
+			Entries:       entries,
+			OutputPath:    outputPath,
+			Format:        format,
+			SplitSize:     splitSize,
+			SplitMode:     splitMode,
+			Encrypt:       encrypt,
+			Verify:        verify,
+			SplitSuffix:   ".%03d", // 分卷后缀 .001/.002
+			KeyLength:     keyLength,
+			Password:      key,
+			EncryptHeader: encryptHeader,
+			SolidSize:     solidSize,
+			Level:         level,
+			StdinName:     stdinName,
+			Method:        method,
+			Resume:        resume,
+			Parity:        parity,
 		}
 
 		// 执行压缩
 		if err := compress.RunCompress(opts); err != nil {
 			log.Error("压缩失败:", err)
 
+			// 已记录检查点时保留输出与续传日志
+			var resumable *compress.ResumableError
+			if errors.As(err, &resumable) {
+				log.Info("已保留压缩进度, 以原参数追加 --resume 继续:", resumable.Journal)
+				return
+			}
+
 			// 失败清理逻辑
 			log.Info("开始清理损坏的压缩文件...")
 			// 清理主压缩包
-			if uc.CheckPathExist(opts.OutputPath) {
+			if !compress.IsStdio(opts.OutputPath) && uc.CheckPathExist(opts.OutputPath) {
 				if err := os.Remove(opts.OutputPath); err != nil {
 					log.Warn("清理主压缩包失败:", opts.OutputPath, ", 错误:", err)
 				} else {
@@ -150,23 +243,48 @@ var compressCmd = &cobra.Command{
 					}
 					volumeNum++
 				}
-			}
-			// 清理临时文件
-			if opts.TempFilePath != "" && uc.CheckPathExist(opts.TempFilePath) {
-				if err := os.Remove(opts.TempFilePath); err != nil {
-					log.Error("清理临时文件失败:", opts.TempFilePath, ", 错误:", err)
-				} else {
-					log.Success("已清理:", opts.TempFilePath)
+				// 清理恢复卷
+				for parityNum := 1; ; parityNum++ {
+					parityPath := fmt.Sprintf("%s.p%03d", opts.OutputPath, parityNum)
+					if !uc.CheckPathExist(parityPath) {
+						break
+					}
+					if err := os.Remove(parityPath); err != nil {
+						log.Warn("清理恢复卷失败:", parityPath, ", 错误:", err)
+					} else {
+						log.Success("已清理:", parityPath)
+					}
+				}
+				// 清理按条目分卷的分包与分包说明文件
+				if splitMode == compress.SplitModeEntries {
+					partPaths := compress.OutputParts(opts.OutputPath)
+					if manifestPath := opts.OutputPath + ".parts"; uc.CheckPathExist(manifestPath) {
+						partPaths = append(partPaths, manifestPath)
+					}
+					for _, partPath := range partPaths {
+						if err := os.Remove(partPath); err != nil {
+							log.Warn("清理分包失败:", partPath, ", 错误:", err)
+						} else {
+							log.Success("已清理:", partPath)
+						}
+					}
+				}
+				// 清理分卷说明文件
+				manifestPath := opts.OutputPath + ".split"
+				if uc.CheckPathExist(manifestPath) {
+					if err := os.Remove(manifestPath); err != nil {
+						log.Warn("清理分卷说明文件失败:", manifestPath, ", 错误:", err)
+					} else {
+						log.Success("已清理:", manifestPath)
+					}
 				}
 			}
-
 			return
 		}
 
 		// 成功提示
 		log.Success("压缩完成, 输出路径:", outputPath)
 		if encrypt {
-			log.Info("加密盐值:", salt)
 			log.Info("密钥长度:", keyLength)
 		}
 		if verify {
@@ -181,15 +299,30 @@ func InitCompress() {
 
 	// 注册命令参数
 	compressCmd.Flags().StringP("output", "o", "", "输出压缩包路径, 简易模式自动补全")
-	compressCmd.Flags().StringP("format", "f", "zip", "压缩格式 (zip/targz)")
+	compressCmd.Flags().StringP("format", "f", "zip", "压缩格式 (zip/7z/targz/tar/tar.zst/tar.xz/tar.lz4)")
 	compressCmd.Flags().Int64P("split", "s", 0, "分卷大小 (字节, 如 104857600 = 100MB)")
-	compressCmd.Flags().BoolP("encrypt", "e", false, "启用 AES 加密 (需指定 --key)")
+	compressCmd.Flags().BoolP("encrypt", "e", false, "启用 AES 加密 (zip 使用 WinZip AES 标准, 需指定 --key)")
 	compressCmd.Flags().StringP("key", "k", "", "加密密钥")
 	compressCmd.Flags().BoolP("verify", "r", false, "压缩后校验完整性 (CRC32)")
 	compressCmd.Flags().IntP("key-length", "l", uc.AES256KeyLength, "密钥长度 (16/24/32, 对应 AES-128/192/256)")
+	compressCmd.Flags().Bool("encrypt-header", false, "7z 同时加密头部, 不输入密码无法查看文件列表")
+	compressCmd.Flags().Int64("solid-size", 0, "7z 固实块大小 (字节, 0 = 全部文件一个固实块)")
+	compressCmd.Flags().Int("level", -1, "压缩等级 (gz/deflate 1-9, zst 1-22, xz/lz4 0-9, -1 = 格式默认), 对 tar 系列与 zip --method 生效")
+	compressCmd.Flags().String("method", compress.DefaultZipMethod, "zip 条目压缩方式 (store/deflate/zstd/xz)")
+	compressCmd.Flags().String("stdin-name", compress.DefaultStdinName, "源路径为 - 时标准输入在压缩包内的文件名")
+	compressCmd.Flags().StringP("base-dir", "C", "", "源路径的解析目录, 条目名为相对该目录的路径 (同 tar -C)")
+	compressCmd.Flags().String("prefix", "", "压缩包内所有条目的路径前缀")
+	compressCmd.Flags().Bool("special-files", false, "归档 FIFO 与设备文件 (设备文件仅 tar 格式)")
+	compressCmd.Flags().String("split-mode", compress.SplitModeBytes, "分卷方式: bytes 把压缩包按字节切开 / entries 按条目分为多个可单独解压的完整压缩包 name.part01.zip (需 --split)")
+	compressCmd.Flags().Int("parity", 0, "分卷时额外生成的恢复卷数量, 可修复同样数量的缺失或损坏分卷 (需 --split)")
+	compressCmd.Flags().Bool("resume", false, "按续传日志继续上次中断的压缩 (tar 系列与 zip, 不支持分卷与标准输入输出)")
+	cmd.AddSelectFlags(compressCmd)
 
 	// 绑定参数到 Viper
 	_ = viper.BindPFlag("compress.format", compressCmd.Flags().Lookup("format"))
 	_ = viper.BindPFlag("compress.split", compressCmd.Flags().Lookup("split"))
+	_ = viper.BindPFlag("compress.split-mode", compressCmd.Flags().Lookup("split-mode"))
 	_ = viper.BindPFlag("compress.key-length", compressCmd.Flags().Lookup("key-length"))
+	_ = viper.BindPFlag("compress.level", compressCmd.Flags().Lookup("level"))
+	_ = viper.BindPFlag("compress.method", compressCmd.Flags().Lookup("method"))
 }
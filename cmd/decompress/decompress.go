@@ -1,30 +1,39 @@
 package decompress
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strconv"
-	"strings"
 
 	"github.com/GoFurry/gf-file-tool/cmd"
 	"github.com/GoFurry/gf-file-tool/core/compress"
+	"github.com/GoFurry/gf-file-tool/utils"
 	uc "github.com/GoFurry/gf-file-tool/utils/compress"
 	"github.com/GoFurry/gf-file-tool/utils/log"
-	"github.com/klauspost/compress/zip"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
 // decompressCmd 解压缩主命令
 var decompressCmd = &cobra.Command{
-	Use:   "decompress [source]",
+	Use:   "decompress [source] [entry...]",
 	Short: "解压缩文件/压缩包",
 	Long: `解压缩文件/压缩包，支持多格式、批量处理、加密解密、分卷合并:
   简易模式:gf-file-tool decompress test.zip
   加密解密:gf-file-tool decompress test.zip -e -k 123456 -l 32
   分卷合并:gf-file-tool decompress split_big.zip.001 -o ./output
-  完整性校验:gf-file-tool decompress test.zip -r --crc32 a18d2fb9`,
-	Args: cobra.ExactArgs(1),
+  独立分包:gf-file-tool decompress data.part01.zip -o ./output
+  7z 解压:gf-file-tool decompress test.7z.001 -e -k 123456
+  完整性校验:gf-file-tool decompress test.zip -r --crc32 a18d2fb9
+  管道模式:ssh host cat db.tar.zst | gf-file-tool decompress - -o ./restore
+  指定条目:gf-file-tool decompress test.zip docs/readme.md config
+  通配筛选:gf-file-tool decompress test.tar.zst --include '**/*.conf' --exclude 'cache/**'
+  增量还原:gf-file-tool decompress backup.zip -o ./data --overwrite newer
+  还原属主:sudo gf-file-tool decompress backup.tar.zst -o / --same-owner
+  断点续传:gf-file-tool decompress big.tar.zst -o ./data --resume`,
+	Args: cobra.MinimumNArgs(1),
 	Run: func(c *cobra.Command, args []string) {
 		// 解析参数
 		outputDir, _ := c.Flags().GetString("output")
@@ -35,10 +44,58 @@ var decompressCmd = &cobra.Command{
 		verify, _ := c.Flags().GetBool("verify")
 		expectedCRC, _ := c.Flags().GetString("crc32")
 		salt, _ := c.Flags().GetString("salt")
+		include, _ := c.Flags().GetStringSlice("include")
+		exclude, _ := c.Flags().GetStringSlice("exclude")
+		specialFiles, _ := c.Flags().GetBool("special-files")
+		sameOwner, _ := c.Flags().GetBool("same-owner")
+		noSamePermissions, _ := c.Flags().GetBool("no-same-permissions")
+		umaskValue, _ := c.Flags().GetString("umask")
+		pathPolicyValue, _ := c.Flags().GetString("path-policy")
+		overwriteValue, _ := c.Flags().GetString("overwrite")
+		resume, _ := c.Flags().GetBool("resume")
+
+		pathPolicy, err := compress.ParsePathPolicy(pathPolicyValue)
+		if err != nil {
+			log.Error(err)
+			return
+		}
+		limits, err := parseLimits(c)
+		if err != nil {
+			log.Error(err)
+			return
+		}
+		overwrite, err := compress.ParseOverwritePolicy(overwriteValue)
+		if err != nil {
+			log.Error(err)
+			return
+		}
+
+		// 权限策略: 默认原样还原, --umask 去掉指定的位, --no-same-permissions 未指定 --umask 时使用进程 umask
+		var umask os.FileMode
+		if umaskValue != "" {
+			value, err := strconv.ParseUint(umaskValue, 8, 32)
+			if err != nil || value > 0777 {
+				log.Error("--umask 必须是八进制权限位, 如 022:", umaskValue)
+				return
+			}
+			umask = os.FileMode(value)
+		} else if noSamePermissions {
+			umask = compress.ProcessUmask()
+		}
+
+		// 条目筛选: 源之后的参数为显式条目
+		filter, err := compress.NewEntryFilter(include, exclude, args[1:])
+		if err != nil {
+			log.Error(err)
+			return
+		}
 
 		// 自动补全输出目录
+		if outputDir == "" && compress.IsStdio(args[0]) {
+			outputDir = compress.DefaultStdinName + "_unzip"
+		}
 		if outputDir == "" {
-			base := filepath.Base(args[0])
+			base := filepath.Base(compress.PartBasePath(args[0])) // 分包去掉 .partNN
 			ext := filepath.Ext(base)
 			// 分卷文件去掉后缀
 			if len(ext) == 4 && ext[0] == '.' {
@@ -57,96 +114,54 @@ var decompressCmd = &cobra.Command{
 			Verify:      verify,
 			EncryptSalt: salt,
 			ExpectedCRC: expectedCRC,
-		}
+			Filter:      filter,
 
-		// 自动读取 Zip 注释中的盐值和密钥长度
-		if encrypt && opts.Format == "zip" {
-			// 读取 Zip 注释
-			r, err := zip.OpenReader(opts.SourcePath)
-			if err == nil {
-				defer r.Close()
-				comment := r.Comment
-				if comment != "" && strings.HasPrefix(comment, "gf-encrypt:") {
-					// 解析注释
-					commentParts := strings.Split(strings.TrimPrefix(comment, "gf-encrypt:"), ";")
-					for _, part := range commentParts {
-						kv := strings.Split(part, "=")
-						if len(kv) != 2 {
-							continue
-						}
-						switch kv[0] {
-						case "salt":
-							opts.EncryptSalt = kv[1]
-							log.Info("从压缩包注释读取盐值:", opts.EncryptSalt)
-						case "key-length":
-							kl, _ := strconv.Atoi(kv[1])
-							keyLength = kl
-							log.Info("从压缩包注释读取密钥长度:", keyLength)
-						}
-					}
-				}
-			}
+			SpecialFiles: specialFiles,
+			SameOwner:    sameOwner,
+			Umask:        umask,
+			PathPolicy:   pathPolicy,
+			Limits:       limits,
+			Overwrite:    overwrite,
+			Resume:       resume,
 		}
 
-		// 解密参数处理
-		if encrypt {
-			if key == "" {
-				log.Error("解密模式下必须指定密钥 (--key/-k)")
-				return
-			}
-			// 默认 AES256
-			if keyLength == 0 {
-				keyLength = uc.AES256KeyLength
-			}
-			// 填充密钥
-			paddedKey, err := uc.PadKey("aes", key)
+		// 自动识别格式: 读取文件头魔数, 不依赖扩展名
+		if opts.Format == "" {
+			detected, err := compress.DetectFormat(opts.SourcePath)
 			if err != nil {
-				log.Error("密钥处理失败:", err)
+				log.Error("自动识别格式失败:", err, ", 请通过 --format 指定")
 				return
 			}
-			if len(paddedKey) < keyLength {
-				paddedKey = append(paddedKey, make([]byte, keyLength-len(paddedKey))...)
+			opts.Format = detected
+			if utils.VerboseMode() {
+				log.Info("识别到压缩格式:", detected)
 			}
-			opts.Key = paddedKey[:keyLength]
 		}
 
-		// 自动识别格式
-		if opts.Format == "" {
-			ext := filepath.Ext(args[0])
-			switch ext {
-			case ".zip":
-				opts.Format = "zip"
-			case ".tar.gz", ".tgz":
-				opts.Format = "targz"
-			default:
-				log.Warn("自动识别格式失败, 默认使用 zip")
-				opts.Format = "zip"
+		// 解密参数处理
+		if encrypt {
+			if err := compress.PrepareDecrypt(&opts, key, keyLength); err != nil {
+				log.Error(err)
+				return
 			}
 		}
 
 		// 执行解压缩
 		if err := compress.RunDecompress(opts); err != nil {
 			log.Error("解压缩失败:", err)
-
-			// 清理损坏的解压文件
-			log.Info("开始清理损坏的解压文件...")
-			if uc.CheckPathExist(opts.OutputDir) {
-				if err := os.RemoveAll(opts.OutputDir); err != nil {
-					log.Warn("清理解压目录失败:", opts.OutputDir, ", 错误:", err)
-				} else {
-					log.Success("已清理解压目录:", opts.OutputDir)
-				}
+			var limitErr *compress.LimitError
+			if errors.As(err, &limitErr) {
+				log.Info("压缩包超出解压上限, 如确认可信可通过 --" + limitErr.Limit + " 调整")
 			}
-			// 清理分卷合并的临时文件
-			mergedPath := opts.SourcePath + ".merged"
-			if uc.CheckPathExist(mergedPath) {
-				if err := os.Remove(mergedPath); err != nil {
-					log.Warn("清理分卷合并临时文件失败:", mergedPath, ", 错误:", err)
-				} else {
-					log.Success("已清理:", mergedPath)
-				}
+
+			// 解压在暂存目录中进行, 失败时由 RunDecompress 删除暂存目录, 不清理输出目录
+			var resumable *compress.ResumableError
+			if errors.As(err, &resumable) {
+				log.Info("已保留暂存目录, 输出目录保持原样, 以原参数追加 --resume 继续:", resumable.Journal)
+			} else {
+				log.Info("已清理暂存目录, 输出目录保持原样:", opts.OutputDir)
 			}
-			return
+			os.Exit(1)
 		}
 	},
 }
@@ -157,15 +172,64 @@ func InitDecompress() {
 
 	// 注册参数
 	decompressCmd.Flags().StringP("output", "o", "", "输出目录（简易模式自动补全为 压缩包名_unzip）")
-	decompressCmd.Flags().StringP("format", "f", "", "压缩格式（自动识别：zip/targz）")
+	decompressCmd.Flags().StringP("format", "f", "", "压缩格式（默认按文件头自动识别：zip/7z/targz/tar/tar.zst/tar.xz/tar.bz2/tar.lz4）")
 	decompressCmd.Flags().BoolP("encrypt", "e", false, "启用解密（需指定 --key）")
 	decompressCmd.Flags().StringP("key", "k", "", "解密密钥")
 	decompressCmd.Flags().IntP("key-length", "l", 32, "密钥长度（AES：16/24/32）")
-	decompressCmd.Flags().StringP("salt", "s", "", "解密盐值（与压缩时一致）")
+	decompressCmd.Flags().StringP("salt", "s", "", "解密盐值（仅旧版本 AES-GCM 加密的压缩包需要）")
 	decompressCmd.Flags().BoolP("verify", "r", false, "解压缩后校验完整性")
 	decompressCmd.Flags().StringP("crc32", "c", "", "预期 CRC32 值（用于校验）")
+	decompressCmd.Flags().StringSlice("include", nil, "只解压匹配的条目（通配模式，支持 **，可多次指定）")
+	decompressCmd.Flags().StringSlice("exclude", nil, "跳过匹配的条目（通配模式，支持 **，优先于 --include）")
+	decompressCmd.Flags().Bool("special-files", false, "还原 FIFO 与设备文件（创建设备文件通常需要 root）")
+	decompressCmd.Flags().Bool("same-owner", false, "按压缩包记录还原属主（通常需要 root，优先按用户名/组名）")
+	decompressCmd.Flags().Bool("no-same-permissions", false, "还原权限时去掉当前 umask 屏蔽的位")
+	decompressCmd.Flags().String("overwrite", string(compress.OverwriteAlways), "目标文件已存在时：always 覆盖 / never 保留 / newer 条目更新时覆盖 / rename 另存为 name (1).ext / ask 逐个询问")
+	decompressCmd.Flags().String("path-policy", string(compress.PathSanitize), "不安全条目（绝对路径、..、经过符号链接、设备文件）的处理：sanitize 去除后解压 / skip 跳过 / error 中止 / trust 不检查")
+	decompressCmd.Flags().Bool("untrusted", false, "按不可信压缩包解压，启用默认上限（总量 4G、单文件 1G、10 万个条目、压缩比 200、路径 32 层）")
+	decompressCmd.Flags().String("max-total-size", "", "解压总大小上限（如 512M/10G）")
+	decompressCmd.Flags().String("max-entry-size", "", "单个条目大小上限（如 100M）")
+	decompressCmd.Flags().Int("max-entries", 0, "条目数上限")
+	decompressCmd.Flags().Int64("max-ratio", 0, "解压总大小与压缩包大小之比的上限")
+	decompressCmd.Flags().Int("max-path-depth", 0, "条目路径层数上限")
+	decompressCmd.Flags().Bool("resume", false, "继续上次中断的解压（复用暂存目录，跳过已解压并校验通过的条目）")
+	decompressCmd.Flags().String("umask", "", "还原权限时去掉的位（八进制，如 022，隐含 --no-same-permissions）")
 
 	// 绑定 Viper
 	_ = viper.BindPFlag("decompress.format", decompressCmd.Flags().Lookup("format"))
 	_ = viper.BindPFlag("decompress.key-length", decompressCmd.Flags().Lookup("key-length"))
+	_ = viper.BindPFlag("decompress.path-policy", decompressCmd.Flags().Lookup("path-policy"))
+	_ = viper.BindPFlag("decompress.overwrite", decompressCmd.Flags().Lookup("overwrite"))
+}
+
+// parseLimits 解析解压上限: --untrusted 提供默认值, 显式指定的单项上限优先
+func parseLimits(c *cobra.Command) (compress.ExtractLimits, error) {
+	var limits compress.ExtractLimits
+	if untrusted, _ := c.Flags().GetBool("untrusted"); untrusted {
+		limits = compress.UntrustedLimits
+	}
+	for flag, target := range map[string]*int64{
+		"max-total-size": &limits.MaxTotalSize,
+		"max-entry-size": &limits.MaxEntrySize,
+	} {
+		value, _ := c.Flags().GetString(flag)
+		if value == "" {
+			continue
+		}
+		size, err := uc.ParseSize(value)
+		if err != nil {
+			return limits, fmt.Errorf("--%s %v", flag, err)
+		}
+		*target = size
+	}
+	if c.Flags().Changed("max-entries") {
+		limits.MaxEntries, _ = c.Flags().GetInt("max-entries")
+	}
+	if c.Flags().Changed("max-ratio") {
+		limits.MaxRatio, _ = c.Flags().GetInt64("max-ratio")
+	}
+	if c.Flags().Changed("max-path-depth") {
+		limits.MaxPathDepth, _ = c.Flags().GetInt("max-path-depth")
+	}
+	return limits, nil
 }
@@ -17,8 +17,9 @@ var decryptCmd = &cobra.Command{
 	Short: "解密文件/目录",
 	Long: `解密文件/目录:
   gf-file-tool decrypt test.enc -k 123456 -o test.txt
-  gf-file-tool decrypt ./docs_enc -k 123456 --algorithm aes`,
-	Args: cobra.MinimumNArgs(1),
+  gf-file-tool decrypt ./docs_enc -k 123456 --algorithm aes
+  gf-file-tool decrypt ./docs_enc -k 123456 -s <盐值> --include '*.enc'`,
+	Args: cobra.ArbitraryArgs, // 源路径可全部来自 --files-from
 	Run: func(c *cobra.Command, args []string) {
 		// 解析参数
 		outputPath, _ := c.Flags().GetString("output")
@@ -39,15 +40,28 @@ var decryptCmd = &cobra.Command{
 		}
 
 		// 批量获取文件
+		sources, err := cmd.SourceArgs(c, args)
+		if err != nil {
+			log.Error(err)
+			return
+		}
+		selector, err := cmd.NewSelector(c)
+		if err != nil {
+			log.Error(err)
+			return
+		}
 		var sourcePaths []string
-		for _, src := range args {
-			files, err := compress.GetFileList(src)
+		for _, src := range sources {
+			files, err := selector.Collect(src)
 			if err != nil {
 				log.Warn("跳过无效路径:", src, ", 错误:", err)
 				continue
 			}
 			sourcePaths = append(sourcePaths, files...)
 		}
+		if skipped := selector.Skipped(); skipped > 0 {
+			log.Info("按筛选条件跳过", skipped, "个文件/目录")
+		}
 		if len(sourcePaths) == 0 {
 			log.Error("无有效解密文件")
 			return
@@ -111,6 +125,7 @@ func InitDecrypt() {
 	decryptCmd.Flags().StringP("key", "k", "", "解密密钥 (必填)")
 	decryptCmd.Flags().IntP("key-length", "l", 32, "密钥长度 (AES 16/24/32)")
 	decryptCmd.Flags().StringP("salt", "s", "", "解密盐值 (必填, 加密时的盐值)")
+	cmd.AddSelectFlags(decryptCmd)
 
 	// 绑定 Viper
 	_ = viper.BindPFlag("decrypt.algorithm", decryptCmd.Flags().Lookup("algorithm"))
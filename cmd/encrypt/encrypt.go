@@ -17,8 +17,9 @@ var encryptCmd = &cobra.Command{
 	Short: "加密文件/目录",
 	Long: `加密文件/目录:
   gf-file-tool encrypt test.txt -k 123456 -o test.enc
-  gf-file-tool encrypt ./docs -k 123456 --algorithm aes`,
-	Args: cobra.MinimumNArgs(1),
+  gf-file-tool encrypt ./docs -k 123456 --algorithm aes
+  gf-file-tool encrypt ./docs -k 123456 --include '**/*.pdf' --newer-than 2024-01-01`,
+	Args: cobra.ArbitraryArgs, // 源路径可全部来自 --files-from
 	Run: func(c *cobra.Command, args []string) {
 		// 解析参数
 		outputPath, _ := c.Flags().GetString("output")
@@ -34,15 +35,28 @@ var encryptCmd = &cobra.Command{
 		}
 
 		// 批量获取文件
+		sources, err := cmd.SourceArgs(c, args)
+		if err != nil {
+			log.Error(err)
+			return
+		}
+		selector, err := cmd.NewSelector(c)
+		if err != nil {
+			log.Error(err)
+			return
+		}
 		var sourcePaths []string
-		for _, src := range args {
-			files, err := compress.GetFileList(src)
+		for _, src := range sources {
+			files, err := selector.Collect(src)
 			if err != nil {
 				log.Warn("跳过无效路径:", src, ", 错误:", err)
 				continue
 			}
 			sourcePaths = append(sourcePaths, files...)
 		}
+		if skipped := selector.Skipped(); skipped > 0 {
+			log.Info("按筛选条件跳过", skipped, "个文件/目录")
+		}
 		if len(sourcePaths) == 0 {
 			log.Error("无有效加密文件")
 			return
@@ -102,6 +116,7 @@ func InitEncrypt() {
 	encryptCmd.Flags().StringP("key", "k", "", "加密密钥 (必填)")
 	encryptCmd.Flags().IntP("key-length", "l", 32, "密钥长度(AES 16/24/32) (DES 8)")
 	encryptCmd.Flags().StringP("salt", "s", "", "加密盐值 (为空自动生成)")
+	cmd.AddSelectFlags(encryptCmd)
 
 	// 绑定 Viper
 	_ = viper.BindPFlag("encrypt.algorithm", encryptCmd.Flags().Lookup("algorithm"))
@@ -2,13 +2,9 @@
 package merge
 
 import (
-	"io"
-	"os"
-	"path/filepath"
-	"strings"
-
 	"github.com/GoFurry/gf-file-tool/cmd"
-	"github.com/GoFurry/gf-file-tool/utils/compress"
+	"github.com/GoFurry/gf-file-tool/core/compress"
+	uc "github.com/GoFurry/gf-file-tool/utils/compress"
 	"github.com/GoFurry/gf-file-tool/utils/log"
 	"github.com/spf13/cobra"
 )
@@ -21,7 +17,7 @@ var mergeCmd = &cobra.Command{
 gf-file-tool merge ./test/split_big.zip -o merged.zip -r`,
 	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		basePath := args[0]
+		basePath := uc.SplitBasePath(args[0])
 		outputPath, _ := cmd.Flags().GetString("output")
 		verify, _ := cmd.Flags().GetBool("verify")
 
@@ -30,76 +26,14 @@ gf-file-tool merge ./test/split_big.zip -o merged.zip -r`,
 			outputPath = basePath + "_merged"
 		}
 
-		// 查找所有分卷文件
-		var volumes []string
-		dir := filepath.Dir(basePath)
-		baseName := filepath.Base(basePath)
-
-		files, _ := os.ReadDir(dir)
-		for _, f := range files {
-			if !f.IsDir() && strings.HasPrefix(f.Name(), baseName+".") {
-				volumes = append(volumes, filepath.Join(dir, f.Name()))
-			}
-		}
-		if len(volumes) == 0 {
-			log.Error("未找到分卷文件")
-			return
-		}
-
-		// 合并分卷
-		log.Info("合并", len(volumes), "个分卷:", volumes)
-		outFile, err := os.Create(outputPath)
+		// 有分卷说明文件时按其中的顺序合并, 并逐卷核对大小与校验值
+		verified, err := compress.MergeSplit(basePath, outputPath)
 		if err != nil {
-			log.Error("创建合并文件失败:", err)
+			log.Error("合并失败:", err)
 			return
 		}
-		defer outFile.Close()
-
-		for _, vol := range volumes {
-			inFile, err := os.Open(vol)
-			if err != nil {
-				log.Error("打开分卷", vol, "失败:", err)
-				return
-			}
-			defer inFile.Close()
-
-			_, err = io.Copy(outFile, inFile)
-			if err != nil {
-				log.Error("合并分卷", vol, "失败", err)
-				return
-			}
-		}
-
-		// 验证 CRC32
-		if verify {
-			// 从分卷说明文件读取预期 CRC32
-			manifestPath := basePath + ".split"
-			if compress.CheckPathExist(manifestPath) {
-				content, _ := os.ReadFile(manifestPath)
-				lines := strings.Split(string(content), "\n")
-				var expectedCRC string
-				for _, line := range lines {
-					if strings.HasPrefix(line, "CRC32:") {
-						expectedCRC = strings.TrimSpace(strings.TrimPrefix(line, "CRC32:"))
-						break
-					}
-				}
-
-				if expectedCRC != "" {
-					actualCRC, err := compress.CalculateCRC32(outputPath)
-					if err != nil {
-						log.Error("校验 CRC32 失败:", err)
-						return
-					}
-					if actualCRC == expectedCRC {
-						log.Success("CRC32 校验通过:", actualCRC)
-					} else {
-						log.Error("CRC32 校验失败: 预期", expectedCRC, ", 实际", actualCRC)
-					}
-				}
-			} else {
-				log.Warn("未找到分卷说明文件, 跳过 CRC32 校验")
-			}
+		if verify && !verified {
+			log.Warn("未找到分卷说明文件, 跳过校验")
 		}
 		log.Success("合并完成, 输出文件:", outputPath)
 	},
@@ -109,5 +43,5 @@ gf-file-tool merge ./test/split_big.zip -o merged.zip -r`,
 func InitMerge() {
 	cmd.GetRootCmd().AddCommand(mergeCmd)
 	mergeCmd.Flags().StringP("output", "o", "", "合并后输出路径")
-	mergeCmd.Flags().BoolP("verify", "r", false, "验证合并后文件的 CRC32")
+	mergeCmd.Flags().BoolP("verify", "r", false, "要求校验 (有 .split 说明文件时总是逐卷校验, 没有时给出提示)")
 }
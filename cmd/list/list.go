@@ -0,0 +1,215 @@
+// Package list /cmd/list/list.go
+package list
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/GoFurry/gf-file-tool/cmd"
+	"github.com/GoFurry/gf-file-tool/core/compress"
+	"github.com/GoFurry/gf-file-tool/utils/log"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// listCmd 列表命令实例
+var listCmd = &cobra.Command{
+	Use:   "list [archive]",
+	Short: "查看压缩包内容",
+	Long: `不解压, 列出压缩包内的文件, 支持 zip/7z/tar 系列, 分卷直接原地读取:
+  简易模式: gf-file-tool list test.zip
+  分卷: gf-file-tool list split_big.zip.001
+  头部加密的 7z: gf-file-tool list test.7z -e -k 123456
+  脚本使用: gf-file-tool list test.tar.zst --json | jq '.entries[].name'`,
+	Args: cobra.ExactArgs(1),
+	Run: func(c *cobra.Command, args []string) {
+		format, _ := c.Flags().GetString("format")
+		encrypt, _ := c.Flags().GetBool("encrypt")
+		key, _ := c.Flags().GetString("key")
+		asJSON, _ := c.Flags().GetBool("json")
+
+		// JSON 输出时日志改走标准错误, 保证标准输出可直接解析
+		if asJSON {
+			log.SetOutput(os.Stderr)
+		}
+
+		opts := compress.DecompressOptions{
+			SourcePath: args[0],
+			Format:     format,
+			Encrypt:    encrypt,
+			Password:   key,
+		}
+		listing, err := compress.RunList(opts)
+		if err != nil {
+			log.Error("读取压缩包失败:", err)
+			return
+		}
+
+		if asJSON {
+			if err := printJSON(listing); err != nil {
+				log.Error("输出 JSON 失败:", err)
+			}
+			return
+		}
+		printTable(listing)
+	},
+}
+
+// printTable 表格输出
+func printTable(listing *compress.ArchiveListing) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	_, _ = fmt.Fprintln(w, "Mode\tSize\tCompressed\tRatio\tMethod\tModified\tCRC32\tEnc\t Name")
+	for _, entry := range listing.Entries {
+		crc := "-"
+		if entry.HasCRC32 {
+			crc = fmt.Sprintf("%08x", entry.CRC32)
+		}
+		enc := "-"
+		if entry.Encrypted {
+			enc = "*"
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%s\t%s\t%s\t %s\n",
+			entry.Mode, entry.Size, formatSize(entry.CompressedSize), formatRatio(entry.CompressedSize, entry.Size),
+			entry.Method, formatTime(entry.Modified), crc, enc, entry.Name)
+	}
+
+	files, size, compressedSize := listing.Totals()
+	_, _ = fmt.Fprintf(w, "\t%d\t%s\t%s\t\t\t\t\t %d 个文件\n",
+		size, formatSize(compressedSize), formatRatio(compressedSize, size), files)
+	_ = w.Flush()
+	if len(listing.Volumes) > 1 {
+		fmt.Printf("格式: %s, 分卷: %d 个, 总大小: %d 字节\n", listing.Format, len(listing.Volumes), listing.ArchiveSize)
+	} else {
+		fmt.Printf("格式: %s\n", listing.Format)
+	}
+}
+
+// formatSize 未记录的大小显示为 -
+func formatSize(size int64) string {
+	if size < 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%d", size)
+}
+
+// formatRatio 压缩率 = 压缩后大小 / 原始大小
+func formatRatio(compressedSize, size int64) string {
+	ratio, ok := ratioOf(compressedSize, size)
+	if !ok {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f%%", ratio*100)
+}
+
+// ratioOf 计算压缩率, 大小未知或原始大小为 0 时无意义
+func ratioOf(compressedSize, size int64) (float64, bool) {
+	if compressedSize < 0 || size <= 0 {
+		return 0, false
+	}
+	return float64(compressedSize) / float64(size), true
+}
+
+// formatTime 修改时间
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
+
+// jsonEntry JSON 输出的条目, 未知的字段输出 null
+type jsonEntry struct {
+	Name           string     `json:"name"`
+	Size           int64      `json:"size"`
+	CompressedSize *int64     `json:"compressed_size"`
+	Ratio          *float64   `json:"ratio"`
+	Method         string     `json:"method"`
+	Modified       *time.Time `json:"mtime"`
+	Mode           string     `json:"mode"`
+	CRC32          *string    `json:"crc32"`
+	Encrypted      bool       `json:"encrypted"`
+	IsDir          bool       `json:"is_dir"`
+}
+
+// jsonListing JSON 输出结构
+type jsonListing struct {
+	Archive     string      `json:"archive"`
+	Format      string      `json:"format"`
+	Volumes     []string    `json:"volumes,omitempty"`
+	ArchiveSize *int64      `json:"archive_size"`
+	Entries     []jsonEntry `json:"entries"`
+	Totals      struct {
+		Files          int      `json:"files"`
+		Size           int64    `json:"size"`
+		CompressedSize *int64   `json:"compressed_size"`
+		Ratio          *float64 `json:"ratio"`
+	} `json:"totals"`
+}
+
+// printJSON JSON 输出
+func printJSON(listing *compress.ArchiveListing) error {
+	out := jsonListing{
+		Archive: listing.Path,
+		Format:  listing.Format,
+		Volumes: listing.Volumes,
+		Entries: make([]jsonEntry, 0, len(listing.Entries)),
+	}
+	if listing.ArchiveSize >= 0 {
+		out.ArchiveSize = &listing.ArchiveSize
+	}
+	for _, entry := range listing.Entries {
+		item := jsonEntry{
+			Name:      entry.Name,
+			Size:      entry.Size,
+			Method:    entry.Method,
+			Mode:      entry.Mode.String(),
+			Encrypted: entry.Encrypted,
+			IsDir:     entry.Mode.IsDir(),
+		}
+		if entry.CompressedSize >= 0 {
+			item.CompressedSize = &entry.CompressedSize
+		}
+		if ratio, ok := ratioOf(entry.CompressedSize, entry.Size); ok {
+			item.Ratio = &ratio
+		}
+		if !entry.Modified.IsZero() {
+			item.Modified = &entry.Modified
+		}
+		if entry.HasCRC32 {
+			crc := fmt.Sprintf("%08x", entry.CRC32)
+			item.CRC32 = &crc
+		}
+		out.Entries = append(out.Entries, item)
+	}
+
+	files, size, compressedSize := listing.Totals()
+	out.Totals.Files = files
+	out.Totals.Size = size
+	if compressedSize >= 0 {
+		out.Totals.CompressedSize = &compressedSize
+	}
+	if ratio, ok := ratioOf(compressedSize, size); ok {
+		out.Totals.Ratio = &ratio
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(out)
+}
+
+// InitList 初始化命令
+func InitList() {
+	cmd.GetRootCmd().AddCommand(listCmd)
+
+	// 注册参数
+	listCmd.Flags().StringP("format", "f", "", "压缩格式（默认按文件头自动识别：zip/7z/targz/tar/tar.zst/tar.xz/tar.bz2/tar.lz4）")
+	listCmd.Flags().BoolP("encrypt", "e", false, "压缩包头部已加密 (7z --encrypt-header), 需指定 --key")
+	listCmd.Flags().StringP("key", "k", "", "解密密钥")
+	listCmd.Flags().Bool("json", false, "以 JSON 输出, 便于脚本处理")
+
+	// 绑定 Viper
+	_ = viper.BindPFlag("list.format", listCmd.Flags().Lookup("format"))
+}
@@ -0,0 +1,77 @@
+// Package cmd /cmd/selection.go
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/GoFurry/gf-file-tool/utils/compress"
+	"github.com/spf13/cobra"
+)
+
+// compress/encrypt/decrypt 都要从源路径中挑选待处理文件, 筛选参数统一在这里注册与解析
+
+// AddSelectFlags 注册文件筛选参数
+func AddSelectFlags(c *cobra.Command) {
+	c.Flags().StringSlice("include", nil, "只处理匹配的文件 (通配模式, 支持 **, 相对源目录, 可多次指定)")
+	c.Flags().StringSlice("exclude", nil, "跳过匹配的文件/目录 (通配模式, 支持 **, 优先于 --include)")
+	c.Flags().Bool("no-ignore", false, "不读取源目录中的 "+compress.IgnoreFileName+" 忽略规则")
+	c.Flags().String("newer-than", "", "只处理修改时间晚于该时间的文件 (7d/12h、2006-01-02 或参照文件)")
+	c.Flags().String("older-than", "", "只处理修改时间早于该时间的文件 (格式同 --newer-than)")
+	c.Flags().String("max-size", "", "只处理不超过该大小的文件 (如 512K/100M/1G)")
+	c.Flags().String("files-from", "", "从文件读取源路径列表, - 为标准输入, 支持 find -print0 的 \\0 分隔")
+	c.Flags().Bool("follow-symlinks", false, "跟随目录内的符号链接, 处理链接目标而不是链接本身")
+}
+
+// NewSelector 按命令参数创建文件筛选器
+func NewSelector(c *cobra.Command) (*compress.FileSelector, error) {
+	include, _ := c.Flags().GetStringSlice("include")
+	exclude, _ := c.Flags().GetStringSlice("exclude")
+	noIgnore, _ := c.Flags().GetBool("no-ignore")
+	newerThan, _ := c.Flags().GetString("newer-than")
+	olderThan, _ := c.Flags().GetString("older-than")
+	maxSize, _ := c.Flags().GetString("max-size")
+	followSymlinks, _ := c.Flags().GetBool("follow-symlinks")
+	// --special-files 只有 compress 注册
+	specialFiles, _ := c.Flags().GetBool("special-files")
+
+	opts := compress.SelectOptions{
+		Include:        include,
+		Exclude:        exclude,
+		NoIgnore:       noIgnore,
+		FollowSymlinks: followSymlinks,
+		SpecialFiles:   specialFiles,
+	}
+	now := time.Now()
+	var err error
+	if opts.NewerThan, err = compress.ParseTimeBound(newerThan, now); err != nil {
+		return nil, fmt.Errorf("--newer-than %v", err)
+	}
+	if opts.OlderThan, err = compress.ParseTimeBound(olderThan, now); err != nil {
+		return nil, fmt.Errorf("--older-than %v", err)
+	}
+	if maxSize != "" {
+		if opts.MaxSize, err = compress.ParseSize(maxSize); err != nil {
+			return nil, fmt.Errorf("--max-size %v", err)
+		}
+	}
+	return compress.NewFileSelector(opts)
+}
+
+// SourceArgs 合并命令行源路径与 --files-from 列表
+func SourceArgs(c *cobra.Command, args []string) ([]string, error) {
+	filesFrom, _ := c.Flags().GetString("files-from")
+	if filesFrom == "" {
+		return args, nil
+	}
+	for _, arg := range args {
+		if arg == "-" && filesFrom == "-" {
+			return nil, fmt.Errorf("--files-from - 与源路径 - 不能同时读取标准输入")
+		}
+	}
+	paths, err := compress.ReadFilesFrom(filesFrom)
+	if err != nil {
+		return nil, err
+	}
+	return append(append([]string{}, args...), paths...), nil
+}
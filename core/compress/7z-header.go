@@ -0,0 +1,465 @@
+// Package compress /core/compress/7z-header.go
+package compress
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ulikunitz/xz/lzma"
+)
+
+// sevenzip 只公开文件列表, 这里按 7z 头部格式读出每个 folder (固实块) 的编码器链, 供 list 显示
+// 头部被编码 (7-Zip 默认 LZMA 压缩头部, --encrypt-header 时再加 AES) 时先解码再读取
+
+var errSevenZipHeader = errors.New("7z 头部损坏")
+
+// 7z 头部中写入端用不到的属性 ID
+const (
+	sevenZipIDArchiveProperties = 0x02
+	sevenZipIDAdditionalStreams = 0x03
+)
+
+// sevenZipMethodNames 编码器 ID 对应的名称, 与 7-Zip 显示一致
+var sevenZipMethodNames = map[string]string{
+	"\x00":             "Copy",
+	"\x03":             "Delta",
+	"\x03\x01\x01":     "LZMA",
+	"\x03\x03\x01\x03": "BCJ",
+	"\x03\x03\x01\x1b": "BCJ2",
+	"\x03\x03\x02\x05": "PPC",
+	"\x03\x03\x04\x01": "IA64",
+	"\x03\x03\x05\x01": "ARM",
+	"\x03\x03\x07\x01": "ARMT",
+	"\x03\x03\x08\x05": "SPARC",
+	"\x04\x01\x08":     "Deflate",
+	"\x04\x01\x09":     "Deflate64",
+	"\x04\x02\x02":     "BZip2",
+	"\x04\xf7\x11\x01": "ZSTD",
+	"\x04\xf7\x11\x02": "Brotli",
+	"\x04\xf7\x11\x04": "LZ4",
+	"\x06\xf1\x07\x01": "7zAES",
+	"\x21":             "LZMA2",
+}
+
+// sevenZipCoder folder 内的单个编码器
+type sevenZipCoder struct {
+	id     []byte
+	props  []byte
+	numIn  int
+	numOut int
+}
+
+// sevenZipFolderInfo 从头部读出的 folder 编码信息
+type sevenZipFolderInfo struct {
+	coders      []sevenZipCoder
+	bindPairs   [][2]int // 绑定对: [输入流序号, 输出流序号]
+	packed      []int    // 打包流依次对应的输入流序号
+	unpackSizes []uint64 // 每个输出流的大小
+}
+
+// Method 编码器链名称, 按解压后数据经过的顺序倒序排列, 如 LZMA2+7zAES
+func (f *sevenZipFolderInfo) Method() string {
+	names := make([]string, 0, len(f.coders))
+	for i := len(f.coders) - 1; i >= 0; i-- {
+		id := f.coders[i].id
+		name, ok := sevenZipMethodNames[string(id)]
+		if !ok {
+			name = fmt.Sprintf("%X", id)
+		}
+		names = append(names, name)
+	}
+	return strings.Join(names, "+")
+}
+
+// Encrypted 编码器链中是否包含 AES
+func (f *sevenZipFolderInfo) Encrypted() bool {
+	for _, coder := range f.coders {
+		if bytes.Equal(coder.id, sevenZipMethodAES) {
+			return true
+		}
+	}
+	return false
+}
+
+// readSevenZipFolders 读取 7z 头部中全部 folder 的编码信息, 顺序与 sevenzip.File.Stream 一致
+func readSevenZipFolders(r io.ReaderAt, size int64, password string) ([]sevenZipFolderInfo, error) {
+	sig := make([]byte, sevenZipSignatureHeaderSize)
+	if _, err := r.ReadAt(sig, 0); err != nil {
+		return nil, fmt.Errorf("读取 7z 签名头失败: %v", err)
+	}
+	if !bytes.Equal(sig[:len(sevenZipSignature)], sevenZipSignature) {
+		return nil, fmt.Errorf("不是 7z 压缩包")
+	}
+	offset := binary.LittleEndian.Uint64(sig[12:20])
+	length := binary.LittleEndian.Uint64(sig[20:28])
+	if offset > uint64(size) || length > uint64(size)-offset || sevenZipSignatureHeaderSize+offset+length > uint64(size) {
+		return nil, errSevenZipHeader
+	}
+	raw := make([]byte, length)
+	if _, err := r.ReadAt(raw, int64(sevenZipSignatureHeaderSize+offset)); err != nil {
+		return nil, fmt.Errorf("读取 7z 头部失败: %v", err)
+	}
+
+	h := &sevenZipHeaderReader{buf: raw}
+	id := h.byte()
+	if id == sevenZipIDEncodedHeader {
+		packPos, packSizes, folders := h.streamsInfo()
+		if h.err != nil {
+			return nil, h.err
+		}
+		if len(folders) != 1 {
+			return nil, errSevenZipHeader
+		}
+		decoded, err := folders[0].decode(r, sevenZipSignatureHeaderSize+int64(packPos), packSizes, password)
+		if err != nil {
+			return nil, fmt.Errorf("解码 7z 头部失败: %v", err)
+		}
+		h = &sevenZipHeaderReader{buf: decoded}
+		id = h.byte()
+	}
+	if id != sevenZipIDHeader {
+		return nil, errSevenZipHeader
+	}
+
+	for h.err == nil {
+		switch h.byte() {
+		case sevenZipIDArchiveProperties:
+			for h.err == nil && h.byte() != 0 {
+				h.bytes(h.number())
+			}
+		case sevenZipIDAdditionalStreams:
+			return nil, fmt.Errorf("不支持带附加流的 7z 头部")
+		case sevenZipIDMainStreamsInfo:
+			_, _, folders := h.streamsInfo()
+			return folders, h.err
+		default:
+			// 没有数据流, 全部是目录或空文件
+			return nil, h.err
+		}
+	}
+	return nil, h.err
+}
+
+// sevenZipHeaderReader 顺序读取头部字段, 越界后记录错误并返回零值
+type sevenZipHeaderReader struct {
+	buf []byte
+	pos int
+	err error
+}
+
+func (h *sevenZipHeaderReader) bytes(n uint64) []byte {
+	if h.err != nil || n > uint64(len(h.buf)-h.pos) {
+		h.err = errSevenZipHeader
+		return nil
+	}
+	b := h.buf[h.pos : h.pos+int(n)]
+	h.pos += int(n)
+	return b
+}
+
+func (h *sevenZipHeaderReader) byte() byte {
+	if b := h.bytes(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+// number 7z 变长整数, 编码方式见 writeSevenZipNumber
+func (h *sevenZipHeaderReader) number() uint64 {
+	first := h.byte()
+	mask := byte(0x80)
+	value := uint64(0)
+	for i := 0; i < 8; i++ {
+		if first&mask == 0 {
+			high := uint64(first & (mask - 1))
+			return value | high<<(8*i)
+		}
+		value |= uint64(h.byte()) << (8 * i)
+		mask >>= 1
+	}
+	return value
+}
+
+// count 读取数量并限制在剩余字节数内, 避免损坏的头部导致超大分配
+func (h *sevenZipHeaderReader) count() int {
+	n := h.number()
+	if n > uint64(len(h.buf)) {
+		h.err = errSevenZipHeader
+		return 0
+	}
+	return int(n)
+}
+
+// digests 跳过 CRC 列表: 全部定义标记 (+ 位图) + 每个已定义项 4 字节
+func (h *sevenZipHeaderReader) digests(n int) {
+	defined := n
+	if h.byte() == 0 {
+		defined = 0
+		for i, b := range h.bytes(uint64(n+7) / 8) {
+			for bit := 0; bit < 8 && i*8+bit < n; bit++ {
+				if b&(0x80>>bit) != 0 {
+					defined++
+				}
+			}
+		}
+	}
+	h.bytes(uint64(defined) * 4)
+}
+
+// packInfo 读取打包流的起始位置与大小
+func (h *sevenZipHeaderReader) packInfo() (uint64, []uint64) {
+	packPos := h.number()
+	packSizes := make([]uint64, h.count())
+	for h.err == nil {
+		switch h.byte() {
+		case sevenZipIDSize:
+			for i := range packSizes {
+				packSizes[i] = h.number()
+			}
+		case sevenZipIDCRC:
+			h.digests(len(packSizes))
+		case sevenZipIDEnd:
+			return packPos, packSizes
+		default:
+			h.err = errSevenZipHeader
+		}
+	}
+	return packPos, packSizes
+}
+
+// streamsInfo 读取 PackInfo 与 UnpackInfo, 子流信息用不到, 读到 UnpackInfo 结束即返回
+func (h *sevenZipHeaderReader) streamsInfo() (packPos uint64, packSizes []uint64, folders []sevenZipFolderInfo) {
+	for h.err == nil {
+		switch h.byte() {
+		case sevenZipIDPackInfo:
+			packPos, packSizes = h.packInfo()
+		case sevenZipIDUnpackInfo:
+			if h.byte() != sevenZipIDFolder {
+				h.err = errSevenZipHeader
+				return
+			}
+			folders = make([]sevenZipFolderInfo, h.count())
+			if h.byte() != 0 {
+				h.err = fmt.Errorf("不支持外部存储的 7z 头部")
+				return
+			}
+			for i := range folders {
+				folders[i] = h.folder()
+			}
+			for h.err == nil {
+				switch h.byte() {
+				case sevenZipIDCodersUnpack:
+					for i := range folders {
+						for j := range folders[i].unpackSizes {
+							folders[i].unpackSizes[j] = h.number()
+						}
+					}
+				case sevenZipIDCRC:
+					h.digests(len(folders))
+				case sevenZipIDEnd:
+					return
+				default:
+					h.err = errSevenZipHeader
+				}
+			}
+		default:
+			h.err = errSevenZipHeader
+		}
+	}
+	return
+}
+
+// folder 读取单个 folder: 编码器列表、绑定对与打包流序号
+func (h *sevenZipHeaderReader) folder() sevenZipFolderInfo {
+	var f sevenZipFolderInfo
+	totalIn, totalOut := 0, 0
+	numCoders := h.count()
+	for i := 0; i < numCoders && h.err == nil; i++ {
+		flag := h.byte()
+		coder := sevenZipCoder{id: h.bytes(uint64(flag & 0x0F)), numIn: 1, numOut: 1}
+		if flag&0x10 != 0 {
+			coder.numIn, coder.numOut = h.count(), h.count()
+		}
+		if flag&0x20 != 0 {
+			coder.props = h.bytes(h.number())
+		}
+		totalIn += coder.numIn
+		totalOut += coder.numOut
+		f.coders = append(f.coders, coder)
+	}
+	if h.err != nil || totalOut == 0 || totalIn < totalOut-1 {
+		h.err = errSevenZipHeader
+		return f
+	}
+	for i := 0; i < totalOut-1; i++ {
+		f.bindPairs = append(f.bindPairs, [2]int{h.count(), h.count()})
+	}
+	if numPacked := totalIn - (totalOut - 1); numPacked == 1 {
+		// 唯一的打包流对应未被绑定的输入流
+		for in := 0; in < totalIn; in++ {
+			if f.bindIn(in) < 0 {
+				f.packed = []int{in}
+				break
+			}
+		}
+	} else {
+		for i := 0; i < numPacked; i++ {
+			f.packed = append(f.packed, h.count())
+		}
+	}
+	f.unpackSizes = make([]uint64, totalOut)
+	return f
+}
+
+// bindIn 输入流绑定的输出流序号, 未绑定返回 -1
+func (f *sevenZipFolderInfo) bindIn(in int) int {
+	for _, pair := range f.bindPairs {
+		if pair[0] == in {
+			return pair[1]
+		}
+	}
+	return -1
+}
+
+// decode 解码编码头部所在的 folder, 只支持头部常用的单输入单输出编码器 (Copy/LZMA/LZMA2/7zAES)
+func (f *sevenZipFolderInfo) decode(r io.ReaderAt, offset int64, packSizes []uint64, password string) ([]byte, error) {
+	streams := make([]io.Reader, len(packSizes))
+	for i, size := range packSizes {
+		streams[i] = io.NewSectionReader(r, offset, int64(size))
+		offset += int64(size)
+	}
+
+	// 最终输出是没有被任何绑定对引用的输出流
+	final := -1
+	for out := range f.unpackSizes {
+		bound := false
+		for _, pair := range f.bindPairs {
+			bound = bound || pair[1] == out
+		}
+		if !bound {
+			final = out
+			break
+		}
+	}
+	if final < 0 {
+		return nil, errSevenZipHeader
+	}
+	for _, coder := range f.coders {
+		if coder.numIn != 1 || coder.numOut != 1 {
+			return nil, fmt.Errorf("不支持多输入输出的编码器")
+		}
+	}
+	reader, err := f.open(streams, final, password, len(f.coders))
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(io.LimitReader(reader, int64(f.unpackSizes[final])))
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(data)) != f.unpackSizes[final] {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return data, nil
+}
+
+// open 按绑定关系从输出流 out 向打包流回溯, 逐级套上解码器, depth 防止损坏头部中的循环绑定
+func (f *sevenZipFolderInfo) open(streams []io.Reader, out int, password string, depth int) (io.Reader, error) {
+	// 编码器都是单输入单输出, 第 i 个编码器的输入输出流序号都是 i
+	if depth < 0 || out >= len(f.coders) {
+		return nil, errSevenZipHeader
+	}
+	in := out
+
+	var source io.Reader
+	if bound := f.bindIn(in); bound >= 0 {
+		reader, err := f.open(streams, bound, password, depth-1)
+		if err != nil {
+			return nil, err
+		}
+		source = reader
+	} else {
+		for i, packed := range f.packed {
+			if packed == in && i < len(streams) {
+				source = streams[i]
+			}
+		}
+		if source == nil {
+			return nil, errSevenZipHeader
+		}
+	}
+
+	coder := f.coders[out]
+	switch string(coder.id) {
+	case "\x00":
+		return source, nil
+	case "\x03\x01\x01":
+		// 补出 .lzma 文件头: 参数 (5 字节) + 解压后大小
+		if len(coder.props) != 5 {
+			return nil, errSevenZipHeader
+		}
+		header := binary.LittleEndian.AppendUint64(append([]byte{}, coder.props...), f.unpackSizes[out])
+		return lzma.NewReader(io.MultiReader(bytes.NewReader(header), source))
+	case string(sevenZipMethodLZMA):
+		if len(coder.props) != 1 || coder.props[0] > 40 {
+			return nil, errSevenZipHeader
+		}
+		dictCap := 1 << 31
+		if p := coder.props[0]; p < 40 {
+			dictCap = (2 | int(p&1)) << (p/2 + 11)
+		}
+		return lzma.Reader2Config{DictCap: dictCap}.NewReader2(source)
+	case string(sevenZipMethodAES):
+		return openSevenZipAES(source, coder.props, password)
+	}
+	return nil, fmt.Errorf("不支持的头部编码器 %X", coder.id)
+}
+
+// openSevenZipAES 解密 7zAES 流, 头部数据量小, 整体读入后解密
+// 参数: 首字节低 6 位为轮数, 0x80/0x40 表示含盐值/IV; 次字节高低 4 位为盐值/IV 的附加长度
+func openSevenZipAES(source io.Reader, props []byte, password string) (io.Reader, error) {
+	if password == "" {
+		return nil, fmt.Errorf("头部已加密, 需要密码")
+	}
+	if len(props) < 1 {
+		return nil, errSevenZipHeader
+	}
+	cycles := int(props[0] & 0x3F)
+	var salt []byte
+	iv := make([]byte, aes.BlockSize)
+	if props[0]&0xC0 != 0 {
+		if len(props) < 2 {
+			return nil, errSevenZipHeader
+		}
+		saltSize := int(props[0]>>7&1) + int(props[1]>>4)
+		ivSize := int(props[0]>>6&1) + int(props[1]&0x0F)
+		if len(props) < 2+saltSize+ivSize {
+			return nil, errSevenZipHeader
+		}
+		salt = props[2 : 2+saltSize]
+		copy(iv, props[2+saltSize:2+saltSize+ivSize])
+	}
+	if cycles == 0x3F || cycles > 24 {
+		return nil, fmt.Errorf("不支持的 7zAES 轮数参数")
+	}
+
+	data, err := io.ReadAll(source)
+	if err != nil {
+		return nil, err
+	}
+	if len(data)%aes.BlockSize != 0 {
+		return nil, errSevenZipHeader
+	}
+	block, err := aes.NewCipher(sevenZipDeriveKey(password, salt, cycles))
+	if err != nil {
+		return nil, err
+	}
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(data, data)
+	return bytes.NewReader(data), nil
+}
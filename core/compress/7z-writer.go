@@ -0,0 +1,578 @@
+// Package compress /core/compress/7z-writer.go
+package compress
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"time"
+	"unicode/utf16"
+
+	"github.com/klauspost/crc32"
+	"github.com/ulikunitz/xz/lzma"
+)
+
+// 7z 文件结构 (全部为小端序):
+//   签名头 32 字节: 签名(6) + 版本(2) + 起始头 CRC(4) + 下一头偏移(8) + 下一头大小(8) + 下一头 CRC(4)
+//   打包流: 每个 folder (固实块) 的压缩数据依次排列
+//   头部: 描述打包流/folder/子流/文件信息的属性树, 可以再被编码 (压缩/加密) 成一个打包流
+// 头部中的整数使用 7z 特有的变长编码, 详见 writeSevenZipNumber.
+
+// 7z 头部属性 ID
+const (
+	sevenZipIDEnd             = 0x00
+	sevenZipIDHeader          = 0x01
+	sevenZipIDMainStreamsInfo = 0x04
+	sevenZipIDFilesInfo       = 0x05
+	sevenZipIDPackInfo        = 0x06
+	sevenZipIDUnpackInfo      = 0x07
+	sevenZipIDSubStreamsInfo  = 0x08
+	sevenZipIDSize            = 0x09
+	sevenZipIDCRC             = 0x0A
+	sevenZipIDFolder          = 0x0B
+	sevenZipIDCodersUnpack    = 0x0C
+	sevenZipIDNumUnpackStream = 0x0D
+	sevenZipIDEmptyStream     = 0x0E
+	sevenZipIDEmptyFile       = 0x0F
+	sevenZipIDName            = 0x11
+	sevenZipIDMTime           = 0x14
+	sevenZipIDWinAttributes   = 0x15
+	sevenZipIDEncodedHeader   = 0x17
+)
+
+// 7z 编码器 ID 与参数
+var (
+	sevenZipSignature  = []byte{'7', 'z', 0xBC, 0xAF, 0x27, 0x1C}
+	sevenZipMethodLZMA = []byte{0x21}                   // LZMA2
+	sevenZipMethodAES  = []byte{0x06, 0xF1, 0x07, 0x01} // 7zAES: AES-256-CBC + SHA-256 密钥派生
+)
+
+const (
+	sevenZipSignatureHeaderSize = 32
+	sevenZipDictSize            = 8 * 1024 * 1024 // LZMA2 字典大小
+	sevenZipAESCycles           = 19              // 密钥派生 2^19 轮 SHA-256, 与 7-Zip 默认值一致
+	sevenZipAttrDirectory       = 0x10            // FILE_ATTRIBUTE_DIRECTORY
+	sevenZipAttrArchive         = 0x20            // FILE_ATTRIBUTE_ARCHIVE
+	sevenZipAttrUnixExtension   = 0x8000          // 高 16 位存放 Unix 权限位
+)
+
+// sevenZipFile 7z 内单个文件的头部信息
+type sevenZipFile struct {
+	name      string
+	size      uint64
+	crc       uint32
+	modTime   time.Time
+	mode      os.FileMode
+	hasStream bool // 数据是否存放在某个固实块中
+}
+
+// sevenZipFolder 一个固实块的编码信息
+type sevenZipFolder struct {
+	packSize     uint64   // 打包流大小
+	unpackSizes  []uint64 // 每个编码器输出流大小, 顺序与 coders 一致
+	aesProps     []byte   // AES 编码器参数, 为空表示未加密
+	lzmaProps    byte     // LZMA2 字典参数
+	subStreams   int      // 该固实块包含的文件数量
+	subSizes     []uint64 // 各文件大小
+	subCRCs      []uint32 // 各文件 CRC32
+	unpackCRC    uint32   // 整个固实块解压数据的 CRC32 (仅编码头部时使用)
+	hasUnpackCRC bool
+}
+
+// sevenZipOutput 7z 输出文件或分卷写入器, 写完头部后需回填开头的签名头
+type sevenZipOutput interface {
+	io.WriteCloser
+	io.WriterAt
+}
+
+// sevenZipWriter 7z 归档写入器
+// 打包流顺序写入, 头部在 Close 时写到文件末尾, 最后回填签名头
+type sevenZipWriter struct {
+	f             sevenZipOutput
+	packed        uint64 // 已写入的打包流总字节数
+	files         []sevenZipFile
+	folders       []*sevenZipFolder
+	aesKey        []byte // 为空表示不加密
+	encryptHeader bool
+
+	// 当前固实块
+	cur     *sevenZipFolder
+	curEnc  *sevenZipFolderEncoder
+	curSize uint64
+}
+
+// newSevenZipWriter 创建 7z 写入器并预留签名头
+// password: 为空表示不加密; encryptHeader: 是否同时加密头部 (文件名等信息)
+func newSevenZipWriter(f sevenZipOutput, password string, encryptHeader bool) (*sevenZipWriter, error) {
+	w := &sevenZipWriter{f: f, encryptHeader: encryptHeader && password != ""}
+	if password != "" {
+		w.aesKey = sevenZipDeriveKey(password, nil, sevenZipAESCycles)
+	}
+	// 预留签名头, 写完头部后回填
+	if _, err := f.Write(make([]byte, sevenZipSignatureHeaderSize)); err != nil {
+		return nil, fmt.Errorf("写入 7z 签名头失败: %v", err)
+	}
+	return w, nil
+}
+
+// addEmpty 记录空文件 (无数据流)
+func (w *sevenZipWriter) addEmpty(name string, modTime time.Time, mode os.FileMode) {
+	w.files = append(w.files, sevenZipFile{name: name, modTime: modTime, mode: mode})
+}
+
+// beginFile 开始写入一个非空文件, 必要时开启新的固实块
+// solidSize: 固实块大小上限, <=0 表示所有文件放在同一个固实块
+func (w *sevenZipWriter) beginFile(solidSize int64) error {
+	if w.cur != nil && solidSize > 0 && w.curSize >= uint64(solidSize) {
+		if err := w.closeFolder(); err != nil {
+			return err
+		}
+	}
+	if w.cur == nil {
+		enc, err := newSevenZipFolderEncoder(w.f, w.aesKey)
+		if err != nil {
+			return err
+		}
+		w.cur, w.curEnc, w.curSize = &sevenZipFolder{}, enc, 0
+	}
+	return nil
+}
+
+// Write 向当前文件写入数据
+func (w *sevenZipWriter) Write(p []byte) (int, error) {
+	n, err := w.curEnc.Write(p)
+	w.curSize += uint64(n)
+	return n, err
+}
+
+// endFile 结束当前文件, 记录大小与 CRC32
+func (w *sevenZipWriter) endFile(name string, size uint64, crc uint32, modTime time.Time, mode os.FileMode) {
+	w.files = append(w.files, sevenZipFile{name: name, size: size, crc: crc, modTime: modTime, mode: mode, hasStream: true})
+	w.cur.subStreams++
+	w.cur.subSizes = append(w.cur.subSizes, size)
+	w.cur.subCRCs = append(w.cur.subCRCs, crc)
+}
+
+// closeFolder 结束当前固实块
+func (w *sevenZipWriter) closeFolder() error {
+	if w.cur == nil {
+		return nil
+	}
+	if err := w.curEnc.close(w.cur); err != nil {
+		return err
+	}
+	w.packed += w.cur.packSize
+	w.folders = append(w.folders, w.cur)
+	w.cur, w.curEnc = nil, nil
+	return nil
+}
+
+// Close 写入头部并回填签名头
+func (w *sevenZipWriter) Close() error {
+	if err := w.closeFolder(); err != nil {
+		return err
+	}
+
+	header := w.buildHeader()
+	headerOffset := w.packed
+
+	// 加密头部: 把头部当作一个固实块编码, 再写入一个描述它的 EncodedHeader
+	if w.encryptHeader {
+		enc, err := newSevenZipFolderEncoder(w.f, w.aesKey)
+		if err != nil {
+			return err
+		}
+		if _, err := enc.Write(header); err != nil {
+			return fmt.Errorf("写入 7z 加密头部失败: %v", err)
+		}
+		folder := &sevenZipFolder{unpackCRC: crc32.ChecksumIEEE(header), hasUnpackCRC: true}
+		if err := enc.close(folder); err != nil {
+			return err
+		}
+
+		encoded := &bytes.Buffer{}
+		encoded.WriteByte(sevenZipIDEncodedHeader)
+		writeSevenZipStreamsInfo(encoded, headerOffset, []*sevenZipFolder{folder}, false)
+		headerOffset += folder.packSize
+		header = encoded.Bytes()
+	}
+
+	if _, err := w.f.Write(header); err != nil {
+		return fmt.Errorf("写入 7z 头部失败: %v", err)
+	}
+
+	// 回填签名头
+	start := make([]byte, 20)
+	binary.LittleEndian.PutUint64(start[0:], headerOffset)
+	binary.LittleEndian.PutUint64(start[8:], uint64(len(header)))
+	binary.LittleEndian.PutUint32(start[16:], crc32.ChecksumIEEE(header))
+
+	sig := make([]byte, 0, sevenZipSignatureHeaderSize)
+	sig = append(sig, sevenZipSignature...)
+	sig = append(sig, 0, 4) // 格式版本 0.4
+	sig = binary.LittleEndian.AppendUint32(sig, crc32.ChecksumIEEE(start))
+	sig = append(sig, start...)
+	if _, err := w.f.WriteAt(sig, 0); err != nil {
+		return fmt.Errorf("回填 7z 签名头失败: %v", err)
+	}
+	return nil
+}
+
+// buildHeader 生成未编码的头部
+func (w *sevenZipWriter) buildHeader() []byte {
+	buf := &bytes.Buffer{}
+	buf.WriteByte(sevenZipIDHeader)
+	if len(w.folders) > 0 {
+		buf.WriteByte(sevenZipIDMainStreamsInfo)
+		writeSevenZipStreamsInfo(buf, 0, w.folders, true)
+	}
+	if len(w.files) > 0 {
+		w.writeFilesInfo(buf)
+	}
+	buf.WriteByte(sevenZipIDEnd)
+	return buf.Bytes()
+}
+
+// writeFilesInfo 写入文件信息: 空流标记、文件名、修改时间、属性
+func (w *sevenZipWriter) writeFilesInfo(buf *bytes.Buffer) {
+	buf.WriteByte(sevenZipIDFilesInfo)
+	writeSevenZipNumber(buf, uint64(len(w.files)))
+
+	// 空流 (目录/空文件) 标记
+	emptyStream := make([]bool, len(w.files))
+	var emptyFile []bool
+	hasEmpty := false
+	for i, file := range w.files {
+		if !file.hasStream {
+			emptyStream[i] = true
+			emptyFile = append(emptyFile, !file.mode.IsDir())
+			hasEmpty = true
+		}
+	}
+	if hasEmpty {
+		writeSevenZipProperty(buf, sevenZipIDEmptyStream, packSevenZipBools(emptyStream))
+		writeSevenZipProperty(buf, sevenZipIDEmptyFile, packSevenZipBools(emptyFile))
+	}
+
+	// 文件名: UTF-16LE 以 0 结尾
+	names := &bytes.Buffer{}
+	names.WriteByte(0) // 非外部存储
+	for _, file := range w.files {
+		for _, r := range utf16.Encode([]rune(file.name)) {
+			_ = binary.Write(names, binary.LittleEndian, r)
+		}
+		names.Write([]byte{0, 0})
+	}
+	writeSevenZipProperty(buf, sevenZipIDName, names.Bytes())
+
+	// 修改时间: Windows FILETIME
+	times := &bytes.Buffer{}
+	times.Write([]byte{1, 0}) // 全部定义, 非外部存储
+	for _, file := range w.files {
+		_ = binary.Write(times, binary.LittleEndian, sevenZipFiletime(file.modTime))
+	}
+	writeSevenZipProperty(buf, sevenZipIDMTime, times.Bytes())
+
+	// 属性: Windows 属性 + Unix 扩展权限位
+	attrs := &bytes.Buffer{}
+	attrs.Write([]byte{1, 0})
+	for _, file := range w.files {
+		_ = binary.Write(attrs, binary.LittleEndian, sevenZipAttributes(file.mode))
+	}
+	writeSevenZipProperty(buf, sevenZipIDWinAttributes, attrs.Bytes())
+
+	buf.WriteByte(sevenZipIDEnd)
+}
+
+// writeSevenZipStreamsInfo 写入 PackInfo/UnpackInfo/SubStreamsInfo
+// packPos: 首个打包流相对签名头末尾的偏移; withSubStreams: 是否写入子流 (文件) 信息
+func writeSevenZipStreamsInfo(buf *bytes.Buffer, packPos uint64, folders []*sevenZipFolder, withSubStreams bool) {
+	// PackInfo
+	buf.WriteByte(sevenZipIDPackInfo)
+	writeSevenZipNumber(buf, packPos)
+	writeSevenZipNumber(buf, uint64(len(folders)))
+	buf.WriteByte(sevenZipIDSize)
+	for _, folder := range folders {
+		writeSevenZipNumber(buf, folder.packSize)
+	}
+	buf.WriteByte(sevenZipIDEnd)
+
+	// UnpackInfo
+	buf.WriteByte(sevenZipIDUnpackInfo)
+	buf.WriteByte(sevenZipIDFolder)
+	writeSevenZipNumber(buf, uint64(len(folders)))
+	buf.WriteByte(0) // 非外部存储
+	for _, folder := range folders {
+		writeSevenZipFolder(buf, folder)
+	}
+	buf.WriteByte(sevenZipIDCodersUnpack)
+	for _, folder := range folders {
+		for _, size := range folder.unpackSizes {
+			writeSevenZipNumber(buf, size)
+		}
+	}
+	hasCRC := false
+	for _, folder := range folders {
+		hasCRC = hasCRC || folder.hasUnpackCRC
+	}
+	if hasCRC {
+		buf.WriteByte(sevenZipIDCRC)
+		buf.WriteByte(1) // 全部定义
+		for _, folder := range folders {
+			_ = binary.Write(buf, binary.LittleEndian, folder.unpackCRC)
+		}
+	}
+	buf.WriteByte(sevenZipIDEnd)
+
+	// SubStreamsInfo
+	if withSubStreams {
+		buf.WriteByte(sevenZipIDSubStreamsInfo)
+		buf.WriteByte(sevenZipIDNumUnpackStream)
+		for _, folder := range folders {
+			writeSevenZipNumber(buf, uint64(folder.subStreams))
+		}
+		// 每个固实块只写前 n-1 个文件大小, 最后一个由总大小推算
+		buf.WriteByte(sevenZipIDSize)
+		for _, folder := range folders {
+			for _, size := range folder.subSizes[:len(folder.subSizes)-1] {
+				writeSevenZipNumber(buf, size)
+			}
+		}
+		buf.WriteByte(sevenZipIDCRC)
+		buf.WriteByte(1)
+		for _, folder := range folders {
+			for _, crc := range folder.subCRCs {
+				_ = binary.Write(buf, binary.LittleEndian, crc)
+			}
+		}
+		buf.WriteByte(sevenZipIDEnd)
+	}
+
+	buf.WriteByte(sevenZipIDEnd)
+}
+
+// writeSevenZipFolder 写入 folder 的编码器链
+// 加密时编码器顺序为 [AES, LZMA2], AES 读取打包流, 其输出绑定到 LZMA2 的输入
+func writeSevenZipFolder(buf *bytes.Buffer, folder *sevenZipFolder) {
+	if folder.aesProps == nil {
+		writeSevenZipNumber(buf, 1)
+		writeSevenZipCoder(buf, sevenZipMethodLZMA, []byte{folder.lzmaProps})
+		return
+	}
+	writeSevenZipNumber(buf, 2)
+	writeSevenZipCoder(buf, sevenZipMethodAES, folder.aesProps)
+	writeSevenZipCoder(buf, sevenZipMethodLZMA, []byte{folder.lzmaProps})
+	// 绑定对: LZMA2 的输入流 (1) ← AES 的输出流 (0)
+	writeSevenZipNumber(buf, 1)
+	writeSevenZipNumber(buf, 0)
+}
+
+// writeSevenZipCoder 写入单个编码器: 标志字节 (ID 长度 | 0x20 有参数) + ID + 参数
+func writeSevenZipCoder(buf *bytes.Buffer, id []byte, props []byte) {
+	buf.WriteByte(byte(len(id)) | 0x20)
+	buf.Write(id)
+	writeSevenZipNumber(buf, uint64(len(props)))
+	buf.Write(props)
+}
+
+// writeSevenZipProperty 写入文件属性: ID + 长度 + 数据
+func writeSevenZipProperty(buf *bytes.Buffer, id byte, data []byte) {
+	buf.WriteByte(id)
+	writeSevenZipNumber(buf, uint64(len(data)))
+	buf.Write(data)
+}
+
+// writeSevenZipNumber 7z 变长整数编码
+// 首字节高位连续 1 的个数表示后续字节数, 首字节剩余位存放数值的最高位部分
+func writeSevenZipNumber(buf *bytes.Buffer, value uint64) {
+	first := byte(0)
+	mask := byte(0x80)
+	i := 0
+	for ; i < 8; i++ {
+		if value < uint64(1)<<(7*(i+1)) {
+			first |= byte(value >> (8 * i))
+			break
+		}
+		first |= mask
+		mask >>= 1
+	}
+	buf.WriteByte(first)
+	for ; i > 0; i-- {
+		buf.WriteByte(byte(value))
+		value >>= 8
+	}
+}
+
+// packSevenZipBools 按位打包布尔数组, 高位在前
+func packSevenZipBools(values []bool) []byte {
+	out := make([]byte, (len(values)+7)/8)
+	for i, v := range values {
+		if v {
+			out[i/8] |= 0x80 >> (i % 8)
+		}
+	}
+	return out
+}
+
+// sevenZipFiletime 转换为 Windows FILETIME (1601-01-01 起的 100 纳秒数)
+func sevenZipFiletime(t time.Time) uint64 {
+	const epochDiff = 116444736000000000
+	return uint64(t.UnixNano()/100 + epochDiff)
+}
+
+// sevenZipAttributes 生成文件属性
+func sevenZipAttributes(mode os.FileMode) uint32 {
+	attr := uint32(sevenZipAttrUnixExtension)
+	unixMode := uint32(mode.Perm())
+	if mode.IsDir() {
+		attr |= sevenZipAttrDirectory
+		unixMode |= 0x4000 // S_IFDIR
+	} else {
+		attr |= sevenZipAttrArchive
+		unixMode |= 0x8000 // S_IFREG
+	}
+	return attr | unixMode<<16
+}
+
+// sevenZipLZMA2Prop 计算 LZMA2 字典参数字节
+func sevenZipLZMA2Prop(dictSize int) byte {
+	for p := byte(0); p < 40; p++ {
+		if uint64(2|uint64(p&1))<<(p/2+11) >= uint64(dictSize) {
+			return p
+		}
+	}
+	return 40
+}
+
+// sevenZipDeriveKey 7zAES 密钥派生
+// SHA-256(循环 2^cycles 次: 盐值 + UTF-16LE 密码 + 8 字节小端计数器)
+func sevenZipDeriveKey(password string, salt []byte, cycles int) []byte {
+	data := append([]byte{}, salt...)
+	for _, r := range utf16.Encode([]rune(password)) {
+		data = binary.LittleEndian.AppendUint16(data, r)
+	}
+	counter := len(data)
+	data = append(data, make([]byte, 8)...)
+
+	hash := sha256.New()
+	for i := uint64(0); i < 1<<cycles; i++ {
+		binary.LittleEndian.PutUint64(data[counter:], i)
+		hash.Write(data)
+	}
+	return hash.Sum(nil)
+}
+
+// ============================== 固实块编码 ==============================
+
+// sevenZipCountWriter 统计写入字节数
+type sevenZipCountWriter struct {
+	w io.Writer
+	n uint64
+}
+
+func (c *sevenZipCountWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += uint64(n)
+	return n, err
+}
+
+// sevenZipCBCWriter AES-256-CBC 加密写入器, 结束时以 0 填充到块大小
+type sevenZipCBCWriter struct {
+	w    io.Writer
+	mode cipher.BlockMode
+	buf  []byte
+}
+
+func (c *sevenZipCBCWriter) Write(p []byte) (int, error) {
+	c.buf = append(c.buf, p...)
+	full := len(c.buf) / aes.BlockSize * aes.BlockSize
+	if full > 0 {
+		c.mode.CryptBlocks(c.buf[:full], c.buf[:full])
+		if _, err := c.w.Write(c.buf[:full]); err != nil {
+			return 0, err
+		}
+		c.buf = append(c.buf[:0], c.buf[full:]...)
+	}
+	return len(p), nil
+}
+
+func (c *sevenZipCBCWriter) Close() error {
+	if len(c.buf) == 0 {
+		return nil
+	}
+	block := make([]byte, aes.BlockSize)
+	copy(block, c.buf)
+	c.mode.CryptBlocks(block, block)
+	_, err := c.w.Write(block)
+	return err
+}
+
+// sevenZipFolderEncoder 固实块编码流水线: 明文 → LZMA2 → (AES-CBC) → 文件
+type sevenZipFolderEncoder struct {
+	lzma     *lzma.Writer2
+	packed   *sevenZipCountWriter // 最终写入文件的字节数
+	lzmaOut  *sevenZipCountWriter // LZMA2 输出字节数 (即 AES 输入)
+	cbc      *sevenZipCBCWriter
+	aesProps []byte
+	unpacked uint64
+}
+
+// newSevenZipFolderEncoder 创建固实块编码器, key 为空表示不加密
+func newSevenZipFolderEncoder(w io.Writer, key []byte) (*sevenZipFolderEncoder, error) {
+	enc := &sevenZipFolderEncoder{packed: &sevenZipCountWriter{w: w}}
+	var lzmaDst io.Writer = enc.packed
+
+	if key != nil {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("初始化 7z AES 加密失败: %v", err)
+		}
+		iv := make([]byte, aes.BlockSize)
+		if _, err := io.ReadFull(rand.Reader, iv); err != nil {
+			return nil, fmt.Errorf("生成 7z AES IV 失败: %v", err)
+		}
+		enc.cbc = &sevenZipCBCWriter{w: enc.packed, mode: cipher.NewCBCEncrypter(block, iv)}
+		// 参数: 首字节 = 轮数 | 0x40 (含 IV), 次字节 = IV 长度 - 1, 随后为 IV (无盐值)
+		enc.aesProps = append([]byte{sevenZipAESCycles | 0x40, aes.BlockSize - 1}, iv...)
+		lzmaDst = enc.cbc
+	}
+	enc.lzmaOut = &sevenZipCountWriter{w: lzmaDst}
+
+	lw, err := lzma.Writer2Config{DictCap: sevenZipDictSize}.NewWriter2(enc.lzmaOut)
+	if err != nil {
+		return nil, fmt.Errorf("初始化 LZMA2 编码器失败: %v", err)
+	}
+	enc.lzma = lw
+	return enc, nil
+}
+
+func (e *sevenZipFolderEncoder) Write(p []byte) (int, error) {
+	n, err := e.lzma.Write(p)
+	e.unpacked += uint64(n)
+	return n, err
+}
+
+// close 结束编码并把大小、参数写入 folder
+func (e *sevenZipFolderEncoder) close(folder *sevenZipFolder) error {
+	if err := e.lzma.Close(); err != nil {
+		return fmt.Errorf("结束 LZMA2 编码失败: %v", err)
+	}
+	if e.cbc != nil {
+		if err := e.cbc.Close(); err != nil {
+			return fmt.Errorf("结束 7z AES 加密失败: %v", err)
+		}
+		folder.unpackSizes = []uint64{e.lzmaOut.n, e.unpacked}
+	} else {
+		folder.unpackSizes = []uint64{e.unpacked}
+	}
+	folder.packSize = e.packed.n
+	folder.aesProps = e.aesProps
+	folder.lzmaProps = sevenZipLZMA2Prop(sevenZipDictSize)
+	return nil
+}
@@ -0,0 +1,254 @@
+package compress
+
+import (
+	"bytes"
+	"hash/crc32"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/bodgit/sevenzip"
+)
+
+// testTreeFiles 测试用源文件: 可压缩文本、随机数据、空文件与子目录
+func testTreeFiles() map[string][]byte {
+	random := make([]byte, 96*1024)
+	rand.New(rand.NewSource(1)).Read(random)
+	return map[string][]byte{
+		"docs/readme.txt":   []byte(strings.Repeat("gf-file-tool 7z round trip\n", 2000)),
+		"docs/empty.txt":    {},
+		"data/random.bin":   random,
+		"data/small.txt":    []byte("hello"),
+		"data/nested/a.txt": []byte(strings.Repeat("a", 5000)),
+	}
+}
+
+// writeTestTree 在临时目录下写出源文件, 返回按路径排序的压缩条目 (含目录)
+func writeTestTree(t *testing.T, files map[string][]byte) []SourceEntry {
+	t.Helper()
+	root := t.TempDir()
+	dirs := map[string]bool{}
+	var entries []SourceEntry
+	for name, data := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, SourceEntry{Path: path, Name: name})
+		for dir := filepath.ToSlash(filepath.Dir(name)); dir != "."; dir = filepath.ToSlash(filepath.Dir(dir)) {
+			if !dirs[dir] {
+				dirs[dir] = true
+				entries = append(entries, SourceEntry{Path: filepath.Join(root, filepath.FromSlash(dir)), Name: dir, Dir: true})
+			}
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	return entries
+}
+
+// readSevenZipFiles 读出 7z 内全部文件内容, 同时核对头部记录的 CRC32
+func readSevenZipFiles(t *testing.T, reader *sevenzip.Reader) map[string][]byte {
+	t.Helper()
+	files := map[string][]byte{}
+	for _, file := range reader.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+		rc, err := file.Open()
+		if err != nil {
+			t.Fatalf("打开 %s 失败: %v", file.Name, err)
+		}
+		data, err := io.ReadAll(rc)
+		_ = rc.Close()
+		if err != nil {
+			t.Fatalf("读取 %s 失败: %v", file.Name, err)
+		}
+		if len(data) > 0 && crc32.ChecksumIEEE(data) != file.CRC32 {
+			t.Fatalf("%s 的 CRC32 与头部记录不一致", file.Name)
+		}
+		files[file.Name] = data
+	}
+	return files
+}
+
+func assertSameFiles(t *testing.T, got, want map[string][]byte) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("文件数 = %d, 期望 %d", len(got), len(want))
+	}
+	for name, data := range want {
+		if !bytes.Equal(got[name], data) {
+			t.Errorf("%s 内容不一致: %d 字节, 期望 %d 字节", name, len(got[name]), len(data))
+		}
+	}
+}
+
+func TestSevenZipWriterRoundTrip(t *testing.T) {
+	cases := []struct {
+		name          string
+		password      string
+		encryptHeader bool
+		solidSize     int64
+		method        string
+	}{
+		{name: "plain", method: "LZMA2"},
+		{name: "encrypt", password: "secret", method: "LZMA2+7zAES"},
+		{name: "encrypt-header", password: "secret", encryptHeader: true, method: "LZMA2+7zAES"},
+		{name: "solid-size", solidSize: 1, method: "LZMA2"},
+	}
+	files := testTreeFiles()
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			output := filepath.Join(t.TempDir(), "out.7z")
+			opts := &CompressOptions{
+				Entries:       writeTestTree(t, files),
+				OutputPath:    output,
+				Format:        "7z",
+				Encrypt:       c.password != "",
+				Password:      c.password,
+				EncryptHeader: c.encryptHeader,
+				SolidSize:     c.solidSize,
+			}
+			if err := (&SevenZipCompressor{}).Compress(opts); err != nil {
+				t.Fatal(err)
+			}
+
+			reader, err := sevenzip.OpenReaderWithPassword(output, c.password)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer reader.Close()
+			assertSameFiles(t, readSevenZipFiles(t, &reader.Reader), files)
+
+			// 固实块划分: 默认全部文件一个块, 固实块大小为 1 时每个非空文件一个块
+			streams := map[int]bool{}
+			nonEmpty := 0
+			for _, file := range reader.File {
+				if !file.FileInfo().IsDir() && file.UncompressedSize > 0 {
+					streams[file.Stream] = true
+					nonEmpty++
+				}
+			}
+			want := 1
+			if c.solidSize > 0 {
+				want = nonEmpty
+			}
+			if len(streams) != want {
+				t.Fatalf("固实块数量 = %d, 期望 %d", len(streams), want)
+			}
+
+			// 编码器链由 list 使用的头部读取器解析
+			in, err := os.Open(output)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer in.Close()
+			info, _ := in.Stat()
+			folders, err := readSevenZipFolders(in, info.Size(), c.password)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(folders) != len(streams) {
+				t.Fatalf("头部 folder 数量 = %d, 期望 %d", len(folders), len(streams))
+			}
+			for _, folder := range folders {
+				if folder.Method() != c.method {
+					t.Fatalf("编码器链 = %s, 期望 %s", folder.Method(), c.method)
+				}
+			}
+
+			if c.password == "" {
+				return
+			}
+			// 不带密码: 加密头部时无法打开, 否则能列出文件名但无法读取内容
+			plain, err := sevenzip.OpenReader(output)
+			if c.encryptHeader {
+				if err == nil {
+					plain.Close()
+					t.Fatal("加密头部的压缩包不带密码也能打开")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer plain.Close()
+			for _, file := range plain.File {
+				if file.UncompressedSize == 0 {
+					continue
+				}
+				// 错误的密钥解出的数据偶尔也能通过 LZMA2 解码, 以读出的内容是否为明文判断
+				rc, err := file.Open()
+				var data []byte
+				if err == nil {
+					data, err = io.ReadAll(rc)
+					rc.Close()
+				}
+				if err == nil && bytes.Equal(data, files[file.Name]) {
+					t.Fatalf("不带密码读出了加密文件 %s", file.Name)
+				}
+			}
+		})
+	}
+}
+
+func TestSevenZipWriterSplitVolumes(t *testing.T) {
+	random := make([]byte, 1024)
+	rand.New(rand.NewSource(2)).Read(random)
+	files := map[string][]byte{
+		"split/text.txt":   []byte(strings.Repeat("split volume ", 300)),
+		"split/random.bin": random,
+	}
+	output := filepath.Join(t.TempDir(), "split.7z")
+	// 分卷小于 32 字节的签名头, 签名头跨越第一、二卷
+	const volumeSize = 24
+	opts := &CompressOptions{
+		Entries:    writeTestTree(t, files),
+		OutputPath: output,
+		Format:     "7z",
+		SplitSize:  volumeSize,
+	}
+	if err := (&SevenZipCompressor{}).Compress(opts); err != nil {
+		t.Fatal(err)
+	}
+
+	volumes, err := filepath.Glob(output + ".[0-9][0-9][0-9]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(volumes) < 3 {
+		t.Fatalf("分卷数量 = %d, 期望至少 3", len(volumes))
+	}
+	sort.Strings(volumes)
+	for i, path := range volumes {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i < len(volumes)-1 && info.Size() != volumeSize || info.Size() > volumeSize {
+			t.Fatalf("%s 大小 = %d, 分卷大小 %d", filepath.Base(path), info.Size(), volumeSize)
+		}
+	}
+
+	// 签名头回写到已关闭的分卷后, 说明文件中的校验值仍与分卷内容一致
+	if err := verifySplitVolumes(volumes[0], false); err != nil {
+		t.Fatal(err)
+	}
+
+	reader, err := sevenzip.OpenReader(volumes[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	if len(reader.Volumes()) != len(volumes) {
+		t.Fatalf("sevenzip 打开了 %d 个分卷, 期望 %d", len(reader.Volumes()), len(volumes))
+	}
+	assertSameFiles(t, readSevenZipFiles(t, &reader.Reader), files)
+}
@@ -1,7 +1,514 @@
 // Package compress /core/compress/7z.go
 package compress
 
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/GoFurry/gf-file-tool/progress"
+	"github.com/GoFurry/gf-file-tool/utils"
+	"github.com/GoFurry/gf-file-tool/utils/compress"
+	"github.com/GoFurry/gf-file-tool/utils/log"
+	"github.com/bodgit/sevenzip"
+	"github.com/klauspost/crc32"
+)
+
 // 7z 是一种热门压缩格式, Golang 原生库不支持, 具有一定难度, 适合作为此项目的学生练手部分.
 
+// ============================== 7z 压缩部分 ==============================
+
 // SevenZipCompressor 7z 压缩器
+// 原生实现 7z 写入: LZMA2 编码、固实块、可选 AES-256 加密 (含头部加密)
 type SevenZipCompressor struct{}
+
+// Compress 7z 压缩逻辑
+func (s *SevenZipCompressor) Compress(opts *CompressOptions) error {
+	// 头部写在文件末尾并需回写签名头, 输出必须可 seek
+	if IsStdio(opts.OutputPath) {
+		return fmt.Errorf("7z 格式需要随机写入, 不支持输出到标准输出, 请使用 zip 或 tar 系列格式")
+	}
+
+	// 分卷时边写边切分, 签名头回填到第一卷, 生成的 .7z.001 可被 7-Zip 直接识别
+	return s.compressSingleFile(*opts)
+}
+
+// compressSingleFile 压缩为单个 7z 压缩包
+func (s *SevenZipCompressor) compressSingleFile(opts CompressOptions) error {
+	// 创建输出文件, 分卷时为分卷写入器
+	var outFile sevenZipOutput
+	var err error
+	if opts.SplitSize > 0 {
+		outFile = createVolumes(&opts)
+	} else if outFile, err = os.Create(opts.OutputPath); err != nil {
+		return fmt.Errorf("创建 7z 文件失败: %v", err)
+	}
+	defer func() {
+		if err := abortArchive(outFile); err != nil {
+			log.Warn("关闭 7z 文件失败:", err)
+		}
+	}()
+
+	// 初始化 7z 写入器, 加密使用原始密码派生密钥
+	password := ""
+	if opts.Encrypt {
+		password = opts.Password
+		if password == "" {
+			return fmt.Errorf("7z 加密需要指定密码")
+		}
+	}
+	writer, err := newSevenZipWriter(outFile, password, opts.EncryptHeader)
+	if err != nil {
+		return err
+	}
+
+	// 批量进度条
+	batchBar := progress.NewBatchProgressBar(len(opts.Entries))
+	defer progress.FinishProgress(batchBar)
+
+	// 遍历文件压缩
+	for _, entry := range opts.Entries {
+		progress.UpdateProgress(batchBar, 1)
+
+		// 目录只记录名称与属性
+		if entry.Dir {
+			info, err := os.Stat(entry.Path)
+			if err != nil {
+				return fmt.Errorf("获取目录信息失败: %s, 错误: %v", entry.Path, err)
+			}
+			writer.addEmpty(entry.Name, info.ModTime(), info.Mode())
+			continue
+		}
+		if entry.special() {
+			log.Warn("7z 格式不支持符号链接与特殊文件, 已跳过:", entry.Path, "(可使用 tar 格式或 --follow-symlinks)")
+			continue
+		}
+		if err := s.addFile(writer, entry, opts.SolidSize); err != nil {
+			return err
+		}
+	}
+
+	// 写入头部
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("写入 7z 头部失败: %v", err)
+	}
+	// 分卷在关闭时生成恢复卷与说明文件, 失败必须返回错误
+	if err := outFile.Close(); err != nil {
+		return fmt.Errorf("关闭 7z 文件失败: %v", err)
+	}
+	return nil
+}
+
+// addFile 写入单个文件到 7z
+func (s *SevenZipCompressor) addFile(writer *sevenZipWriter, entry SourceEntry, solidSize int64) error {
+	srcPath, relPath := entry.Path, entry.Name
+
+	// 打开源文件 (- 为标准输入, 大小未知时边读边写), 大文件的一段只读取该段
+	var file io.ReadCloser
+	var fileInfo os.FileInfo
+	var err error
+	if entry.chunk != nil {
+		file, fileInfo, err = openChunkSource(entry)
+	} else {
+		file, fileInfo, err = openSource(srcPath)
+	}
+	if err != nil {
+		return fmt.Errorf("打开文件失败: %s, 错误: %v", srcPath, err)
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			log.Warn("文件关闭失败")
+		}
+	}()
+
+	// 空文件不占用数据流
+	if fileInfo.Size() == 0 {
+		writer.addEmpty(relPath, fileInfo.ModTime(), fileInfo.Mode())
+		return nil
+	}
+	if err := writer.beginFile(solidSize); err != nil {
+		return err
+	}
+
+	// 单个文件进度条
+	fileBar := progress.NewFileProgressBar(fileInfo.Size(), relPath)
+	defer progress.FinishProgress(fileBar)
+
+	// 分块拷贝, 同时计算 CRC32
+	hash := crc32.NewIEEE()
+	buf := make([]byte, 4*1024*1024) // 4MB 缓冲区
+	totalWritten := int64(0)
+	for {
+		n, err := file.Read(buf)
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("读取文件失败: %s, 错误: %v", srcPath, err)
+		}
+		if n == 0 {
+			break
+		}
+
+		if _, err := writer.Write(buf[:n]); err != nil {
+			return fmt.Errorf("写入 7z 失败: %s, 错误: %v", srcPath, err)
+		}
+		hash.Write(buf[:n])
+
+		totalWritten += int64(n)
+		if fileBar != nil {
+			_ = fileBar.Set64(totalWritten)
+		}
+	}
+	writer.endFile(relPath, uint64(totalWritten), hash.Sum32(), fileInfo.ModTime(), fileInfo.Mode())
+
+	if utils.VerboseMode() {
+		log.Success("已压缩:", relPath, "/", totalWritten, "字节")
+	}
+	return nil
+}
+
+// ============================== 7z 解压缩部分 ==============================
+
+// SevenZipDecompressor 7z 解压缩器
+// 基于 github.com/bodgit/sevenzip, 支持固实压缩包、.7z.001 分卷以及 AES-256 加密压缩包
+type SevenZipDecompressor struct{}
+
+// Decompress 7z 解压缩逻辑
+func (s *SevenZipDecompressor) Decompress(opts DecompressOptions) error {
+	if IsStdio(opts.SourcePath) {
+		return fmt.Errorf("7z 格式需要随机访问, 不支持从标准输入解压")
+	}
+
+	reader, err := openSevenZip(opts)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := reader.Close(); err != nil && utils.VerboseMode() {
+			log.Warn("关闭 7z 压缩包失败:", err)
+		}
+	}()
+
+	if utils.VerboseMode() && len(reader.Volumes()) > 1 {
+		log.Info("7z 分卷:", reader.Volumes())
+	}
+
+	// 批量进度条
+	batchBar := progress.NewBatchProgressBar(len(reader.File))
+	defer progress.FinishProgress(batchBar)
+
+	// 目录的时间与权限在全部条目解压后还原
+	restorer := newMetadataRestorer(opts)
+	defer restorer.finish()
+
+	// 按压缩包内顺序解压, 固实压缩包顺序读取可复用同一个解码流
+	for _, file := range reader.File {
+		progress.UpdateProgress(batchBar, 1)
+
+		// 按筛选条件跳过
+		if !opts.Filter.Match(file.Name, file.FileInfo().IsDir()) {
+			continue
+		}
+
+		// 构建输出路径, 不安全的条目按路径策略修正、跳过或中止
+		outputPath, ok, err := opts.guard.resolve(file.Name, file.FileInfo().IsDir())
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		if err := opts.limiter.addEntry(file.Name, int64(file.UncompressedSize)); err != nil {
+			return err
+		}
+		if outputPath, ok, err = opts.conflicts.resolve(file.Name, outputPath, file.FileInfo().IsDir(), file.Modified); err != nil {
+			return err
+		} else if !ok {
+			continue
+		}
+		if utils.VerboseMode() {
+			log.Newline()
+			log.Info("解压文件:", file.Name, "→", outputPath)
+		}
+
+		// 处理目录
+		if file.FileInfo().IsDir() {
+			if err := compress.MkdirIfNotExist(outputPath); err != nil {
+				return fmt.Errorf("创建目录失败: %s, 错误: %v", outputPath, err)
+			}
+			restorer.deferDir(outputPath, sevenZipMetadata(file))
+			continue
+		}
+
+		// 创建文件目录
+		if err := compress.MkdirIfNotExist(filepath.Dir(outputPath)); err != nil {
+			return fmt.Errorf("创建文件目录失败: %s, 错误: %v", filepath.Dir(outputPath), err)
+		}
+
+		crc, err := s.extractFile(opts, file, outputPath)
+		if err != nil {
+			return err
+		}
+
+		// 还原权限与时间, 7z 不记录属主
+		restorer.apply(outputPath, sevenZipMetadata(file))
+
+		// 完整性校验, 7z 头部记录了每个文件的 CRC32
+		if opts.Verify {
+			if file.CRC32 != 0 && crc != file.CRC32 {
+				return fmt.Errorf("文件 %s CRC32 不匹配: 预期 %08x, 实际 %08x", file.Name, file.CRC32, crc)
+			}
+			if utils.VerboseMode() {
+				log.Newline()
+				log.Success("文件", file.Name, "CRC32 校验通过")
+			}
+		}
+	}
+
+	return nil
+}
+
+// sevenZipMetadata 读取条目的权限、修改时间与访问时间
+func sevenZipMetadata(file *sevenzip.File) entryMetadata {
+	meta := newEntryMetadata(file.Mode(), file.Modified)
+	meta.AccessTime = file.Accessed
+	return meta
+}
+
+// extractFile 解压 7z 内单个文件
+// return: 解压数据的 CRC32、错误
+func (s *SevenZipDecompressor) extractFile(opts DecompressOptions, file *sevenzip.File, outputPath string) (uint32, error) {
+	// 打开压缩包内文件
+	srcFile, err := file.Open()
+	if err != nil {
+		return 0, fmt.Errorf("打开压缩包内文件失败: %s, 错误: %v", file.Name, describeSevenZipError(err))
+	}
+	defer func() {
+		if err := srcFile.Close(); err != nil && utils.VerboseMode() {
+			log.Warn("关闭压缩包内文件失败:", file.Name, ", 错误:", err)
+		}
+	}()
+
+	// 创建输出文件
+	dstFile, err := os.Create(outputPath)
+	if err != nil {
+		return 0, fmt.Errorf("创建输出文件失败: %s, 错误: %v", outputPath, err)
+	}
+	defer func() {
+		if err := dstFile.Close(); err != nil && utils.VerboseMode() {
+			log.Warn("关闭输出文件失败:", outputPath, ", 错误:", err)
+		}
+	}()
+
+	// 单个文件进度条
+	fileBar := progress.NewFileProgressBar(int64(file.UncompressedSize), file.Name)
+	defer progress.FinishProgress(fileBar)
+
+	// 分块拷贝, 同时计算 CRC32, 按实际解出的字节检查解压上限
+	data := opts.limiter.reader(file.Name, srcFile)
+	hash := crc32.NewIEEE()
+	buf := make([]byte, 4*1024*1024) // 4MB 缓冲区
+	totalWritten := int64(0)
+	for {
+		n, err := data.Read(buf)
+		if n > 0 {
+			if _, err := dstFile.Write(buf[:n]); err != nil {
+				return 0, fmt.Errorf("写入文件失败: %s, 错误: %v", outputPath, err)
+			}
+			hash.Write(buf[:n])
+
+			totalWritten += int64(n)
+			if fileBar != nil {
+				_ = fileBar.Set64(totalWritten)
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, fmt.Errorf("读取压缩包内文件失败: %s, 错误: %v", file.Name, describeSevenZipError(err))
+		}
+	}
+
+	return hash.Sum32(), nil
+}
+
+// List 读取 7z 头部的文件列表, 压缩方式为文件所在固实块的编码器链
+// 同一固实块内的文件共用压缩数据, 单个文件没有压缩后大小
+func (s *SevenZipDecompressor) List(opts DecompressOptions) ([]ArchiveEntry, error) {
+	if IsStdio(opts.SourcePath) {
+		return nil, fmt.Errorf("7z 格式需要随机访问, 不支持从标准输入读取")
+	}
+	reader, err := openSevenZip(opts)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := reader.Close(); err != nil && utils.VerboseMode() {
+			log.Warn("关闭 7z 压缩包失败:", err)
+		}
+	}()
+
+	// sevenzip 不公开编码器, 头部由 readSevenZipFolders 另行读取, 读取失败只影响压缩方式一列
+	password := ""
+	if opts.Encrypt {
+		password = opts.Password
+	}
+	folders, err := readSevenZipFolders(reader.volumes, reader.volumes.Size(), password)
+	if err != nil && utils.VerboseMode() {
+		log.Warn("读取 7z 编码器信息失败:", err)
+	}
+
+	entries := make([]ArchiveEntry, 0, len(reader.File))
+	for _, file := range reader.File {
+		mode := file.Mode()
+		entry := ArchiveEntry{
+			Name:           file.Name,
+			Size:           int64(file.UncompressedSize),
+			CompressedSize: -1,
+			Method:         "7z",
+			Modified:       file.Modified,
+			Mode:           mode,
+			CRC32:          file.CRC32,
+			HasCRC32:       !mode.IsDir() && file.UncompressedSize > 0,
+		}
+		// 目录与空文件没有数据流, 不属于任何固实块
+		if mode.IsDir() || file.UncompressedSize == 0 {
+			entry.Method = "-"
+		} else if file.Stream < len(folders) {
+			entry.Method = folders[file.Stream].Method()
+			entry.Encrypted = folders[file.Stream].Encrypted()
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// Test 逐个文件解压并比对 7z 头部记录的 CRC32, 不写入磁盘
+func (s *SevenZipDecompressor) Test(opts DecompressOptions, report func(TestResult)) error {
+	if IsStdio(opts.SourcePath) {
+		return fmt.Errorf("7z 格式需要随机访问, 不支持从标准输入读取")
+	}
+	reader, err := openSevenZip(opts)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := reader.Close(); err != nil && utils.VerboseMode() {
+			log.Warn("关闭 7z 压缩包失败:", err)
+		}
+	}()
+
+	for _, file := range reader.File {
+		if file.FileInfo().IsDir() {
+			report(TestResult{Name: file.Name})
+			continue
+		}
+		report(s.testFile(file))
+	}
+	return nil
+}
+
+// WriteEntry 解压单个文件写入 w, 并比对 CRC32
+func (s *SevenZipDecompressor) WriteEntry(opts DecompressOptions, name string, w io.Writer) error {
+	if IsStdio(opts.SourcePath) {
+		return fmt.Errorf("7z 格式需要随机访问, 不支持从标准输入读取")
+	}
+	reader, err := openSevenZip(opts)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	for _, file := range reader.File {
+		if compress.CleanEntryName(file.Name) != name {
+			continue
+		}
+		if file.FileInfo().IsDir() {
+			return errEntryIsDir(name)
+		}
+		srcFile, err := file.Open()
+		if err != nil {
+			return fmt.Errorf("打开压缩包内文件失败: %s, 错误: %v", name, describeSevenZipError(err))
+		}
+		defer srcFile.Close()
+
+		hash := crc32.NewIEEE()
+		if _, err := io.Copy(io.MultiWriter(w, hash), srcFile); err != nil {
+			return fmt.Errorf("读取压缩包内文件失败: %s, 错误: %v", name, describeSevenZipError(err))
+		}
+		if file.UncompressedSize > 0 && hash.Sum32() != file.CRC32 {
+			return fmt.Errorf("文件 %s CRC32 不匹配: 预期 %08x, 实际 %08x", name, file.CRC32, hash.Sum32())
+		}
+		return nil
+	}
+	return errEntryNotFound(name)
+}
+
+// testFile 校验 7z 内单个文件
+func (s *SevenZipDecompressor) testFile(file *sevenzip.File) TestResult {
+	result := TestResult{Name: file.Name}
+	srcFile, err := file.Open()
+	if err != nil {
+		result.Err = describeSevenZipError(err)
+		return result
+	}
+	defer srcFile.Close()
+
+	hash := crc32.NewIEEE()
+	result.Size, err = io.Copy(hash, srcFile)
+	switch {
+	case err != nil:
+		result.Err = fmt.Errorf("读取失败: %v", describeSevenZipError(err))
+	case uint64(result.Size) != file.UncompressedSize:
+		result.Err = fmt.Errorf("大小不匹配: 预期 %d, 实际 %d", file.UncompressedSize, result.Size)
+	case file.UncompressedSize > 0 && hash.Sum32() != file.CRC32:
+		result.Err = fmt.Errorf("CRC32 不匹配: 预期 %08x, 实际 %08x", file.CRC32, hash.Sum32())
+	}
+	return result
+}
+
+// sevenZipReader 在分卷上打开的 7z 读取器
+type sevenZipReader struct {
+	*sevenzip.Reader
+	volumes *volumeReader
+}
+
+// Volumes 分卷路径
+func (r *sevenZipReader) Volumes() []string { return r.volumes.Paths() }
+
+// Close 关闭全部分卷
+func (r *sevenZipReader) Close() error { return r.volumes.Close() }
+
+// openSevenZip 打开 7z 压缩包, 分卷按 .001/.002 顺序原地拼接, 无需先合并
+func openSevenZip(opts DecompressOptions) (*sevenZipReader, error) {
+	password := ""
+	if opts.Encrypt {
+		password = opts.Password
+	}
+
+	volumes, err := openVolumes(opts.SourcePath)
+	if err != nil {
+		return nil, err
+	}
+	// 头部加密的压缩包在这一步就需要密码
+	reader, err := sevenzip.NewReaderWithPassword(volumes, volumes.Size(), password)
+	if err != nil {
+		_ = volumes.Close()
+		err = volumes.describe(err)
+		if !opts.Encrypt {
+			return nil, fmt.Errorf("打开 7z 压缩包失败: %v (若压缩包已加密请使用 --encrypt/--key)", err)
+		}
+		return nil, fmt.Errorf("打开 7z 压缩包失败: %v (密码错误或文件损坏)", err)
+	}
+	return &sevenZipReader{Reader: reader, volumes: volumes}, nil
+}
+
+// describeSevenZipError 为加密相关的读取错误补充提示
+func describeSevenZipError(err error) error {
+	var readErr *sevenzip.ReadError
+	if errors.As(err, &readErr) && readErr.Encrypted {
+		return fmt.Errorf("%v (文件已加密, 请检查 --encrypt/--key 是否正确)", err)
+	}
+	return err
+}
@@ -0,0 +1,216 @@
+// Package compress /core/compress/codec.go
+package compress
+
+import (
+	"bufio"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
+	"github.com/ulikunitz/xz"
+)
+
+// tar 本身只负责把多个文件打包成一个流, 压缩交给外层的编解码器 (codec) 完成.
+// 把编解码器抽象成接口后, tar.gz/tar.zst/tar.xz 等格式可以共用同一套 tar 打包逻辑.
+
+// TarCodec tar 外层压缩编解码器接口
+type TarCodec interface {
+	// Name 格式名 如 tar.zst
+	Name() string
+	// Levels 压缩等级范围与默认值
+	Levels() (min, max, def int)
+	// NewWriter 创建压缩写入器, level 已经过范围校验
+	NewWriter(w io.Writer, level int) (io.WriteCloser, error)
+	// NewReader 创建解压读取器
+	NewReader(r io.Reader) (io.ReadCloser, error)
+}
+
+// NewTarCodec 创建对应格式的编解码器
+func NewTarCodec(format string) (TarCodec, error) {
+	switch format {
+	case "tar":
+		return &PlainCodec{}, nil
+	case "targz", "tar.gz", "tgz":
+		return &GzipCodec{}, nil
+	case "tar.zst", "tzst":
+		return &ZstdCodec{}, nil
+	case "tar.xz", "txz":
+		return &XzCodec{}, nil
+	case "tar.bz2", "tbz2":
+		return &Bzip2Codec{}, nil
+	case "tar.lz4":
+		return &Lz4Codec{}, nil
+	default:
+		return nil, fmt.Errorf("不支持的 tar 压缩格式: %s", format)
+	}
+}
+
+// errBzip2ReadOnly bzip2 只支持解压
+var errBzip2ReadOnly = errors.New("bzip2 只支持解压, 压缩请使用 tar.gz/tar.zst/tar.xz/tar.lz4")
+
+// ResolveLevel 校验压缩等级, 负数表示使用编解码器默认等级
+func ResolveLevel(codec TarCodec, level int) (int, error) {
+	minLevel, maxLevel, defLevel := codec.Levels()
+	if level < 0 {
+		return defLevel, nil
+	}
+	if level < minLevel || level > maxLevel {
+		return 0, fmt.Errorf("%s 压缩等级超出范围: %d, 支持 %d-%d", codec.Name(), level, minLevel, maxLevel)
+	}
+	return level, nil
+}
+
+// ============================== tar (不压缩) ==============================
+
+// PlainCodec 不压缩, 直接输出 tar 流
+type PlainCodec struct{}
+
+func (c *PlainCodec) Name() string                { return "tar" }
+func (c *PlainCodec) Levels() (min, max, def int) { return 0, 0, 0 }
+
+func (c *PlainCodec) NewWriter(w io.Writer, _ int) (io.WriteCloser, error) {
+	return nopWriteCloser{w}, nil
+}
+
+func (c *PlainCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return io.NopCloser(r), nil
+}
+
+// nopWriteCloser Close 不关闭底层写入器
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// ============================== gzip ==============================
+
+// GzipCodec gzip 编解码器, 等级 1-9, 默认 9 (最高压缩率)
+type GzipCodec struct{}
+
+func (c *GzipCodec) Name() string                { return "tar.gz" }
+func (c *GzipCodec) Levels() (min, max, def int) { return 1, 9, gzip.BestCompression }
+
+func (c *GzipCodec) NewWriter(w io.Writer, level int) (io.WriteCloser, error) {
+	return gzip.NewWriterLevel(w, level)
+}
+
+func (c *GzipCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+// ============================== zstd ==============================
+
+// ZstdCodec zstd 编解码器, 等级 1-22 (对应 zstd 命令行等级), 默认 3
+type ZstdCodec struct{}
+
+func (c *ZstdCodec) Name() string                { return "tar.zst" }
+func (c *ZstdCodec) Levels() (min, max, def int) { return 1, 22, 3 }
+
+func (c *ZstdCodec) NewWriter(w io.Writer, level int) (io.WriteCloser, error) {
+	return zstd.NewWriter(w, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)))
+}
+
+func (c *ZstdCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	decoder, err := zstd.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return decoder.IOReadCloser(), nil
+}
+
+// ============================== xz ==============================
+
+// xzDictSizes xz 预设等级对应的字典大小, 与 xz 命令行 -0 ~ -9 一致
+var xzDictSizes = [...]int{
+	256 << 10, 1 << 20, 2 << 20, 4 << 20, 4 << 20,
+	8 << 20, 8 << 20, 16 << 20, 32 << 20, 64 << 20,
+}
+
+// XzCodec xz 编解码器, 等级 0-9 决定字典大小, 默认 6
+type XzCodec struct{}
+
+func (c *XzCodec) Name() string                { return "tar.xz" }
+func (c *XzCodec) Levels() (min, max, def int) { return 0, 9, 6 }
+
+func (c *XzCodec) NewWriter(w io.Writer, level int) (io.WriteCloser, error) {
+	return xz.WriterConfig{DictCap: xzDictSizes[level]}.NewWriter(w)
+}
+
+func (c *XzCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	reader, err := xz.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(reader), nil
+}
+
+// ============================== bzip2 ==============================
+
+// Bzip2Codec bzip2 解码器, 标准库只提供 bzip2 解压, 因此 tar.bz2 与 zip bzip2 条目只支持读取
+type Bzip2Codec struct{}
+
+func (c *Bzip2Codec) Name() string                { return "tar.bz2" }
+func (c *Bzip2Codec) Levels() (min, max, def int) { return 0, 0, 0 }
+
+func (c *Bzip2Codec) NewWriter(io.Writer, int) (io.WriteCloser, error) {
+	return nil, errBzip2ReadOnly
+}
+
+func (c *Bzip2Codec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return io.NopCloser(bzip2.NewReader(r)), nil
+}
+
+// ============================== lz4 ==============================
+
+// lz4Levels lz4 等级 0-9, 0 为最快模式
+var lz4Levels = [...]lz4.CompressionLevel{
+	lz4.Fast, lz4.Level1, lz4.Level2, lz4.Level3, lz4.Level4,
+	lz4.Level5, lz4.Level6, lz4.Level7, lz4.Level8, lz4.Level9,
+}
+
+// Lz4Codec lz4 编解码器, 速度优先, 默认最快模式
+type Lz4Codec struct{}
+
+func (c *Lz4Codec) Name() string                { return "tar.lz4" }
+func (c *Lz4Codec) Levels() (min, max, def int) { return 0, 9, 0 }
+
+func (c *Lz4Codec) NewWriter(w io.Writer, level int) (io.WriteCloser, error) {
+	writer := lz4.NewWriter(w)
+	if err := writer.Apply(lz4.CompressionLevelOption(lz4Levels[level])); err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
+
+func (c *Lz4Codec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	src := bufio.NewReader(r)
+	return io.NopCloser(&lz4FramesReader{src: src, r: lz4.NewReader(src)}), nil
+}
+
+// lz4FramesReader 依次读取拼接的多个 lz4 帧, lz4.Reader 读完一帧即返回 EOF
+type lz4FramesReader struct {
+	src *bufio.Reader
+	r   *lz4.Reader
+}
+
+func (f *lz4FramesReader) Read(p []byte) (int, error) {
+	for {
+		n, err := f.r.Read(p)
+		if err != io.EOF {
+			return n, err
+		}
+		// 帧结束后还有数据时继续读下一帧
+		if _, peekErr := f.src.Peek(1); peekErr != nil {
+			return n, io.EOF
+		}
+		f.r.Reset(f.src)
+		if n > 0 {
+			return n, nil
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package compress
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// codecFormats 可写入的 tar 系列格式, tar.bz2 只支持读取
+var codecFormats = []string{"tar", "targz", "tar.zst", "tar.xz", "tar.lz4"}
+
+// readOutputTree 读出目录下全部文件内容, 键为统一使用 / 分隔的相对路径
+func readOutputTree(t *testing.T, root string) map[string][]byte {
+	t.Helper()
+	files := map[string][]byte{}
+	err := filepath.WalkDir(root, func(path string, entry os.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(root, path)
+		files[filepath.ToSlash(rel)] = data
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+	return files
+}
+
+// codecSample 编解码器测试数据: 可压缩文本与随机数据拼接
+func codecSample() []byte {
+	random := make([]byte, 32*1024)
+	rand.New(rand.NewSource(3)).Read(random)
+	return append([]byte(strings.Repeat("tar codec round trip\n", 4000)), random...)
+}
+
+func TestTarCodecLevelsRoundTrip(t *testing.T) {
+	sample := codecSample()
+	for _, format := range codecFormats {
+		codec, err := NewTarCodec(format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		minLevel, maxLevel, _ := codec.Levels()
+		for level := minLevel; level <= maxLevel; level++ {
+			t.Run(fmt.Sprintf("%s/%d", codec.Name(), level), func(t *testing.T) {
+				var buf bytes.Buffer
+				writer, err := codec.NewWriter(&buf, level)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if _, err := writer.Write(sample); err != nil {
+					t.Fatal(err)
+				}
+				if err := writer.Close(); err != nil {
+					t.Fatal(err)
+				}
+
+				reader, err := codec.NewReader(&buf)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got, err := io.ReadAll(reader)
+				_ = reader.Close()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(got, sample) {
+					t.Fatalf("解压结果不一致: %d 字节, 期望 %d 字节", len(got), len(sample))
+				}
+			})
+		}
+	}
+}
+
+func TestResolveLevel(t *testing.T) {
+	for _, format := range codecFormats {
+		codec, _ := NewTarCodec(format)
+		minLevel, maxLevel, defLevel := codec.Levels()
+		if level, err := ResolveLevel(codec, -1); err != nil || level != defLevel {
+			t.Errorf("%s: 默认等级 = %d, %v, 期望 %d", format, level, err, defLevel)
+		}
+		if _, err := ResolveLevel(codec, maxLevel+1); err == nil {
+			t.Errorf("%s: 等级 %d 超出范围应当报错", format, maxLevel+1)
+		}
+		if level, err := ResolveLevel(codec, minLevel); err != nil || level != minLevel {
+			t.Errorf("%s: 等级 %d 应当有效: %v", format, minLevel, err)
+		}
+	}
+}
+
+func TestTarFormatsRoundTrip(t *testing.T) {
+	files := testTreeFiles()
+	for _, format := range codecFormats {
+		minLevel, maxLevel, _ := mustCodec(t, format).Levels()
+		for _, level := range []int{-1, minLevel, maxLevel} {
+			t.Run(fmt.Sprintf("%s/%d", format, level), func(t *testing.T) {
+				dir := t.TempDir()
+				archive := filepath.Join(dir, "out."+format)
+				err := RunCompress(CompressOptions{
+					Entries:    writeTestTree(t, files),
+					OutputPath: archive,
+					Format:     format,
+					Level:      level,
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				// 不指定格式, 由魔数识别
+				detected, err := DetectFormat(archive)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if codec, _ := NewTarCodec(detected); codec == nil || codec.Name() != mustCodec(t, format).Name() {
+					t.Fatalf("识别格式 = %s, 期望 %s", detected, format)
+				}
+
+				output := filepath.Join(dir, "out")
+				if err := RunDecompress(DecompressOptions{SourcePath: archive, OutputDir: output}); err != nil {
+					t.Fatal(err)
+				}
+				assertSameFiles(t, readOutputTree(t, output), files)
+			})
+		}
+	}
+}
+
+func mustCodec(t *testing.T, format string) TarCodec {
+	t.Helper()
+	codec, err := NewTarCodec(format)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return codec
+}
+
+// testdata/bzip2.tar.bz2 与 testdata/bzip2.zip 由 testdata/bzip2.sh 使用 bzip2 与 Info-ZIP zip -Z bzip2 生成
+const bzip2FixtureSHA256 = "bec4e53df54fea8eddec7089176ed31ae5197acf719b6d2f081fc9b7a9314b08"
+
+func TestBzip2Fixtures(t *testing.T) {
+	for _, name := range []string{"bzip2.tar.bz2", "bzip2.zip"} {
+		t.Run(name, func(t *testing.T) {
+			archive := filepath.Join("testdata", name)
+			output := t.TempDir()
+			if err := RunDecompress(DecompressOptions{SourcePath: archive, OutputDir: output}); err != nil {
+				t.Fatal(err)
+			}
+			got := readOutputTree(t, output)
+			sum := sha256.Sum256(got["docs/lines.txt"])
+			if len(got) != 2 || hex.EncodeToString(sum[:]) != bzip2FixtureSHA256 || len(got["docs/empty.txt"]) != 0 {
+				t.Fatalf("解压结果不一致: %d 个文件, lines.txt sha256 %x", len(got), sum)
+			}
+		})
+	}
+
+	// zip 中央目录记录的压缩方式
+	reader, err := zip.OpenReader(filepath.Join("testdata", "bzip2.zip"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	if reader.File[0].Method != zipMethodBzip2 {
+		t.Fatalf("压缩方式 = %s, 期望 bzip2", zipMethodName(reader.File[0].Method))
+	}
+}
+
+func TestBzip2ReadOnly(t *testing.T) {
+	if _, err := (&Bzip2Codec{}).NewWriter(io.Discard, 9); !errors.Is(err, errBzip2ReadOnly) {
+		t.Fatalf("NewWriter = %v", err)
+	}
+	for _, format := range []string{"tar.bz2", "tbz2"} {
+		if _, err := NewCompressor(format); !errors.Is(err, errBzip2ReadOnly) {
+			t.Errorf("NewCompressor(%s) = %v", format, err)
+		}
+	}
+	if _, err := newZipMethodCompressor(zipMethodBzip2, -1); err == nil {
+		t.Fatal("zip bzip2 不应支持写入")
+	}
+
+	output := filepath.Join(t.TempDir(), "out.tar.bz2")
+	err := RunCompress(CompressOptions{Entries: writeTestTree(t, testTreeFiles()), OutputPath: output, Format: "tar.bz2", Level: -1})
+	if err == nil {
+		t.Fatal("tar.bz2 不应支持压缩")
+	}
+	if _, err := os.Stat(output); !os.IsNotExist(err) {
+		t.Fatalf("失败后不应留下输出: %v", err)
+	}
+}
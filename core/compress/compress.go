@@ -4,6 +4,8 @@ package compress
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/GoFurry/gf-file-tool/utils"
 	"github.com/GoFurry/gf-file-tool/utils/compress"
@@ -12,18 +14,27 @@ import (
 
 // CompressOptions 压缩配置
 type CompressOptions struct {
-	SourcePaths  []string // 待压缩文件/目录列表
-	OutputPath   string   // 输出压缩包路径
-	Format       string   // 压缩格式 zip/7z/targz
-	SplitSize    int64    // 分卷字节大小 =0不分卷
-	Encrypt      bool     // 是否加密
-	Key          []byte   // 加密密钥
-	KeyLength    int      // 密钥长度 16/24/32
-	EncryptSalt  string   // 加密盐值
-	Verify       bool     // 是否校验完整性
-	SplitSuffix  string   // 分卷后缀 如.001/.002
-	TotalSize    int64    // 分卷文件总大小
-	TempFilePath string   // 临时文件路径
+	Entries       []SourceEntry // 待压缩条目 (文件/目录及其在压缩包内的路径)
+	OutputPath    string        // 输出压缩包路径
+	Format        string        // 压缩格式 zip/7z/targz/tar/tar.zst/tar.xz/tar.lz4
+	SplitSize     int64         // 分卷字节大小 =0不分卷
+	SplitMode     string        // 分卷方式 bytes 按字节切开 (默认) / entries 按条目分为多个完整的压缩包
+	Encrypt       bool          // 是否加密
+	KeyLength     int           // 密钥长度 16/24/32
+	Verify        bool          // 是否校验完整性
+	SplitSuffix   string        // 分卷后缀 如.001/.002
+	Parity        int           // 恢复卷数量, 需要分卷, 最多可修复同样数量的缺失或损坏分卷
+	TotalSize     int64         // 待压缩文件总大小
+	Password      string        // 原始密码 (zip/7z 按各自标准由密码派生密钥)
+	EncryptHeader bool          // 7z 是否加密头部 (文件名列表)
+	SolidSize     int64         // 7z 固实块大小 <=0 表示全部文件一个固实块
+	Level         int           // 压缩等级 (tar 系列与 zip 条目), 负数表示使用格式默认等级
+	Method        string        // zip 条目压缩方式 store/deflate/zstd/xz, 空表示 deflate
+	StdinName     string        // 源路径为 - 时标准输入在压缩包内的文件名
+	Resume        bool          // 按续传日志继续上次中断的压缩 (tar 系列与 zip, 输出到文件且不分卷)
+
+	journal *journal // 由 RunCompress 创建的续传日志, 不支持续传时为 nil
+	parts   []string // 按条目分卷时已写出的分包
 }
 
 // Compressor 压缩器接口
@@ -36,10 +47,16 @@ func NewCompressor(format string) (Compressor, error) {
 	switch format {
 	case "zip":
 		return &ZipCompressor{}, nil
-	case "targz":
-		return &TarGzCompressor{}, nil
+	case "7z":
+		return &SevenZipCompressor{}, nil
 	default:
-		return nil, fmt.Errorf("不支持的压缩格式：%s，仅支持 zip/targz", format)
+		if codec, err := NewTarCodec(format); err == nil {
+			if _, ok := codec.(*Bzip2Codec); ok {
+				return nil, errBzip2ReadOnly
+			}
+			return &TarCompressor{Codec: codec}, nil
+		}
+		return nil, fmt.Errorf("不支持的压缩格式：%s，仅支持 zip/7z/targz/tar/tar.zst/tar.xz/tar.lz4", format)
 	}
 }
 
@@ -48,28 +65,83 @@ func RunCompress(opts CompressOptions) error {
 	log.Info("压缩开始")
 
 	// 参数校验
-	if len(opts.SourcePaths) == 0 {
+	if len(opts.Entries) == 0 {
 		return fmt.Errorf("待压缩文件列表为空")
 	}
+	entries, err := dedupeEntries(opts.Entries)
+	if err != nil {
+		return err
+	}
+	opts.Entries = entries
 	if opts.OutputPath == "" {
 		return fmt.Errorf("输出路径不能为空")
 	}
+	if opts.Parity < 0 || opts.Parity >= maxParityShards {
+		return fmt.Errorf("恢复卷数量无效: %d, 应为 1-%d", opts.Parity, maxParityShards-1)
+	}
+	if opts.Parity > 0 && opts.SplitSize <= 0 {
+		return fmt.Errorf("恢复卷需要分卷, 请同时指定 --split")
+	}
+	if opts.SplitMode, err = ParseSplitMode(opts.SplitMode); err != nil {
+		return err
+	}
+	if opts.SplitMode == SplitModeEntries {
+		if opts.SplitSize <= 0 {
+			return fmt.Errorf("按条目分卷需要指定分卷大小 --split")
+		}
+		if opts.SplitSize < minPartSize {
+			return fmt.Errorf("按条目分卷时分卷大小至少为 %d 字节", minPartSize)
+		}
+		if opts.Parity > 0 {
+			return fmt.Errorf("恢复卷只支持按字节分卷, 按条目分卷的每个分包都能单独解压")
+		}
+		for _, entry := range opts.Entries {
+			if IsStdio(entry.Path) {
+				return fmt.Errorf("按条目分卷需要预先知道文件大小, 不支持标准输入")
+			}
+		}
+	}
 
 	// 计算文件总大小
 	var totalSize int64
-	for _, src := range opts.SourcePaths {
-		info, err := os.Stat(src)
+	files := 0
+	for _, entry := range opts.Entries {
+		// 目录、链接与特殊文件不占空间, 标准输入大小未知
+		if entry.Dir {
+			continue
+		}
+		files++
+		if IsStdio(entry.Path) || entry.special() {
+			continue
+		}
+		info, err := os.Stat(entry.Path)
 		if err != nil {
-			return fmt.Errorf("获取文件大小失败：%s，错误：%v", src, err)
+			return fmt.Errorf("获取文件大小失败：%s，错误：%v", entry.Path, err)
 		}
 		totalSize += info.Size()
 	}
 	opts.TotalSize = totalSize
 
-	// 创建输出目录
-	outputDir := compress.GetDir(opts.OutputPath)
-	if err := compress.MkdirIfNotExist(outputDir); err != nil {
-		return fmt.Errorf("创建输出目录失败：%v", err)
+	// 输出到标准输出时无法分卷, 也无法回读校验
+	if IsStdio(opts.OutputPath) {
+		if opts.SplitSize > 0 {
+			return fmt.Errorf("输出到标准输出时不支持分卷压缩")
+		}
+		if opts.Format == "zip" {
+			if err := CheckStdoutZipMethod(opts.Method); err != nil {
+				return err
+			}
+		}
+		if opts.Verify {
+			log.Warn("输出到标准输出, 跳过压缩包 CRC32 校验")
+			opts.Verify = false
+		}
+	} else {
+		// 创建输出目录
+		outputDir := compress.GetDir(opts.OutputPath)
+		if err := compress.MkdirIfNotExist(outputDir); err != nil {
+			return fmt.Errorf("创建输出目录失败：%v", err)
+		}
 	}
 
 	// 创建压缩器
@@ -78,82 +150,95 @@ func RunCompress(opts CompressOptions) error {
 		return err
 	}
 
+	// 续传日志: tar 系列与 zip 输出到文件且不分卷时记录检查点
+	if resumableCompress(opts) {
+		if opts.journal, err = openCompressJournal(&opts); err != nil {
+			return err
+		}
+	} else if opts.Resume {
+		return fmt.Errorf("--resume 只支持输出到文件的 tar 系列与 zip 压缩, 不支持分卷与标准输入")
+	}
+
 	// 执行压缩
 	if utils.VerboseMode() {
 		log.Info("压缩任务信息:")
-		fmt.Printf("   - 文件数量: %d\n", len(opts.SourcePaths))
-		fmt.Printf("   - 总大小: %d 字节\n", totalSize)
-		fmt.Printf("   - 格式: %s\n", opts.Format)
-		fmt.Printf("   - 分卷大小: %d 字节\n", opts.SplitSize)
-		fmt.Printf("   - 加密: %t\n", opts.Encrypt)
-		fmt.Println("--------------------------------")
-	}
-	err = compressor.Compress(&opts)
+		out := log.Output()
+		fmt.Fprintf(out, "   - 文件数量: %d, 目录数量: %d\n", files, len(opts.Entries)-files)
+		fmt.Fprintf(out, "   - 总大小: %d 字节\n", totalSize)
+		fmt.Fprintf(out, "   - 格式: %s\n", opts.Format)
+		fmt.Fprintf(out, "   - 分卷大小: %d 字节\n", opts.SplitSize)
+		fmt.Fprintf(out, "   - 加密: %t\n", opts.Encrypt)
+		fmt.Fprintln(out, "--------------------------------")
+	}
+	if opts.SplitMode == SplitModeEntries {
+		err = compressParts(compressor, &opts)
+	} else {
+		err = compressor.Compress(&opts)
+	}
 	if err != nil {
-		return fmt.Errorf("压缩失败: %v", err)
+		return opts.journal.wrap(fmt.Errorf("压缩失败: %v", err))
 	}
+	opts.journal.remove()
 
-	// 完整性校验
+	// 完整性校验, 分卷按拼接后的内容计算
 	if opts.Verify {
 		if utils.VerboseMode() {
 			log.Info("开始校验压缩包完整性...")
 		}
+		crc, err := outputCRC32(opts)
+		if err != nil {
+			return fmt.Errorf("计算 CRC32 失败: %v", err)
+		}
+		if utils.VerboseMode() {
+			log.Success("压缩包 CRC32:", crc)
+		}
+	}
+	return nil
+}
 
-		// 分卷场景校验临时完整包
-		verifyPath := opts.OutputPath
-		if opts.SplitSize > 0 {
-			verifyPath = opts.TempFilePath // 使用临时文件路径
-			if !compress.CheckPathExist(verifyPath) {
-				log.Warn("分卷压缩: 临时包已清理, 跳过 CRC32 校验")
-			} else {
-				// 计算 CRC32
-				crc, err := compress.CalculateCRC32(verifyPath)
-				if err != nil {
-					// 校验失败清理临时文件
-					removeErr := os.Remove(opts.TempFilePath)
-					if removeErr != nil {
-						log.Warn("临时文件清理失败:", removeErr)
-					}
-					return fmt.Errorf("计算 CRC32 失败：%v", err)
-				}
-				if utils.VerboseMode() {
-					log.Success("压缩包 CRC32:", crc)
-				}
-				// 校验完成后立即清理临时文件
-				removeErr := os.Remove(opts.TempFilePath)
-				if removeErr != nil {
-					log.Warn("临时文件清理失败:", removeErr)
-				}
-			}
-		} else {
-			// 非分卷场景
-			crc, err := compress.CalculateCRC32(verifyPath)
+// outputCRC32 压缩包的 CRC32, 分卷按说明文件逐卷读回校验后取其中记录的值, 按条目分卷时为各分包的 CRC32
+func outputCRC32(opts CompressOptions) (string, error) {
+	if opts.SplitMode == SplitModeEntries {
+		var crcs []string
+		for _, path := range opts.parts {
+			crc, err := archiveCRC32(path)
 			if err != nil {
-				return fmt.Errorf("计算 CRC32 失败:%v", err)
-			}
-			if utils.VerboseMode() {
-				log.Success("压缩包 CRC32:", crc)
-			}
-		}
-	} else {
-		// 不校验时直接清理临时文件
-		if opts.SplitSize > 0 && compress.CheckPathExist(opts.TempFilePath) {
-			removeErr := os.Remove(opts.TempFilePath)
-			if removeErr != nil {
-				log.Warn("临时文件清理失败:", removeErr)
+				return "", err
 			}
+			crcs = append(crcs, filepath.Base(path)+" "+crc)
 		}
+		return strings.Join(crcs, ", "), nil
+	}
+	if opts.SplitSize <= 0 {
+		return archiveCRC32(opts.OutputPath)
+	}
+	manifest, err := readSplitManifest(opts.OutputPath)
+	if err != nil {
+		return "", err
+	}
+	if manifest == nil {
+		return "", fmt.Errorf("未找到分卷说明文件: %s", splitManifestPath(opts.OutputPath))
 	}
+	if err := manifest.verify(false); err != nil {
+		return "", err
+	}
+	return manifest.CRC32, nil
+}
 
-	// 兜底清理
-	if opts.SplitSize > 0 && compress.CheckPathExist(opts.TempFilePath) {
-		removeErr := os.Remove(opts.TempFilePath)
-		if removeErr != nil {
-			log.Warn("临时文件清理失败:", removeErr)
+// resumableCompress 压缩任务是否可以记录检查点: tar 系列与 zip, 输出到文件, 不分卷, 不含标准输入
+func resumableCompress(opts CompressOptions) bool {
+	if opts.Format != "zip" {
+		if _, err := NewTarCodec(opts.Format); err != nil {
+			return false
 		}
-		if utils.VerboseMode() {
-			log.Info("清理临时文件:", opts.TempFilePath)
+	}
+	if IsStdio(opts.OutputPath) || opts.SplitSize > 0 {
+		return false
+	}
+	for _, entry := range opts.Entries {
+		if IsStdio(entry.Path) {
+			return false
 		}
 	}
-	return nil
+	return true
 }
@@ -2,7 +2,12 @@
 package compress
 
 import (
+	"archive/zip"
+	"errors"
 	"fmt"
+	"os"
+	"strconv"
+	"strings"
 
 	"github.com/GoFurry/gf-file-tool/utils"
 	"github.com/GoFurry/gf-file-tool/utils/compress"
@@ -11,14 +16,29 @@ import (
 
 // DecompressOptions 解压缩配置
 type DecompressOptions struct {
-	SourcePath  string // 压缩包路径
-	OutputDir   string // 输出目录
-	Format      string // 压缩格式 zip/7z/targz
-	Encrypt     bool   // 是否加密
-	Key         []byte // 解密密钥
-	Verify      bool   // 校验完整性
-	EncryptSalt string // 解密盐值
-	ExpectedCRC string // 预期 CRC32
+	SourcePath  string       // 压缩包路径
+	OutputDir   string       // 输出目录, 各格式解压时为 RunDecompress 创建的暂存目录
+	Format      string       // 压缩格式 zip/7z/targz/tar/tar.zst/tar.xz/tar.bz2/tar.lz4
+	Encrypt     bool         // 是否加密
+	Key         []byte       // 解密密钥
+	Password    string       // 原始密码 (7z 等标准格式直接使用密码而非补全后的密钥)
+	Verify      bool         // 校验完整性
+	EncryptSalt string       // 解密盐值
+	ExpectedCRC string       // 预期 CRC32
+	Filter      *EntryFilter // 条目筛选, nil 表示解压全部
+
+	SpecialFiles bool            // 还原 FIFO 与设备文件 (创建设备文件通常需要 root)
+	SameOwner    bool            // 还原属主 (通常需要 root), 目标系统存在同名用户/组时按名称, 否则按数字 id
+	Umask        os.FileMode     // 从记录的权限中去掉的位, 零值表示原样还原
+	PathPolicy   PathPolicy      // 绝对路径、..、符号链接逃逸与设备文件的处理策略, 空表示 sanitize
+	Limits       ExtractLimits   // 解压上限, 零值表示不限制
+	Overwrite    OverwritePolicy // 输出路径已存在时的处理, 空表示 always
+	Resume       bool            // 复用上次中断时的暂存目录, 跳过已解压并校验通过的条目
+
+	guard     *pathGuard        // 由 RunDecompress 按 PathPolicy 创建
+	limiter   *extractLimiter   // 由 RunDecompress 按 Limits 创建, 不限制时为 nil
+	conflicts *conflictResolver // 由 RunDecompress 按 Overwrite 创建
+	journal   *extractJournal   // 由 RunDecompress 创建的续传日志, 标准输入时为 nil
 }
 
 // Decompressor 解压缩器接口
@@ -31,31 +51,105 @@ func NewDecompressor(format string) (Decompressor, error) {
 	switch format {
 	case "zip":
 		return &ZipDecompressor{}, nil
-	case "targz":
-		return &TarGzDecompressor{}, nil
 	case "7z":
-		return nil, fmt.Errorf("7z 格式暂未实现")
+		return &SevenZipDecompressor{}, nil
 	default:
+		if codec, err := NewTarCodec(format); err == nil {
+			return &TarDecompressor{Codec: codec}, nil
+		}
 		return nil, fmt.Errorf("不支持的解压缩格式: %s", format)
 	}
 }
 
+// PrepareDecrypt 由原始密码补全解密配置
+// 标准加密 (WinZip AES/ZipCrypto/7z) 只需要密码; 旧版本 AES-GCM 压缩包还需要补全后的密钥与盐值,
+// 盐值与密钥长度优先从 zip 注释 (gf-encrypt:salt=...;key-length=...) 读取
+func PrepareDecrypt(opts *DecompressOptions, password string, keyLength int) error {
+	if password == "" {
+		return fmt.Errorf("解密模式下必须指定密钥 (--key/-k)")
+	}
+	opts.Password = password
+
+	// 自动读取 Zip 注释中的盐值和密钥长度
+	if opts.Format == "zip" && !IsStdio(opts.SourcePath) {
+		if comment, err := readZipComment(opts.SourcePath); err == nil && strings.HasPrefix(comment, "gf-encrypt:") {
+			for _, part := range strings.Split(strings.TrimPrefix(comment, "gf-encrypt:"), ";") {
+				kv := strings.Split(part, "=")
+				if len(kv) != 2 {
+					continue
+				}
+				switch kv[0] {
+				case "salt":
+					opts.EncryptSalt = kv[1]
+					log.Info("从压缩包注释读取盐值:", opts.EncryptSalt)
+				case "key-length":
+					keyLength, _ = strconv.Atoi(kv[1])
+					log.Info("从压缩包注释读取密钥长度:", keyLength)
+				}
+			}
+		}
+	}
+
+	// 默认 AES256
+	if keyLength == 0 {
+		keyLength = compress.AES256KeyLength
+	}
+	// 填充密钥
+	paddedKey, err := compress.PadKey("aes", password)
+	if err != nil {
+		return fmt.Errorf("密钥处理失败: %v", err)
+	}
+	if len(paddedKey) < keyLength {
+		paddedKey = append(paddedKey, make([]byte, keyLength-len(paddedKey))...)
+	}
+	opts.Key = paddedKey[:keyLength]
+	return nil
+}
+
+// readZipComment 读取 zip 注释, 分卷原地读取
+func readZipComment(path string) (string, error) {
+	volumes, err := openVolumes(path)
+	if err != nil {
+		return "", err
+	}
+	defer volumes.Close()
+	reader, err := zip.NewReader(volumes, volumes.Size())
+	if err != nil {
+		return "", err
+	}
+	return reader.Comment, nil
+}
+
 // RunDecompress 统一解压缩入口
 func RunDecompress(opts DecompressOptions) error {
 	// 参数校验
-	if opts.SourcePath == "" || !compress.CheckPathExist(opts.SourcePath) {
+	if opts.SourcePath == "" || !archiveExists(opts.SourcePath) {
 		return fmt.Errorf("压缩包不存在: %s", opts.SourcePath)
 	}
 	if opts.OutputDir == "" {
 		opts.OutputDir = "."
 	}
-	if err := compress.MkdirIfNotExist(opts.OutputDir); err != nil {
-		return fmt.Errorf("创建输出目录失败: %v", err)
-	}
 	// 加密参数校验
 	if opts.Encrypt && len(opts.Key) == 0 {
 		return fmt.Errorf("解密模式必须指定有效密钥")
 	}
+	// 逐个询问需要从标准输入读取回答
+	if opts.Overwrite == OverwriteAsk && IsStdio(opts.SourcePath) {
+		return fmt.Errorf("--overwrite ask 需要从标准输入读取回答, 不能解压标准输入中的压缩包")
+	}
+	// 有分卷说明文件时先逐卷校验, 指出缺失或损坏的具体分卷, 有恢复卷时先修复
+	if err := verifySplitVolumes(opts.SourcePath, true); err != nil {
+		return err
+	}
+
+	// 未指定格式时根据文件头识别
+	if opts.Format == "" {
+		format, err := DetectFormat(opts.SourcePath)
+		if err != nil {
+			return err
+		}
+		opts.Format = format
+	}
 
 	// 创建解压缩器
 	decompressor, err := NewDecompressor(opts.Format)
@@ -63,6 +157,18 @@ func RunDecompress(opts DecompressOptions) error {
 		return err
 	}
 
+	// 按条目分卷的分包依次解压分包说明文件中的全部分包, 缺少分包时不解压
+	sources, parts, err := partSources(opts.SourcePath)
+	if err != nil {
+		return err
+	}
+	if parts {
+		log.Info("按条目分卷, 依次解压", len(sources), "个分包")
+		if opts.Filter != nil {
+			opts.Filter.parts = true
+		}
+	}
+
 	if utils.VerboseMode() {
 		log.Info("开始解压缩:", opts.SourcePath, "→", opts.OutputDir)
 		if opts.Encrypt {
@@ -73,14 +179,77 @@ func RunDecompress(opts DecompressOptions) error {
 		}
 	}
 
-	// 执行解压缩
-	err = decompressor.Decompress(opts)
+	// 续传日志记录已解压的条目, 标准输入无法重新读取, 分包中的条目序号各自独立, 都不记录
+	if IsStdio(opts.SourcePath) || parts {
+		if opts.Resume {
+			return fmt.Errorf("--resume 不支持解压标准输入中的压缩包与按条目分卷的分包")
+		}
+	} else if opts.journal, err = openExtractJournal(opts); err != nil {
+		return err
+	}
+
+	// 解压到暂存目录, 失败时只删除暂存目录, 输出目录保持原样
+	staging, err := newStagingDir(opts.OutputDir, opts.PathPolicy, opts.journal.stagingPath())
+	if err != nil {
+		return err
+	}
+	opts.journal.setStaging(staging.path)
+	outputDir := opts.OutputDir
+	opts.OutputDir = staging.path
+	opts.guard = newPathGuard(staging.path, staging.target, opts.PathPolicy)
+	opts.limiter = newExtractLimiter(opts.Limits, sources...)
+	opts.conflicts = newConflictResolver(opts.Overwrite, staging)
+
+	// 执行解压缩, 分包全部解压后拼接大文件的各段
+	sourcePath := opts.SourcePath
+	for _, source := range sources {
+		if parts && utils.VerboseMode() {
+			log.Info("解压分包:", source)
+		}
+		opts.SourcePath = source
+		if err = decompressor.Decompress(opts); err != nil {
+			if parts {
+				err = fmt.Errorf("%s: %w", source, err)
+			}
+			break
+		}
+	}
+	opts.SourcePath = sourcePath
+	if err == nil && parts {
+		err = joinChunks(opts)
+	}
 	if err != nil {
-		return fmt.Errorf("解压缩失败: %v", err)
+		// 超出上限的错误可能被格式内部包装, 直接返回原始的 *LimitError, 这类错误续传也无法完成
+		if limitErr := opts.limiter.exceeded(); limitErr != nil {
+			staging.discard()
+			opts.journal.finish()
+			return fmt.Errorf("解压缩失败: %w", limitErr)
+		}
+		// 已记录解压进度时保留暂存目录供 --resume 继续
+		err = opts.journal.fail(fmt.Errorf("解压缩失败: %w", err))
+		var resumable *ResumableError
+		if !errors.As(err, &resumable) {
+			staging.discard()
+		}
+		return err
+	}
+	if err := staging.commit(); err != nil {
+		staging.discard()
+		opts.journal.finish()
+		return fmt.Errorf("写入输出目录失败: %w", err)
+	}
+	opts.journal.finish()
+	opts.OutputDir = outputDir
+	opts.guard.report()
+	opts.conflicts.report()
+
+	// 提示未找到的条目
+	if missing := opts.Filter.Unmatched(); len(missing) > 0 {
+		log.Warn("压缩包内未找到:", strings.Join(missing, ", "))
 	}
 
 	// 整体压缩包校验
-	if opts.Verify && opts.ExpectedCRC != "" {
+	if opts.Verify && opts.ExpectedCRC != "" && !IsStdio(opts.SourcePath) && !parts {
 		if ok, err := compress.VerifyFileCRC32(opts.SourcePath, opts.ExpectedCRC); err != nil {
 			log.Warn("校验压缩包失败:", err)
 		} else if !ok {
@@ -0,0 +1,485 @@
+// Package compress /core/compress/deflate64.go
+package compress
+
+import (
+	"bufio"
+	"compress/flate"
+	"errors"
+	"fmt"
+	"io"
+	"math/bits"
+	"sync"
+)
+
+// Deflate64 (zip 压缩方式 9, 又称 Enhanced Deflate) 是 PKWARE 对 deflate 的私有扩展,
+// Windows 资源管理器压缩超过 2GB 的文件时会自动使用. 与 deflate 相比只有三处不同:
+//   - 滑动窗口从 32KB 扩大到 64KB
+//   - 长度码 285 不再表示固定长度 258, 而是 3 + 16 位附加值
+//   - 距离码 30、31 被启用, 附加 14 位, 可回溯到 64KB
+// 标准库不支持, 这里按 compress/flate 的思路实现一个只读解码器: 哈夫曼码查表解码,
+// 输出直接写入 64KB 窗口后批量拷贝. 输入通过 ReadByte 按需读取 (不是 flate.Reader 时自动加缓冲),
+// 只在解码需要时才取下一个字节, 压缩数据结束时不会多读, 因此也能用于流式读取 zip.
+
+const deflate64WindowSize = 1 << 16
+
+// 哈夫曼查表参数: 一级表按码的低 9 位索引, 更长的码经链接表再查剩余位;
+// 表项低 4 位为码长, 其余位为符号 (一级表中码长超过 9 时为链接表序号)
+const (
+	deflate64ChunkBits  = 9
+	deflate64NumChunks  = 1 << deflate64ChunkBits
+	deflate64CountMask  = 15
+	deflate64ValueShift = 4
+)
+
+var (
+	// 长度码 257-285 的基础长度与附加位数
+	deflate64LenBase  = [29]int{3, 4, 5, 6, 7, 8, 9, 10, 11, 13, 15, 17, 19, 23, 27, 31, 35, 43, 51, 59, 67, 83, 99, 115, 131, 163, 195, 227, 3}
+	deflate64LenExtra = [29]uint{0, 0, 0, 0, 0, 0, 0, 0, 1, 1, 1, 1, 2, 2, 2, 2, 3, 3, 3, 3, 4, 4, 4, 4, 5, 5, 5, 5, 16}
+	// 距离码 0-31 的基础距离与附加位数
+	deflate64DistBase = [32]int{1, 2, 3, 4, 5, 7, 9, 13, 17, 25, 33, 49, 65, 97, 129, 193, 257, 385, 513, 769,
+		1025, 1537, 2049, 3073, 4097, 6145, 8193, 12289, 16385, 24577, 32769, 49153}
+	deflate64DistExtra = [32]uint{0, 0, 0, 0, 1, 1, 2, 2, 3, 3, 4, 4, 5, 5, 6, 6, 7, 7, 8, 8, 9, 9, 10, 10, 11, 11, 12, 12, 13, 13, 14, 14}
+	// 动态块中码长码的排列顺序
+	deflate64CodeOrder = [19]int{16, 17, 18, 0, 8, 7, 9, 6, 10, 5, 11, 4, 12, 3, 13, 2, 14, 1, 15}
+
+	errDeflate64Corrupt = errors.New("deflate64 数据损坏")
+
+	// 固定哈夫曼码表只构建一次, 各解码器共享
+	deflate64FixedOnce sync.Once
+	deflate64FixedLit  deflate64Huffman
+	deflate64FixedDist deflate64Huffman
+)
+
+// deflate64Huffman 规范哈夫曼码的查找表
+type deflate64Huffman struct {
+	min      uint // 最短码长, 至少读入这么多位才开始查表
+	chunks   [deflate64NumChunks]uint32
+	links    [][]uint32
+	linkMask uint64
+}
+
+// build 根据码长构建查找表, 只拒绝超额的码长, 未分配的码在解码时报错
+func (h *deflate64Huffman) build(lengths []int) error {
+	var count [16]int
+	for _, l := range lengths {
+		count[l]++
+	}
+	count[0] = 0
+	h.min, h.links, h.linkMask = 0, nil, 0
+	h.chunks = [deflate64NumChunks]uint32{}
+
+	maxLen := 0
+	var nextCode [16]int
+	code := 0
+	for l := 1; l < 16; l++ {
+		code = (code + count[l-1]) << 1
+		nextCode[l] = code
+		if count[l] > 0 {
+			if h.min == 0 {
+				h.min = uint(l)
+			}
+			maxLen = l
+		}
+	}
+	left := 1
+	for l := 1; l < 16; l++ {
+		left = left<<1 - count[l]
+		if left < 0 {
+			return errDeflate64Corrupt // 码长超额
+		}
+	}
+
+	// 长于 9 位的码按低 9 位分组, 每组一张链接表; 规范哈夫曼码中这些前缀都排在最后
+	if maxLen > deflate64ChunkBits {
+		numLinks := 1 << (maxLen - deflate64ChunkBits)
+		h.linkMask = uint64(numLinks - 1)
+		first := nextCode[deflate64ChunkBits+1] >> 1
+		h.links = make([][]uint32, deflate64NumChunks-first)
+		for prefix := first; prefix < deflate64NumChunks; prefix++ {
+			reverse := int(bits.Reverse16(uint16(prefix))) >> (16 - deflate64ChunkBits)
+			h.chunks[reverse] = uint32(prefix-first)<<deflate64ValueShift | (deflate64ChunkBits + 1)
+			h.links[prefix-first] = make([]uint32, numLinks)
+		}
+	}
+
+	for sym, l := range lengths {
+		if l == 0 {
+			continue
+		}
+		code := nextCode[l]
+		nextCode[l]++
+		chunk := uint32(sym)<<deflate64ValueShift | uint32(l)
+		// 码按高位在前写入比特流, 查表按读入顺序 (低位在前) 索引, 需要反转
+		reverse := int(bits.Reverse16(uint16(code))) >> (16 - l)
+		if l <= deflate64ChunkBits {
+			for off := reverse; off < deflate64NumChunks; off += 1 << l {
+				h.chunks[off] = chunk
+			}
+			continue
+		}
+		link := h.links[h.chunks[reverse&(deflate64NumChunks-1)]>>deflate64ValueShift]
+		for off := reverse >> deflate64ChunkBits; off < len(link); off += 1 << (l - deflate64ChunkBits) {
+			link[off] = chunk
+		}
+	}
+	return nil
+}
+
+// deflate64Reader Deflate64 解码器
+type deflate64Reader struct {
+	r      flate.Reader
+	bitBuf uint64
+	bitCnt uint
+
+	window [deflate64WindowSize]byte
+	wpos   int  // 下一个写入位置
+	rpos   int  // 下一个交给调用方的位置
+	full   bool // 窗口已写满过一轮, 可回溯整个窗口
+
+	final    bool // 当前为最后一个块
+	inBlock  bool
+	stored   int // 存储块剩余字节, -1 表示非存储块
+	lit      *deflate64Huffman
+	dist     *deflate64Huffman
+	litDyn   deflate64Huffman // 动态块的码表存储
+	distDyn  deflate64Huffman
+	copyLen  int // 待复制的匹配长度
+	copyDist int
+	err      error
+}
+
+// newDeflate64Reader 创建解码器, r 不实现 flate.Reader 时自动加缓冲
+func newDeflate64Reader(r io.Reader) io.ReadCloser {
+	fr, ok := r.(flate.Reader)
+	if !ok {
+		fr = bufio.NewReader(r)
+	}
+	return &deflate64Reader{r: fr, stored: -1}
+}
+
+func (d *deflate64Reader) Close() error { return nil }
+
+// noEOF 压缩数据中途读到结尾属于截断
+func noEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// bits 读取 n 位 (低位在前)
+func (d *deflate64Reader) bits(n uint) (int, error) {
+	for d.bitCnt < n {
+		b, err := d.r.ReadByte()
+		if err != nil {
+			return 0, noEOF(err)
+		}
+		d.bitBuf |= uint64(b) << d.bitCnt
+		d.bitCnt += 8
+	}
+	v := int(d.bitBuf & (1<<n - 1))
+	d.bitBuf >>= n
+	d.bitCnt -= n
+	return v, nil
+}
+
+// decode 查表解码一个符号, 读入的位数不超过该符号的码长
+func (d *deflate64Reader) decode(h *deflate64Huffman) (int, error) {
+	n := h.min
+	b, nb := d.bitBuf, d.bitCnt
+	for {
+		for nb < n {
+			c, err := d.r.ReadByte()
+			if err != nil {
+				d.bitBuf, d.bitCnt = b, nb
+				return 0, noEOF(err)
+			}
+			b |= uint64(c) << nb
+			nb += 8
+		}
+		chunk := h.chunks[b&(deflate64NumChunks-1)]
+		n = uint(chunk & deflate64CountMask)
+		if n > deflate64ChunkBits {
+			chunk = h.links[chunk>>deflate64ValueShift][(b>>deflate64ChunkBits)&h.linkMask]
+			n = uint(chunk & deflate64CountMask)
+		}
+		if n <= nb {
+			if n == 0 {
+				return 0, errDeflate64Corrupt
+			}
+			d.bitBuf, d.bitCnt = b>>n, nb-n
+			return int(chunk >> deflate64ValueShift), nil
+		}
+	}
+}
+
+func (d *deflate64Reader) Read(p []byte) (int, error) {
+	for {
+		if d.rpos < d.wpos {
+			n := copy(p, d.window[d.rpos:d.wpos])
+			d.rpos += n
+			return n, nil
+		}
+		if d.err != nil {
+			return 0, d.err
+		}
+		d.err = d.fill()
+	}
+}
+
+// fill 解码直到窗口写满、数据结束或出错, 已解出的数据在错误之前交给调用方
+func (d *deflate64Reader) fill() error {
+	if d.wpos == len(d.window) {
+		d.wpos, d.rpos, d.full = 0, 0, true
+	}
+	for d.wpos < len(d.window) {
+		// 先完成未复制完的匹配
+		if d.copyLen > 0 {
+			d.copyMatch()
+			continue
+		}
+		if !d.inBlock {
+			if d.final {
+				return io.EOF
+			}
+			if err := d.readBlockHeader(); err != nil {
+				return err
+			}
+			continue
+		}
+		// 存储块
+		if d.stored >= 0 {
+			if d.stored == 0 {
+				d.inBlock = false
+				continue
+			}
+			n := min(d.stored, len(d.window)-d.wpos)
+			read, err := io.ReadFull(d.r, d.window[d.wpos:d.wpos+n])
+			d.wpos += read
+			d.stored -= read
+			if err != nil {
+				return noEOF(err)
+			}
+			continue
+		}
+		// 压缩块
+		if err := d.decodeSymbol(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyMatch 把匹配复制到窗口, 最多写到窗口末尾, 剩余部分下次继续.
+// 源区间与目标重叠时按距离为周期成倍复制
+func (d *deflate64Reader) copyMatch() {
+	end := min(d.wpos+d.copyLen, len(d.window))
+	dst := d.wpos
+	src := dst - d.copyDist
+	if src < 0 {
+		src += len(d.window)
+		dst += copy(d.window[dst:end], d.window[src:])
+		src = 0
+	}
+	for dst < end {
+		dst += copy(d.window[dst:end], d.window[src:dst])
+	}
+	d.copyLen -= dst - d.wpos
+	d.wpos = dst
+}
+
+// decodeSymbol 解码一个字面量或匹配
+func (d *deflate64Reader) decodeSymbol() error {
+	sym, err := d.decode(d.lit)
+	if err != nil {
+		return err
+	}
+	switch {
+	case sym < 256:
+		d.window[d.wpos] = byte(sym)
+		d.wpos++
+		return nil
+	case sym == 256:
+		d.inBlock = false
+		return nil
+	}
+
+	sym -= 257
+	if sym >= len(deflate64LenBase) {
+		return errDeflate64Corrupt
+	}
+	extra, err := d.bits(deflate64LenExtra[sym])
+	if err != nil {
+		return err
+	}
+	length := deflate64LenBase[sym] + extra
+
+	dsym, err := d.decode(d.dist)
+	if err != nil {
+		return err
+	}
+	if dsym >= len(deflate64DistBase) {
+		return errDeflate64Corrupt
+	}
+	extra, err = d.bits(deflate64DistExtra[dsym])
+	if err != nil {
+		return err
+	}
+	dist := deflate64DistBase[dsym] + extra
+	if !d.full && dist > d.wpos {
+		return fmt.Errorf("%w: 回溯距离 %d 超出已解压的数据", errDeflate64Corrupt, dist)
+	}
+	d.copyLen = length
+	d.copyDist = dist
+	return nil
+}
+
+// readBlockHeader 读取块头并准备码表
+func (d *deflate64Reader) readBlockHeader() error {
+	final, err := d.bits(1)
+	if err != nil {
+		return err
+	}
+	d.final = final == 1
+	blockType, err := d.bits(2)
+	if err != nil {
+		return err
+	}
+	d.stored = -1
+	switch blockType {
+	case 0:
+		return d.readStoredHeader()
+	case 1:
+		deflate64FixedOnce.Do(deflate64FixedTables)
+		d.lit, d.dist = &deflate64FixedLit, &deflate64FixedDist
+	case 2:
+		if err := d.dynamicTables(); err != nil {
+			return err
+		}
+		d.lit, d.dist = &d.litDyn, &d.distDyn
+	default:
+		return errDeflate64Corrupt
+	}
+	d.inBlock = true
+	return nil
+}
+
+// readStoredHeader 存储块: 丢弃当前字节剩余位, 读取 LEN/NLEN
+func (d *deflate64Reader) readStoredHeader() error {
+	d.bitBuf, d.bitCnt = 0, 0
+	var buf [4]byte
+	if _, err := io.ReadFull(d.r, buf[:]); err != nil {
+		return noEOF(err)
+	}
+	length := int(buf[0]) | int(buf[1])<<8
+	nlength := int(buf[2]) | int(buf[3])<<8
+	if length != ^nlength&0xffff {
+		return errDeflate64Corrupt
+	}
+	d.stored = length
+	d.inBlock = true
+	return nil
+}
+
+// deflate64FixedTables 固定哈夫曼码表, 距离码 32 个均为 5 位
+func deflate64FixedTables() {
+	lengths := make([]int, 288)
+	for i := range lengths {
+		switch {
+		case i < 144:
+			lengths[i] = 8
+		case i < 256:
+			lengths[i] = 9
+		case i < 280:
+			lengths[i] = 7
+		default:
+			lengths[i] = 8
+		}
+	}
+	_ = deflate64FixedLit.build(lengths)
+	dists := make([]int, 32)
+	for i := range dists {
+		dists[i] = 5
+	}
+	_ = deflate64FixedDist.build(dists)
+}
+
+// dynamicTables 读取动态哈夫曼码表
+func (d *deflate64Reader) dynamicTables() error {
+	nlen, err := d.bits(5)
+	if err != nil {
+		return err
+	}
+	ndist, err := d.bits(5)
+	if err != nil {
+		return err
+	}
+	ncode, err := d.bits(4)
+	if err != nil {
+		return err
+	}
+	nlen += 257
+	ndist++
+	ncode += 4
+
+	// 码长码
+	codeLengths := make([]int, 19)
+	for i := 0; i < ncode; i++ {
+		v, err := d.bits(3)
+		if err != nil {
+			return err
+		}
+		codeLengths[deflate64CodeOrder[i]] = v
+	}
+	var lencode deflate64Huffman
+	if err := lencode.build(codeLengths); err != nil {
+		return err
+	}
+
+	// 字面量/长度与距离码长
+	lengths := make([]int, nlen+ndist)
+	for i := 0; i < len(lengths); {
+		sym, err := d.decode(&lencode)
+		if err != nil {
+			return err
+		}
+		if sym < 16 {
+			lengths[i] = sym
+			i++
+			continue
+		}
+		value, repeat := 0, 0
+		switch sym {
+		case 16:
+			if i == 0 {
+				return errDeflate64Corrupt
+			}
+			value = lengths[i-1]
+			repeat, err = d.bits(2)
+			repeat += 3
+		case 17:
+			repeat, err = d.bits(3)
+			repeat += 3
+		default:
+			repeat, err = d.bits(7)
+			repeat += 11
+		}
+		if err != nil {
+			return err
+		}
+		if i+repeat > len(lengths) {
+			return errDeflate64Corrupt
+		}
+		for ; repeat > 0; repeat-- {
+			lengths[i] = value
+			i++
+		}
+	}
+	if lengths[256] == 0 {
+		return fmt.Errorf("%w: 缺少块结束符", errDeflate64Corrupt)
+	}
+	if err := d.litDyn.build(lengths[:nlen]); err != nil {
+		return err
+	}
+	return d.distDyn.build(lengths[nlen:])
+}
@@ -0,0 +1,173 @@
+package compress
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"math/rand"
+	"testing"
+)
+
+// testdata/deflate64.zip 由 testdata/deflate64.py 独立编码生成, 并经 Info-ZIP unzip (USE_DEFLATE64) 校验,
+// 覆盖存储块、固定与动态哈夫曼块、长度码 285 (16 位附加值, 含最大长度 65538) 与距离码 30/31 (含最大距离 65536)
+const (
+	deflate64FixtureSize   = 146788
+	deflate64FixtureSHA256 = "8e65d7c6f9856349b402982b87eab212b67add09e1a2ee0d8bc421eedf110dc4"
+)
+
+// openDeflate64Fixture 打开测试压缩包, 返回其中的 Deflate64 条目
+func openDeflate64Fixture(t testing.TB) (*zip.ReadCloser, *zip.File) {
+	t.Helper()
+	reader, err := zip.OpenReader("testdata/deflate64.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := reader.File[0]
+	if file.Method != zipMethodDeflate64 {
+		reader.Close()
+		t.Fatalf("压缩方式 = %d, 期望 %d", file.Method, zipMethodDeflate64)
+	}
+	return reader, file
+}
+
+// deflate64FixtureRaw 读出未解压的 Deflate64 数据
+func deflate64FixtureRaw(t testing.TB) []byte {
+	t.Helper()
+	reader, file := openDeflate64Fixture(t)
+	defer reader.Close()
+	raw, err := file.OpenRaw()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func assertDeflate64Fixture(t *testing.T, data []byte) {
+	t.Helper()
+	sum := sha256.Sum256(data)
+	if len(data) != deflate64FixtureSize || hex.EncodeToString(sum[:]) != deflate64FixtureSHA256 {
+		t.Fatalf("解压结果不一致: %d 字节, sha256 %x", len(data), sum)
+	}
+}
+
+func TestDeflate64KnownAnswer(t *testing.T) {
+	reader, file := openDeflate64Fixture(t)
+	defer reader.Close()
+	// 经 zip.RegisterDecompressor 注册的解码器读取, archive/zip 在结尾核对 CRC32
+	rc, err := file.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertDeflate64Fixture(t, data)
+}
+
+func TestDeflate64StopsAtStreamEnd(t *testing.T) {
+	// 流式读取时压缩数据后紧跟数据描述符, 解码器不能多读
+	const trailer = "PK\x07\x08 data descriptor"
+	raw := deflate64FixtureRaw(t)
+	src := bufio.NewReader(io.MultiReader(bytes.NewReader(raw), bytes.NewReader([]byte(trailer))))
+
+	// 用不规则的读取大小, 覆盖匹配跨越多次 Read 的情况
+	var out bytes.Buffer
+	decoder := newDeflate64Reader(src)
+	buf := make([]byte, 1000)
+	for {
+		n, err := decoder.Read(buf[:len(buf)-out.Len()%7])
+		out.Write(buf[:n])
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	assertDeflate64Fixture(t, out.Bytes())
+
+	rest, err := io.ReadAll(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(rest) != trailer {
+		t.Fatalf("压缩数据之后剩余 %q, 期望 %q", rest, trailer)
+	}
+}
+
+func TestDeflate64Corrupt(t *testing.T) {
+	raw := deflate64FixtureRaw(t)
+	if _, err := io.ReadAll(newDeflate64Reader(bytes.NewReader(raw[:len(raw)/2]))); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("截断的数据应返回 io.ErrUnexpectedEOF, 实际: %v", err)
+	}
+	// 固定哈夫曼块的第一个符号就是距离 1 的匹配, 回溯超出已输出的数据
+	if _, err := io.ReadAll(newDeflate64Reader(bytes.NewReader([]byte{0x03, 0x02}))); !errors.Is(err, errDeflate64Corrupt) {
+		t.Fatalf("越界的距离应返回 errDeflate64Corrupt, 实际: %v", err)
+	}
+}
+
+// huffmanOnlyDeflate 只含字面量的 deflate 流不使用长度码 285 与距离码, 同时也是合法的 Deflate64 流
+func huffmanOnlyDeflate(t testing.TB, size int) ([]byte, []byte) {
+	t.Helper()
+	words := []string{"gf-file-tool ", "deflate64 ", "huffman ", "window ", "zip ", "\n"}
+	rnd := rand.New(rand.NewSource(64))
+	var plain bytes.Buffer
+	for plain.Len() < size {
+		plain.WriteString(words[rnd.Intn(len(words))])
+	}
+	var compressed bytes.Buffer
+	writer, err := flate.NewWriter(&compressed, flate.HuffmanOnly)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writer.Write(plain.Bytes())
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return plain.Bytes(), compressed.Bytes()
+}
+
+func TestDeflate64DecodesHuffmanOnlyDeflate(t *testing.T) {
+	plain, compressed := huffmanOnlyDeflate(t, 1<<20)
+	got, err := io.ReadAll(newDeflate64Reader(bytes.NewReader(compressed)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, plain) {
+		t.Fatalf("解压结果不一致: %d 字节, 期望 %d 字节", len(got), len(plain))
+	}
+}
+
+func BenchmarkDeflate64(b *testing.B) {
+	plain, literals := huffmanOnlyDeflate(b, 4<<20)
+	for _, c := range []struct {
+		name string
+		raw  []byte
+		size int64
+	}{
+		{"fixture", deflate64FixtureRaw(b), deflate64FixtureSize},
+		{"literals", literals, int64(len(plain))},
+	} {
+		b.Run(c.name, func(b *testing.B) {
+			b.SetBytes(c.size)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				n, err := io.Copy(io.Discard, newDeflate64Reader(bytes.NewReader(c.raw)))
+				if err != nil || n != c.size {
+					b.Fatal(n, err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+// Package compress /core/compress/detect.go
+package compress
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/GoFurry/gf-file-tool/utils/compress"
+)
+
+// 扩展名可以被随意修改, 而文件头部的魔数 (magic number) 由格式本身决定,
+// 因此通过读取文件开头的若干字节来识别格式要比匹配扩展名可靠得多.
+
+// sniffSize 读取的头部字节数, tar 的 ustar 标记位于 257 偏移处
+const sniffSize = 512
+
+// formatMagic 格式魔数表, 按顺序匹配
+var formatMagic = []struct {
+	format string
+	offset int
+	magic  []byte
+}{
+	{"zip", 0, []byte("PK\x03\x04")},                      // 本地文件头
+	{"zip", 0, []byte("PK\x05\x06")},                      // 空压缩包 (只有中央目录结尾)
+	{"zip", 0, []byte("PK\x07\x08")},                      // 跨卷压缩包标记
+	{"7z", 0, []byte{'7', 'z', 0xBC, 0xAF, 0x27, 0x1C}},   // 7z 签名
+	{"targz", 0, []byte{0x1F, 0x8B}},                      // gzip
+	{"tar.xz", 0, []byte{0xFD, '7', 'z', 'X', 'Z', 0x00}}, // xz
+	{"tar.zst", 0, []byte{0x28, 0xB5, 0x2F, 0xFD}},        // zstd
+	{"tar.bz2", 0, []byte("BZh")},                         // bzip2
+	{"tar.lz4", 0, []byte{0x04, 0x22, 0x4D, 0x18}},        // lz4 frame
+	{"tar", 257, []byte("ustar")},                         // POSIX/GNU tar
+}
+
+// DetectFormat 根据文件头部魔数识别压缩格式
+// path: 压缩包路径, 分卷 (.001/.split) 读取第一卷, - 表示标准输入
+// return: 格式名 zip/7z/targz/tar/tar.xz/tar.zst/tar.bz2/tar.lz4、错误
+func DetectFormat(path string) (string, error) {
+	// 标准输入只能 Peek, 读取的字节需留给后续解压
+	if IsStdio(path) {
+		head, err := stdinReader().Peek(sniffSize)
+		if err != nil && err != io.EOF {
+			return "", fmt.Errorf("读取标准输入头部失败: %v", err)
+		}
+		format := DetectFormatBytes(head)
+		if format == "" {
+			return "", fmt.Errorf("无法识别标准输入的压缩格式")
+		}
+		return format, nil
+	}
+
+	format, err := detectFileFormat(firstVolumePath(path))
+	if err != nil && compress.IsSplitFile(path) {
+		// 第一卷缺失或损坏时使用分卷说明文件中记录的格式, 分卷稍后由恢复卷修复
+		if manifest, _ := readSplitManifest(compress.SplitBasePath(path)); manifest != nil && manifest.Format != "" {
+			return manifest.Format, nil
+		}
+	}
+	return format, err
+}
+
+// detectFileFormat 读取文件头识别格式
+func detectFileFormat(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("打开文件失败: %v", err)
+	}
+	defer file.Close()
+
+	head := make([]byte, sniffSize)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", fmt.Errorf("读取文件头失败: %v", err)
+	}
+
+	format := DetectFormatBytes(head[:n])
+	if format == "" {
+		return "", fmt.Errorf("无法识别压缩格式: %s", path)
+	}
+	return format, nil
+}
+
+// DetectFormatBytes 根据已读取的头部字节识别格式, 无法识别返回空字符串
+func DetectFormatBytes(head []byte) string {
+	for _, m := range formatMagic {
+		end := m.offset + len(m.magic)
+		if len(head) >= end && bytes.Equal(head[m.offset:end], m.magic) {
+			return m.format
+		}
+	}
+	return ""
+}
+
+// archiveExists 压缩包是否存在, 分卷只要第一卷或分卷说明文件存在即可 (缺失的分卷可能由恢复卷修复)
+func archiveExists(path string) bool {
+	if IsStdio(path) || compress.CheckPathExist(firstVolumePath(path)) {
+		return true
+	}
+	return compress.IsSplitFile(path) && compress.CheckPathExist(splitManifestPath(compress.SplitBasePath(path)))
+}
+
+// firstVolumePath 分卷文件返回第一卷路径 (有说明文件时以其为准), 否则原样返回
+func firstVolumePath(path string) string {
+	if !compress.IsSplitFile(path) {
+		return path
+	}
+	base := compress.SplitBasePath(path)
+	if manifest, err := readSplitManifest(base); err == nil && manifest != nil {
+		return manifest.volumePath(0)
+	}
+	return base + ".001"
+}
@@ -0,0 +1,81 @@
+package compress
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDetectFormatBytes(t *testing.T) {
+	tarHead := make([]byte, sniffSize)
+	copy(tarHead[257:], "ustar\x0000")
+
+	cases := []struct {
+		name string
+		head []byte
+		want string
+	}{
+		{"zip", []byte("PK\x03\x04\x14\x00"), "zip"},
+		{"empty-zip", []byte("PK\x05\x06\x00\x00"), "zip"},
+		{"spanned-zip", []byte("PK\x07\x08PK\x03\x04"), "zip"},
+		{"7z", []byte{'7', 'z', 0xBC, 0xAF, 0x27, 0x1C, 0x00, 0x04}, "7z"},
+		{"gzip", []byte{0x1F, 0x8B, 0x08, 0x00}, "targz"},
+		{"xz", []byte{0xFD, '7', 'z', 'X', 'Z', 0x00, 0x00}, "tar.xz"},
+		{"zstd", []byte{0x28, 0xB5, 0x2F, 0xFD, 0x04}, "tar.zst"},
+		{"bzip2", []byte("BZh91AY&SY"), "tar.bz2"},
+		{"tar", tarHead, "tar"},
+		{"short-tar", tarHead[:260], ""},
+		{"unknown", []byte("hello world"), ""},
+		{"empty", nil, ""},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := DetectFormatBytes(c.head); got != c.want {
+				t.Fatalf("DetectFormatBytes = %q, 期望 %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestDetectFormatIgnoresExtension(t *testing.T) {
+	dir := t.TempDir()
+	// 扩展名与内容不符时以魔数为准
+	path := filepath.Join(dir, "archive.zip")
+	if err := os.WriteFile(path, []byte{0x1F, 0x8B, 0x08, 0x00, 0x00}, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	format, err := DetectFormat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if format != "targz" {
+		t.Fatalf("DetectFormat = %q, 期望 targz", format)
+	}
+
+	unknown := filepath.Join(dir, "data.bin")
+	if err := os.WriteFile(unknown, []byte("not an archive"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DetectFormat(unknown); err == nil {
+		t.Fatal("无法识别的文件应当返回错误")
+	}
+}
+
+func TestDetectFormatSplitReadsFirstVolume(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "archive.7z")
+	// 第一卷为 7z 签名, 第二卷内容任意
+	if err := os.WriteFile(base+".001", []byte{'7', 'z', 0xBC, 0xAF, 0x27, 0x1C, 0x00}, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(base+".002", []byte("PK\x03\x04"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	format, err := DetectFormat(base + ".002")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if format != "7z" {
+		t.Fatalf("DetectFormat = %q, 期望 7z", format)
+	}
+}
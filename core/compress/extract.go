@@ -0,0 +1,145 @@
+// Package compress /core/compress/extract.go
+package compress
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/GoFurry/gf-file-tool/utils"
+	"github.com/GoFurry/gf-file-tool/utils/compress"
+	"github.com/GoFurry/gf-file-tool/utils/log"
+)
+
+// 解压时还原目录以外的特殊条目: 符号链接、硬链接、FIFO 与设备文件.
+// tar 与 zip 共用, 普通文件仍由各格式自己写出.
+
+// maxSymlinkTarget zip 符号链接条目的内容即链接目标, 超过该长度视为损坏
+const maxSymlinkTarget = 64 * 1024
+
+// removeExisting 删除输出路径上已存在的文件或链接, 目录保留
+func removeExisting(outputPath string) error {
+	info, err := os.Lstat(outputPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return fmt.Errorf("已存在同名目录: %s", outputPath)
+	}
+	return os.Remove(outputPath)
+}
+
+// extractSymlink 创建符号链接, 链接目标原样保留
+func extractSymlink(name, target, outputPath string) error {
+	if err := compress.MkdirIfNotExist(filepath.Dir(outputPath)); err != nil {
+		return fmt.Errorf("创建文件目录失败: %s, 错误: %v", filepath.Dir(outputPath), err)
+	}
+	if err := removeExisting(outputPath); err != nil {
+		return fmt.Errorf("替换已有文件失败: %s, 错误: %v", outputPath, err)
+	}
+	if err := os.Symlink(target, outputPath); err != nil {
+		return fmt.Errorf("创建符号链接失败: %s → %s, 错误: %v", name, target, err)
+	}
+	if utils.VerboseMode() {
+		log.Success("已创建符号链接:", name, "→", target)
+	}
+	return nil
+}
+
+// extractHardlink 创建指向已解压文件的硬链接, 文件系统不支持硬链接时复制内容
+// linkname: 链接目标在压缩包内的路径, 按路径策略检查, 不在输出目录内时跳过
+func extractHardlink(opts DecompressOptions, name, linkname, outputPath string) error {
+	targetPath, ok, err := opts.guard.resolveLink(name, linkname)
+	if err != nil || !ok {
+		return err
+	}
+	targetPath = opts.conflicts.actual(targetPath)
+	if _, err := os.Lstat(targetPath); err != nil {
+		if opts.conflicts.wasKept(targetPath) {
+			log.Warn("硬链接的目标保留了已有文件, 跳过:", name, "→", linkname)
+			return nil
+		}
+		return fmt.Errorf("硬链接 %s 的目标 %s 未解压", name, linkname)
+	}
+	if err := compress.MkdirIfNotExist(filepath.Dir(outputPath)); err != nil {
+		return fmt.Errorf("创建文件目录失败: %s, 错误: %v", filepath.Dir(outputPath), err)
+	}
+	if err := removeExisting(outputPath); err != nil {
+		return fmt.Errorf("替换已有文件失败: %s, 错误: %v", outputPath, err)
+	}
+	if err := os.Link(targetPath, outputPath); err != nil {
+		if utils.VerboseMode() {
+			log.Warn("创建硬链接失败, 改为复制:", name, ", 错误:", err)
+		}
+		return copyFile(targetPath, outputPath)
+	}
+	if utils.VerboseMode() {
+		log.Success("已创建硬链接:", name, "→", linkname)
+	}
+	return nil
+}
+
+// extractSpecial 创建 FIFO 或设备文件, 返回是否已创建
+// 未开启 SpecialFiles 时跳过; 设备文件还需路径策略允许
+func extractSpecial(opts DecompressOptions, name string, mode os.FileMode, major, minor int64, outputPath string) (bool, error) {
+	if !opts.SpecialFiles {
+		log.Warn("跳过特殊文件:", name, "(需 --special-files)")
+		return false, nil
+	}
+	if mode&os.ModeDevice != 0 {
+		if ok, err := opts.guard.allowDevice(name); !ok {
+			return false, err
+		}
+	}
+	if err := compress.MkdirIfNotExist(filepath.Dir(outputPath)); err != nil {
+		return false, fmt.Errorf("创建文件目录失败: %s, 错误: %v", filepath.Dir(outputPath), err)
+	}
+	if err := removeExisting(outputPath); err != nil {
+		return false, fmt.Errorf("替换已有文件失败: %s, 错误: %v", outputPath, err)
+	}
+	if err := makeSpecial(outputPath, mode, major, minor); err != nil {
+		return false, fmt.Errorf("创建特殊文件失败: %s, 错误: %v", name, err)
+	}
+	if utils.VerboseMode() {
+		log.Success("已创建特殊文件:", name)
+	}
+	return true, nil
+}
+
+// readSymlinkTarget 读取 zip 符号链接条目的内容作为链接目标
+func readSymlinkTarget(r io.Reader, name string) (string, error) {
+	target, err := io.ReadAll(io.LimitReader(r, maxSymlinkTarget+1))
+	if err != nil {
+		return "", fmt.Errorf("读取符号链接失败: %s, 错误: %v", name, err)
+	}
+	if len(target) == 0 || len(target) > maxSymlinkTarget {
+		return "", fmt.Errorf("符号链接目标无效: %s", name)
+	}
+	return string(target), nil
+}
+
+// copyFile 复制文件内容与权限
+func copyFile(src, dst string) error {
+	srcFile, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer srcFile.Close()
+	info, err := srcFile.Stat()
+	if err != nil {
+		return err
+	}
+	dstFile, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dstFile, srcFile); err != nil {
+		_ = dstFile.Close()
+		return err
+	}
+	return dstFile.Close()
+}
@@ -0,0 +1,153 @@
+// Package compress /core/compress/filter.go
+package compress
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/GoFurry/gf-file-tool/utils/compress"
+)
+
+// EntryFilter 解压条目筛选
+// 指定了条目列表或 --include 时只解压匹配的条目, --exclude 优先级最高
+type EntryFilter struct {
+	Include []string // 包含的通配模式
+	Exclude []string // 排除的通配模式
+	Entries []string // 显式指定的条目路径, 指定目录时包含其下所有文件
+
+	found map[string]bool // 已匹配到的显式条目
+	isDir map[string]bool // 按目录前缀匹配到的显式条目
+	parts bool            // 解压按条目分卷的各分包: 大文件的各段按原文件名匹配, 每个分包都要读完
+}
+
+// NewEntryFilter 创建筛选器并校验模式语法, 不需要筛选时返回 nil
+func NewEntryFilter(include, exclude, entries []string) (*EntryFilter, error) {
+	if len(include) == 0 && len(exclude) == 0 && len(entries) == 0 {
+		return nil, nil
+	}
+	for _, pattern := range append(append([]string{}, include...), exclude...) {
+		if err := compress.ValidatePattern(pattern); err != nil {
+			return nil, fmt.Errorf("无效的通配模式: %s, 错误: %v", pattern, err)
+		}
+	}
+	filter := &EntryFilter{Include: include, Exclude: exclude, found: make(map[string]bool), isDir: make(map[string]bool)}
+	for _, entry := range entries {
+		filter.Entries = append(filter.Entries, compress.CleanEntryName(entry))
+	}
+	return filter, nil
+}
+
+// Match 条目是否需要解压, nil 筛选器匹配全部条目, dir 表示条目本身是目录
+func (f *EntryFilter) Match(name string, dir bool) bool {
+	if f == nil {
+		return true
+	}
+	name = compress.CleanEntryName(name)
+	if base, _, _, ok := parseChunkName(name); ok && f.parts {
+		name = base
+	}
+	for _, pattern := range f.Exclude {
+		if compress.MatchPattern(pattern, name) {
+			return false
+		}
+	}
+	if len(f.Include) == 0 && len(f.Entries) == 0 {
+		return true
+	}
+	matched := false
+	for _, entry := range f.Entries {
+		switch {
+		case name == entry:
+			f.found[entry] = true
+			f.isDir[entry] = f.isDir[entry] || dir
+			matched = true
+		case strings.HasPrefix(name, entry+"/"):
+			f.found[entry] = true
+			f.isDir[entry] = true
+			matched = true
+		}
+	}
+	if matched {
+		return true
+	}
+	for _, pattern := range f.Include {
+		if compress.MatchPattern(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Done 只按显式条目筛选且全部找到时返回 true, 顺序读取的格式 (tar) 可提前结束
+// 指定的是目录时其下文件数未知, 按条目分卷时之后的分包中还有同一文件的其他段, 都不提前结束
+func (f *EntryFilter) Done() bool {
+	if f == nil || f.parts || len(f.Include) > 0 || len(f.Entries) == 0 {
+		return false
+	}
+	for _, dir := range f.isDir {
+		if dir {
+			return false
+		}
+	}
+	return len(f.Unmatched()) == 0
+}
+
+// Unmatched 未在压缩包中找到的显式条目
+func (f *EntryFilter) Unmatched() []string {
+	if f == nil {
+		return nil
+	}
+	var missing []string
+	for _, entry := range f.Entries {
+		if !f.found[entry] {
+			missing = append(missing, entry)
+		}
+	}
+	return missing
+}
+
+// EntryWriter 把单个条目的内容写入 w 的接口, 由各格式解压缩器实现
+type EntryWriter interface {
+	WriteEntry(opts DecompressOptions, name string, w io.Writer) error
+}
+
+// RunCat 把压缩包内单个条目输出到 w, 不解压其他条目
+func RunCat(opts DecompressOptions, name string, w io.Writer) error {
+	if opts.SourcePath == "" || !archiveExists(opts.SourcePath) {
+		return fmt.Errorf("压缩包不存在: %s", opts.SourcePath)
+	}
+	if opts.Format == "" {
+		format, err := DetectFormat(opts.SourcePath)
+		if err != nil {
+			return err
+		}
+		opts.Format = format
+	}
+
+	// 复用解压缩器的格式分发
+	decompressor, err := NewDecompressor(opts.Format)
+	if err != nil {
+		return err
+	}
+	writer, ok := decompressor.(EntryWriter)
+	if !ok {
+		return fmt.Errorf("%s 格式不支持读取单个条目", opts.Format)
+	}
+	return writer.WriteEntry(opts, compress.CleanEntryName(name), w)
+}
+
+// errEntryNotFound 条目不存在
+func errEntryNotFound(name string) error {
+	return fmt.Errorf("压缩包内不存在: %s", name)
+}
+
+// errEntryIsLink 条目是链接
+func errEntryIsLink(name, target string) error {
+	return fmt.Errorf("%s 是链接 (→ %s), 请读取链接目标", name, target)
+}
+
+// errEntryIsDir 条目是目录
+func errEntryIsDir(name string) error {
+	return fmt.Errorf("%s 是目录, 只能输出文件", name)
+}
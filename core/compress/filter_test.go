@@ -0,0 +1,141 @@
+package compress
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// nestedTreeFiles 多层目录的测试文件, 第一个源文件位于根目录, 条目路径保持目录结构
+func nestedTreeFiles() map[string][]byte {
+	return map[string][]byte{
+		"a.txt":            []byte("root file"),
+		"docs/guide.md":    []byte("# guide"),
+		"docs/api/ref.md":  []byte("# ref"),
+		"logs/app.log":     []byte(strings.Repeat("log line\n", 100)),
+		"logs/old/app.log": []byte("old log"),
+		"src/main.go":      []byte("package main"),
+	}
+}
+
+// pickFiles 按名称挑选测试文件
+func pickFiles(files map[string][]byte, names ...string) map[string][]byte {
+	picked := map[string][]byte{}
+	for _, name := range names {
+		picked[name] = files[name]
+	}
+	return picked
+}
+
+func TestEntryFilterMatch(t *testing.T) {
+	filter, err := NewEntryFilter([]string{"*.md"}, []string{"docs/api/**"}, []string{"./logs/", "src/main.go", "missing.txt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var matched []string
+	for name := range nestedTreeFiles() {
+		if filter.Match(name, false) {
+			matched = append(matched, name)
+		}
+	}
+	sort.Strings(matched)
+	want := []string{"docs/guide.md", "logs/app.log", "logs/old/app.log", "src/main.go"}
+	if !reflect.DeepEqual(matched, want) {
+		t.Fatalf("匹配 = %v, 期望 %v", matched, want)
+	}
+	if missing := filter.Unmatched(); !reflect.DeepEqual(missing, []string{"missing.txt"}) {
+		t.Fatalf("未找到 = %v", missing)
+	}
+
+	// 无筛选条件时返回 nil, nil 筛选器匹配全部
+	if filter, err := NewEntryFilter(nil, nil, nil); err != nil || filter != nil {
+		t.Fatalf("NewEntryFilter() = %v, %v", filter, err)
+	}
+	var none *EntryFilter
+	if !none.Match("any", false) || none.Done() || none.Unmatched() != nil {
+		t.Fatal("nil 筛选器应当匹配全部")
+	}
+	if _, err := NewEntryFilter([]string{"["}, nil, nil); err == nil {
+		t.Fatal("无效的通配模式应当报错")
+	}
+}
+
+func TestEntryFilterDone(t *testing.T) {
+	filter, _ := NewEntryFilter(nil, nil, []string{"a.txt", "src/main.go"})
+	filter.Match("a.txt", false)
+	if filter.Done() {
+		t.Fatal("还有条目未找到")
+	}
+	filter.Match("src/main.go", false)
+	if !filter.Done() {
+		t.Fatal("显式条目全部找到后应当提前结束")
+	}
+
+	// 指定目录时其下文件数未知, 不提前结束
+	filter, _ = NewEntryFilter(nil, nil, []string{"docs"})
+	filter.Match("docs/guide.md", false)
+	if filter.Done() {
+		t.Fatal("指定目录时不应提前结束")
+	}
+}
+
+func TestDecompressFilter(t *testing.T) {
+	files := nestedTreeFiles()
+	for _, format := range []string{"zip", "7z", "tar.gz"} {
+		t.Run(format, func(t *testing.T) {
+			archive := writeTestArchive(t, files, CompressOptions{Format: format})
+			filter, err := NewEntryFilter(nil, []string{"old"}, []string{"logs", "a.txt"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			output := t.TempDir()
+			if err := RunDecompress(DecompressOptions{SourcePath: archive, OutputDir: output, Filter: filter}); err != nil {
+				t.Fatal(err)
+			}
+			assertSameFiles(t, readOutputTree(t, output), pickFiles(files, "a.txt", "logs/app.log"))
+		})
+	}
+
+	// 标准输入的 zip 按本地文件头顺序筛选
+	t.Run("zip-stdin", func(t *testing.T) {
+		archive := writeTestArchive(t, files, CompressOptions{Format: "zip"})
+		data, err := os.ReadFile(archive)
+		if err != nil {
+			t.Fatal(err)
+		}
+		withStdio(t, data)
+		filter, _ := NewEntryFilter([]string{"*.md"}, nil, nil)
+		output := t.TempDir()
+		if err := RunDecompress(DecompressOptions{SourcePath: StdioPath, OutputDir: output, Filter: filter}); err != nil {
+			t.Fatal(err)
+		}
+		assertSameFiles(t, readOutputTree(t, output), pickFiles(files, "docs/guide.md", "docs/api/ref.md"))
+	})
+}
+
+func TestRunCat(t *testing.T) {
+	files := nestedTreeFiles()
+	for _, format := range []string{"zip", "7z", "tar.zst"} {
+		t.Run(format, func(t *testing.T) {
+			archive := writeTestArchive(t, files, CompressOptions{Format: format})
+			var out bytes.Buffer
+			if err := RunCat(DecompressOptions{SourcePath: archive}, "./logs/app.log", &out); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(out.Bytes(), files["logs/app.log"]) {
+				t.Fatalf("输出 %d 字节, 期望 %d 字节", out.Len(), len(files["logs/app.log"]))
+			}
+			if err := RunCat(DecompressOptions{SourcePath: archive}, "nope.txt", &out); err == nil || !strings.Contains(err.Error(), "不存在") {
+				t.Fatalf("条目不存在应当报错, 实际: %v", err)
+			}
+		})
+	}
+
+	if err := RunCat(DecompressOptions{SourcePath: filepath.Join(t.TempDir(), "none.zip")}, "a", &bytes.Buffer{}); err == nil {
+		t.Fatal("压缩包不存在应当报错")
+	}
+}
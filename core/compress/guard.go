@@ -0,0 +1,203 @@
+// Package compress /core/compress/guard.go
+package compress
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/GoFurry/gf-file-tool/utils/log"
+)
+
+// 解压路径防护, 所有格式共用:
+//   - 绝对路径、盘符与 .. 路径段: 按策略去除、跳过或中止
+//   - 父目录是已存在 (含本次解压出) 的符号链接: 写入会落到输出目录之外, 无法修正, 按不安全条目处理
+//   - 目标本身是符号链接: 目录条目按不安全条目处理, 其余条目按覆盖策略处理 (覆盖时替换链接, 不会写穿)
+//   - 暂存目录与输出目录中已有的内容都要检查, 合并时同样会经过输出目录中的符号链接
+//   - 设备文件: 按不安全条目处理, 只有 trust 策略且开启 --special-files 时创建
+//   - 硬链接的目标按同样的规则检查
+// 符号链接条目本身的目标原样保留: 链接只会被替换, 不会被写穿.
+
+// PathPolicy 不安全条目的处理策略
+type PathPolicy string
+
+const (
+	PathSanitize PathPolicy = "sanitize" // 去除绝对路径与 .. 后解压, 无法修正的条目跳过 (默认)
+	PathSkip     PathPolicy = "skip"     // 跳过所有不安全条目
+	PathError    PathPolicy = "error"    // 遇到不安全条目时中止解压
+	PathTrust    PathPolicy = "trust"    // 信任压缩包, 不做检查 (仅用于可信的备份还原)
+)
+
+// ParsePathPolicy 解析 --path-policy, 空字符串表示默认 sanitize
+func ParsePathPolicy(name string) (PathPolicy, error) {
+	switch policy := PathPolicy(strings.ToLower(strings.TrimSpace(name))); policy {
+	case "":
+		return PathSanitize, nil
+	case PathSanitize, PathSkip, PathError, PathTrust:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("不支持的路径策略: %s, 仅支持 sanitize/skip/error/trust", name)
+	}
+}
+
+// pathGuard 解析条目在输出目录内的路径, 记录被修正与跳过的条目
+type pathGuard struct {
+	root      string // 实际写入的暂存目录
+	target    string // 最终的输出目录
+	policy    PathPolicy
+	dirs      map[string]bool // 已确认是真实目录的路径
+	sanitized int
+	skipped   int
+}
+
+// newPathGuard 创建路径防护, root 为写入的暂存目录, target 为合并到的输出目录
+func newPathGuard(root, target string, policy PathPolicy) *pathGuard {
+	if policy == "" {
+		policy = PathSanitize
+	}
+	return &pathGuard{root: filepath.Clean(root), target: filepath.Clean(target), policy: policy, dirs: make(map[string]bool)}
+}
+
+// resolve 返回条目的输出路径, ok 为 false 表示按策略跳过, err 非 nil 表示按策略中止
+// dir: 条目本身是目录
+func (g *pathGuard) resolve(name string, dir bool) (string, bool, error) {
+	if g.policy == PathTrust {
+		return filepath.Join(g.root, name), true, nil
+	}
+
+	clean, changed := sanitizeEntryName(name)
+	if clean == "" {
+		return "", false, g.reject(name, "路径为空或只含 ..")
+	}
+	if changed {
+		if g.policy != PathSanitize {
+			return "", false, g.reject(name, "包含绝对路径或 ..")
+		}
+		g.sanitized++
+		log.Warn("已去除条目名中不安全的部分:", name, "→", clean)
+	}
+
+	outputPath := filepath.Join(g.root, filepath.FromSlash(clean))
+	if reason := g.unsafePath(g.root, outputPath, dir); reason != "" {
+		return "", false, g.reject(name, reason)
+	}
+	if reason := g.unsafePath(g.target, filepath.Join(g.target, filepath.FromSlash(clean)), dir); reason != "" {
+		return "", false, g.reject(name, reason)
+	}
+	return outputPath, true, nil
+}
+
+// unsafePath 检查 root 下的 path 是否经过符号链接, 返回原因, 安全时返回空
+func (g *pathGuard) unsafePath(root, path string, dir bool) string {
+	if via := g.symlinkParent(root, path); via != "" {
+		return "经过符号链接 " + via
+	}
+	// 目标本身是符号链接: 目录无法安全地复用
+	if info, err := os.Lstat(path); dir && err == nil && info.Mode()&os.ModeSymlink != 0 {
+		return "目标是符号链接"
+	}
+	return ""
+}
+
+// resolveLink 返回硬链接目标的路径, 目标必须是输出目录内已解压的非链接文件
+func (g *pathGuard) resolveLink(name, linkname string) (string, bool, error) {
+	if g.policy == PathTrust {
+		return filepath.Join(g.root, linkname), true, nil
+	}
+	clean, changed := sanitizeEntryName(linkname)
+	if clean == "" || changed {
+		return "", false, g.reject(name, "硬链接目标 "+linkname+" 不在输出目录内")
+	}
+	targetPath := filepath.Join(g.root, filepath.FromSlash(clean))
+	if via := g.symlinkParent(g.root, targetPath); via != "" {
+		return "", false, g.reject(name, "硬链接目标经过符号链接 "+via)
+	}
+	if info, err := os.Lstat(targetPath); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		return "", false, g.reject(name, "硬链接目标是符号链接")
+	}
+	return targetPath, true, nil
+}
+
+// allowDevice 设备文件是否可以创建, 只有 trust 策略允许
+func (g *pathGuard) allowDevice(name string) (bool, error) {
+	if g.policy == PathTrust {
+		return true, nil
+	}
+	return false, g.reject(name, "设备文件 (需 --path-policy trust)")
+}
+
+// reject 按策略处理不安全条目: error 策略返回错误, 其余记录后跳过
+func (g *pathGuard) reject(name, reason string) error {
+	if g.policy == PathError {
+		return fmt.Errorf("不安全的条目: %s (%s)", name, reason)
+	}
+	g.skipped++
+	log.Warn("跳过不安全的条目:", name, "("+reason+")")
+	return nil
+}
+
+// symlinkParent 检查 root 与 path 之间的各级父目录, 返回第一个符号链接, 都不是时返回空
+func (g *pathGuard) symlinkParent(root, path string) string {
+	rel, err := filepath.Rel(root, filepath.Dir(path))
+	if err != nil || rel == "." {
+		return ""
+	}
+	current := root
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		current = filepath.Join(current, part)
+		if g.dirs[current] {
+			continue
+		}
+		info, err := os.Lstat(current)
+		if err != nil {
+			// 尚未创建, 其下的路径也不存在
+			return ""
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return current
+		}
+		if info.IsDir() {
+			g.dirs[current] = true
+		}
+	}
+	return ""
+}
+
+// report 输出被修正与跳过的条目数
+func (g *pathGuard) report() {
+	if g == nil {
+		return
+	}
+	if g.sanitized > 0 {
+		log.Warn("共修正", g.sanitized, "个不安全的条目名")
+	}
+	if g.skipped > 0 {
+		log.Warn("因路径不安全跳过", g.skipped, "个条目")
+	}
+}
+
+// sanitizeEntryName 去掉盘符、开头的 / 与所有 . 和 .. 路径段, changed 表示去掉了盘符、/ 或 ..
+// 盘符与 \ 分隔符只在 Windows 上识别
+func sanitizeEntryName(name string) (string, bool) {
+	changed := false
+	if volume := filepath.VolumeName(name); volume != "" {
+		name = name[len(volume):]
+		changed = true
+	}
+	name = filepath.ToSlash(name)
+	if strings.HasPrefix(name, "/") {
+		changed = true
+	}
+	var parts []string
+	for _, part := range strings.Split(name, "/") {
+		switch part {
+		case "", ".":
+		case "..":
+			changed = true
+		default:
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, "/"), changed
+}
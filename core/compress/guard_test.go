@@ -0,0 +1,172 @@
+package compress
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// rawEntry 直接写入测试压缩包的条目, 名称不做任何处理
+type rawEntry struct {
+	name     string
+	body     string
+	typeflag byte // tar 条目类型, 0 表示普通文件
+	linkname string
+}
+
+// writeRawTar 用 archive/tar 写出包含任意条目名的 tar 包
+func writeRawTar(t *testing.T, entries []rawEntry) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "raw.tar")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	writer := tar.NewWriter(file)
+	for _, entry := range entries {
+		header := &tar.Header{Name: entry.name, Mode: 0o644, Size: int64(len(entry.body)), Typeflag: entry.typeflag, Linkname: entry.linkname}
+		switch entry.typeflag {
+		case 0:
+			header.Typeflag = tar.TypeReg
+		case tar.TypeDir:
+			header.Mode = 0o755
+		case tar.TypeSymlink, tar.TypeLink, tar.TypeChar:
+			header.Size = 0
+		}
+		if err := writer.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if header.Size > 0 {
+			if _, err := writer.Write([]byte(entry.body)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// writeRawZip 用 archive/zip 写出包含任意条目名的 zip 包
+func writeRawZip(t *testing.T, entries []rawEntry) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "raw.zip")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	writer := zip.NewWriter(file)
+	for _, entry := range entries {
+		w, err := writer.Create(entry.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(entry.body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestSanitizeEntryName(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		clean   string
+		changed bool
+	}{
+		{"a/b.txt", "a/b.txt", false},
+		{"./a//b.txt", "a/b.txt", false},
+		{"a/", "a", false},
+		{"/etc/passwd", "etc/passwd", true},
+		{"../../evil.txt", "evil.txt", true},
+		{"a/../../b.txt", "a/b.txt", true},
+		{"..", "", true},
+		{"", "", false},
+	} {
+		clean, changed := sanitizeEntryName(tc.name)
+		if clean != tc.clean || changed != tc.changed {
+			t.Errorf("sanitizeEntryName(%q) = %q, %t, 期望 %q, %t", tc.name, clean, changed, tc.clean, tc.changed)
+		}
+	}
+}
+
+func TestParsePathPolicy(t *testing.T) {
+	for input, want := range map[string]PathPolicy{"": PathSanitize, " Skip ": PathSkip, "error": PathError, "TRUST": PathTrust} {
+		if got, err := ParsePathPolicy(input); err != nil || got != want {
+			t.Errorf("ParsePathPolicy(%q) = %q, %v", input, got, err)
+		}
+	}
+	if _, err := ParsePathPolicy("ignore"); err == nil {
+		t.Fatal("未知策略应报错")
+	}
+}
+
+func TestPathPolicyTraversal(t *testing.T) {
+	entries := []rawEntry{
+		{name: "ok.txt", body: "ok"},
+		{name: "../evil.txt", body: "evil"},
+		{name: "/abs/evil.txt", body: "abs"},
+		{name: "..", body: "dots"},
+	}
+	archives := map[string]string{"tar": writeRawTar(t, entries), "zip": writeRawZip(t, entries)}
+
+	for format, archive := range archives {
+		for _, tc := range []struct {
+			policy PathPolicy
+			files  map[string][]byte
+			fail   bool
+		}{
+			{PathSanitize, map[string][]byte{"ok.txt": []byte("ok"), "evil.txt": []byte("evil"), "abs/evil.txt": []byte("abs")}, false},
+			{PathSkip, map[string][]byte{"ok.txt": []byte("ok")}, false},
+			{PathError, nil, true},
+		} {
+			t.Run(format+"/"+string(tc.policy), func(t *testing.T) {
+				parent := t.TempDir()
+				output := filepath.Join(parent, "out")
+				err := RunDecompress(DecompressOptions{SourcePath: archive, OutputDir: output, PathPolicy: tc.policy})
+				if tc.fail {
+					if err == nil {
+						t.Fatal("error 策略遇到不安全条目应中止")
+					}
+				} else {
+					if err != nil {
+						t.Fatal(err)
+					}
+					assertSameFiles(t, readOutputTree(t, output), tc.files)
+				}
+				// 任何策略都不会写到输出目录之外
+				if _, err := os.Stat(filepath.Join(parent, "evil.txt")); !os.IsNotExist(err) {
+					t.Fatalf("条目逃逸到输出目录之外: %v", err)
+				}
+			})
+		}
+	}
+}
+
+func TestPathPolicyHardlinkOutside(t *testing.T) {
+	archive := writeRawTar(t, []rawEntry{
+		{name: "ok.txt", body: "ok"},
+		{name: "steal", typeflag: tar.TypeLink, linkname: "../secret.txt"},
+	})
+	parent := t.TempDir()
+	if err := os.WriteFile(filepath.Join(parent, "secret.txt"), []byte("secret"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	output := filepath.Join(parent, "out")
+	if err := RunDecompress(DecompressOptions{SourcePath: archive, OutputDir: output}); err != nil {
+		t.Fatal(err)
+	}
+	assertSameFiles(t, readOutputTree(t, output), map[string][]byte{"ok.txt": []byte("ok")})
+
+	if err := RunDecompress(DecompressOptions{SourcePath: archive, OutputDir: t.TempDir(), PathPolicy: PathError}); err == nil {
+		t.Fatal("error 策略下指向输出目录之外的硬链接应中止")
+	}
+}
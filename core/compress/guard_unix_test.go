@@ -0,0 +1,102 @@
+//go:build linux || darwin
+
+package compress
+
+import (
+	"archive/tar"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPathPolicySymlinkEscape(t *testing.T) {
+	// 先解压出指向外部的符号链接, 再经过它写文件
+	archive := writeRawTar(t, []rawEntry{
+		{name: "link", typeflag: tar.TypeSymlink, linkname: ".."},
+		{name: "link/pwned.txt", body: "pwned"},
+		{name: "link/sub", typeflag: tar.TypeDir},
+		{name: "ok.txt", body: "ok"},
+	})
+	for _, policy := range []PathPolicy{PathSanitize, PathSkip} {
+		parent := t.TempDir()
+		output := filepath.Join(parent, "out")
+		if err := RunDecompress(DecompressOptions{SourcePath: archive, OutputDir: output, PathPolicy: policy}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := os.Stat(filepath.Join(parent, "pwned.txt")); !os.IsNotExist(err) {
+			t.Fatalf("%s: 经过符号链接写到了输出目录之外: %v", policy, err)
+		}
+		if _, err := os.Stat(filepath.Join(parent, "sub")); !os.IsNotExist(err) {
+			t.Fatalf("%s: 经过符号链接创建了外部目录: %v", policy, err)
+		}
+		if data, err := os.ReadFile(filepath.Join(output, "ok.txt")); err != nil || string(data) != "ok" {
+			t.Fatalf("%s: 安全条目应正常解压: %q, %v", policy, data, err)
+		}
+	}
+
+	parent := t.TempDir()
+	if err := RunDecompress(DecompressOptions{SourcePath: archive, OutputDir: filepath.Join(parent, "out"), PathPolicy: PathError}); err == nil {
+		t.Fatal("error 策略遇到经过符号链接的条目应中止")
+	}
+	if _, err := os.Stat(filepath.Join(parent, "pwned.txt")); !os.IsNotExist(err) {
+		t.Fatalf("经过符号链接写到了输出目录之外: %v", err)
+	}
+}
+
+func TestPathPolicyReplacesExistingSymlink(t *testing.T) {
+	parent := t.TempDir()
+	outside := filepath.Join(parent, "outside.txt")
+	if err := os.WriteFile(outside, []byte("keep"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	output := filepath.Join(parent, "out")
+	if err := os.Mkdir(output, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(output, "file.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	// 同名的符号链接被替换, 不会写穿到链接目标
+	archive := writeRawTar(t, []rawEntry{{name: "file.txt", body: "new"}})
+	if err := RunDecompress(DecompressOptions{SourcePath: archive, OutputDir: output}); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(outside); string(data) != "keep" {
+		t.Fatalf("链接目标被改写: %q", data)
+	}
+	info, err := os.Lstat(filepath.Join(output, "file.txt"))
+	if err != nil || !info.Mode().IsRegular() {
+		t.Fatalf("符号链接应被替换为普通文件: %v, %v", info, err)
+	}
+}
+
+func TestPathPolicyDeviceNodes(t *testing.T) {
+	archive := writeRawTar(t, []rawEntry{
+		{name: "null", typeflag: tar.TypeChar},
+		{name: "ok.txt", body: "ok"},
+	})
+	// 设备文件只有 trust 策略才创建
+	output := t.TempDir()
+	if err := RunDecompress(DecompressOptions{SourcePath: archive, OutputDir: output, SpecialFiles: true}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Lstat(filepath.Join(output, "null")); !os.IsNotExist(err) {
+		t.Fatalf("sanitize 策略不应创建设备文件: %v", err)
+	}
+	if err := RunDecompress(DecompressOptions{SourcePath: archive, OutputDir: t.TempDir(), SpecialFiles: true, PathPolicy: PathError}); err == nil {
+		t.Fatal("error 策略遇到设备文件应中止")
+	}
+
+	if os.Geteuid() != 0 {
+		t.Skip("创建设备文件需要 root")
+	}
+	output = t.TempDir()
+	if err := RunDecompress(DecompressOptions{SourcePath: archive, OutputDir: output, SpecialFiles: true, PathPolicy: PathTrust}); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Lstat(filepath.Join(output, "null"))
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		t.Fatalf("trust 策略应创建设备文件: %v, %v", info, err)
+	}
+}
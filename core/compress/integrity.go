@@ -0,0 +1,88 @@
+// Package compress /core/compress/integrity.go
+package compress
+
+import (
+	"fmt"
+)
+
+// 完整性校验不落盘: 每个条目的数据都完整地流过解密与解压, 再丢弃,
+// 由各格式自身的校验机制判断是否损坏 —— zip 的 CRC32、tar 外层压缩流的校验和、
+// WinZip AES 的 HMAC 认证码、旧版本 AES-GCM 的认证标签、7z 头部记录的 CRC32.
+
+// TestResult 单个条目的校验结果
+type TestResult struct {
+	Name string // 条目路径
+	Size int64  // 校验时读出的明文字节数
+	Err  error  // nil 表示通过
+}
+
+// TestReport 校验报告
+type TestReport struct {
+	Path    string       // 压缩包路径
+	Format  string       // 压缩格式
+	Results []TestResult // 各条目结果
+	Err     error        // 压缩包级错误 (目录损坏、流被截断等), 其后的条目无法继续校验
+}
+
+// Failed 校验失败的条目数
+func (r *TestReport) Failed() int {
+	failed := 0
+	for _, result := range r.Results {
+		if result.Err != nil {
+			failed++
+		}
+	}
+	return failed
+}
+
+// OK 是否全部通过
+func (r *TestReport) OK() bool {
+	return r.Err == nil && r.Failed() == 0
+}
+
+// Tester 不解压到磁盘的完整性校验接口, 由各格式解压缩器实现
+// report 在每个条目校验完成后调用; 返回的错误为压缩包级错误
+type Tester interface {
+	Test(opts DecompressOptions, report func(TestResult)) error
+}
+
+// RunTest 完整性校验入口, onResult 可为 nil, 用于实时输出每个条目的结果
+// 返回的错误表示无法开始校验 (文件不存在、格式无法识别), 校验中发现的问题记录在报告中
+func RunTest(opts DecompressOptions, onResult func(TestResult)) (*TestReport, error) {
+	// 参数校验, 分卷只要第一卷存在即可
+	if opts.SourcePath == "" || !archiveExists(opts.SourcePath) {
+		return nil, fmt.Errorf("压缩包不存在: %s", opts.SourcePath)
+	}
+
+	// 未指定格式时根据文件头识别
+	if opts.Format == "" {
+		format, err := DetectFormat(opts.SourcePath)
+		if err != nil {
+			return nil, err
+		}
+		opts.Format = format
+	}
+
+	// 复用解压缩器的格式分发
+	decompressor, err := NewDecompressor(opts.Format)
+	if err != nil {
+		return nil, err
+	}
+	tester, ok := decompressor.(Tester)
+	if !ok {
+		return nil, fmt.Errorf("%s 格式不支持完整性校验", opts.Format)
+	}
+
+	report := &TestReport{Path: opts.SourcePath, Format: opts.Format}
+	// 有分卷说明文件时先逐卷校验, 有问题的分卷作为压缩包级错误报告, 不修改分卷
+	if report.Err = verifySplitVolumes(opts.SourcePath, false); report.Err != nil {
+		return report, nil
+	}
+	report.Err = tester.Test(opts, func(result TestResult) {
+		report.Results = append(report.Results, result)
+		if onResult != nil {
+			onResult(result)
+		}
+	})
+	return report, nil
+}
@@ -0,0 +1,166 @@
+package compress
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeTestArchive 压缩测试文件, 返回压缩包路径
+func writeTestArchive(t *testing.T, files map[string][]byte, opts CompressOptions) string {
+	t.Helper()
+	if opts.OutputPath == "" {
+		opts.OutputPath = filepath.Join(t.TempDir(), "test."+opts.Format)
+	}
+	opts.Entries = writeTestTree(t, files)
+	if opts.Level == 0 {
+		opts.Level = -1
+	}
+	if err := RunCompress(opts); err != nil {
+		t.Fatal(err)
+	}
+	return opts.OutputPath
+}
+
+// flipByte 翻转文件中指定偏移处的一个字节
+func flipByte(t *testing.T, path string, offset int64) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[offset] ^= 0xFF
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunTestIntact(t *testing.T) {
+	files := testTreeFiles()
+	for _, format := range []string{"zip", "7z", "tar", "tar.zst", "tar.xz"} {
+		t.Run(format, func(t *testing.T) {
+			archive := writeTestArchive(t, files, CompressOptions{Format: format})
+			report, err := RunTest(DecompressOptions{SourcePath: archive}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !report.OK() {
+				t.Fatalf("完好的压缩包校验失败: %v %+v", report.Err, report.Results)
+			}
+			var count int
+			for _, result := range report.Results {
+				if data, ok := files[result.Name]; ok {
+					count++
+					if result.Size != int64(len(data)) {
+						t.Errorf("%s: 校验读出 %d 字节, 期望 %d", result.Name, result.Size, len(data))
+					}
+				}
+			}
+			if count != len(files) {
+				t.Fatalf("校验了 %d 个文件, 期望 %d", count, len(files))
+			}
+		})
+	}
+}
+
+func TestRunTestCorruptZipEntry(t *testing.T) {
+	files := testTreeFiles()
+	archive := writeTestArchive(t, files, CompressOptions{Format: "zip", Method: "store"})
+
+	// 在 data/random.bin 的数据区中间翻转一个字节, 只有这个条目的 CRC32 不匹配
+	reader, err := zip.OpenReader(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var offset int64
+	for _, file := range reader.File {
+		if file.Name == "data/random.bin" {
+			offset, err = file.DataOffset()
+			if err != nil {
+				t.Fatal(err)
+			}
+			offset += int64(file.CompressedSize64 / 2)
+		}
+	}
+	_ = reader.Close()
+	flipByte(t, archive, offset)
+
+	var reported []string
+	report, err := RunTest(DecompressOptions{SourcePath: archive}, func(result TestResult) {
+		reported = append(reported, result.Name)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.OK() || report.Failed() != 1 {
+		t.Fatalf("应当只有 1 个条目失败, 实际 %d (%v)", report.Failed(), report.Err)
+	}
+	for _, result := range report.Results {
+		if (result.Err != nil) != (result.Name == "data/random.bin") {
+			t.Errorf("%s: 校验结果 %v", result.Name, result.Err)
+		}
+	}
+	if len(reported) != len(report.Results) {
+		t.Fatalf("回调 %d 次, 报告 %d 条", len(reported), len(report.Results))
+	}
+}
+
+func TestRunTestTruncatedTar(t *testing.T) {
+	archive := writeTestArchive(t, testTreeFiles(), CompressOptions{Format: "tar.zst"})
+	info, err := os.Stat(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Truncate(archive, info.Size()/2); err != nil {
+		t.Fatal(err)
+	}
+	report, err := RunTest(DecompressOptions{SourcePath: archive}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.OK() {
+		t.Fatal("截断的 tar.zst 应当校验失败")
+	}
+}
+
+func TestRunTestCorrupt7z(t *testing.T) {
+	archive := writeTestArchive(t, testTreeFiles(), CompressOptions{Format: "7z"})
+	// 签名头之后即为压缩数据
+	flipByte(t, archive, 64)
+	report, err := RunTest(DecompressOptions{SourcePath: archive}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.OK() {
+		t.Fatal("损坏的 7z 应当校验失败")
+	}
+}
+
+func TestRunTestWrongPassword(t *testing.T) {
+	files := testTreeFiles()
+	archive := writeTestArchive(t, files, CompressOptions{Format: "zip", Encrypt: true, Password: "right", KeyLength: 32})
+
+	opts := DecompressOptions{SourcePath: archive, Encrypt: true}
+	if err := PrepareDecrypt(&opts, "right", 32); err != nil {
+		t.Fatal(err)
+	}
+	report, err := RunTest(opts, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.OK() {
+		t.Fatalf("密码正确时校验失败: %v %+v", report.Err, report.Results)
+	}
+
+	if err := PrepareDecrypt(&opts, "wrong", 32); err != nil {
+		t.Fatal(err)
+	}
+	report, err = RunTest(opts, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.OK() {
+		t.Fatal("密码错误时应当校验失败")
+	}
+}
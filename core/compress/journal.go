@@ -0,0 +1,556 @@
+// Package compress /core/compress/journal.go
+package compress
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/GoFurry/gf-file-tool/utils"
+	"github.com/GoFurry/gf-file-tool/utils/log"
+)
+
+// 续传日志: 压缩与解压都在输出旁边记录已完成的条目与字节偏移, 任务中断后用 --resume 继续.
+// 日志为 JSON Lines, 第一行是任务信息, 之后每行一条记录; 进程被结束时最后一行可能不完整, 读取时忽略.
+//   - 压缩 (tar 系列与 zip, 输出到文件且不分卷): 每写出约 journalInterval 字节记录一个检查点,
+//     记录前先结束当前压缩帧 (tar 系列) 并刷盘, 续传时把输出截断到检查点再追加.
+//     tar 可以在大文件中间设检查点; zip 只在条目之间, 续传后按日志中的记录重建中央目录.
+//   - 解压 (非标准输入): 记录已解压条目在暂存目录中的路径、大小与 CRC32, 续传时复用暂存目录,
+//     大小与 CRC32 都与记录一致的条目跳过, 其余重新解压.
+// 日志在第一个检查点才创建, 任务成功后删除.
+
+const (
+	journalVersion  = 1
+	journalInterval = 64 << 20 // 检查点间隔 (字节)
+	journalSuffix   = ".gf-journal"
+)
+
+// ResumableError 任务失败但已记录检查点, 可以用 --resume 继续
+type ResumableError struct {
+	Journal string // 续传日志路径
+	Err     error
+}
+
+// Error 错误信息
+func (e *ResumableError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap 原始错误
+func (e *ResumableError) Unwrap() error {
+	return e.Err
+}
+
+// journalHeader 日志第一行, 续传时必须与本次任务一致
+type journalHeader struct {
+	Version     int    `json:"version"`
+	Kind        string `json:"kind"`              // compress/decompress
+	Fingerprint string `json:"fingerprint"`       // 任务参数与输入的摘要
+	Staging     string `json:"staging,omitempty"` // 解压的暂存目录
+}
+
+// journal 续传日志文件
+type journal struct {
+	path      string
+	header    journalHeader
+	file      *os.File
+	validEnd  int64             // 上次日志中完整记录的结尾, 续写前截断到此处
+	records   []json.RawMessage // 续传时读取的上次记录
+	pending   [][]byte          // 尚未写入的记录
+	committed bool              // 本次已写入记录
+}
+
+// openJournal 打开日志: resume 时读取并校验上次的日志, 否则删除旧日志
+func openJournal(path string, header journalHeader, resume bool) (*journal, error) {
+	header.Version = journalVersion
+	j := &journal{path: path, header: header}
+	previous, records, validEnd, err := readJournal(path)
+	if os.IsNotExist(err) {
+		if resume {
+			log.Warn("未找到续传日志, 从头开始:", path)
+		}
+		return j, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取续传日志失败: %s, 错误: %v", path, err)
+	}
+
+	if !resume {
+		discardStaging(previous.Staging)
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("删除旧的续传日志失败: %v", err)
+		}
+		return j, nil
+	}
+	// 不符时保留日志与已有的输出, 参数写错时修正后仍可续传
+	if previous.Version != journalVersion || previous.Kind != header.Kind || previous.Fingerprint != header.Fingerprint {
+		return nil, &ResumableError{Journal: path, Err: fmt.Errorf("续传日志与本次任务不符 (条目列表、参数或压缩包已改变), 确认后可去掉 --resume 重新开始")}
+	}
+	j.header.Staging = previous.Staging
+	j.records = records
+	j.validEnd = validEnd
+	log.Info("从续传日志继续:", path)
+	return j, nil
+}
+
+// readJournal 读取日志, 忽略末尾不完整的记录
+func readJournal(path string) (journalHeader, []json.RawMessage, int64, error) {
+	var header journalHeader
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return header, nil, 0, err
+	}
+
+	var records []json.RawMessage
+	validEnd := int64(0)
+	for len(data) > 0 {
+		end := bytes.IndexByte(data, '\n')
+		if end < 0 || !json.Valid(data[:end]) {
+			break
+		}
+		if validEnd == 0 {
+			if err := json.Unmarshal(data[:end], &header); err != nil {
+				return header, nil, 0, err
+			}
+		} else {
+			records = append(records, json.RawMessage(data[:end]))
+		}
+		validEnd += int64(end + 1)
+		data = data[end+1:]
+	}
+	if validEnd == 0 {
+		return header, nil, 0, fmt.Errorf("日志为空或已损坏")
+	}
+	return header, records, validEnd, nil
+}
+
+// discardStaging 删除旧日志记录的暂存目录, 只删除按暂存目录规则命名的路径
+func discardStaging(path string) {
+	if path == "" || !strings.Contains(filepath.Base(path), ".gf-staging-") {
+		return
+	}
+	if _, err := os.Stat(path); err != nil {
+		return
+	}
+	if err := os.RemoveAll(path); err != nil {
+		log.Warn("清理上次的暂存目录失败:", path, ", 错误:", err)
+		return
+	}
+	log.Info("已清理上次未完成的暂存目录:", path)
+}
+
+// add 追加一条记录, commit 时才写入文件
+func (j *journal) add(record any) {
+	if j == nil {
+		return
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return
+	}
+	j.pending = append(j.pending, data)
+}
+
+// commit 写入待提交的记录, sync 为 true 时刷盘 (压缩检查点必须在输出刷盘之后落盘)
+func (j *journal) commit(sync bool) error {
+	if j == nil || len(j.pending) == 0 {
+		return nil
+	}
+	if j.file == nil {
+		if err := j.create(); err != nil {
+			return fmt.Errorf("写入续传日志失败: %v", err)
+		}
+	}
+	writer := bufio.NewWriter(j.file)
+	for _, line := range j.pending {
+		_, _ = writer.Write(line)
+		_ = writer.WriteByte('\n')
+	}
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("写入续传日志失败: %v", err)
+	}
+	j.pending = nil
+	j.committed = true
+	if sync {
+		return j.file.Sync()
+	}
+	return nil
+}
+
+// create 创建日志文件, 续传时在上次完整的记录之后续写
+func (j *journal) create() error {
+	if j.validEnd > 0 {
+		file, err := os.OpenFile(j.path, os.O_WRONLY, 0644)
+		if err != nil {
+			return err
+		}
+		if err := file.Truncate(j.validEnd); err != nil {
+			_ = file.Close()
+			return err
+		}
+		if _, err := file.Seek(j.validEnd, io.SeekStart); err != nil {
+			_ = file.Close()
+			return err
+		}
+		j.file = file
+		return nil
+	}
+
+	file, err := os.Create(j.path)
+	if err != nil {
+		return err
+	}
+	data, _ := json.Marshal(j.header)
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		_ = file.Close()
+		return err
+	}
+	j.file = file
+	return nil
+}
+
+// resumable 是否已有可续传的检查点 (本次或之前的运行)
+func (j *journal) resumable() bool {
+	return j != nil && (j.committed || len(j.records) > 0)
+}
+
+// close 关闭日志文件, 保留日志供下次续传
+func (j *journal) close() {
+	if j == nil || j.file == nil {
+		return
+	}
+	_ = j.file.Close()
+	j.file = nil
+}
+
+// remove 任务成功后删除日志
+func (j *journal) remove() {
+	if j == nil {
+		return
+	}
+	j.close()
+	if err := os.Remove(j.path); err != nil && !os.IsNotExist(err) {
+		log.Warn("删除续传日志失败:", j.path, ", 错误:", err)
+	}
+}
+
+// wrap 任务失败时保留日志, 已有检查点的错误包装为 *ResumableError
+func (j *journal) wrap(err error) error {
+	if j == nil {
+		return err
+	}
+	if commitErr := j.commit(true); commitErr != nil {
+		log.Warn(commitErr)
+	}
+	j.close()
+	if !j.resumable() {
+		_ = os.Remove(j.path)
+		return err
+	}
+	return &ResumableError{Journal: j.path, Err: err}
+}
+
+// fingerprint 任务参数与输入的摘要
+func fingerprint(parts ...any) string {
+	hash := sha256.New()
+	for _, part := range parts {
+		_, _ = fmt.Fprintf(hash, "%v\x00", part)
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// ============================== 压缩检查点 ==============================
+
+// compressCheckpoint 压缩检查点: 前 Entry 个条目与第 Entry 个条目的前 Data 字节已写出, 输出文件前 Offset 字节已刷盘
+type compressCheckpoint struct {
+	Entry   int            `json:"entry"`
+	Data    int64          `json:"data,omitempty"`
+	Offset  int64          `json:"offset"`
+	Size    int64          `json:"size,omitempty"`  // Data 非 0 时记录源文件的大小与修改时间, 续传前确认未改变
+	ModTime int64          `json:"mtime,omitempty"` // 纳秒
+	Zip     []zipDirRecord `json:"zip,omitempty"`   // 本检查点新完成的 zip 条目的中央目录记录
+}
+
+// openCompressJournal 为压缩任务打开日志, 条目列表或参数改变后不能续传
+func openCompressJournal(opts *CompressOptions) (*journal, error) {
+	names := make([]string, 0, len(opts.Entries))
+	for _, entry := range opts.Entries {
+		names = append(names, fmt.Sprintf("%s:%t:%d", entry.Name, entry.Dir, entry.Mode))
+	}
+	header := journalHeader{
+		Kind:        "compress",
+		Fingerprint: fingerprint(opts.Format, opts.Level, opts.Method, opts.Encrypt, opts.KeyLength, strings.Join(names, "\x00")),
+	}
+	return openJournal(opts.OutputPath+journalSuffix, header, opts.Resume)
+}
+
+// compressResume 上次的最后一个检查点与此前所有 zip 中央目录记录, 没有检查点时 ok 为 false
+func (j *journal) compressResume() (last compressCheckpoint, zipRecords []zipDirRecord, ok bool, err error) {
+	if j == nil {
+		return last, nil, false, nil
+	}
+	for _, raw := range j.records {
+		var checkpoint compressCheckpoint
+		if err := json.Unmarshal(raw, &checkpoint); err != nil {
+			return last, nil, false, fmt.Errorf("续传日志已损坏: %v", err)
+		}
+		zipRecords = append(zipRecords, checkpoint.Zip...)
+		last, ok = checkpoint, true
+	}
+	return last, zipRecords, ok, nil
+}
+
+// checkpoint 记录压缩检查点并刷盘, 调用前输出必须已刷盘
+func (j *journal) checkpoint(checkpoint compressCheckpoint) error {
+	if j == nil {
+		return nil
+	}
+	j.add(checkpoint)
+	if err := j.commit(true); err != nil {
+		return err
+	}
+	if utils.VerboseMode() {
+		log.Info("检查点: 条目", checkpoint.Entry, "偏移", checkpoint.Offset)
+	}
+	return nil
+}
+
+// openOutputAt 打开已有的输出文件并截断到 offset, 用于续传追加 (zip 关闭后还要回读中央目录)
+func openOutputAt(path string, offset int64) (*os.File, error) {
+	file, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err == nil && info.Size() < offset {
+		err = fmt.Errorf("输出文件 %s 比续传日志记录的短 (%d < %d), 无法续传", path, info.Size(), offset)
+	}
+	if err == nil {
+		err = file.Truncate(offset)
+	}
+	if err == nil {
+		_, err = file.Seek(offset, io.SeekStart)
+	}
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	return file, nil
+}
+
+// ============================== 解压记录 ==============================
+
+// extractRecord 已解压到暂存目录的条目
+type extractRecord struct {
+	Index int    `json:"index"` // 条目在压缩包中的序号
+	Name  string `json:"name"`
+	Path  string `json:"path"` // 相对暂存目录的实际路径 (可能已按 --overwrite rename 重命名)
+	Size  int64  `json:"size"`
+	CRC32 uint32 `json:"crc32"`
+}
+
+// extractJournal 解压续传日志, nil 表示不记录 (标准输入)
+type extractJournal struct {
+	*journal
+	staging string
+	done    map[int]extractRecord // 上次已解压的条目
+	current *journalReader        // 正在解压的条目数据
+	written int64                 // 上次提交后解压的字节数
+}
+
+// decompressJournalPath 解压日志的路径: 与暂存目录一样放在输出目录的上级目录中, 不写入输出目录
+func decompressJournalPath(target string) string {
+	if abs, err := filepath.Abs(target); err == nil {
+		target = abs
+	}
+	return filepath.Join(filepath.Dir(target), "."+filepath.Base(target)+journalSuffix)
+}
+
+// openExtractJournal 为解压任务打开日志, 压缩包或影响解压结果的参数改变后不能续传
+func openExtractJournal(opts DecompressOptions) (*extractJournal, error) {
+	paths, _, err := archiveVolumes(opts.SourcePath)
+	if err != nil {
+		return nil, err
+	}
+	parts := []any{opts.Format}
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, path, info.Size(), info.ModTime().UnixNano())
+	}
+	parts = append(parts, extractSettings(opts)...)
+	j, err := openJournal(decompressJournalPath(opts.OutputDir), journalHeader{Kind: "decompress", Fingerprint: fingerprint(parts...)}, opts.Resume)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &extractJournal{journal: j, done: make(map[int]extractRecord)}
+	for _, raw := range j.records {
+		var record extractRecord
+		if err := json.Unmarshal(raw, &record); err != nil {
+			return nil, fmt.Errorf("续传日志已损坏: %v", err)
+		}
+		e.done[record.Index] = record
+	}
+	return e, nil
+}
+
+// extractSettings 决定解压结果的参数: 筛选条件、覆盖策略、权限、路径策略、属主与上限
+// 空值按默认值记录, 省略参数与显式指定默认值视为相同
+func extractSettings(opts DecompressOptions) []any {
+	var filter EntryFilter
+	if opts.Filter != nil {
+		filter = *opts.Filter
+	}
+	overwrite, _ := ParseOverwritePolicy(string(opts.Overwrite))
+	policy, _ := ParsePathPolicy(string(opts.PathPolicy))
+	return []any{
+		filter.Include, filter.Exclude, filter.Entries,
+		overwrite, opts.Umask, policy, opts.SameOwner, opts.SpecialFiles,
+		opts.Limits,
+	}
+}
+
+// stagingPath 上次记录的暂存目录, 不存在时为空
+func (e *extractJournal) stagingPath() string {
+	if e == nil || e.header.Staging == "" {
+		return ""
+	}
+	if info, err := os.Stat(e.header.Staging); err != nil || !info.IsDir() {
+		return ""
+	}
+	return e.header.Staging
+}
+
+// setStaging 记录本次使用的暂存目录
+func (e *extractJournal) setStaging(path string) {
+	if e == nil {
+		return
+	}
+	// 日志中记录绝对路径, 条目的输出路径与 path 同样是相对当前目录的
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		abs = path
+	}
+	if e.header.Staging != abs {
+		// 暂存目录已不存在, 上次的记录作废
+		e.header.Staging = abs
+		e.done = make(map[int]extractRecord)
+		e.validEnd = 0
+	}
+	e.staging = path
+}
+
+// fail 解压失败时保留日志, 已有记录的错误包装为 *ResumableError
+func (e *extractJournal) fail(err error) error {
+	if e == nil {
+		return err
+	}
+	return e.wrap(err)
+}
+
+// finish 解压成功后删除日志
+func (e *extractJournal) finish() {
+	if e == nil {
+		return
+	}
+	e.remove()
+}
+
+// skip 条目已在上次解压并校验通过时返回 true, 重命名过的路径登记给冲突处理供硬链接查找
+func (e *extractJournal) skip(opts DecompressOptions, index int, name, outputPath string) bool {
+	if e == nil {
+		return false
+	}
+	record, ok := e.done[index]
+	if !ok || record.Name != name {
+		return false
+	}
+	path := filepath.Join(e.staging, filepath.FromSlash(record.Path))
+	if !verifyExtracted(path, record) {
+		if utils.VerboseMode() {
+			log.Info("上次解压的条目校验失败, 重新解压:", name)
+		}
+		return false
+	}
+	if path != outputPath {
+		opts.conflicts.renamed[outputPath] = path
+	}
+	if utils.VerboseMode() {
+		log.Info("上次已解压, 跳过:", name)
+	}
+	return true
+}
+
+// verifyExtracted 暂存文件的大小与 CRC32 是否与记录一致
+func verifyExtracted(path string, record extractRecord) bool {
+	info, err := os.Lstat(path)
+	if err != nil || !info.Mode().IsRegular() || info.Size() != record.Size {
+		return false
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+	hash := crc32.NewIEEE()
+	if _, err := io.Copy(hash, file); err != nil {
+		return false
+	}
+	return hash.Sum32() == record.CRC32
+}
+
+// track 包装条目数据, 统计写入的字节数与 CRC32
+func (e *extractJournal) track(r io.Reader) io.Reader {
+	if e == nil {
+		return r
+	}
+	e.current = &journalReader{r: r, hash: crc32.NewIEEE()}
+	return e.current
+}
+
+// extracted 记录条目已解压, 每解压约 journalInterval 字节写入一次日志
+// 解压的记录不需要刷盘: 续传时会重新校验暂存文件
+func (e *extractJournal) extracted(index int, name, outputPath string) error {
+	if e == nil || e.current == nil {
+		return nil
+	}
+	rel, err := filepath.Rel(e.staging, outputPath)
+	if err != nil {
+		return nil
+	}
+	e.add(extractRecord{Index: index, Name: name, Path: filepath.ToSlash(rel), Size: e.current.n, CRC32: e.current.hash.Sum32()})
+	e.written += e.current.n
+	e.current = nil
+	if e.written < journalInterval {
+		return nil
+	}
+	e.written = 0
+	return e.commit(false)
+}
+
+// journalReader 统计读取的字节数与 CRC32
+type journalReader struct {
+	r    io.Reader
+	hash hash.Hash32
+	n    int64
+}
+
+// Read 读取并计算
+func (r *journalReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.hash.Write(p[:n])
+	r.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,356 @@
+package compress
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// resumeDataSize 续传测试的大文件大小, 超过一个检查点间隔
+const resumeDataSize = journalInterval + 16<<20
+
+// writeRandomFile 写出 size 字节的伪随机数据, 压缩后仍超过检查点间隔
+func writeRandomFile(t *testing.T, path string, size int64, seed int64) {
+	t.Helper()
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if _, err := io.CopyN(file, rand.New(rand.NewSource(seed)), size); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// fileHash 文件内容的 SHA-256
+func fileHash(t *testing.T, path string) [sha256.Size]byte {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		t.Fatal(err)
+	}
+	var sum [sha256.Size]byte
+	copy(sum[:], hash.Sum(nil))
+	return sum
+}
+
+// readJournalRecords 读取续传日志的任务信息与记录
+func readJournalRecords(t *testing.T, path string) (journalHeader, []json.RawMessage) {
+	t.Helper()
+	header, records, _, err := readJournal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return header, records
+}
+
+// asResumable 错误必须是 *ResumableError, 返回其日志路径
+func asResumable(t *testing.T, err error) string {
+	t.Helper()
+	var resumable *ResumableError
+	if !errors.As(err, &resumable) {
+		t.Fatalf("期望 *ResumableError, 得到 %v", err)
+	}
+	if _, statErr := os.Stat(resumable.Journal); statErr != nil {
+		t.Fatalf("失败后应保留续传日志: %v", statErr)
+	}
+	return resumable.Journal
+}
+
+func TestCompressResume(t *testing.T) {
+	if testing.Short() {
+		t.Skip("需要写出超过检查点间隔的数据")
+	}
+	for _, tc := range []struct {
+		format   string
+		method   string
+		midEntry bool // 检查点落在大文件中间 (tar 系列分帧), 否则在条目之间 (zip)
+	}{
+		{"tar", "", true},
+		{"tar.zst", "", true},
+		{"targz", "", true},
+		{"zip", "store", false},
+	} {
+		t.Run(tc.format, func(t *testing.T) {
+			root := t.TempDir()
+			big := filepath.Join(root, "a.bin")
+			writeRandomFile(t, big, resumeDataSize, 1)
+			want := fileHash(t, big)
+			if err := os.WriteFile(filepath.Join(root, "b.txt"), []byte("after checkpoint"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			// c 尚不存在, 写到它时失败, 模拟中断
+			missing := filepath.Join(root, "c")
+			entries := []SourceEntry{
+				{Path: big, Name: "a.bin"},
+				{Path: filepath.Join(root, "b.txt"), Name: "b.txt"},
+				{Path: missing, Name: "c", Dir: true},
+			}
+			opts := CompressOptions{Entries: entries, OutputPath: filepath.Join(t.TempDir(), "resume."+tc.format), Format: tc.format, Level: -1, Method: tc.method}
+
+			journalPath := asResumable(t, RunCompress(opts))
+			if journalPath != opts.OutputPath+journalSuffix {
+				t.Fatalf("续传日志路径 = %s", journalPath)
+			}
+			_, records := readJournalRecords(t, journalPath)
+			var last compressCheckpoint
+			if err := json.Unmarshal(records[len(records)-1], &last); err != nil {
+				t.Fatal(err)
+			}
+			if last.Offset < journalInterval/2 || (last.Data > 0) != tc.midEntry {
+				t.Fatalf("检查点 = %+v", last)
+			}
+
+			// 条目列表改变后不能续传, 日志保留
+			changed := opts
+			changed.Resume, changed.Entries = true, entries[:2]
+			asResumable(t, RunCompress(changed))
+
+			// 改写检查点之前的数据但保留大小与修改时间: 续传不会重新读取这部分, 压缩包中仍是原始数据
+			info, err := os.Stat(big)
+			if err != nil {
+				t.Fatal(err)
+			}
+			file, err := os.OpenFile(big, os.O_WRONLY, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := file.WriteAt(bytes.Repeat([]byte{0xEE}, 16), 0); err != nil {
+				t.Fatal(err)
+			}
+			_ = file.Close()
+			if err := os.Chtimes(big, info.ModTime(), info.ModTime()); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := os.Mkdir(missing, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			opts.Resume = true
+			if err := RunCompress(opts); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := os.Stat(journalPath); !os.IsNotExist(err) {
+				t.Fatalf("成功后应删除续传日志: %v", err)
+			}
+
+			output := t.TempDir()
+			if err := RunDecompress(DecompressOptions{SourcePath: opts.OutputPath, OutputDir: output}); err != nil {
+				t.Fatal(err)
+			}
+			if fileHash(t, filepath.Join(output, "a.bin")) != want {
+				t.Fatal("续传后的大文件内容不一致 (检查点之前的数据应来自上次的输出)")
+			}
+			if data, err := os.ReadFile(filepath.Join(output, "b.txt")); err != nil || string(data) != "after checkpoint" {
+				t.Fatalf("b.txt = %q, %v", data, err)
+			}
+			if info, err := os.Stat(filepath.Join(output, "c")); err != nil || !info.IsDir() {
+				t.Fatalf("c 应为目录: %v", err)
+			}
+		})
+	}
+}
+
+func TestCompressResumeSourceChanged(t *testing.T) {
+	if testing.Short() {
+		t.Skip("需要写出超过检查点间隔的数据")
+	}
+	root := t.TempDir()
+	big := filepath.Join(root, "a.bin")
+	writeRandomFile(t, big, resumeDataSize, 2)
+	missing := filepath.Join(root, "c")
+	opts := CompressOptions{
+		Entries:    []SourceEntry{{Path: big, Name: "a.bin"}, {Path: missing, Name: "c", Dir: true}},
+		OutputPath: filepath.Join(t.TempDir(), "changed.tar"),
+		Format:     "tar",
+		Level:      -1,
+	}
+	asResumable(t, RunCompress(opts))
+
+	// 中断在其中间的文件已改变, 不能续传
+	if err := os.Chtimes(big, time.Now(), time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(missing, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	opts.Resume = true
+	if err := RunCompress(opts); err == nil {
+		t.Fatal("源文件改变后续传应失败")
+	}
+}
+
+func TestDecompressResume(t *testing.T) {
+	if testing.Short() {
+		t.Skip("需要解压超过检查点间隔的数据")
+	}
+	for _, format := range []string{"tar.zst", "zip"} {
+		t.Run(format, func(t *testing.T) {
+			root := t.TempDir()
+			big := filepath.Join(root, "a.bin")
+			writeRandomFile(t, big, resumeDataSize, 3)
+			want := fileHash(t, big)
+			if err := os.WriteFile(filepath.Join(root, "z.txt"), []byte("last"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			archive := filepath.Join(t.TempDir(), "resume."+format)
+			entries := []SourceEntry{{Path: big, Name: "a.bin"}, {Path: filepath.Join(root, "z.txt"), Name: "z.txt"}}
+			if err := RunCompress(CompressOptions{Entries: entries, OutputPath: archive, Format: format, Level: -1, Method: "store"}); err != nil {
+				t.Fatal(err)
+			}
+
+			// 输出目录中的同名目录使最后一个条目失败, 模拟中断
+			output := t.TempDir()
+			blocker := filepath.Join(output, "z.txt")
+			if err := os.Mkdir(blocker, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			journalPath := asResumable(t, RunDecompress(DecompressOptions{SourcePath: archive, OutputDir: output}))
+			// 日志与暂存目录都在输出目录的上级目录中
+			if want := filepath.Join(filepath.Dir(output), "."+filepath.Base(output)+journalSuffix); journalPath != want {
+				t.Fatalf("日志路径 = %s, 期望 %s", journalPath, want)
+			}
+			header, records := readJournalRecords(t, journalPath)
+			if filepath.Dir(header.Staging) != filepath.Dir(output) {
+				t.Fatalf("暂存目录 = %s, 期望位于 %s", header.Staging, filepath.Dir(output))
+			}
+			if len(records) != 1 {
+				t.Fatalf("日志记录 = %d 条", len(records))
+			}
+			staged, err := os.Stat(filepath.Join(header.Staging, "a.bin"))
+			if err != nil {
+				t.Fatalf("失败后应保留暂存目录: %v", err)
+			}
+
+			// 解压参数改变后不能续传
+			asResumable(t, RunDecompress(DecompressOptions{SourcePath: archive, OutputDir: output, Resume: true, Overwrite: OverwriteNever}))
+
+			// 压缩包改变后不能续传
+			info, err := os.Stat(archive)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Chtimes(archive, info.ModTime(), info.ModTime().Add(time.Second)); err != nil {
+				t.Fatal(err)
+			}
+			asResumable(t, RunDecompress(DecompressOptions{SourcePath: archive, OutputDir: output, Resume: true}))
+			if err := os.Chtimes(archive, info.ModTime(), info.ModTime()); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := os.Remove(blocker); err != nil {
+				t.Fatal(err)
+			}
+			if err := RunDecompress(DecompressOptions{SourcePath: archive, OutputDir: output, Resume: true}); err != nil {
+				t.Fatal(err)
+			}
+			// 上次解压的文件校验通过后直接复用, 不再重新写出
+			done, err := os.Stat(filepath.Join(output, "a.bin"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !os.SameFile(staged, done) {
+				t.Fatal("续传应复用上次已解压的文件")
+			}
+			if fileHash(t, filepath.Join(output, "a.bin")) != want {
+				t.Fatal("续传后的文件内容不一致")
+			}
+			if data, err := os.ReadFile(blocker); err != nil || string(data) != "last" {
+				t.Fatalf("z.txt = %q, %v", data, err)
+			}
+			if _, err := os.Stat(journalPath); !os.IsNotExist(err) {
+				t.Fatalf("成功后应删除续传日志: %v", err)
+			}
+			if _, err := os.Stat(header.Staging); !os.IsNotExist(err) {
+				t.Fatalf("成功后不应留下暂存目录: %v", err)
+			}
+		})
+	}
+}
+
+func TestDecompressWithoutResumeDiscardsPrevious(t *testing.T) {
+	if testing.Short() {
+		t.Skip("需要解压超过检查点间隔的数据")
+	}
+	root := t.TempDir()
+	big := filepath.Join(root, "a.bin")
+	writeRandomFile(t, big, resumeDataSize, 4)
+	if err := os.WriteFile(filepath.Join(root, "z.txt"), []byte("last"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	archive := filepath.Join(t.TempDir(), "fresh.tar")
+	entries := []SourceEntry{{Path: big, Name: "a.bin"}, {Path: filepath.Join(root, "z.txt"), Name: "z.txt"}}
+	if err := RunCompress(CompressOptions{Entries: entries, OutputPath: archive, Format: "tar", Level: -1}); err != nil {
+		t.Fatal(err)
+	}
+	output := t.TempDir()
+	blocker := filepath.Join(output, "z.txt")
+	if err := os.Mkdir(blocker, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	journalPath := asResumable(t, RunDecompress(DecompressOptions{SourcePath: archive, OutputDir: output}))
+	header, _ := readJournalRecords(t, journalPath)
+
+	// 不带 --resume 重新开始: 上次的暂存目录与日志被清理
+	if err := os.Remove(blocker); err != nil {
+		t.Fatal(err)
+	}
+	if err := RunDecompress(DecompressOptions{SourcePath: archive, OutputDir: output}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(header.Staging); !os.IsNotExist(err) {
+		t.Fatalf("应清理上次的暂存目录: %v", err)
+	}
+	if _, err := os.Stat(journalPath); !os.IsNotExist(err) {
+		t.Fatalf("成功后应删除续传日志: %v", err)
+	}
+}
+
+func TestExtractSettingsFingerprint(t *testing.T) {
+	filter, err := NewEntryFilter([]string{"*.txt"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := DecompressOptions{Filter: filter}
+	sum := func(opts DecompressOptions) string { return fingerprint(extractSettings(opts)...) }
+
+	// 显式指定默认值与省略参数相同
+	explicit := base
+	explicit.Overwrite, explicit.PathPolicy = OverwriteAlways, PathSanitize
+	if sum(explicit) != sum(base) {
+		t.Fatal("显式指定默认值不应改变指纹")
+	}
+
+	for name, mutate := range map[string]func(*DecompressOptions){
+		"include": func(o *DecompressOptions) { o.Filter = &EntryFilter{Include: []string{"*.bin"}} },
+		"exclude": func(o *DecompressOptions) { o.Filter = &EntryFilter{Include: filter.Include, Exclude: []string{"x"}} },
+		"entries": func(o *DecompressOptions) {
+			o.Filter = &EntryFilter{Include: filter.Include, Entries: []string{"a.txt"}}
+		},
+		"no-filter":     func(o *DecompressOptions) { o.Filter = nil },
+		"overwrite":     func(o *DecompressOptions) { o.Overwrite = OverwriteNever },
+		"umask":         func(o *DecompressOptions) { o.Umask = 0o022 },
+		"path-policy":   func(o *DecompressOptions) { o.PathPolicy = PathSkip },
+		"same-owner":    func(o *DecompressOptions) { o.SameOwner = true },
+		"special-files": func(o *DecompressOptions) { o.SpecialFiles = true },
+		"limits":        func(o *DecompressOptions) { o.Limits.MaxEntries = 10 },
+	} {
+		changed := base
+		mutate(&changed)
+		if sum(changed) == sum(base) {
+			t.Errorf("%s 改变后指纹应不同", name)
+		}
+	}
+}
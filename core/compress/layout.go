@@ -0,0 +1,169 @@
+// Package compress /core/compress/layout.go
+package compress
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// 压缩包内的路径布局与 tar -C 的习惯一致:
+//   - 未指定 --base-dir: 每个源以自身名字作为顶层条目, ./a/docs 与 /b/src 分别为 docs/... 与 src/...,
+//     源为 . 时其内容直接位于压缩包根部
+//   - 指定 --base-dir: 相对源路径按 base-dir 解析, 条目名为相对 base-dir 的路径,
+//     如 --base-dir /srv www/html 得到 www/html/...
+//   - --prefix 追加在所有条目名之前
+// 条目名只取决于源参数本身, 与源的先后顺序无关.
+
+// SourceEntry 待压缩条目
+type SourceEntry struct {
+	Path string      // 磁盘路径, - 表示标准输入
+	Name string      // 压缩包内路径, / 分隔, 目录不带结尾的 /
+	Dir  bool        // 是否为目录
+	Mode os.FileMode // 文件类型, 符号链接条目带 os.ModeSymlink, 零值表示普通文件
+
+	chunk *entryChunk // 按条目分卷时大文件中的一段, nil 表示整个文件
+}
+
+// special 是否为只写入条目头的类型: 目录、符号链接、FIFO、设备文件
+func (e SourceEntry) special() bool {
+	return e.Dir || e.Mode&(os.ModeSymlink|os.ModeNamedPipe|os.ModeDevice) != 0
+}
+
+// statEntry 获取条目的文件信息, 符号链接条目不跟随, 其余 (含 --follow-symlinks 跟随的链接) 跟随
+func statEntry(entry SourceEntry) (os.FileInfo, error) {
+	if entry.Mode&os.ModeSymlink != 0 {
+		return os.Lstat(entry.Path)
+	}
+	return os.Stat(entry.Path)
+}
+
+// EntryLayout 压缩包内路径布局
+type EntryLayout struct {
+	BaseDir string // 源路径的解析目录, 为空表示按源参数自身的名字
+	Prefix  string // 条目名前缀, / 分隔
+}
+
+// NewEntryLayout 校验 base-dir 与前缀
+func NewEntryLayout(baseDir, prefix string) (*EntryLayout, error) {
+	layout := &EntryLayout{}
+	if baseDir != "" {
+		info, err := os.Stat(baseDir)
+		if err != nil {
+			return nil, fmt.Errorf("--base-dir 不存在: %s", baseDir)
+		}
+		if !info.IsDir() {
+			return nil, fmt.Errorf("--base-dir 不是目录: %s", baseDir)
+		}
+		if layout.BaseDir, err = filepath.Abs(baseDir); err != nil {
+			return nil, err
+		}
+	}
+	if prefix != "" {
+		cleaned := path.Clean(strings.ReplaceAll(prefix, "\\", "/"))
+		if path.IsAbs(cleaned) || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+			return nil, fmt.Errorf("--prefix 必须是相对路径且不能包含 ..: %s", prefix)
+		}
+		if cleaned != "." {
+			layout.Prefix = cleaned
+		}
+	}
+	return layout, nil
+}
+
+// Resolve 源参数对应的磁盘路径, 指定 BaseDir 时相对路径按 BaseDir 解析
+func (l *EntryLayout) Resolve(src string) string {
+	if l.BaseDir != "" && !filepath.IsAbs(src) {
+		return filepath.Join(l.BaseDir, src)
+	}
+	return src
+}
+
+// Root 源在压缩包内的顶层路径 (不含前缀), 为空表示源目录的内容直接位于压缩包根部
+func (l *EntryLayout) Root(src string) (string, error) {
+	if l.BaseDir != "" {
+		absPath, err := filepath.Abs(l.Resolve(src))
+		if err != nil {
+			return "", err
+		}
+		rel, err := filepath.Rel(l.BaseDir, absPath)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return "", fmt.Errorf("源路径不在 --base-dir 内: %s", src)
+		}
+		if rel == "." {
+			return "", nil
+		}
+		return filepath.ToSlash(rel), nil
+	}
+
+	name := filepath.Base(filepath.Clean(src))
+	if name == "." || name == ".." || name == string(filepath.Separator) {
+		return "", nil
+	}
+	return name, nil
+}
+
+// Name 拼接条目名 前缀/顶层路径/相对路径, 全部为空时返回空
+// rel 为源内的相对路径, 源本身为 .
+func (l *EntryLayout) Name(root, rel string) string {
+	var parts []string
+	for _, part := range []string{l.Prefix, root, rel} {
+		if part != "" && part != "." {
+			parts = append(parts, part)
+		}
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return path.Join(parts...)
+}
+
+// symlinkInfo 符号链接的文件信息, 大小为链接目标的长度
+type symlinkInfo struct {
+	os.FileInfo
+	size int64
+}
+
+// Size 链接目标长度
+func (i symlinkInfo) Size() int64 {
+	return i.size
+}
+
+// openSymlinkSource 以链接目标作为内容打开符号链接, 用于 zip 等把链接目标存为条目数据的格式
+func openSymlinkSource(path string) (io.ReadCloser, os.FileInfo, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	target, err := os.Readlink(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return io.NopCloser(strings.NewReader(target)), symlinkInfo{FileInfo: info, size: int64(len(target))}, nil
+}
+
+// dedupeEntries 去掉重复的条目, 不同的源文件映射到同一条目名时报错
+func dedupeEntries(entries []SourceEntry) ([]SourceEntry, error) {
+	seen := make(map[string]SourceEntry, len(entries))
+	result := make([]SourceEntry, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Name == "" && IsStdio(entry.Path) {
+			entry.Name = DefaultStdinName
+		}
+		if entry.Name == "" {
+			return nil, fmt.Errorf("条目名为空: %s", entry.Path)
+		}
+		if prev, ok := seen[entry.Name]; ok {
+			if (prev.Dir && entry.Dir) || prev.Path == entry.Path {
+				continue
+			}
+			return nil, fmt.Errorf("压缩包内路径重复: %s (%s 与 %s), 请使用 --base-dir 或 --prefix 区分", entry.Name, prev.Path, entry.Path)
+		}
+		seen[entry.Name] = entry
+		result = append(result, entry)
+	}
+	return result, nil
+}
@@ -0,0 +1,135 @@
+package compress
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEntryLayoutRoot(t *testing.T) {
+	base := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(base, "www", "html"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	plain, err := NewEntryLayout("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for src, want := range map[string]string{
+		"./a/docs": "docs",
+		"/b/src/":  "src",
+		".":        "",
+		"..":       "",
+		"file.txt": "file.txt",
+	} {
+		if got, err := plain.Root(src); err != nil || got != want {
+			t.Errorf("Root(%q) = %q, %v, 期望 %q", src, got, err, want)
+		}
+	}
+
+	layout, err := NewEntryLayout(base, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for src, want := range map[string]string{
+		"www/html":                 "www/html",
+		filepath.Join(base, "www"): "www",
+		".":                        "",
+	} {
+		if got, err := layout.Root(src); err != nil || got != want {
+			t.Errorf("base-dir Root(%q) = %q, %v, 期望 %q", src, got, err, want)
+		}
+	}
+	if _, err := layout.Root(t.TempDir()); err == nil {
+		t.Error("源路径不在 --base-dir 内应当报错")
+	}
+	if got := layout.Resolve("www"); got != filepath.Join(base, "www") {
+		t.Errorf("Resolve = %s", got)
+	}
+}
+
+func TestEntryLayoutPrefix(t *testing.T) {
+	layout, err := NewEntryLayout("", "backup\\2024/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := layout.Name("docs", "api/ref.md"); got != "backup/2024/docs/api/ref.md" {
+		t.Fatalf("Name = %s", got)
+	}
+	if got := layout.Name("docs", "."); got != "backup/2024/docs" {
+		t.Fatalf("Name = %s", got)
+	}
+	if got := (&EntryLayout{}).Name("", "."); got != "" {
+		t.Fatalf("Name = %q, 期望空", got)
+	}
+
+	for _, prefix := range []string{"/abs", "..", "../up", "a/../../b"} {
+		if _, err := NewEntryLayout("", prefix); err == nil {
+			t.Errorf("--prefix %q 应当报错", prefix)
+		}
+	}
+	file := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(file, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewEntryLayout(file, ""); err == nil {
+		t.Error("--base-dir 不是目录应当报错")
+	}
+}
+
+func TestDedupeEntries(t *testing.T) {
+	entries, err := dedupeEntries([]SourceEntry{
+		{Path: "/a/docs", Name: "docs", Dir: true},
+		{Path: "/b/docs", Name: "docs", Dir: true},
+		{Path: "/a/docs/x.txt", Name: "docs/x.txt"},
+		{Path: "/a/docs/x.txt", Name: "docs/x.txt"},
+		{Path: StdioPath},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 || entries[2].Name != DefaultStdinName {
+		t.Fatalf("去重结果 = %+v", entries)
+	}
+
+	_, err = dedupeEntries([]SourceEntry{
+		{Path: "/a/x.txt", Name: "x.txt"},
+		{Path: "/b/x.txt", Name: "x.txt"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "重复") {
+		t.Fatalf("不同源文件映射到同一条目应当报错, 实际: %v", err)
+	}
+	if _, err := dedupeEntries([]SourceEntry{{Path: "/a"}}); err == nil {
+		t.Fatal("条目名为空应当报错")
+	}
+}
+
+func TestDirectoryEntriesRoundTrip(t *testing.T) {
+	files := testTreeFiles()
+	for _, format := range []string{"zip", "7z", "tar.gz"} {
+		t.Run(format, func(t *testing.T) {
+			entries := writeTestTree(t, files)
+			// 空目录只能通过目录条目保留
+			empty := filepath.Join(t.TempDir(), "empty")
+			if err := os.Mkdir(empty, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			entries = append(entries, SourceEntry{Path: empty, Name: "data/empty", Dir: true})
+
+			archive := filepath.Join(t.TempDir(), "dirs."+format)
+			if err := RunCompress(CompressOptions{Entries: entries, OutputPath: archive, Format: format, Level: -1}); err != nil {
+				t.Fatal(err)
+			}
+			output := t.TempDir()
+			if err := RunDecompress(DecompressOptions{SourcePath: archive, OutputDir: output}); err != nil {
+				t.Fatal(err)
+			}
+			assertSameFiles(t, readOutputTree(t, output), files)
+			if info, err := os.Stat(filepath.Join(output, "data", "empty")); err != nil || !info.IsDir() {
+				t.Fatalf("空目录未还原: %v", err)
+			}
+		})
+	}
+}
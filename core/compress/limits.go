@@ -0,0 +1,175 @@
+// Package compress /core/compress/limits.go
+package compress
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// 解压上限, 用于解压不可信的压缩包 (压缩炸弹):
+// 压缩包中声明的大小不可信, 只用于提前拒绝, 实际按解出的字节计数, 超出任一上限立即停止并返回 *LimitError.
+// 压缩比按 解压总字节数 / 压缩包大小 计算, 标准输入按已读取的字节计算.
+
+// ExtractLimits 解压上限, 各项为 0 表示不限制
+type ExtractLimits struct {
+	MaxTotalSize int64 // 解压总字节数
+	MaxEntrySize int64 // 单个条目的字节数
+	MaxEntries   int   // 条目数 (含目录与链接)
+	MaxRatio     int64 // 解压总字节数与压缩包大小之比
+	MaxPathDepth int   // 条目路径的层数
+}
+
+// UntrustedLimits --untrusted 使用的默认上限
+var UntrustedLimits = ExtractLimits{
+	MaxTotalSize: 4 << 30,
+	MaxEntrySize: 1 << 30,
+	MaxEntries:   100000,
+	MaxRatio:     200,
+	MaxPathDepth: 32,
+}
+
+// ratioMinSize 解压总量低于该值时不检查压缩比, 避免小压缩包的高压缩比误报
+const ratioMinSize = 1 << 20
+
+// LimitError 超出解压上限
+type LimitError struct {
+	Limit string // 超出的上限, 对应的命令行参数名
+	Name  string // 触发上限的条目
+	Value int64  // 实际值
+	Max   int64  // 上限
+}
+
+// Error 错误信息
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("超出解压上限 --%s: %s (%d > %d)", e.Limit, e.Name, e.Value, e.Max)
+}
+
+// extractLimiter 统计解压进度并检查上限, nil 表示不限制
+type extractLimiter struct {
+	limits      ExtractLimits
+	archiveSize int64        // 压缩包大小, 标准输入为 0
+	input       *countReader // 标准输入已读取的字节
+	entries     int
+	total       int64
+	err         *LimitError // 第一次超出的上限
+}
+
+// newExtractLimiter 创建上限检查, 未设置任何上限时返回 nil, 压缩包大小按全部 sources (按条目分卷的各分包) 计算
+func newExtractLimiter(limits ExtractLimits, sources ...string) *extractLimiter {
+	if limits == (ExtractLimits{}) {
+		return nil
+	}
+	l := &extractLimiter{limits: limits}
+	for _, source := range sources {
+		if IsStdio(source) {
+			continue
+		}
+		if paths, _, err := archiveVolumes(source); err == nil {
+			for _, path := range paths {
+				if info, err := os.Stat(path); err == nil {
+					l.archiveSize += info.Size()
+				}
+			}
+		}
+	}
+	return l
+}
+
+// countInput 统计标准输入读取的字节, 用于计算压缩比
+func (l *extractLimiter) countInput(r io.Reader) io.Reader {
+	if l == nil || l.archiveSize > 0 {
+		return r
+	}
+	l.input = &countReader{r: r}
+	return l.input
+}
+
+// addEntry 登记一个待解压条目, 检查条目数、路径层数与声明的大小
+func (l *extractLimiter) addEntry(name string, size int64) error {
+	if l == nil {
+		return nil
+	}
+	l.entries++
+	if l.limits.MaxEntries > 0 && l.entries > l.limits.MaxEntries {
+		return l.fail("max-entries", name, int64(l.entries), int64(l.limits.MaxEntries))
+	}
+	if depth := strings.Count(strings.Trim(name, "/"), "/") + 1; l.limits.MaxPathDepth > 0 && depth > l.limits.MaxPathDepth {
+		return l.fail("max-path-depth", name, int64(depth), int64(l.limits.MaxPathDepth))
+	}
+	if l.limits.MaxEntrySize > 0 && size > l.limits.MaxEntrySize {
+		return l.fail("max-entry-size", name, size, l.limits.MaxEntrySize)
+	}
+	return nil
+}
+
+// reader 包装条目数据, 读取时按实际字节检查单条目大小、总大小与压缩比
+func (l *extractLimiter) reader(name string, r io.Reader) io.Reader {
+	if l == nil {
+		return r
+	}
+	return &limitReader{l: l, name: name, r: r}
+}
+
+// exceeded 返回第一次超出的上限, 格式内部可能已把它包装进其他错误
+func (l *extractLimiter) exceeded() error {
+	if l == nil || l.err == nil {
+		return nil
+	}
+	return l.err
+}
+
+// fail 记录超出的上限
+func (l *extractLimiter) fail(limit, name string, value, max int64) error {
+	if l.err == nil {
+		l.err = &LimitError{Limit: limit, Name: name, Value: value, Max: max}
+	}
+	return l.err
+}
+
+// limitReader 条目数据读取器
+type limitReader struct {
+	l    *extractLimiter
+	name string
+	r    io.Reader
+	n    int64
+}
+
+// Read 读取并计数
+func (r *limitReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	l := r.l
+	l.total += int64(n)
+
+	if l.limits.MaxEntrySize > 0 && r.n > l.limits.MaxEntrySize {
+		return n, l.fail("max-entry-size", r.name, r.n, l.limits.MaxEntrySize)
+	}
+	if l.limits.MaxTotalSize > 0 && l.total > l.limits.MaxTotalSize {
+		return n, l.fail("max-total-size", r.name, l.total, l.limits.MaxTotalSize)
+	}
+	if l.limits.MaxRatio > 0 && l.total >= ratioMinSize {
+		size := l.archiveSize
+		if l.input != nil {
+			size = l.input.n
+		}
+		if size > 0 && l.total/size > l.limits.MaxRatio {
+			return n, l.fail("max-ratio", r.name, l.total/size, l.limits.MaxRatio)
+		}
+	}
+	return n, err
+}
+
+// countReader 统计读取的字节数
+type countReader struct {
+	r io.Reader
+	n int64
+}
+
+// Read 读取并计数
+func (c *countReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,147 @@
+package compress
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// limitTreeFiles 限制测试用的文件: 一个高压缩比的大文件、一个小文件与一个 4 层深的文件
+func limitTreeFiles() map[string][]byte {
+	return map[string][]byte{
+		"big.bin":           make([]byte, 3<<20),
+		"small.txt":         []byte("small"),
+		"deep/x/y/deep.txt": []byte("deep"),
+	}
+}
+
+func TestExtractLimits(t *testing.T) {
+	files := limitTreeFiles()
+	for _, format := range []string{"zip", "targz", "7z"} {
+		archive := writeTestArchive(t, files, CompressOptions{Format: format})
+		for _, tc := range []struct {
+			limits ExtractLimits
+			limit  string // 期望超出的上限, 空表示成功
+		}{
+			{ExtractLimits{MaxTotalSize: 2 << 20}, "max-total-size"},
+			{ExtractLimits{MaxEntrySize: 1 << 20}, "max-entry-size"},
+			{ExtractLimits{MaxEntries: 3}, "max-entries"},
+			{ExtractLimits{MaxRatio: 50}, "max-ratio"},
+			{ExtractLimits{MaxPathDepth: 3}, "max-path-depth"},
+			{ExtractLimits{MaxTotalSize: 4 << 20, MaxEntrySize: 3 << 20, MaxEntries: 7, MaxPathDepth: 4, MaxRatio: 1 << 20}, ""},
+		} {
+			name := tc.limit
+			if name == "" {
+				name = "within"
+			}
+			t.Run(format+"/"+name, func(t *testing.T) {
+				output := t.TempDir()
+				err := RunDecompress(DecompressOptions{SourcePath: archive, OutputDir: output, Limits: tc.limits})
+				if tc.limit == "" {
+					if err != nil {
+						t.Fatal(err)
+					}
+					assertSameFiles(t, readOutputTree(t, output), files)
+					return
+				}
+				var limitErr *LimitError
+				if !errors.As(err, &limitErr) {
+					t.Fatalf("期望 *LimitError, 得到 %v", err)
+				}
+				if limitErr.Limit != tc.limit || limitErr.Value <= limitErr.Max {
+					t.Fatalf("LimitError = %+v, 期望 --%s", limitErr, tc.limit)
+				}
+			})
+		}
+	}
+}
+
+func TestExtractLimitsCountActualBytes(t *testing.T) {
+	// 声明的大小不可信: 声明 1 字节的条目实际解出更多时仍按实际字节拦截
+	l := newExtractLimiter(ExtractLimits{MaxEntrySize: 10, MaxTotalSize: 15}, filepath.Join(t.TempDir(), "missing"))
+	if err := l.addEntry("a", 1); err != nil {
+		t.Fatal(err)
+	}
+	_, err := io.ReadAll(l.reader("a", bytes.NewReader(make([]byte, 11))))
+	var limitErr *LimitError
+	if !errors.As(err, &limitErr) || limitErr.Limit != "max-entry-size" || limitErr.Value != 11 {
+		t.Fatalf("期望 --max-entry-size, 得到 %v", err)
+	}
+
+	// 总量跨条目累计
+	l = newExtractLimiter(ExtractLimits{MaxTotalSize: 15}, "")
+	if _, err := io.ReadAll(l.reader("a", bytes.NewReader(make([]byte, 10)))); err != nil {
+		t.Fatal(err)
+	}
+	_, err = io.ReadAll(l.reader("b", bytes.NewReader(make([]byte, 10))))
+	if !errors.As(err, &limitErr) || limitErr.Limit != "max-total-size" || limitErr.Name != "b" {
+		t.Fatalf("期望 --max-total-size, 得到 %v", err)
+	}
+	if l.exceeded() != error(limitErr) {
+		t.Fatal("exceeded 应返回第一次超出的上限")
+	}
+}
+
+func TestExtractLimitsStdinRatio(t *testing.T) {
+	// 标准输入按已读取的字节计算压缩比
+	archive := writeTestArchive(t, map[string][]byte{"big.bin": make([]byte, 3<<20)}, CompressOptions{Format: "tar.zst"})
+	data, err := os.ReadFile(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	withStdio(t, data)
+	err = RunDecompress(DecompressOptions{SourcePath: StdioPath, OutputDir: t.TempDir(), Format: "tar.zst", Limits: ExtractLimits{MaxRatio: 50}})
+	var limitErr *LimitError
+	if !errors.As(err, &limitErr) || limitErr.Limit != "max-ratio" {
+		t.Fatalf("期望 --max-ratio, 得到 %v", err)
+	}
+}
+
+func TestNoLimitsNoLimiter(t *testing.T) {
+	if newExtractLimiter(ExtractLimits{}, "") != nil {
+		t.Fatal("未设置上限时不应创建检查")
+	}
+}
+
+func TestLimitError(t *testing.T) {
+	// 错误信息包含命令行参数名、条目与实际值/上限
+	err := &LimitError{Limit: "max-path-depth", Name: "a/b/c/d.txt", Value: 4, Max: 3}
+	if got, want := err.Error(), "超出解压上限 --max-path-depth: a/b/c/d.txt (4 > 3)"; got != want {
+		t.Fatalf("Error() = %q, 期望 %q", got, want)
+	}
+
+	// 路径层数按 / 分隔计算, 首尾的 / 不计入
+	l := newExtractLimiter(ExtractLimits{MaxPathDepth: 3}, "")
+	for _, name := range []string{"a", "a/b/c", "/a/b/c/"} {
+		if err := l.addEntry(name, 0); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+	}
+	var limitErr *LimitError
+	if err := l.addEntry("a/b/c/d.txt", 0); !errors.As(err, &limitErr) {
+		t.Fatalf("期望 *LimitError, 得到 %v", err)
+	}
+	if *limitErr != (LimitError{Limit: "max-path-depth", Name: "a/b/c/d.txt", Value: 4, Max: 3}) {
+		t.Fatalf("LimitError = %+v", limitErr)
+	}
+
+	// 条目数与声明的大小
+	l = newExtractLimiter(ExtractLimits{MaxEntries: 1, MaxEntrySize: 10}, "")
+	if err := l.addEntry("big", 11); !errors.As(err, &limitErr) || *limitErr != (LimitError{Limit: "max-entry-size", Name: "big", Value: 11, Max: 10}) {
+		t.Fatalf("期望 --max-entry-size, 得到 %v", err)
+	}
+	l = newExtractLimiter(ExtractLimits{MaxEntries: 1}, "")
+	_ = l.addEntry("first", 0)
+	if err := l.addEntry("second", 0); !errors.As(err, &limitErr) || *limitErr != (LimitError{Limit: "max-entries", Name: "second", Value: 2, Max: 1}) {
+		t.Fatalf("期望 --max-entries, 得到 %v", err)
+	}
+}
+
+func TestUntrustedLimits(t *testing.T) {
+	if UntrustedLimits.MaxPathDepth != 32 || UntrustedLimits.MaxEntries != 100000 || UntrustedLimits.MaxRatio != 200 {
+		t.Fatalf("UntrustedLimits = %+v", UntrustedLimits)
+	}
+}
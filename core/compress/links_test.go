@@ -0,0 +1,233 @@
+//go:build linux || darwin
+
+package compress
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+// writeLinkTree 写出含符号链接的源目录: data/a.txt、data/link → a.txt、dangling → missing
+func writeLinkTree(t *testing.T) []SourceEntry {
+	t.Helper()
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "data"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "data", "a.txt"), []byte("link target"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("a.txt", filepath.Join(root, "data", "link")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("missing", filepath.Join(root, "dangling")); err != nil {
+		t.Fatal(err)
+	}
+	return []SourceEntry{
+		{Path: filepath.Join(root, "data"), Name: "data", Dir: true},
+		{Path: filepath.Join(root, "data", "a.txt"), Name: "data/a.txt"},
+		{Path: filepath.Join(root, "data", "link"), Name: "data/link", Mode: os.ModeSymlink},
+		{Path: filepath.Join(root, "dangling"), Name: "dangling", Mode: os.ModeSymlink},
+	}
+}
+
+func TestSymlinkRoundTrip(t *testing.T) {
+	cases := []struct {
+		format   string
+		password string
+	}{
+		{format: "tar.gz"},
+		{format: "zip"},
+		{format: "zip", password: "secret"},
+	}
+	for _, c := range cases {
+		name := c.format
+		if c.password != "" {
+			name += "-encrypt"
+		}
+		t.Run(name, func(t *testing.T) {
+			archive := filepath.Join(t.TempDir(), "links."+c.format)
+			opts := CompressOptions{Entries: writeLinkTree(t), OutputPath: archive, Format: c.format, Level: -1}
+			dopts := DecompressOptions{SourcePath: archive, OutputDir: t.TempDir()}
+			if c.password != "" {
+				opts.Encrypt, opts.Password, opts.KeyLength = true, c.password, 32
+				dopts.Encrypt, dopts.Key, dopts.Password = true, []byte(c.password), c.password
+			}
+			if err := RunCompress(opts); err != nil {
+				t.Fatal(err)
+			}
+			if err := RunDecompress(dopts); err != nil {
+				t.Fatal(err)
+			}
+
+			// 链接目标原样保留, 悬空链接也能还原
+			for name, want := range map[string]string{"data/link": "a.txt", "dangling": "missing"} {
+				target, err := os.Readlink(filepath.Join(dopts.OutputDir, filepath.FromSlash(name)))
+				if err != nil || target != want {
+					t.Errorf("%s → %q, %v, 期望 → %q", name, target, err, want)
+				}
+			}
+			data, err := os.ReadFile(filepath.Join(dopts.OutputDir, "data", "link"))
+			if err != nil || string(data) != "link target" {
+				t.Errorf("通过符号链接读取 = %q, %v", data, err)
+			}
+		})
+	}
+}
+
+func TestZipSymlinkStored(t *testing.T) {
+	// 符号链接条目始终 Store 且不加密, 普通文件仍按 --method 压缩并加密
+	archive := filepath.Join(t.TempDir(), "links.zip")
+	opts := CompressOptions{Entries: writeLinkTree(t), OutputPath: archive, Format: "zip", Level: -1, Method: "zstd"}
+	opts.Encrypt, opts.Password, opts.KeyLength = true, "secret", 32
+	if err := RunCompress(opts); err != nil {
+		t.Fatal(err)
+	}
+	reader, err := zip.OpenReader(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	for _, file := range reader.File {
+		_, aes := parseWinZipAESExtra(file.Extra)
+		switch file.Name {
+		case "data/link", "dangling":
+			if file.Method != zip.Store || file.Flags&zipFlagEncrypted != 0 || aes {
+				t.Errorf("%s: method=%d flags=%#x aes=%v, 期望 Store 且不加密", file.Name, file.Method, file.Flags, aes)
+			}
+			target, err := readZipSymlink(file, DecompressOptions{})
+			if err != nil || target != map[string]string{"data/link": "a.txt", "dangling": "missing"}[file.Name] {
+				t.Errorf("%s → %q, %v", file.Name, target, err)
+			}
+		case "data/a.txt":
+			if file.Flags&zipFlagEncrypted == 0 || !aes {
+				t.Errorf("%s 应使用 AES 加密", file.Name)
+			}
+		}
+	}
+
+	// 只含链接的加密包不应被当作旧版格式
+	archive = filepath.Join(t.TempDir(), "only-links.zip")
+	entries := writeLinkTree(t)[2:]
+	if err := RunCompress(CompressOptions{Entries: entries, OutputPath: archive, Format: "zip", Level: -1, Encrypt: true, Password: "secret", KeyLength: 32}); err != nil {
+		t.Fatal(err)
+	}
+	output := t.TempDir()
+	if err := RunDecompress(DecompressOptions{SourcePath: archive, OutputDir: output, Encrypt: true, Key: []byte("secret"), Password: "secret"}); err != nil {
+		t.Fatal(err)
+	}
+	if target, err := os.Readlink(filepath.Join(output, "data", "link")); err != nil || target != "a.txt" {
+		t.Fatalf("data/link → %q, %v", target, err)
+	}
+}
+
+func TestSymlinkSkippedBy7z(t *testing.T) {
+	archive := filepath.Join(t.TempDir(), "links.7z")
+	if err := RunCompress(CompressOptions{Entries: writeLinkTree(t), OutputPath: archive, Format: "7z"}); err != nil {
+		t.Fatal(err)
+	}
+	output := t.TempDir()
+	if err := RunDecompress(DecompressOptions{SourcePath: archive, OutputDir: output}); err != nil {
+		t.Fatal(err)
+	}
+	assertSameFiles(t, readOutputTree(t, output), map[string][]byte{"data/a.txt": []byte("link target")})
+	if _, err := os.Lstat(filepath.Join(output, "data", "link")); !os.IsNotExist(err) {
+		t.Fatalf("7z 不应写入符号链接: %v", err)
+	}
+}
+
+func TestHardlinkTarRoundTrip(t *testing.T) {
+	root := t.TempDir()
+	first := filepath.Join(root, "first.txt")
+	if err := os.WriteFile(first, []byte("shared inode"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	second := filepath.Join(root, "second.txt")
+	if err := os.Link(first, second); err != nil {
+		t.Fatal(err)
+	}
+
+	archive := filepath.Join(t.TempDir(), "hardlink.tar")
+	entries := []SourceEntry{{Path: first, Name: "first.txt"}, {Path: second, Name: "second.txt"}}
+	if err := RunCompress(CompressOptions{Entries: entries, OutputPath: archive, Format: "tar", Level: -1}); err != nil {
+		t.Fatal(err)
+	}
+	// 第二个条目只记录链接, 数据只存一份
+	headers := readTarHeaders(t, archive)
+	if len(headers) != 2 || headers[1].Typeflag != tar.TypeLink || headers[1].Linkname != "first.txt" || headers[1].Size != 0 {
+		t.Fatalf("硬链接数据不应重复存储: %+v", headers)
+	}
+
+	output := t.TempDir()
+	if err := RunDecompress(DecompressOptions{SourcePath: archive, OutputDir: output}); err != nil {
+		t.Fatal(err)
+	}
+	a, errA := os.Stat(filepath.Join(output, "first.txt"))
+	b, errB := os.Stat(filepath.Join(output, "second.txt"))
+	if errA != nil || errB != nil || !os.SameFile(a, b) {
+		t.Fatalf("解压后应为同一 inode: %v %v", errA, errB)
+	}
+}
+
+func TestFIFORoundTrip(t *testing.T) {
+	root := t.TempDir()
+	fifo := filepath.Join(root, "pipe")
+	if err := syscall.Mkfifo(fifo, 0o640); err != nil {
+		t.Skip("无法创建 FIFO:", err)
+	}
+	entries := []SourceEntry{{Path: fifo, Name: "pipe", Mode: os.ModeNamedPipe}}
+
+	for _, format := range []string{"tar", "zip"} {
+		t.Run(format, func(t *testing.T) {
+			archive := filepath.Join(t.TempDir(), "fifo."+format)
+			if err := RunCompress(CompressOptions{Entries: entries, OutputPath: archive, Format: format, Level: -1}); err != nil {
+				t.Fatal(err)
+			}
+
+			// 未开启 SpecialFiles 时跳过
+			output := t.TempDir()
+			if err := RunDecompress(DecompressOptions{SourcePath: archive, OutputDir: output}); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := os.Lstat(filepath.Join(output, "pipe")); !os.IsNotExist(err) {
+				t.Fatalf("未开启 --special-files 时不应创建 FIFO: %v", err)
+			}
+
+			output = t.TempDir()
+			if err := RunDecompress(DecompressOptions{SourcePath: archive, OutputDir: output, SpecialFiles: true}); err != nil {
+				t.Fatal(err)
+			}
+			info, err := os.Lstat(filepath.Join(output, "pipe"))
+			if err != nil || info.Mode()&os.ModeNamedPipe == 0 {
+				t.Fatalf("FIFO 未还原: %v", err)
+			}
+		})
+	}
+}
+
+// readTarHeaders 读出 tar 包内全部条目头
+func readTarHeaders(t *testing.T, path string) []*tar.Header {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	var headers []*tar.Header
+	reader := tar.NewReader(file)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return headers
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		headers = append(headers, header)
+	}
+}
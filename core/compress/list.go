@@ -0,0 +1,107 @@
+// Package compress /core/compress/list.go
+package compress
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// ArchiveEntry 压缩包内条目信息
+type ArchiveEntry struct {
+	Name           string      // 条目路径
+	Size           int64       // 原始大小
+	CompressedSize int64       // 压缩后大小, -1 表示格式未记录 (tar 系列整体压缩、7z 固实块)
+	Method         string      // 压缩方式
+	Modified       time.Time   // 修改时间
+	Mode           os.FileMode // 权限与类型
+	CRC32          uint32      // CRC32
+	HasCRC32       bool        // 是否记录了 CRC32 (tar 与 WinZip AE-2 不记录)
+	Encrypted      bool        // 是否加密
+}
+
+// ArchiveListing 压缩包列表
+type ArchiveListing struct {
+	Path        string         // 压缩包路径
+	Format      string         // 压缩格式
+	Volumes     []string       // 分卷路径, 非分卷为压缩包本身
+	ArchiveSize int64          // 压缩包 (全部分卷) 总大小, 标准输入为 -1
+	Entries     []ArchiveEntry // 条目列表
+}
+
+// Totals 汇总文件数、原始大小与压缩后大小
+// 有条目未记录压缩后大小时, 以压缩包总大小作为压缩后大小
+func (l *ArchiveListing) Totals() (files int, size, compressedSize int64) {
+	known := true
+	for _, entry := range l.Entries {
+		if !entry.Mode.IsDir() {
+			files++
+		}
+		size += entry.Size
+		if entry.CompressedSize < 0 {
+			known = false
+		}
+		compressedSize += max(entry.CompressedSize, 0)
+	}
+	if !known {
+		compressedSize = l.ArchiveSize
+	}
+	return files, size, compressedSize
+}
+
+// Lister 只读取目录信息、不解压的列表接口, 由各格式解压缩器实现
+type Lister interface {
+	List(opts DecompressOptions) ([]ArchiveEntry, error)
+}
+
+// RunList 列表入口
+func RunList(opts DecompressOptions) (*ArchiveListing, error) {
+	listing := &ArchiveListing{Path: opts.SourcePath, ArchiveSize: -1}
+
+	// 参数校验, 分卷只要第一卷存在即可
+	if !IsStdio(opts.SourcePath) {
+		if opts.SourcePath == "" || !archiveExists(opts.SourcePath) {
+			return nil, fmt.Errorf("压缩包不存在: %s", opts.SourcePath)
+		}
+		volumes, _, err := archiveVolumes(opts.SourcePath)
+		if err != nil {
+			return nil, err
+		}
+		listing.Volumes = volumes
+		listing.ArchiveSize = 0
+		for _, volume := range volumes {
+			info, err := os.Stat(volume)
+			if err != nil {
+				return nil, fmt.Errorf("读取分卷信息失败: %s, 错误: %v", volume, err)
+			}
+			listing.ArchiveSize += info.Size()
+		}
+	}
+
+	// 未指定格式时根据文件头识别
+	if opts.Format == "" {
+		format, err := DetectFormat(opts.SourcePath)
+		if err != nil {
+			return nil, err
+		}
+		opts.Format = format
+	}
+	listing.Format = opts.Format
+
+	// 复用解压缩器的格式分发
+	decompressor, err := NewDecompressor(opts.Format)
+	if err != nil {
+		return nil, err
+	}
+	lister, ok := decompressor.(Lister)
+	if !ok {
+		return nil, fmt.Errorf("%s 格式不支持列表", opts.Format)
+	}
+
+	entries, err := lister.List(opts)
+	if err != nil {
+		return nil, err
+	}
+	listing.Entries = entries
+	return listing, nil
+}
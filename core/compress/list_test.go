@@ -0,0 +1,121 @@
+package compress
+
+import (
+	"hash/crc32"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunList(t *testing.T) {
+	files := testTreeFiles()
+	cases := []struct {
+		format    string
+		encrypt   bool
+		hasCRC32  bool
+		knownSize bool // 是否记录每个条目的压缩后大小
+		markEnc   bool // 是否标记加密条目
+	}{
+		{format: "zip", hasCRC32: true, knownSize: true},
+		{format: "zip", encrypt: true, knownSize: true, markEnc: true},
+		{format: "7z", hasCRC32: true},
+		{format: "7z", encrypt: true, hasCRC32: true, markEnc: true},
+		{format: "tar.zst"},
+	}
+	for _, c := range cases {
+		name := c.format
+		if c.encrypt {
+			name += "-encrypt"
+		}
+		t.Run(name, func(t *testing.T) {
+			archive := filepath.Join(t.TempDir(), "list."+c.format)
+			opts := CompressOptions{
+				Entries:    writeTestTree(t, files),
+				OutputPath: archive,
+				Format:     c.format,
+				Level:      -1,
+			}
+			if c.encrypt {
+				opts.Encrypt, opts.Password, opts.KeyLength = true, "secret", 32
+			}
+			if err := RunCompress(opts); err != nil {
+				t.Fatal(err)
+			}
+
+			dopts := DecompressOptions{SourcePath: archive}
+			if c.encrypt {
+				dopts.Encrypt, dopts.Key, dopts.Password = true, []byte("secret"), "secret"
+			}
+			listing, err := RunList(dopts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if listing.Format != c.format || len(listing.Volumes) != 1 || listing.ArchiveSize <= 0 {
+				t.Fatalf("列表头部不正确: format=%s volumes=%v size=%d", listing.Format, listing.Volumes, listing.ArchiveSize)
+			}
+
+			var total int64
+			dirs := 0
+			for _, entry := range listing.Entries {
+				if entry.Mode.IsDir() {
+					dirs++
+					continue
+				}
+				data, ok := files[entry.Name]
+				if !ok {
+					t.Fatalf("多出条目 %s", entry.Name)
+				}
+				if entry.Size != int64(len(data)) {
+					t.Errorf("%s: 大小 = %d, 期望 %d", entry.Name, entry.Size, len(data))
+				}
+				if c.hasCRC32 && len(data) > 0 && (!entry.HasCRC32 || entry.CRC32 != crc32.ChecksumIEEE(data)) {
+					t.Errorf("%s: CRC32 = %08x (%t), 期望 %08x", entry.Name, entry.CRC32, entry.HasCRC32, crc32.ChecksumIEEE(data))
+				}
+				if c.markEnc && len(data) > 0 && !entry.Encrypted {
+					t.Errorf("%s: 未标记为加密", entry.Name)
+				}
+				if entry.Method == "" {
+					t.Errorf("%s: 压缩方式为空", entry.Name)
+				}
+				total += entry.Size
+			}
+
+			// data、data/nested、docs 三个目录条目
+			if dirs != 3 {
+				t.Fatalf("目录条目 = %d, 期望 3", dirs)
+			}
+			count, size, compressedSize := listing.Totals()
+			if count != len(files) || size != total {
+				t.Fatalf("汇总 = %d 个文件 %d 字节, 期望 %d 个文件 %d 字节", count, size, len(files), total)
+			}
+			// 未记录单个条目压缩后大小时, 以压缩包总大小汇总
+			if !c.knownSize && compressedSize != listing.ArchiveSize {
+				t.Fatalf("压缩后大小 = %d, 期望压缩包大小 %d", compressedSize, listing.ArchiveSize)
+			}
+		})
+	}
+}
+
+func TestRunListSplitVolumes(t *testing.T) {
+	files := testTreeFiles()
+	archive := filepath.Join(t.TempDir(), "split.7z")
+	err := RunCompress(CompressOptions{
+		Entries:    writeTestTree(t, files),
+		OutputPath: archive,
+		Format:     "7z",
+		SplitSize:  32 * 1024,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	listing, err := RunList(DecompressOptions{SourcePath: archive + ".001"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listing.Volumes) < 2 {
+		t.Fatalf("分卷数量 = %d, 期望至少 2", len(listing.Volumes))
+	}
+	if count, _, _ := listing.Totals(); count != len(files) {
+		t.Fatalf("文件数 = %d, 期望 %d", count, len(files))
+	}
+}
@@ -0,0 +1,14 @@
+package compress
+
+import (
+	"os"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+// TestMain 测试时开启静默模式, 不输出进度条
+func TestMain(m *testing.M) {
+	viper.Set("quiet", true)
+	os.Exit(m.Run())
+}
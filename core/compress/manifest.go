@@ -0,0 +1,387 @@
+// Package compress /core/compress/manifest.go
+package compress
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/GoFurry/gf-file-tool/progress"
+	"github.com/GoFurry/gf-file-tool/utils"
+	"github.com/GoFurry/gf-file-tool/utils/compress"
+	"github.com/GoFurry/gf-file-tool/utils/log"
+)
+
+// 分卷说明文件 <压缩包>.split (JSON), 与分卷一起写出, 记录分卷的顺序、大小与 SHA-256/CRC32,
+// 以及拼接后整个压缩包的校验值. 读取分卷时以说明文件为准查找分卷, 不再依赖编号与目录顺序;
+// 合并、解压与完整性校验据此指出缺失、大小不符或内容损坏的具体分卷. 没有说明文件时仍按 .001/.002 编号查找.
+
+// splitManifestVersion 当前说明文件版本, 读取时拒绝更高的版本
+const splitManifestVersion = 1
+
+// splitManifest 分卷说明文件
+type splitManifest struct {
+	Version    int           `json:"version"`
+	Archive    string        `json:"archive"`          // 压缩包文件名 (不含分卷后缀)
+	Format     string        `json:"format"`           // 压缩格式, 第一卷损坏时代替文件头识别
+	VolumeSize int64         `json:"volume_size"`      // 分卷大小, 只有最后一卷可以较小
+	Size       int64         `json:"size"`             // 拼接后的总大小
+	SHA256     string        `json:"sha256"`           // 拼接后的 SHA-256
+	CRC32      string        `json:"crc32"`            // 拼接后的 CRC32
+	Volumes    []splitVolume `json:"volumes"`          // 按顺序排列的分卷
+	Parity     *splitParity  `json:"parity,omitempty"` // 恢复卷, 未生成时省略, 不认识该字段的读取方仍可使用分卷
+
+	dir string // 说明文件所在目录, 分卷与其在同一目录
+}
+
+// splitVolume 单个分卷的记录
+type splitVolume struct {
+	Name   string `json:"name"` // 分卷文件名
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+	CRC32  string `json:"crc32"`
+}
+
+// splitManifestPath 压缩包 base 的说明文件路径
+func splitManifestPath(base string) string {
+	return base + ".split"
+}
+
+// readSplitManifest 读取压缩包 base 的说明文件, 不存在时返回 nil
+func readSplitManifest(base string) (*splitManifest, error) {
+	path := splitManifestPath(base)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取分卷说明文件失败: %v", err)
+	}
+	var manifest splitManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("无法解析分卷说明文件 %s: %v", path, err)
+	}
+	if manifest.Version < 1 || manifest.Version > splitManifestVersion {
+		return nil, fmt.Errorf("不支持的分卷说明文件版本: %d, 仅支持 %d", manifest.Version, splitManifestVersion)
+	}
+	if len(manifest.Volumes) == 0 {
+		return nil, fmt.Errorf("分卷说明文件中没有分卷: %s", path)
+	}
+	volumes := manifest.Volumes
+	if manifest.Parity != nil {
+		if manifest.Parity.Algorithm != parityAlgorithm {
+			return nil, fmt.Errorf("不支持的恢复卷编码: %s", manifest.Parity.Algorithm)
+		}
+		volumes = append(volumes[:len(volumes):len(volumes)], manifest.Parity.Volumes...)
+	}
+	for _, volume := range volumes {
+		// 分卷名只能是同一目录下的文件名
+		if volume.Name == "" || filepath.Base(volume.Name) != volume.Name || strings.ContainsAny(volume.Name, `/\`) {
+			return nil, fmt.Errorf("分卷说明文件中的分卷名无效: %q", volume.Name)
+		}
+	}
+	manifest.dir = filepath.Dir(path)
+	return &manifest, nil
+}
+
+// write 写出说明文件, 先写临时文件再改名, 中断时不会留下不完整的说明文件
+func (m *splitManifest) write(base string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := splitManifestPath(base)
+	tempPath := path + ".tmp"
+	if err := os.WriteFile(tempPath, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("写入分卷说明文件失败: %v", err)
+	}
+	if err := os.Rename(tempPath, path); err != nil {
+		_ = os.Remove(tempPath)
+		return fmt.Errorf("写入分卷说明文件失败: %v", err)
+	}
+	return nil
+}
+
+// volumePath 第 i 卷 (从 0 开始) 的路径
+func (m *splitManifest) volumePath(i int) string {
+	return filepath.Join(m.dir, m.Volumes[i].Name)
+}
+
+// volumePaths 按顺序排列的全部分卷路径
+func (m *splitManifest) volumePaths() []string {
+	paths := make([]string, len(m.Volumes))
+	for i := range m.Volumes {
+		paths[i] = m.volumePath(i)
+	}
+	return paths
+}
+
+// volumeProblem 缺失、大小不符或内容损坏的分卷
+type volumeProblem struct {
+	Index  int    // 分卷序号, 从 0 开始
+	Path   string // 分卷路径
+	Reason string
+}
+
+// volumeDamageError 有分卷缺失或损坏, 保留问题列表供恢复卷修复
+type volumeDamageError struct {
+	problems []volumeProblem
+}
+
+func (e *volumeDamageError) Error() string {
+	details := make([]string, len(e.problems))
+	for i, problem := range e.problems {
+		details[i] = problem.Path + " (" + problem.Reason + ")"
+	}
+	return fmt.Sprintf("%d 个分卷缺失或损坏: %s", len(e.problems), strings.Join(details, ", "))
+}
+
+// volumeProblemsError 列出全部有问题的分卷, 没有问题时返回 nil
+func volumeProblemsError(problems []volumeProblem) error {
+	if len(problems) == 0 {
+		return nil
+	}
+	return &volumeDamageError{problems: problems}
+}
+
+// checkPresent 只核对分卷是否存在以及大小, 不读取内容
+func (m *splitManifest) checkPresent() []volumeProblem {
+	var problems []volumeProblem
+	for i, volume := range m.Volumes {
+		path := m.volumePath(i)
+		info, err := os.Stat(path)
+		switch {
+		case os.IsNotExist(err):
+			problems = append(problems, volumeProblem{i, path, "缺失"})
+		case err != nil:
+			problems = append(problems, volumeProblem{i, path, err.Error()})
+		case info.Size() < volume.Size:
+			problems = append(problems, volumeProblem{i, path, fmt.Sprintf("被截断: %d 字节, 应为 %d 字节", info.Size(), volume.Size)})
+		case info.Size() != volume.Size:
+			problems = append(problems, volumeProblem{i, path, fmt.Sprintf("大小不符: %d 字节, 应为 %d 字节", info.Size(), volume.Size)})
+		}
+	}
+	return problems
+}
+
+// check 核对分卷大小后逐卷读取并核对校验值, 读出的内容同时写入 w (可为 nil)
+// 返回全部有问题的分卷, w 非 nil 时有分卷缺失或大小不符就不再读取; err 只表示写入 w 失败
+func (m *splitManifest) check(w io.Writer) ([]volumeProblem, error) {
+	problems := m.checkPresent()
+	if len(problems) > 0 && w != nil {
+		return problems, nil
+	}
+	skip := make(map[int]bool, len(problems))
+	for _, problem := range problems {
+		skip[problem.Index] = true
+	}
+
+	batchBar := progress.NewBatchProgressBar(len(m.Volumes))
+	defer progress.FinishProgress(batchBar)
+	for i, volume := range m.Volumes {
+		progress.UpdateProgress(batchBar, 1)
+		if skip[i] {
+			continue
+		}
+		path := m.volumePath(i)
+		sum, err := checksumFile(path, w)
+		var writeErr *checksumWriteError
+		if errors.As(err, &writeErr) {
+			return nil, writeErr.err
+		}
+		switch {
+		case err != nil:
+			problems = append(problems, volumeProblem{i, path, err.Error()})
+		case sum.sha256() != volume.SHA256:
+			problems = append(problems, volumeProblem{i, path, "SHA-256 不符"})
+		case sum.crc32() != volume.CRC32:
+			problems = append(problems, volumeProblem{i, path, "CRC32 不符"})
+		default:
+			if utils.VerboseMode() {
+				log.Success("分卷校验通过:", path)
+			}
+		}
+	}
+	return problems, nil
+}
+
+// verify 按说明文件逐卷校验, 有问题时返回列出全部问题分卷的错误
+// repair 为 true 且有恢复卷时先尝试修复, 修复成功返回 nil
+func (m *splitManifest) verify(repair bool) error {
+	log.Info("按分卷说明文件校验", len(m.Volumes), "个分卷...")
+	problems, err := m.check(nil)
+	if err != nil {
+		return err
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	if m.Parity == nil {
+		return volumeProblemsError(problems)
+	}
+	if !repair {
+		return fmt.Errorf("%v (有 %d 个恢复卷, merge 与 decompress 会尝试修复)", volumeProblemsError(problems), len(m.Parity.Volumes))
+	}
+	return m.repair(problems)
+}
+
+// verifySplitVolumes 分卷压缩包有说明文件时逐卷校验, 不是分卷或没有说明文件时直接返回
+// repair 为 true 时用恢复卷修复有问题的分卷
+func verifySplitVolumes(path string, repair bool) error {
+	if IsStdio(path) || !compress.IsSplitFile(path) {
+		return nil
+	}
+	manifest, err := readSplitManifest(compress.SplitBasePath(path))
+	if err != nil || manifest == nil {
+		return err
+	}
+	return manifest.verify(repair)
+}
+
+// ============================== 校验值 ==============================
+
+// checksum 同时计算 SHA-256 与 CRC32
+type checksum struct {
+	sha  hash.Hash
+	crc  hash.Hash32
+	size int64
+}
+
+// newChecksum 创建校验值计算
+func newChecksum() *checksum {
+	return &checksum{sha: sha256.New(), crc: crc32.NewIEEE()}
+}
+
+// Write 累加校验值
+func (c *checksum) Write(p []byte) (int, error) {
+	c.sha.Write(p)
+	c.crc.Write(p)
+	c.size += int64(len(p))
+	return len(p), nil
+}
+
+// sha256 十六进制 SHA-256
+func (c *checksum) sha256() string { return hex.EncodeToString(c.sha.Sum(nil)) }
+
+// crc32 十六进制 CRC32, 与 crc32 命令的输出格式一致
+func (c *checksum) crc32() string { return fmt.Sprintf("%08x", c.crc.Sum32()) }
+
+// checksumWriteError 计算校验值时转写到 w 失败, 与读取分卷失败区分
+type checksumWriteError struct{ err error }
+
+func (e *checksumWriteError) Error() string { return e.err.Error() }
+
+// checksumFile 计算文件的校验值, w 非 nil 时同时把内容写入 w
+func checksumFile(path string, w io.Writer) (*checksum, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	sum := newChecksum()
+	buf := make([]byte, 4*1024*1024) // 4MB 缓冲区
+	for {
+		n, err := file.Read(buf)
+		if n > 0 {
+			sum.Write(buf[:n])
+			if w != nil {
+				if _, err := w.Write(buf[:n]); err != nil {
+					return nil, &checksumWriteError{err}
+				}
+			}
+		}
+		if err == io.EOF {
+			return sum, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("读取失败: %v", err)
+		}
+	}
+}
+
+// ============================== 合并 ==============================
+
+// MergeSplit 把分卷按顺序合并为一个完整的压缩包
+// 有说明文件时按其中的顺序合并, 边合并边逐卷核对并校验整个压缩包, 返回 true;
+// 发现问题且有恢复卷时先修复分卷再重新合并. 没有说明文件时按 .001/.002 编号合并, 不做校验, 返回 false
+func MergeSplit(source, outputPath string) (bool, error) {
+	base := compress.SplitBasePath(source)
+	manifest, err := readSplitManifest(base)
+	if err != nil {
+		return false, err
+	}
+
+	outFile, err := os.Create(outputPath)
+	if err != nil {
+		return false, fmt.Errorf("创建合并文件失败: %v", err)
+	}
+	err = mergeVolumes(manifest, base, outFile)
+	var damaged *volumeDamageError
+	if errors.As(err, &damaged) && manifest.Parity != nil {
+		// 合并时遇到缺失的分卷就停止读取, 修复前先完整校验一遍
+		if err = manifest.verify(true); err == nil {
+			err = rewind(outFile)
+		}
+		if err == nil {
+			err = mergeVolumes(manifest, base, outFile)
+		}
+	}
+	if closeErr := outFile.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("关闭合并文件失败: %v", closeErr)
+	}
+	if err != nil {
+		_ = os.Remove(outputPath)
+		return false, err
+	}
+	return manifest != nil, nil
+}
+
+// rewind 清空文件并回到开头
+func rewind(file *os.File) error {
+	if err := file.Truncate(0); err != nil {
+		return err
+	}
+	_, err := file.Seek(0, io.SeekStart)
+	return err
+}
+
+// mergeVolumes 把分卷依次写入 out, 有说明文件时核对每一卷与整个压缩包
+func mergeVolumes(manifest *splitManifest, base string, out *os.File) error {
+	if manifest == nil {
+		paths, err := compress.SplitVolumePaths(base)
+		if err != nil {
+			return err
+		}
+		log.Info("未找到分卷说明文件, 按编号合并", len(paths), "个分卷")
+		for _, path := range paths {
+			if _, err := checksumFile(path, out); err != nil {
+				return fmt.Errorf("合并分卷 %s 失败: %v", path, err)
+			}
+		}
+		return nil
+	}
+
+	log.Info("按分卷说明文件合并", len(manifest.Volumes), "个分卷")
+	whole := newChecksum()
+	problems, err := manifest.check(io.MultiWriter(out, whole))
+	if err != nil {
+		return fmt.Errorf("写入合并文件失败: %v", err)
+	}
+	if err := volumeProblemsError(problems); err != nil {
+		return err
+	}
+	if whole.sha256() != manifest.SHA256 || whole.crc32() != manifest.CRC32 {
+		return fmt.Errorf("合并后的压缩包校验值不符: SHA-256 %s, 应为 %s", whole.sha256(), manifest.SHA256)
+	}
+	log.Success("压缩包校验通过, CRC32:", whole.crc32())
+	return nil
+}
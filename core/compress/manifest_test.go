@@ -0,0 +1,186 @@
+package compress
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeSplitArchive 分卷压缩测试文件, 返回压缩包基础名与按顺序拼接的全部分卷内容
+func writeSplitArchive(t *testing.T, format string) (string, []byte) {
+	t.Helper()
+	base := filepath.Join(t.TempDir(), "split."+format)
+	writeTestArchive(t, testTreeFiles(), CompressOptions{Format: format, OutputPath: base, SplitSize: 16 << 10})
+	var joined []byte
+	for n := 1; ; n++ {
+		data, err := os.ReadFile(splitVolumePath(base, "", n))
+		if os.IsNotExist(err) {
+			return base, joined
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		joined = append(joined, data...)
+	}
+}
+
+func TestSplitManifestWritten(t *testing.T) {
+	base, joined := writeSplitArchive(t, "zip")
+	manifest, err := readSplitManifest(base)
+	if err != nil || manifest == nil {
+		t.Fatalf("readSplitManifest = %v, %v", manifest, err)
+	}
+	sum := sha256.Sum256(joined)
+	if manifest.Archive != "split.zip" || manifest.VolumeSize != 16<<10 || manifest.Size != int64(len(joined)) || manifest.SHA256 != hex.EncodeToString(sum[:]) {
+		t.Fatalf("说明文件 = %+v", manifest)
+	}
+	if len(manifest.Volumes) < 2 {
+		t.Fatalf("分卷数 = %d", len(manifest.Volumes))
+	}
+	for i, volume := range manifest.Volumes {
+		data, err := os.ReadFile(manifest.volumePath(i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		sum := sha256.Sum256(data)
+		if volume.Name != filepath.Base(splitVolumePath(base, "", i+1)) || volume.Size != int64(len(data)) || volume.SHA256 != hex.EncodeToString(sum[:]) {
+			t.Fatalf("分卷 %d = %+v", i, volume)
+		}
+	}
+	if err := manifest.verify(false); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSplitManifestLocatesVolumes(t *testing.T) {
+	base, _ := writeSplitArchive(t, "tar.zst")
+	manifest, err := readSplitManifest(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 分卷改名后只要说明文件中的记录一致, 仍能按其中的顺序找到
+	for i := range manifest.Volumes {
+		name := "renamed-" + manifest.Volumes[i].Name
+		if err := os.Rename(manifest.volumePath(i), filepath.Join(manifest.dir, name)); err != nil {
+			t.Fatal(err)
+		}
+		manifest.Volumes[i].Name = name
+	}
+	if err := manifest.write(base); err != nil {
+		t.Fatal(err)
+	}
+
+	output := t.TempDir()
+	if err := RunDecompress(DecompressOptions{SourcePath: splitManifestPath(base), OutputDir: output}); err != nil {
+		t.Fatal(err)
+	}
+	assertSameFiles(t, readOutputTree(t, output), testTreeFiles())
+}
+
+func TestSplitManifestReportsDamagedVolumes(t *testing.T) {
+	base, _ := writeSplitArchive(t, "zip")
+	first := splitVolumePath(base, "", 1)
+	second := splitVolumePath(base, "", 2)
+
+	// 大小不变、内容损坏的分卷只能按校验值发现
+	flipByte(t, second, 10)
+	err := RunDecompress(DecompressOptions{SourcePath: first, OutputDir: t.TempDir()})
+	if err == nil || !strings.Contains(err.Error(), second) || !strings.Contains(err.Error(), "SHA-256") {
+		t.Fatalf("期望 %s 校验值不符, 得到 %v", second, err)
+	}
+	report, err := RunTest(DecompressOptions{SourcePath: first}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Err == nil || !strings.Contains(report.Err.Error(), second) {
+		t.Fatalf("test 应报告损坏的分卷, 得到 %v", report.Err)
+	}
+
+	// 缺失与截断的分卷一起列出
+	if err := os.Remove(second); err != nil {
+		t.Fatal(err)
+	}
+	third := splitVolumePath(base, "", 3)
+	if err := os.Truncate(third, 5); err != nil {
+		t.Fatal(err)
+	}
+	err = RunDecompress(DecompressOptions{SourcePath: first, OutputDir: t.TempDir()})
+	if err == nil || !strings.Contains(err.Error(), second+" (缺失)") || !strings.Contains(err.Error(), third+" (被截断") {
+		t.Fatalf("期望列出缺失与截断的分卷, 得到 %v", err)
+	}
+}
+
+func TestMergeSplit(t *testing.T) {
+	base, joined := writeSplitArchive(t, "7z")
+	output := filepath.Join(t.TempDir(), "merged.7z")
+	checked, err := MergeSplit(splitVolumePath(base, "", 1), output)
+	if err != nil || !checked {
+		t.Fatalf("MergeSplit = %t, %v", checked, err)
+	}
+	if data, _ := os.ReadFile(output); !bytes.Equal(data, joined) {
+		t.Fatal("合并后的内容与分卷拼接不一致")
+	}
+
+	// 损坏的分卷: 合并失败且不留下输出
+	flipByte(t, splitVolumePath(base, "", 2), 0)
+	broken := filepath.Join(t.TempDir(), "broken.7z")
+	if _, err := MergeSplit(base, broken); err == nil {
+		t.Fatal("分卷损坏时合并应失败")
+	}
+	if _, err := os.Stat(broken); !os.IsNotExist(err) {
+		t.Fatal("合并失败后应删除输出")
+	}
+
+	// 没有说明文件时按编号合并, 不做校验
+	if err := os.Remove(splitManifestPath(base)); err != nil {
+		t.Fatal(err)
+	}
+	checked, err = MergeSplit(base, broken)
+	if err != nil || checked {
+		t.Fatalf("无说明文件时 MergeSplit = %t, %v", checked, err)
+	}
+}
+
+func TestReadSplitManifestRejects(t *testing.T) {
+	base := filepath.Join(t.TempDir(), "bad.zip")
+	valid := splitManifest{Version: splitManifestVersion, Archive: "bad.zip", Volumes: []splitVolume{{Name: "bad.zip.001"}}}
+	for name, mutate := range map[string]func(*splitManifest){
+		"future-version": func(m *splitManifest) { m.Version = splitManifestVersion + 1 },
+		"no-volumes":     func(m *splitManifest) { m.Volumes = nil },
+		"parent-dir":     func(m *splitManifest) { m.Volumes[0].Name = "../bad.zip.001" },
+		"subdir":         func(m *splitManifest) { m.Volumes[0].Name = "sub/bad.zip.001" },
+		"empty-name":     func(m *splitManifest) { m.Volumes[0].Name = "" },
+	} {
+		manifest := valid
+		manifest.Volumes = append([]splitVolume(nil), valid.Volumes...)
+		mutate(&manifest)
+		data, err := json.Marshal(manifest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(splitManifestPath(base), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := readSplitManifest(base); err == nil {
+			t.Errorf("%s: 应拒绝该说明文件", name)
+		}
+	}
+
+	if err := os.WriteFile(splitManifestPath(base), []byte("{not json"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readSplitManifest(base); err == nil {
+		t.Fatal("无法解析的说明文件应报错")
+	}
+	if err := os.Remove(splitManifestPath(base)); err != nil {
+		t.Fatal(err)
+	}
+	if manifest, err := readSplitManifest(base); manifest != nil || err != nil {
+		t.Fatalf("没有说明文件时应返回 nil, nil, 得到 %v, %v", manifest, err)
+	}
+}
@@ -0,0 +1,258 @@
+// Package compress /core/compress/metadata.go
+package compress
+
+import (
+	"encoding/binary"
+	"os"
+	"os/user"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/GoFurry/gf-file-tool/utils"
+	"github.com/GoFurry/gf-file-tool/utils/log"
+)
+
+// 条目元数据的归档与还原:
+//   - tar 使用 PAX 格式, 记录纳秒级修改/访问时间、uid/gid 与用户名/组名, 扩展属性写入 SCHILY.xattr.* 记录
+//   - zip 写入 Info-ZIP 扩展时间戳 (0x5455) 与 Unix 属主 (0x7875) 扩展字段
+//   - 7z 只记录修改时间与权限
+// 解压时总是还原时间与权限 (按 Umask 去掉部分位), 属主只在 SameOwner 时还原
+
+const (
+	zipExtraExtTime   = 0x5455 // Info-ZIP 扩展时间戳
+	zipExtraUnixOwner = 0x7875 // Info-ZIP Unix 属主 (第 3 版, 变长 uid/gid)
+
+	paxXattrPrefix = "SCHILY.xattr."
+)
+
+// entryMetadata 条目元数据
+type entryMetadata struct {
+	Mode       os.FileMode       // 权限位 (含 setuid/setgid/sticky), 零值表示不修改
+	ModTime    time.Time         // 修改时间, 零值表示不修改
+	AccessTime time.Time         // 访问时间, 零值时使用修改时间
+	Uid, Gid   int               // 数字属主, -1 表示未记录
+	Uname      string            // 用户名, 目标系统存在同名用户时优先于 Uid
+	Gname      string            // 组名
+	Xattrs     map[string]string // 扩展属性
+	Link       bool              // 符号链接: 不修改权限, 时间与属主作用于链接本身
+}
+
+// newEntryMetadata 未记录属主的元数据
+func newEntryMetadata(mode os.FileMode, modTime time.Time) entryMetadata {
+	return entryMetadata{Mode: mode, ModTime: modTime, Uid: -1, Gid: -1}
+}
+
+// permBits 权限位与 setuid/setgid/sticky
+func permBits(mode os.FileMode) os.FileMode {
+	return mode & (os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky)
+}
+
+// pendingDir 待还原元数据的目录
+type pendingDir struct {
+	path string
+	meta entryMetadata
+}
+
+// metadataRestorer 按解压配置还原条目元数据
+// 目录的元数据推迟到 finish 时还原: 写入目录内容会改掉目录的修改时间, 只读目录也无法继续写入
+type metadataRestorer struct {
+	opts   DecompressOptions
+	dirs   []pendingDir
+	users  map[string]int // 用户名 → uid 缓存, -1 表示目标系统不存在
+	groups map[string]int // 组名 → gid 缓存
+}
+
+// newMetadataRestorer 创建元数据还原器
+func newMetadataRestorer(opts DecompressOptions) *metadataRestorer {
+	return &metadataRestorer{opts: opts, users: make(map[string]int), groups: make(map[string]int)}
+}
+
+// deferDir 记录目录, 在 finish 时还原
+func (r *metadataRestorer) deferDir(path string, meta entryMetadata) {
+	r.dirs = append(r.dirs, pendingDir{path: path, meta: meta})
+}
+
+// finish 由深到浅还原目录元数据
+func (r *metadataRestorer) finish() {
+	sort.SliceStable(r.dirs, func(i, j int) bool {
+		return strings.Count(r.dirs[i].path, string(os.PathSeparator)) > strings.Count(r.dirs[j].path, string(os.PathSeparator))
+	})
+	for _, dir := range r.dirs {
+		r.apply(dir.path, dir.meta)
+	}
+	r.dirs = nil
+}
+
+// apply 还原单个条目的元数据, 失败只记录警告
+// 顺序为 属主 → 权限 → 扩展属性 → 时间: chown 会清除 setuid 位, 其余操作都会改变 ctime 但不影响 mtime
+func (r *metadataRestorer) apply(path string, meta entryMetadata) {
+	if r.opts.SameOwner {
+		if uid, gid := r.owner(meta); uid >= 0 || gid >= 0 {
+			if err := os.Lchown(path, uid, gid); err != nil {
+				log.Warn("还原属主失败:", path, ", 错误:", err)
+			}
+		}
+	}
+	if !meta.Link && meta.Mode != 0 {
+		if err := os.Chmod(path, permBits(meta.Mode)&^r.opts.Umask); err != nil && utils.VerboseMode() {
+			log.Warn("设置文件权限失败:", path, ", 错误:", err)
+		}
+	}
+	if len(meta.Xattrs) > 0 {
+		if err := writeXattrs(path, meta.Xattrs); err != nil && utils.VerboseMode() {
+			log.Warn("还原扩展属性失败:", path, ", 错误:", err)
+		}
+	}
+	if !meta.ModTime.IsZero() {
+		atime := meta.AccessTime
+		if atime.IsZero() {
+			atime = meta.ModTime
+		}
+		var err error
+		if meta.Link {
+			err = lchtimes(path, atime, meta.ModTime)
+		} else {
+			err = os.Chtimes(path, atime, meta.ModTime)
+		}
+		if err != nil && utils.VerboseMode() {
+			log.Warn("还原修改时间失败:", path, ", 错误:", err)
+		}
+	}
+}
+
+// owner 解析目标属主: 目标系统存在同名用户/组时按名称, 否则按记录的数字 id
+func (r *metadataRestorer) owner(meta entryMetadata) (uid, gid int) {
+	uid, gid = meta.Uid, meta.Gid
+	if meta.Uname != "" {
+		id, ok := r.users[meta.Uname]
+		if !ok {
+			id = -1
+			if u, err := user.Lookup(meta.Uname); err == nil {
+				id, _ = strconv.Atoi(u.Uid)
+			}
+			r.users[meta.Uname] = id
+		}
+		if id >= 0 {
+			uid = id
+		}
+	}
+	if meta.Gname != "" {
+		id, ok := r.groups[meta.Gname]
+		if !ok {
+			id = -1
+			if g, err := user.LookupGroup(meta.Gname); err == nil {
+				id, _ = strconv.Atoi(g.Gid)
+			}
+			r.groups[meta.Gname] = id
+		}
+		if id >= 0 {
+			gid = id
+		}
+	}
+	return uid, gid
+}
+
+// xattrsFromPAX 从 PAX 记录中取出扩展属性
+func xattrsFromPAX(records map[string]string) map[string]string {
+	var xattrs map[string]string
+	for key, value := range records {
+		if name, ok := strings.CutPrefix(key, paxXattrPrefix); ok && name != "" {
+			if xattrs == nil {
+				xattrs = make(map[string]string)
+			}
+			xattrs[name] = value
+		}
+	}
+	return xattrs
+}
+
+// zipMetadataExtra 生成扩展时间戳与 Unix 属主扩展字段, 本地文件头与中央目录共用
+// atime 为零值时只记录修改时间, uid/gid 为负数时不写属主字段
+func zipMetadataExtra(mtime, atime time.Time, uid, gid int) []byte {
+	var extra []byte
+	flags, size := byte(1), 5
+	if !atime.IsZero() {
+		flags, size = 3, 9
+	}
+	extra = binary.LittleEndian.AppendUint16(extra, zipExtraExtTime)
+	extra = binary.LittleEndian.AppendUint16(extra, uint16(size))
+	extra = append(extra, flags)
+	extra = binary.LittleEndian.AppendUint32(extra, uint32(mtime.Unix()))
+	if !atime.IsZero() {
+		extra = binary.LittleEndian.AppendUint32(extra, uint32(atime.Unix()))
+	}
+
+	if uid >= 0 && gid >= 0 {
+		extra = binary.LittleEndian.AppendUint16(extra, zipExtraUnixOwner)
+		extra = binary.LittleEndian.AppendUint16(extra, 11)
+		extra = append(extra, 1, 4)
+		extra = binary.LittleEndian.AppendUint32(extra, uint32(uid))
+		extra = append(extra, 4)
+		extra = binary.LittleEndian.AppendUint32(extra, uint32(gid))
+	}
+	return extra
+}
+
+// parseZipMetadataExtra 从扩展字段中读取时间与属主, 未记录的字段保持原值
+// 中央目录的 0x5455 可能只有修改时间, 按实际长度读取
+func parseZipMetadataExtra(extra []byte, meta *entryMetadata) {
+	for len(extra) >= 4 {
+		tag := binary.LittleEndian.Uint16(extra[0:2])
+		size := int(binary.LittleEndian.Uint16(extra[2:4]))
+		if len(extra) < 4+size {
+			return
+		}
+		field := extra[4 : 4+size]
+		extra = extra[4+size:]
+
+		switch tag {
+		case zipExtraExtTime:
+			if len(field) < 1 {
+				continue
+			}
+			flags := field[0]
+			field = field[1:]
+			if flags&1 != 0 && len(field) >= 4 {
+				meta.ModTime = time.Unix(int64(binary.LittleEndian.Uint32(field[0:4])), 0)
+				field = field[4:]
+			}
+			if flags&2 != 0 && len(field) >= 4 {
+				meta.AccessTime = time.Unix(int64(binary.LittleEndian.Uint32(field[0:4])), 0)
+			}
+		case zipExtraUnixOwner:
+			if len(field) < 2 || field[0] != 1 {
+				continue
+			}
+			uid, rest, ok := readZipOwnerID(field[1:])
+			if !ok {
+				continue
+			}
+			gid, _, ok := readZipOwnerID(rest)
+			if !ok {
+				continue
+			}
+			meta.Uid, meta.Gid = uid, gid
+		}
+	}
+}
+
+// readZipOwnerID 读取 0x7875 中 1 字节长度 + 小端 id
+func readZipOwnerID(field []byte) (int, []byte, bool) {
+	if len(field) < 1 {
+		return 0, nil, false
+	}
+	size := int(field[0])
+	if size == 0 || size > 8 || len(field) < 1+size {
+		return 0, nil, false
+	}
+	var id uint64
+	for i := size; i >= 1; i-- {
+		id = id<<8 | uint64(field[i])
+	}
+	if id > 1<<31-1 {
+		return 0, nil, false
+	}
+	return int(id), field[1+size:], true
+}
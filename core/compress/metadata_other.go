@@ -0,0 +1,36 @@
+//go:build !linux && !darwin
+
+// Package compress /core/compress/metadata_other.go
+package compress
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"time"
+)
+
+// ProcessUmask 当前平台没有 umask
+func ProcessUmask() os.FileMode {
+	return 0
+}
+
+// statOwnerTime 当前平台不读取属主与访问时间
+func statOwnerTime(string, os.FileInfo) (uid, gid int, atime time.Time, ok bool) {
+	return -1, -1, time.Time{}, false
+}
+
+// lchtimes 当前平台不支持修改符号链接本身的时间
+func lchtimes(string, time.Time, time.Time) error {
+	return nil
+}
+
+// readXattrs 当前平台不读取扩展属性
+func readXattrs(string) (map[string]string, error) {
+	return nil, nil
+}
+
+// writeXattrs 当前平台不支持扩展属性
+func writeXattrs(string, map[string]string) error {
+	return fmt.Errorf("%s 不支持扩展属性", runtime.GOOS)
+}
@@ -0,0 +1,64 @@
+package compress
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestZipMetadataExtraRoundTrip(t *testing.T) {
+	mtime := time.Unix(1714566896, 0)
+	atime := time.Unix(1714570000, 0)
+
+	meta := newEntryMetadata(0o644, time.Time{})
+	parseZipMetadataExtra(zipMetadataExtra(mtime, atime, 1000, 100000), &meta)
+	if !meta.ModTime.Equal(mtime) || !meta.AccessTime.Equal(atime) || meta.Uid != 1000 || meta.Gid != 100000 {
+		t.Fatalf("元数据 = %+v", meta)
+	}
+
+	// 只记录修改时间, 不写属主
+	meta = newEntryMetadata(0o644, time.Time{})
+	parseZipMetadataExtra(zipMetadataExtra(mtime, time.Time{}, -1, -1), &meta)
+	if !meta.ModTime.Equal(mtime) || !meta.AccessTime.IsZero() || meta.Uid != -1 || meta.Gid != -1 {
+		t.Fatalf("元数据 = %+v", meta)
+	}
+}
+
+func TestParseZipMetadataExtraMalformed(t *testing.T) {
+	for name, extra := range map[string][]byte{
+		"truncated-field":  {0x55, 0x54, 0x09, 0x00, 0x03, 0x01},
+		"empty-ext-time":   {0x55, 0x54, 0x00, 0x00},
+		"owner-version-2":  {0x75, 0x78, 0x03, 0x00, 0x02, 0x01, 0x01},
+		"owner-zero-size":  {0x75, 0x78, 0x04, 0x00, 0x01, 0x00, 0x01, 0x00},
+		"owner-huge-id":    {0x75, 0x78, 0x0B, 0x00, 0x01, 0x04, 0xFF, 0xFF, 0xFF, 0xFF, 0x04, 0x01, 0x00, 0x00, 0x00},
+		"short-ext-header": {0x55},
+	} {
+		meta := newEntryMetadata(0, time.Time{})
+		parseZipMetadataExtra(extra, &meta)
+		if !meta.ModTime.IsZero() || meta.Uid != -1 || meta.Gid != -1 {
+			t.Errorf("%s: 损坏的扩展字段不应改变元数据: %+v", name, meta)
+		}
+	}
+
+	// 未知字段跳过, 后面的字段照常读取
+	meta := newEntryMetadata(0, time.Time{})
+	extra := append([]byte{0x0A, 0x00, 0x02, 0x00, 0xAA, 0xBB}, zipMetadataExtra(time.Unix(100, 0), time.Time{}, 7, 8)...)
+	parseZipMetadataExtra(extra, &meta)
+	if meta.ModTime.Unix() != 100 || meta.Uid != 7 || meta.Gid != 8 {
+		t.Fatalf("元数据 = %+v", meta)
+	}
+}
+
+func TestXattrsFromPAX(t *testing.T) {
+	got := xattrsFromPAX(map[string]string{
+		"SCHILY.xattr.user.comment": "hello",
+		"SCHILY.xattr.":             "ignored",
+		"mtime":                     "1.5",
+	})
+	if !reflect.DeepEqual(got, map[string]string{"user.comment": "hello"}) {
+		t.Fatalf("xattrsFromPAX = %v", got)
+	}
+	if xattrsFromPAX(map[string]string{"mtime": "1"}) != nil {
+		t.Fatal("没有扩展属性时应返回 nil")
+	}
+}
@@ -0,0 +1,94 @@
+//go:build linux || darwin
+
+// Package compress /core/compress/metadata_unix.go
+package compress
+
+import (
+	"os"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// ProcessUmask 当前进程的 umask, --no-same-permissions 未指定 --umask 时使用
+func ProcessUmask() os.FileMode {
+	mask := syscall.Umask(0)
+	syscall.Umask(mask)
+	return os.FileMode(mask) & os.ModePerm
+}
+
+// statOwnerTime 读取源文件的属主与访问时间, info 为符号链接时不跟随
+func statOwnerTime(path string, info os.FileInfo) (uid, gid int, atime time.Time, ok bool) {
+	var stat unix.Stat_t
+	var err error
+	if info.Mode()&os.ModeSymlink != 0 {
+		err = unix.Lstat(path, &stat)
+	} else {
+		err = unix.Stat(path, &stat)
+	}
+	if err != nil {
+		return -1, -1, time.Time{}, false
+	}
+	return int(stat.Uid), int(stat.Gid), time.Unix(stat.Atim.Unix()), true
+}
+
+// lchtimes 修改符号链接本身的访问与修改时间
+func lchtimes(path string, atime, mtime time.Time) error {
+	ts := []unix.Timespec{unix.NsecToTimespec(atime.UnixNano()), unix.NsecToTimespec(mtime.UnixNano())}
+	return unix.UtimesNanoAt(unix.AT_FDCWD, path, ts, unix.AT_SYMLINK_NOFOLLOW)
+}
+
+// readXattrs 读取扩展属性, 不跟随符号链接, 文件系统不支持时返回 nil
+func readXattrs(path string) (map[string]string, error) {
+	size, err := unix.Llistxattr(path, nil)
+	if err != nil || size == 0 {
+		if err == unix.ENOTSUP || err == unix.EOPNOTSUPP {
+			return nil, nil
+		}
+		return nil, err
+	}
+	buf := make([]byte, size)
+	if size, err = unix.Llistxattr(path, buf); err != nil {
+		return nil, err
+	}
+
+	xattrs := make(map[string]string)
+	for _, name := range splitXattrNames(buf[:size]) {
+		valueSize, err := unix.Lgetxattr(path, name, nil)
+		if err != nil {
+			return nil, err
+		}
+		value := make([]byte, valueSize)
+		if valueSize, err = unix.Lgetxattr(path, name, value); err != nil {
+			return nil, err
+		}
+		xattrs[name] = string(value[:valueSize])
+	}
+	return xattrs, nil
+}
+
+// writeXattrs 写入扩展属性, 不跟随符号链接
+func writeXattrs(path string, xattrs map[string]string) error {
+	for name, value := range xattrs {
+		if err := unix.Lsetxattr(path, name, []byte(value), 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitXattrNames 拆分 listxattr 返回的 \0 分隔名称列表
+func splitXattrNames(buf []byte) []string {
+	var names []string
+	start := 0
+	for i, b := range buf {
+		if b == 0 {
+			if i > start {
+				names = append(names, string(buf[start:i]))
+			}
+			start = i + 1
+		}
+	}
+	return names
+}
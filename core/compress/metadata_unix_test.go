@@ -0,0 +1,177 @@
+//go:build linux || darwin
+
+package compress
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// writeMetadataTree 写出带特定权限与时间的源目录: dir/ (0750) 与 dir/run.sh (0777)、dir/link → run.sh
+func writeMetadataTree(t *testing.T, mtime time.Time) []SourceEntry {
+	t.Helper()
+	root := t.TempDir()
+	dir := filepath.Join(root, "dir")
+	file := filepath.Join(dir, "run.sh")
+	link := filepath.Join(dir, "link")
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, []byte("#!/bin/sh\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("run.sh", link); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(file, 0o777); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(dir, 0o750); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{file, dir} {
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := lchtimes(link, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	return []SourceEntry{
+		{Path: dir, Name: "dir", Dir: true},
+		{Path: link, Name: "dir/link", Mode: os.ModeSymlink},
+		{Path: file, Name: "dir/run.sh"},
+	}
+}
+
+func TestMetadataRestore(t *testing.T) {
+	mtime := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	for _, format := range []string{"tar", "zip", "7z"} {
+		t.Run(format, func(t *testing.T) {
+			entries := writeMetadataTree(t, mtime)
+			if format == "7z" {
+				entries = append(entries[:1], entries[2:]...) // 7z 不支持符号链接
+			}
+			archive := filepath.Join(t.TempDir(), "meta."+format)
+			if err := RunCompress(CompressOptions{Entries: entries, OutputPath: archive, Format: format, Level: -1}); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, umask := range []os.FileMode{0, 0o027} {
+				output := t.TempDir()
+				if err := RunDecompress(DecompressOptions{SourcePath: archive, OutputDir: output, Umask: umask}); err != nil {
+					t.Fatal(err)
+				}
+				// 目录的时间在写入内容后才还原, 权限按 umask 去掉部分位
+				for name, perm := range map[string]os.FileMode{"dir": 0o750, "dir/run.sh": 0o777} {
+					info, err := os.Stat(filepath.Join(output, filepath.FromSlash(name)))
+					if err != nil {
+						t.Fatal(err)
+					}
+					if want := perm &^ umask; info.Mode().Perm() != want {
+						t.Errorf("umask %03o: %s 权限 = %03o, 期望 %03o", umask, name, info.Mode().Perm(), want)
+					}
+					if !info.ModTime().Equal(mtime) {
+						t.Errorf("%s 修改时间 = %v, 期望 %v", name, info.ModTime(), mtime)
+					}
+				}
+				if format == "7z" {
+					continue
+				}
+				// 符号链接的时间作用于链接本身
+				info, err := os.Lstat(filepath.Join(output, "dir", "link"))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !info.ModTime().Equal(mtime) {
+					t.Errorf("符号链接修改时间 = %v, 期望 %v", info.ModTime(), mtime)
+				}
+			}
+		})
+	}
+}
+
+func TestMetadataTarNanoseconds(t *testing.T) {
+	mtime := time.Date(2021, 3, 4, 5, 6, 7, 123456789, time.UTC)
+	entries := writeMetadataTree(t, mtime)
+	archive := filepath.Join(t.TempDir(), "ns.tar")
+	if err := RunCompress(CompressOptions{Entries: entries, OutputPath: archive, Format: "tar", Level: -1}); err != nil {
+		t.Fatal(err)
+	}
+	output := t.TempDir()
+	if err := RunDecompress(DecompressOptions{SourcePath: archive, OutputDir: output}); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(filepath.Join(output, "dir", "run.sh"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.ModTime().Equal(mtime) {
+		t.Fatalf("PAX 修改时间 = %v, 期望 %v", info.ModTime(), mtime)
+	}
+}
+
+func TestMetadataTarXattrs(t *testing.T) {
+	entries := writeMetadataTree(t, time.Now())
+	file := entries[2].Path
+	if err := unix.Lsetxattr(file, "user.gf-test", []byte("value"), 0); err != nil {
+		t.Skip("文件系统不支持扩展属性:", err)
+	}
+	archive := filepath.Join(t.TempDir(), "xattr.tar")
+	if err := RunCompress(CompressOptions{Entries: entries, OutputPath: archive, Format: "tar", Level: -1}); err != nil {
+		t.Fatal(err)
+	}
+	output := t.TempDir()
+	if err := RunDecompress(DecompressOptions{SourcePath: archive, OutputDir: output}); err != nil {
+		t.Fatal(err)
+	}
+	xattrs, err := readXattrs(filepath.Join(output, "dir", "run.sh"))
+	if err != nil || xattrs["user.gf-test"] != "value" {
+		t.Fatalf("扩展属性 = %v, %v", xattrs, err)
+	}
+}
+
+func TestMetadataSameOwner(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("修改属主需要 root")
+	}
+	// 目标系统不存在的 uid/gid, 按数字还原
+	const uid, gid = 54321, 54322
+	for _, format := range []string{"tar", "zip"} {
+		t.Run(format, func(t *testing.T) {
+			entries := writeMetadataTree(t, time.Now())
+			for _, entry := range entries {
+				if err := os.Lchown(entry.Path, uid, gid); err != nil {
+					t.Fatal(err)
+				}
+			}
+			archive := filepath.Join(t.TempDir(), "owner."+format)
+			if err := RunCompress(CompressOptions{Entries: entries, OutputPath: archive, Format: format, Level: -1}); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, sameOwner := range []bool{false, true} {
+				output := t.TempDir()
+				if err := RunDecompress(DecompressOptions{SourcePath: archive, OutputDir: output, SameOwner: sameOwner}); err != nil {
+					t.Fatal(err)
+				}
+				for _, name := range []string{"dir", "dir/run.sh", "dir/link"} {
+					info, err := os.Lstat(filepath.Join(output, filepath.FromSlash(name)))
+					if err != nil {
+						t.Fatal(err)
+					}
+					stat := info.Sys().(*syscall.Stat_t)
+					restored := int(stat.Uid) == uid && int(stat.Gid) == gid
+					if restored != sameOwner {
+						t.Errorf("SameOwner=%t: %s 属主 = %d:%d", sameOwner, name, stat.Uid, stat.Gid)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,199 @@
+// Package compress /core/compress/overwrite.go
+package compress
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/GoFurry/gf-file-tool/utils/log"
+)
+
+// 输出路径已存在时的处理, 所有格式共用. 目录条目总是合并到已有目录中, 不算冲突.
+// 条目先写入暂存目录, 按输出目录中的同名路径判断冲突; 决定覆盖时在合并阶段改名替换已有的文件或链接:
+// 不会写穿已有的符号链接, 也不会改到与之共用 inode 的其他硬链接.
+
+// OverwritePolicy 输出路径已存在时的处理策略
+type OverwritePolicy string
+
+const (
+	OverwriteAlways OverwritePolicy = "always" // 覆盖 (默认)
+	OverwriteNever  OverwritePolicy = "never"  // 保留已有文件, 跳过条目
+	OverwriteNewer  OverwritePolicy = "newer"  // 条目的修改时间晚于已有文件时覆盖
+	OverwriteRename OverwritePolicy = "rename" // 另存为 name (1).ext
+	OverwriteAsk    OverwritePolicy = "ask"    // 逐个询问, 从标准输入读取回答
+)
+
+// ParseOverwritePolicy 解析 --overwrite, 空字符串表示默认 always
+func ParseOverwritePolicy(name string) (OverwritePolicy, error) {
+	switch policy := OverwritePolicy(strings.ToLower(strings.TrimSpace(name))); policy {
+	case "":
+		return OverwriteAlways, nil
+	case OverwriteAlways, OverwriteNever, OverwriteNewer, OverwriteRename, OverwriteAsk:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("不支持的覆盖策略: %s, 仅支持 always/never/newer/rename/ask", name)
+	}
+}
+
+// conflictResolver 处理已存在的输出路径并统计各类决定
+type conflictResolver struct {
+	policy      OverwritePolicy
+	staging     *stagingDir
+	renamed     map[string]string // 原暂存路径 → 重命名后的暂存路径, 供硬链接查找目标
+	keptPaths   map[string]bool   // 保留已有文件而未解压的暂存路径
+	overwritten int
+	kept        int
+	renames     int
+}
+
+// newConflictResolver 创建冲突处理, 按 staging 的输出目录判断冲突
+func newConflictResolver(policy OverwritePolicy, staging *stagingDir) *conflictResolver {
+	if policy == "" {
+		policy = OverwriteAlways
+	}
+	return &conflictResolver{policy: policy, staging: staging, renamed: make(map[string]string), keptPaths: make(map[string]bool)}
+}
+
+// resolve 返回实际写入的暂存路径, ok 为 false 表示保留已有文件并跳过条目
+// dir: 条目本身是目录, modTime: 条目的修改时间, 未记录时为零值
+func (r *conflictResolver) resolve(name, outputPath string, dir bool, modTime time.Time) (string, bool, error) {
+	if dir {
+		return outputPath, true, nil
+	}
+	// 压缩包内的同名条目以后出现的为准, 同名目录由各格式写入时报错
+	if info, err := os.Lstat(outputPath); err == nil && !info.IsDir() {
+		if err := os.Remove(outputPath); err != nil {
+			return "", false, fmt.Errorf("替换重复条目失败: %s, 错误: %v", outputPath, err)
+		}
+	}
+
+	targetPath := r.staging.targetPath(outputPath)
+	info, err := os.Lstat(targetPath)
+	if os.IsNotExist(err) {
+		return outputPath, true, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	// 同名目录无法替换
+	if info.IsDir() {
+		if r.policy == OverwriteNever {
+			return r.keep(outputPath, "已存在同名目录, 保留:", targetPath)
+		}
+		return "", false, fmt.Errorf("已存在同名目录, 无法替换: %s", targetPath)
+	}
+
+	policy := r.policy
+	if policy == OverwriteAsk {
+		if policy, err = r.ask(name); err != nil {
+			return "", false, err
+		}
+	}
+
+	switch policy {
+	case OverwriteNever:
+		return r.keep(outputPath, "已存在, 保留:", targetPath)
+	case OverwriteNewer:
+		// zip 与 MS-DOS 时间只精确到秒, 按秒比较
+		if modTime.IsZero() || !modTime.Truncate(time.Second).After(info.ModTime().Truncate(time.Second)) {
+			return r.keep(outputPath, "已存在且不旧于条目, 保留:", targetPath)
+		}
+	case OverwriteRename:
+		newPath, shown := uniquePath(outputPath, targetPath)
+		r.renamed[outputPath] = newPath
+		r.renames++
+		log.Info("已存在, 另存为:", shown)
+		return newPath, true, nil
+	}
+
+	// 合并时改名替换
+	r.overwritten++
+	log.Info("已存在, 覆盖:", targetPath)
+	return outputPath, true, nil
+}
+
+// keep 记录保留已有文件的决定
+func (r *conflictResolver) keep(outputPath, message, targetPath string) (string, bool, error) {
+	r.kept++
+	r.keptPaths[outputPath] = true
+	log.Info(message, targetPath)
+	return "", false, nil
+}
+
+// ask 询问单个冲突的处理方式, 选择全部覆盖/全部跳过后不再询问
+func (r *conflictResolver) ask(name string) (OverwritePolicy, error) {
+	for {
+		fmt.Fprintf(os.Stderr, "%s 已存在, 是否覆盖? [y]是 [n]否 [r]重命名 [A]全部覆盖 [N]全部跳过: ", name)
+		line, err := stdinReader().ReadString('\n')
+		if err != nil && line == "" {
+			return "", fmt.Errorf("读取回答失败: %v", err)
+		}
+		switch strings.TrimSpace(line) {
+		case "y", "Y":
+			return OverwriteAlways, nil
+		case "n":
+			return OverwriteNever, nil
+		case "r", "R":
+			return OverwriteRename, nil
+		case "A":
+			r.policy = OverwriteAlways
+			return OverwriteAlways, nil
+		case "N":
+			r.policy = OverwriteNever
+			return OverwriteNever, nil
+		}
+	}
+}
+
+// actual 返回路径重命名后的实际位置, 未重命名时原样返回
+func (r *conflictResolver) actual(outputPath string) string {
+	if newPath, ok := r.renamed[outputPath]; ok {
+		return newPath
+	}
+	return outputPath
+}
+
+// wasKept 路径是否因保留已有文件而未解压
+func (r *conflictResolver) wasKept(outputPath string) bool {
+	return r.keptPaths[outputPath]
+}
+
+// report 输出冲突处理汇总
+func (r *conflictResolver) report() {
+	if r == nil || r.overwritten+r.kept+r.renames == 0 {
+		return
+	}
+	log.Info(fmt.Sprintf("已存在的文件: 覆盖 %d 个, 保留 %d 个, 另存 %d 个", r.overwritten, r.kept, r.renames))
+}
+
+// uniquePath 返回暂存与输出目录中都不存在的 name (1).ext 形式路径, .tar.gz 等双扩展名整体保留
+func uniquePath(outputPath, targetPath string) (string, string) {
+	stem, ext := splitExt(filepath.Base(outputPath))
+	for i := 1; ; i++ {
+		base := fmt.Sprintf("%s (%d)%s", stem, i, ext)
+		candidate := filepath.Join(filepath.Dir(outputPath), base)
+		shown := filepath.Join(filepath.Dir(targetPath), base)
+		if _, err := os.Lstat(candidate); !os.IsNotExist(err) {
+			continue
+		}
+		if _, err := os.Lstat(shown); os.IsNotExist(err) {
+			return candidate, shown
+		}
+	}
+}
+
+// splitExt 拆分文件名与扩展名, 以 . 开头的隐藏文件不视为扩展名
+func splitExt(base string) (string, string) {
+	ext := filepath.Ext(base)
+	if ext == base {
+		return base, ""
+	}
+	stem := strings.TrimSuffix(base, ext)
+	if inner := filepath.Ext(stem); strings.EqualFold(inner, ".tar") && inner != stem {
+		return strings.TrimSuffix(stem, inner), inner + ext
+	}
+	return stem, ext
+}
@@ -0,0 +1,138 @@
+package compress
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseOverwritePolicy(t *testing.T) {
+	for input, want := range map[string]OverwritePolicy{"": OverwriteAlways, "never": OverwriteNever, " Newer": OverwriteNewer, "rename": OverwriteRename, "ASK": OverwriteAsk} {
+		if got, err := ParseOverwritePolicy(input); err != nil || got != want {
+			t.Errorf("ParseOverwritePolicy(%q) = %q, %v", input, got, err)
+		}
+	}
+	if _, err := ParseOverwritePolicy("skip"); err == nil {
+		t.Fatal("未知策略应报错")
+	}
+}
+
+func TestUniquePath(t *testing.T) {
+	// 暂存目录与输出目录中已存在的名字都要避开
+	staging, target := t.TempDir(), t.TempDir()
+	for dir, names := range map[string][]string{
+		staging: {"a (2).txt"},
+		target:  {"a.txt", "a (1).txt", "b.tar.gz", ".bashrc", "noext"},
+	} {
+		for _, name := range names {
+			if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	for name, want := range map[string]string{
+		"a.txt":    "a (3).txt",
+		"b.tar.gz": "b (1).tar.gz",
+		".bashrc":  ".bashrc (1)",
+		"noext":    "noext (1)",
+	} {
+		got, shown := uniquePath(filepath.Join(staging, name), filepath.Join(target, name))
+		if got != filepath.Join(staging, want) || shown != filepath.Join(target, want) {
+			t.Errorf("uniquePath(%s) = %s, %s, 期望 %s", name, got, shown, want)
+		}
+	}
+}
+
+// overwriteFixture 在输出目录中预先写入 a.txt, 修改时间为 existing
+func overwriteFixture(t *testing.T, existing time.Time) string {
+	t.Helper()
+	output := t.TempDir()
+	path := filepath.Join(output, "a.txt")
+	if err := os.WriteFile(path, []byte("old"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, existing, existing); err != nil {
+		t.Fatal(err)
+	}
+	return output
+}
+
+func TestOverwritePolicies(t *testing.T) {
+	files := map[string][]byte{"a.txt": []byte("new"), "dir/b.txt": []byte("b")}
+	past, future := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+	for _, format := range []string{"zip", "tar", "7z"} {
+		archive := writeTestArchive(t, files, CompressOptions{Format: format})
+		for _, tc := range []struct {
+			name     string
+			policy   OverwritePolicy
+			existing time.Time
+			want     map[string][]byte
+		}{
+			{"always", OverwriteAlways, future, map[string][]byte{"a.txt": []byte("new"), "dir/b.txt": []byte("b")}},
+			{"never", OverwriteNever, past, map[string][]byte{"a.txt": []byte("old"), "dir/b.txt": []byte("b")}},
+			{"newer-older-file", OverwriteNewer, past, map[string][]byte{"a.txt": []byte("new"), "dir/b.txt": []byte("b")}},
+			{"newer-newer-file", OverwriteNewer, future, map[string][]byte{"a.txt": []byte("old"), "dir/b.txt": []byte("b")}},
+			{"rename", OverwriteRename, past, map[string][]byte{"a.txt": []byte("old"), "a (1).txt": []byte("new"), "dir/b.txt": []byte("b")}},
+		} {
+			t.Run(format+"/"+tc.name, func(t *testing.T) {
+				output := overwriteFixture(t, tc.existing)
+				if err := RunDecompress(DecompressOptions{SourcePath: archive, OutputDir: output, Overwrite: tc.policy}); err != nil {
+					t.Fatal(err)
+				}
+				assertSameFiles(t, readOutputTree(t, output), tc.want)
+			})
+		}
+	}
+}
+
+func TestOverwriteAsk(t *testing.T) {
+	files := map[string][]byte{"a.txt": []byte("new"), "b.txt": []byte("new"), "c.txt": []byte("new")}
+	archive := writeTestArchive(t, files, CompressOptions{Format: "tar"})
+	output := t.TempDir()
+	for name := range files {
+		if err := os.WriteFile(filepath.Join(output, name), []byte("old"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// a.txt 先答无效内容再答否, b.txt 重命名, 其余全部覆盖
+	withStdio(t, []byte("x\nn\nr\nA\n"))
+	if err := RunDecompress(DecompressOptions{SourcePath: archive, OutputDir: output, Overwrite: OverwriteAsk}); err != nil {
+		t.Fatal(err)
+	}
+	assertSameFiles(t, readOutputTree(t, output), map[string][]byte{
+		"a.txt": []byte("old"), "b.txt": []byte("old"), "b (1).txt": []byte("new"), "c.txt": []byte("new"),
+	})
+
+	// 标准输入用于读取压缩包时无法询问
+	err := RunDecompress(DecompressOptions{SourcePath: StdioPath, OutputDir: output, Format: "tar", Overwrite: OverwriteAsk})
+	if err == nil {
+		t.Fatal("从标准输入解压时不能使用 ask")
+	}
+}
+
+func TestOverwriteKeepsHardlinkedFile(t *testing.T) {
+	archive := writeTestArchive(t, map[string][]byte{"a.txt": []byte("new")}, CompressOptions{Format: "zip"})
+	parent := t.TempDir()
+	shared := filepath.Join(parent, "shared.txt")
+	if err := os.WriteFile(shared, []byte("shared"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	output := filepath.Join(parent, "out")
+	if err := os.Mkdir(output, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Link(shared, filepath.Join(output, "a.txt")); err != nil {
+		t.Skip("文件系统不支持硬链接:", err)
+	}
+
+	// 覆盖时先删除再写入, 与之共用 inode 的文件保持不变
+	if err := RunDecompress(DecompressOptions{SourcePath: archive, OutputDir: output}); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(shared); string(data) != "shared" {
+		t.Fatalf("共用 inode 的文件被改写: %q", data)
+	}
+	assertSameFiles(t, readOutputTree(t, output), map[string][]byte{"a.txt": []byte("new")})
+}
@@ -0,0 +1,274 @@
+// Package compress /core/compress/parity.go
+package compress
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/GoFurry/gf-file-tool/progress"
+	"github.com/GoFurry/gf-file-tool/utils"
+	"github.com/GoFurry/gf-file-tool/utils/log"
+	"github.com/klauspost/reedsolomon"
+)
+
+// 恢复卷 (--parity N): K 个分卷视为 K 个等长的数据分片 (较短的最后一卷末尾补零), 按 Reed-Solomon 编码
+// 生成 N 个恢复卷 <压缩包>.p001/.p002..., 任意不超过 N 个分卷缺失或损坏时都能由其余分卷与恢复卷重建.
+// 编码对每个字节位置独立进行, 因此按固定大小的条带逐段读写, 内存占用与分卷大小无关.
+// 恢复卷的大小与校验值记录在分卷说明文件中; 修复出的分卷先写入临时文件, 校验通过后才替换原文件.
+
+const (
+	parityAlgorithm  = "reed-solomon" // GF(2^8), 与 klauspost/reedsolomon 默认的编码矩阵一致
+	parityStripeSize = 256 * 1024     // 每次读写的条带大小
+	maxParityShards  = 256            // GF(2^8) 下分卷数与恢复卷数之和的上限
+)
+
+// splitParity 说明文件中的恢复卷记录
+type splitParity struct {
+	Algorithm string        `json:"algorithm"`
+	ShardSize int64         `json:"shard_size"` // 分片大小, 较短的分卷末尾按零补齐到该大小
+	Volumes   []splitVolume `json:"volumes"`
+}
+
+// parityVolumePath 压缩包 base 第 n 个 (从 1 开始) 恢复卷的路径
+func parityVolumePath(base string, n int) string {
+	return fmt.Sprintf("%s.p%03d", base, n)
+}
+
+// parityPath 第 i 个 (从 0 开始) 恢复卷的路径
+func (m *splitManifest) parityPath(i int) string {
+	return filepath.Join(m.dir, m.Parity.Volumes[i].Name)
+}
+
+// newParityEncoder 创建编码器, 分卷数与恢复卷数之和不能超过 256
+func newParityEncoder(dataShards, parityShards int) (reedsolomon.Encoder, error) {
+	if dataShards+parityShards > maxParityShards {
+		return nil, fmt.Errorf("分卷数 %d 与恢复卷数 %d 之和超过 %d, 请增大分卷大小或减少恢复卷", dataShards, parityShards, maxParityShards)
+	}
+	return reedsolomon.New(dataShards, parityShards)
+}
+
+// writeParity 读取已写出的分卷, 生成 count 个恢复卷
+// volumes 为分卷记录, 第一卷最大, 其大小即分片大小
+func writeParity(base string, paths []string, volumes []splitVolume, count int) (*splitParity, error) {
+	encoder, err := newParityEncoder(len(volumes), count)
+	if err != nil {
+		return nil, err
+	}
+	parity := &splitParity{Algorithm: parityAlgorithm, ShardSize: volumes[0].Size}
+	log.Info("生成", count, "个恢复卷...")
+
+	sources := make([]*os.File, len(paths))
+	defer closeFiles(sources)
+	for i, path := range paths {
+		if sources[i], err = os.Open(path); err != nil {
+			return nil, fmt.Errorf("读取分卷 %s 失败: %v", path, err)
+		}
+	}
+	outputs := make([]*os.File, count)
+	defer closeFiles(outputs)
+	sums := make([]*checksum, count)
+	for j := range outputs {
+		path := parityVolumePath(base, j+1)
+		if outputs[j], err = os.Create(path); err != nil {
+			return nil, fmt.Errorf("创建恢复卷 %s 失败: %v", path, err)
+		}
+		sums[j] = newChecksum()
+	}
+
+	shards, buffers := stripeBuffers(len(volumes) + count)
+	stripes := int((parity.ShardSize + parityStripeSize - 1) / parityStripeSize)
+	batchBar := progress.NewBatchProgressBar(stripes)
+	defer progress.FinishProgress(batchBar)
+	for off := int64(0); off < parity.ShardSize; off += parityStripeSize {
+		progress.UpdateProgress(batchBar, 1)
+		n := min(parityStripeSize, parity.ShardSize-off)
+		for i, source := range sources {
+			if shards[i], err = readShard(source, buffers[i][:n], off, volumes[i].Size); err != nil {
+				return nil, fmt.Errorf("读取分卷 %s 失败: %v", paths[i], err)
+			}
+		}
+		for j := range outputs {
+			shards[len(sources)+j] = buffers[len(sources)+j][:n]
+		}
+		if err := encoder.Encode(shards); err != nil {
+			return nil, fmt.Errorf("生成恢复卷失败: %v", err)
+		}
+		for j, output := range outputs {
+			shard := shards[len(sources)+j]
+			if _, err := output.Write(shard); err != nil {
+				return nil, fmt.Errorf("写入恢复卷 %s 失败: %v", output.Name(), err)
+			}
+			sums[j].Write(shard)
+		}
+	}
+
+	for j, output := range outputs {
+		if err := output.Close(); err != nil {
+			return nil, fmt.Errorf("关闭恢复卷 %s 失败: %v", output.Name(), err)
+		}
+		outputs[j] = nil
+		parity.Volumes = append(parity.Volumes, splitVolume{
+			Name:   filepath.Base(parityVolumePath(base, j+1)),
+			Size:   sums[j].size,
+			SHA256: sums[j].sha256(),
+			CRC32:  sums[j].crc32(),
+		})
+		if utils.VerboseMode() {
+			log.Success("生成恢复卷:", parityVolumePath(base, j+1), sums[j].size, "字节")
+		}
+	}
+	return parity, nil
+}
+
+// repair 用恢复卷重建有问题的分卷, 重建后的分卷校验通过才替换原文件
+func (m *splitManifest) repair(problems []volumeProblem) error {
+	dataShards, parityShards := len(m.Volumes), len(m.Parity.Volumes)
+
+	// 恢复卷同样需要校验, 损坏的恢复卷不能参与重建
+	log.Info("校验", parityShards, "个恢复卷...")
+	available := 0
+	sources := make([]*os.File, dataShards+parityShards)
+	defer closeFiles(sources)
+	for j, volume := range m.Parity.Volumes {
+		path := m.parityPath(j)
+		if sum, err := checksumFile(path, nil); err != nil || sum.sha256() != volume.SHA256 || sum.size != volume.Size {
+			log.Warn("恢复卷缺失或损坏, 不参与修复:", path)
+			continue
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		sources[dataShards+j] = file
+		available++
+	}
+	if len(problems) > available {
+		return fmt.Errorf("%w, 可用的恢复卷只有 %d 个, 无法修复", volumeProblemsError(problems), available)
+	}
+
+	damaged := make(map[int]bool, len(problems))
+	for _, problem := range problems {
+		damaged[problem.Index] = true
+	}
+	for i := range m.Volumes {
+		if damaged[i] {
+			continue
+		}
+		file, err := os.Open(m.volumePath(i))
+		if err != nil {
+			return fmt.Errorf("读取分卷 %s 失败: %v", m.volumePath(i), err)
+		}
+		sources[i] = file
+	}
+
+	// 重建的分卷先写入临时文件
+	outputs := make(map[int]*os.File, len(problems))
+	defer func() {
+		for _, output := range outputs {
+			_ = output.Close()
+			_ = os.Remove(output.Name())
+		}
+	}()
+	for _, problem := range problems {
+		output, err := os.Create(problem.Path + ".repair")
+		if err != nil {
+			return fmt.Errorf("创建修复文件失败: %v", err)
+		}
+		outputs[problem.Index] = output
+	}
+
+	encoder, err := newParityEncoder(dataShards, parityShards)
+	if err != nil {
+		return err
+	}
+	log.Info("使用恢复卷修复", len(problems), "个分卷...")
+	shardSize := m.Parity.ShardSize
+	shards, buffers := stripeBuffers(dataShards + parityShards)
+	stripes := int((shardSize + parityStripeSize - 1) / parityStripeSize)
+	batchBar := progress.NewBatchProgressBar(stripes)
+	defer progress.FinishProgress(batchBar)
+	for off := int64(0); off < shardSize; off += parityStripeSize {
+		progress.UpdateProgress(batchBar, 1)
+		n := min(parityStripeSize, shardSize-off)
+		for i, source := range sources {
+			if source == nil {
+				shards[i] = buffers[i][:0] // 长度为 0 表示缺失, 重建时复用缓冲区
+				continue
+			}
+			size := shardSize
+			if i < dataShards {
+				size = m.Volumes[i].Size
+			}
+			if shards[i], err = readShard(source, buffers[i][:n], off, size); err != nil {
+				return fmt.Errorf("读取 %s 失败: %v", source.Name(), err)
+			}
+		}
+		if err := encoder.ReconstructData(shards); err != nil {
+			return fmt.Errorf("修复分卷失败: %v", err)
+		}
+		for i, output := range outputs {
+			// 去掉较短分卷末尾补的零
+			if end := min(n, m.Volumes[i].Size-off); end > 0 {
+				if _, err := output.Write(shards[i][:end]); err != nil {
+					return fmt.Errorf("写入修复文件失败: %v", err)
+				}
+			}
+		}
+	}
+
+	// 校验通过后替换原文件
+	for i, output := range outputs {
+		if err := output.Close(); err != nil {
+			return fmt.Errorf("写入修复文件失败: %v", err)
+		}
+		volume := m.Volumes[i]
+		sum, err := checksumFile(output.Name(), nil)
+		if err != nil {
+			return err
+		}
+		if sum.sha256() != volume.SHA256 || sum.size != volume.Size {
+			return fmt.Errorf("修复后的分卷校验失败: %s", m.volumePath(i))
+		}
+		if err := os.Rename(output.Name(), m.volumePath(i)); err != nil {
+			return fmt.Errorf("替换分卷 %s 失败: %v", m.volumePath(i), err)
+		}
+		delete(outputs, i)
+		log.Success("已修复分卷:", m.volumePath(i))
+	}
+	return nil
+}
+
+// stripeBuffers 分配分片切片与各分片的条带缓冲区
+func stripeBuffers(count int) ([][]byte, [][]byte) {
+	shards := make([][]byte, count)
+	buffers := make([][]byte, count)
+	for i := range buffers {
+		buffers[i] = make([]byte, parityStripeSize)
+	}
+	return shards, buffers
+}
+
+// readShard 读取分卷在 off 处的一段条带, 超出分卷大小 size 的部分补零
+func readShard(file *os.File, p []byte, off, size int64) ([]byte, error) {
+	n := max(min(int64(len(p)), size-off), 0)
+	if n > 0 {
+		if _, err := file.ReadAt(p[:n], off); err == io.EOF {
+			return nil, fmt.Errorf("文件被截断")
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	clear(p[n:])
+	return p, nil
+}
+
+// closeFiles 关闭尚未关闭的文件
+func closeFiles(files []*os.File) {
+	for _, file := range files {
+		if file != nil {
+			_ = file.Close()
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package compress
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// 分卷大于条带大小, 修复需要跨多个条带; 数据不是分卷大小的整数倍, 最后一卷较短
+const (
+	parityTestVolumeSize = parityStripeSize + 4096
+	parityTestDataSize   = 4*parityTestVolumeSize + 1234
+)
+
+// writeParityTestSet 写出带 parity 个恢复卷的分卷, 返回原始数据与第一卷路径
+func writeParityTestSet(t *testing.T, parity int) ([]byte, string) {
+	t.Helper()
+	data := make([]byte, parityTestDataSize)
+	rand.New(rand.NewSource(int64(parity))).Read(data)
+
+	base := filepath.Join(t.TempDir(), "data.tar")
+	volumes := createVolumes(&CompressOptions{OutputPath: base, Format: "tar", SplitSize: parityTestVolumeSize, Parity: parity})
+	if _, err := volumes.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := volumes.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if len(volumes.Paths()) != 5 {
+		t.Fatalf("分卷数量 = %d, 期望 5", len(volumes.Paths()))
+	}
+	return data, volumes.Paths()[0]
+}
+
+// readVolumes 按顺序拼接全部分卷
+func readVolumes(t *testing.T, first string) []byte {
+	t.Helper()
+	volumes, err := openVolumes(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer volumes.Close()
+	out := make([]byte, volumes.Size())
+	if _, err := volumes.ReadAt(out, 0); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+// damage 删除、改写或截断一个分卷/恢复卷
+func damage(t *testing.T, path, how string) {
+	t.Helper()
+	switch how {
+	case "delete":
+		if err := os.Remove(path); err != nil {
+			t.Fatal(err)
+		}
+	case "corrupt":
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		content[len(content)/2] ^= 0xFF
+		if err := os.WriteFile(path, content, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	case "truncate":
+		if err := os.Truncate(path, 100); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestParityRepair(t *testing.T) {
+	cases := []struct {
+		name    string
+		parity  int
+		volumes map[int]string // 分卷序号 (从 1 开始) → 损坏方式
+		parts   map[int]string // 恢复卷序号 (从 1 开始) → 损坏方式
+	}{
+		{
+			name:    "N 个分卷",
+			parity:  2,
+			volumes: map[int]string{1: "delete", 3: "corrupt"},
+		},
+		{
+			name:    "N 个分卷 (含最后一卷) 与一个恢复卷",
+			parity:  3,
+			volumes: map[int]string{2: "delete", 5: "truncate"},
+			parts:   map[int]string{2: "corrupt"},
+		},
+		{
+			name:    "单个恢复卷",
+			parity:  1,
+			volumes: map[int]string{4: "corrupt"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			data, first := writeParityTestSet(t, c.parity)
+			base := first[:len(first)-len(".001")]
+			for n, how := range c.volumes {
+				damage(t, splitVolumePath(base, "", n), how)
+			}
+			for n, how := range c.parts {
+				damage(t, parityVolumePath(base, n), how)
+			}
+
+			if err := verifySplitVolumes(first, false); err == nil {
+				t.Fatal("损坏的分卷在不修复时应校验失败")
+			}
+			if err := verifySplitVolumes(first, true); err != nil {
+				t.Fatalf("修复失败: %v", err)
+			}
+			if err := verifySplitVolumes(first, false); err != nil {
+				t.Fatalf("修复后校验失败: %v", err)
+			}
+			if got := readVolumes(t, first); !bytes.Equal(got, data) {
+				t.Fatal("修复后的分卷与原始数据不一致")
+			}
+			if leftovers, _ := filepath.Glob(base + ".*.repair"); len(leftovers) > 0 {
+				t.Fatalf("残留修复临时文件: %v", leftovers)
+			}
+		})
+	}
+}
+
+func TestParityRepairTooManyDamaged(t *testing.T) {
+	_, first := writeParityTestSet(t, 2)
+	base := first[:len(first)-len(".001")]
+	// 两个恢复卷中一个也已损坏, 只剩一个可用, 不足以修复两个分卷
+	damage(t, splitVolumePath(base, "", 1), "delete")
+	damage(t, splitVolumePath(base, "", 4), "corrupt")
+	damage(t, parityVolumePath(base, 1), "corrupt")
+	corrupted, err := os.ReadFile(splitVolumePath(base, "", 4))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = verifySplitVolumes(first, true)
+	var damageErr *volumeDamageError
+	if !errors.As(err, &damageErr) {
+		t.Fatalf("应返回 volumeDamageError, 实际: %v", err)
+	}
+	if len(damageErr.problems) != 2 {
+		t.Fatalf("问题分卷数量 = %d, 期望 2", len(damageErr.problems))
+	}
+
+	// 修复失败时不改动现有分卷, 也不留下临时文件
+	if _, err := os.Stat(splitVolumePath(base, "", 1)); !os.IsNotExist(err) {
+		t.Fatalf("缺失的分卷不应被创建: %v", err)
+	}
+	if after, _ := os.ReadFile(splitVolumePath(base, "", 4)); !bytes.Equal(after, corrupted) {
+		t.Fatal("修复失败时改动了损坏的分卷")
+	}
+	if leftovers, _ := filepath.Glob(base + ".*.repair"); len(leftovers) > 0 {
+		t.Fatalf("残留修复临时文件: %v", leftovers)
+	}
+}
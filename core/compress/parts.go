@@ -0,0 +1,531 @@
+// Package compress /core/compress/parts.go
+package compress
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/GoFurry/gf-file-tool/utils"
+	"github.com/GoFurry/gf-file-tool/utils/compress"
+	"github.com/GoFurry/gf-file-tool/utils/log"
+)
+
+// 按条目分卷 (--split-mode entries): 条目按顺序分配到若干个完整的压缩包 name.part01.zip/name.part02.zip...,
+// 每个分包都能单独解压. 压缩后的大小事先无法知道, 因此按未压缩大小加上格式开销的上限规划,
+// 保证每个分包都不超过分卷大小; 可压缩的数据得到的分包会明显小于分卷大小.
+// 超过一个分包容量的文件切成若干段, 条目名带续段标记 name.gf-chunk-002-of-005,
+// 单独解压时得到各段文件 (可用 cat 按文件名顺序拼接), decompress 解压全部分包后自动拼接为原文件.
+// 全部分包写完后写出分包说明文件 <压缩包>.parts (JSON), 列出各分包的文件名与大小. 解压时只有说明文件中列出的
+// 分包才按分包处理, 碰巧名为 report.part01.zip 的普通压缩包仍单独解压; 缺少分包或大小不符时报错, 不解压其余分包.
+
+// 分卷方式
+const (
+	SplitModeBytes   = "bytes"   // 把一个压缩包按字节切开 (默认)
+	SplitModeEntries = "entries" // 按条目分为多个完整的压缩包
+)
+
+const (
+	minPartSize  = 1 << 20   // 按条目分卷时分卷大小的下限
+	partReserve  = 64 * 1024 // 每个分包的格式开销: 压缩包头尾、外层压缩的帧与块头、7z 头部
+	entryReserve = 1024      // 每个条目的开销: tar 头与块对齐, zip 本地头/中央目录/数据描述符/AES 头, 另计条目名
+	minChunkSize = 64 * 1024 // 分包剩余空间放不下这么大的一段时从下一个分包开始
+	chunkMarker  = ".gf-chunk-"
+)
+
+var (
+	partPattern  = regexp.MustCompile(`^(.+)\.part(\d{2,})((?:\.[^.]+)*)$`)
+	chunkPattern = regexp.MustCompile(`^(.+)\.gf-chunk-(\d+)-of-(\d+)$`)
+)
+
+// partsManifestVersion 当前分包说明文件版本, 读取时拒绝更高的版本
+const partsManifestVersion = 1
+
+// partsManifest 分包说明文件
+type partsManifest struct {
+	Version int          `json:"version"`
+	Archive string       `json:"archive"` // 压缩包文件名 (不含 .partNN)
+	Format  string       `json:"format"`
+	Parts   []partRecord `json:"parts"` // 按顺序排列的分包
+
+	dir string // 说明文件所在目录, 分包与其在同一目录
+}
+
+// partRecord 单个分包的记录
+type partRecord struct {
+	Name string `json:"name"` // 分包文件名
+	Size int64  `json:"size"`
+}
+
+// entryChunk 大文件中的一段
+type entryChunk struct {
+	offset int64
+	size   int64
+}
+
+// ParseSplitMode 解析 --split-mode, 空字符串表示默认 bytes
+func ParseSplitMode(name string) (string, error) {
+	switch name {
+	case "", SplitModeBytes:
+		return SplitModeBytes, nil
+	case SplitModeEntries:
+		return SplitModeEntries, nil
+	default:
+		return "", fmt.Errorf("不支持的分卷方式: %s, 仅支持 bytes/entries", name)
+	}
+}
+
+// partPath 压缩包 output 的第 n 个分包路径, 编号补零到 width 位, .tar.gz 等双扩展名整体保留
+func partPath(output string, n, width int) string {
+	stem, ext := splitExt(filepath.Base(output))
+	return filepath.Join(filepath.Dir(output), partName(stem, ext, n, width))
+}
+
+// partName 第 n 个分包的文件名
+func partName(stem, ext string, n, width int) string {
+	return fmt.Sprintf("%s.part%0*d%s", stem, width, n, ext)
+}
+
+// partWidth 分包编号的位数, 至少 2 位
+func partWidth(count int) int {
+	return max(2, len(strconv.Itoa(count)))
+}
+
+// partsManifestPath 压缩包 base 的分包说明文件路径
+func partsManifestPath(base string) string {
+	return base + ".parts"
+}
+
+// readPartsManifest 读取压缩包 base 的分包说明文件, 不存在时返回 nil
+func readPartsManifest(base string) (*partsManifest, error) {
+	path := partsManifestPath(base)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取分包说明文件失败: %v", err)
+	}
+	var manifest partsManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("无法解析分包说明文件 %s: %v", path, err)
+	}
+	if manifest.Version < 1 || manifest.Version > partsManifestVersion {
+		return nil, fmt.Errorf("不支持的分包说明文件版本: %d, 仅支持 %d", manifest.Version, partsManifestVersion)
+	}
+	if len(manifest.Parts) == 0 {
+		return nil, fmt.Errorf("分包说明文件中没有分包: %s", path)
+	}
+	for _, part := range manifest.Parts {
+		// 分包名只能是同一目录下的文件名
+		if part.Name == "" || filepath.Base(part.Name) != part.Name || strings.ContainsAny(part.Name, `/\`) {
+			return nil, fmt.Errorf("分包说明文件中的分包名无效: %q", part.Name)
+		}
+	}
+	manifest.dir = filepath.Dir(path)
+	return &manifest, nil
+}
+
+// write 写出说明文件, 先写临时文件再改名, 中断时不会留下不完整的说明文件
+func (m *partsManifest) write(base string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := partsManifestPath(base)
+	tempPath := path + ".tmp"
+	if err := os.WriteFile(tempPath, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("写入分包说明文件失败: %v", err)
+	}
+	if err := os.Rename(tempPath, path); err != nil {
+		_ = os.Remove(tempPath)
+		return fmt.Errorf("写入分包说明文件失败: %v", err)
+	}
+	return nil
+}
+
+// lists 说明文件是否列出了文件名为 name 的分包
+func (m *partsManifest) lists(name string) bool {
+	for _, part := range m.Parts {
+		if part.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// paths 核对全部分包存在且大小一致, 返回按顺序排列的分包路径, 有问题时列出全部问题分包
+func (m *partsManifest) paths() ([]string, error) {
+	paths := make([]string, len(m.Parts))
+	var problems []string
+	for i, part := range m.Parts {
+		paths[i] = filepath.Join(m.dir, part.Name)
+		info, err := os.Stat(paths[i])
+		switch {
+		case os.IsNotExist(err):
+			problems = append(problems, paths[i]+" (缺失)")
+		case err != nil:
+			problems = append(problems, paths[i]+" ("+err.Error()+")")
+		case info.Size() != part.Size:
+			problems = append(problems, fmt.Sprintf("%s (大小不符: %d 字节, 应为 %d 字节)", paths[i], info.Size(), part.Size))
+		}
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("共 %d 个分包, 其中 %d 个缺失或大小不符: %s", len(m.Parts), len(problems), strings.Join(problems, ", "))
+	}
+	return paths, nil
+}
+
+// partBase 分包路径去掉 .partNN 后的压缩包路径, 文件名不是 .partNN 形式时为空
+func partBase(path string) string {
+	m := partPattern.FindStringSubmatch(filepath.Base(path))
+	if m == nil {
+		return ""
+	}
+	return filepath.Join(filepath.Dir(path), m[1]+m[3])
+}
+
+// partSet 分包所属压缩包的分包说明文件, 不是说明文件中列出的分包时返回 nil
+func partSet(path string) (*partsManifest, error) {
+	base := partBase(path)
+	if base == "" || IsStdio(path) {
+		return nil, nil
+	}
+	manifest, err := readPartsManifest(base)
+	if err != nil || manifest == nil || !manifest.lists(filepath.Base(path)) {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// PartBasePath 分包路径去掉 .partNN 后的压缩包路径, 不是分包说明文件中列出的分包时原样返回
+func PartBasePath(path string) string {
+	if manifest, _ := partSet(path); manifest != nil {
+		return partBase(path)
+	}
+	return path
+}
+
+// partSources 分包所属的全部分包路径, 不是分包时返回自身
+// 按分包说明文件确定分包, 缺少分包或大小不符时返回错误
+func partSources(path string) ([]string, bool, error) {
+	manifest, err := partSet(path)
+	if err != nil {
+		return nil, false, err
+	}
+	if manifest == nil {
+		return []string{path}, false, nil
+	}
+	paths, err := manifest.paths()
+	return paths, true, err
+}
+
+// OutputParts 压缩输出 output 已写出的分包, 用于失败时清理
+// 失败时可能还没有写出说明文件, 按编号查找, 遇到第一个不存在的编号为止
+func OutputParts(output string) []string {
+	for width := 2; width <= 4; width++ {
+		var paths []string
+		for n := 1; compress.CheckPathExist(partPath(output, n, width)); n++ {
+			paths = append(paths, partPath(output, n, width))
+		}
+		if len(paths) > 0 {
+			return paths
+		}
+	}
+	return nil
+}
+
+// ============================== 压缩 ==============================
+
+// compressParts 按规划逐个压缩分包并写出分包说明文件, 最后删除之前同名压缩包留下的多余分包
+func compressParts(compressor Compressor, opts *CompressOptions) error {
+	parts, err := planParts(opts.Entries, opts.SplitSize)
+	if err != nil {
+		return err
+	}
+	// 先删除旧的说明文件, 中途失败时不会把新旧混杂的分包当成一组
+	if err := os.Remove(partsManifestPath(opts.OutputPath)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("删除旧的分包说明文件失败: %v", err)
+	}
+	width := partWidth(len(parts))
+	manifest := &partsManifest{Version: partsManifestVersion, Archive: filepath.Base(opts.OutputPath), Format: opts.Format}
+	log.Info("按条目分为", len(parts), "个分包")
+	for i, entries := range parts {
+		part := *opts
+		part.Entries = entries
+		part.OutputPath = partPath(opts.OutputPath, i+1, width)
+		part.SplitSize = 0
+		if err := compressor.Compress(&part); err != nil {
+			return fmt.Errorf("%s: %v", part.OutputPath, err)
+		}
+		opts.parts = append(opts.parts, part.OutputPath)
+
+		info, err := os.Stat(part.OutputPath)
+		if err != nil {
+			return err
+		}
+		manifest.Parts = append(manifest.Parts, partRecord{Name: filepath.Base(part.OutputPath), Size: info.Size()})
+		if info.Size() > opts.SplitSize {
+			log.Warn("分包超出分卷大小:", part.OutputPath, info.Size(), "字节")
+		} else if utils.VerboseMode() {
+			log.Success("生成分包:", part.OutputPath, info.Size(), "字节")
+		}
+	}
+	if err := removeNumbered(len(parts)+1, func(n int) string { return partPath(opts.OutputPath, n, width) }, "分包"); err != nil {
+		return err
+	}
+	return manifest.write(opts.OutputPath)
+}
+
+// planParts 按顺序把条目分配到各分包, 超过一个分包容量的文件切成若干段, limit 不小于 minPartSize (由 RunCompress 校验)
+func planParts(entries []SourceEntry, limit int64) ([][]SourceEntry, error) {
+	capacity := limit - partReserve
+
+	var parts [][]SourceEntry
+	var current []SourceEntry
+	used := int64(0)
+	add := func(entry SourceEntry, cost int64) {
+		if used+cost > capacity && len(current) > 0 {
+			parts = append(parts, current)
+			current, used = nil, 0
+		}
+		current = append(current, entry)
+		used += cost
+	}
+
+	for _, entry := range entries {
+		size, err := plannedSize(entry)
+		if err != nil {
+			return nil, err
+		}
+		if cost := entryCost(len(entry.Name), size); cost <= capacity {
+			add(entry, cost)
+			continue
+		}
+
+		// 第一段先用掉当前分包的剩余空间, 之后每段占满一个分包
+		var chunks []entryChunk
+		avail := capacity - used
+		for offset := int64(0); offset < size; avail = capacity {
+			n := min(chunkPayload(len(entry.Name), avail), size-offset)
+			if n < min(minChunkSize, size-offset) {
+				continue
+			}
+			chunks = append(chunks, entryChunk{offset: offset, size: n})
+			offset += n
+		}
+		for i := range chunks {
+			piece := entry
+			piece.Name = chunkName(entry.Name, i+1, len(chunks))
+			piece.chunk = &chunks[i]
+			add(piece, entryCost(len(piece.Name), chunks[i].size))
+		}
+	}
+	return append(parts, current), nil
+}
+
+// plannedSize 条目写入压缩包的数据大小: 普通文件为文件大小, 符号链接为链接目标长度, 其余为 0
+func plannedSize(entry SourceEntry) (int64, error) {
+	if entry.Dir {
+		return 0, nil
+	}
+	info, err := statEntry(entry)
+	if err != nil {
+		return 0, fmt.Errorf("获取文件大小失败：%s，错误：%v", entry.Path, err)
+	}
+	if info.Mode().IsRegular() || info.Mode()&os.ModeSymlink != 0 {
+		return info.Size(), nil
+	}
+	return 0, nil
+}
+
+// entryCost 条目在分包中占用大小的上限: 不可压缩的数据经各压缩算法后略有膨胀 (lz4 约 0.4%), 按 1/64 预留
+func entryCost(nameLen int, size int64) int64 {
+	return entryReserve + 4*int64(nameLen) + size + size/64
+}
+
+// chunkPayload avail 字节的空间能放下的一段数据大小, 续段标记按最长 32 字节预留
+func chunkPayload(nameLen int, avail int64) int64 {
+	return max(avail-entryReserve-4*int64(nameLen+32), 0) * 64 / 65
+}
+
+// chunkName 第 index 段 (从 1 开始, 共 total 段) 的条目名, 编号至少 3 位, 按文件名排序即为拼接顺序
+func chunkName(name string, index, total int) string {
+	width := max(3, len(strconv.Itoa(total)))
+	return fmt.Sprintf("%s%s%0*d-of-%0*d", name, chunkMarker, width, index, width, total)
+}
+
+// parseChunkName 解析续段标记, 返回原文件名与段号
+func parseChunkName(name string) (string, int, int, bool) {
+	m := chunkPattern.FindStringSubmatch(name)
+	if m == nil {
+		return "", 0, 0, false
+	}
+	index, _ := strconv.Atoi(m[2])
+	total, _ := strconv.Atoi(m[3])
+	if index < 1 || index > total {
+		return "", 0, 0, false
+	}
+	return m[1], index, total, true
+}
+
+// chunkInfo 大文件中一段的文件信息, 大小为该段大小
+type chunkInfo struct {
+	os.FileInfo
+	size int64
+}
+
+// Size 该段大小
+func (i chunkInfo) Size() int64 {
+	return i.size
+}
+
+// openChunkSource 打开大文件中的一段
+func openChunkSource(entry SourceEntry) (io.ReadCloser, os.FileInfo, error) {
+	file, info, err := openSource(entry.Path)
+	if err != nil {
+		return nil, nil, err
+	}
+	chunk := entry.chunk
+	if chunk.offset+chunk.size > info.Size() {
+		_ = file.Close()
+		return nil, nil, fmt.Errorf("文件在压缩过程中变小了")
+	}
+	section := io.NewSectionReader(file.(*os.File), chunk.offset, chunk.size)
+	return struct {
+		io.Reader
+		io.Closer
+	}{section, file}, chunkInfo{FileInfo: info, size: chunk.size}, nil
+}
+
+// ============================== 解压 ==============================
+
+// joinChunks 把暂存目录中大文件的各段按顺序拼接为原文件
+// 缺少某些段时报错, 解压失败时暂存目录被删除, 不会留下各段文件
+func joinChunks(opts DecompressOptions) error {
+	root := opts.OutputDir
+	pieces := make(map[string][]string) // 原文件的暂存路径 → 按段号排列的各段路径
+	err := filepath.WalkDir(root, func(path string, entry os.DirEntry, err error) error {
+		if err != nil || !entry.Type().IsRegular() {
+			return err
+		}
+		base, index, total, ok := parseChunkName(path)
+		if !ok {
+			return nil
+		}
+		if pieces[base] == nil {
+			pieces[base] = make([]string, total)
+		}
+		if len(pieces[base]) != total {
+			return fmt.Errorf("分段数不一致: %s", path)
+		}
+		pieces[base][index-1] = path
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("查找分段文件失败: %v", err)
+	}
+
+	bases := make([]string, 0, len(pieces))
+	for base := range pieces {
+		bases = append(bases, base)
+	}
+	sort.Strings(bases)
+	for _, base := range bases {
+		name := base
+		if rel, err := filepath.Rel(root, base); err == nil {
+			name = filepath.ToSlash(rel)
+		}
+		if missing := missingChunks(pieces[base]); missing != "" {
+			return fmt.Errorf("缺少分段: %s (缺少第 %s 段, 共 %d 段)", name, missing, len(pieces[base]))
+		}
+		if err := joinChunk(opts, name, base, pieces[base]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// missingChunks 缺少的段号, 不缺少时为空
+func missingChunks(paths []string) string {
+	missing := ""
+	for i, path := range paths {
+		if path != "" {
+			continue
+		}
+		if missing != "" {
+			missing += ", "
+		}
+		missing += strconv.Itoa(i + 1)
+	}
+	return missing
+}
+
+// joinChunk 第一段改名为原文件后依次追加其余各段, 权限与修改时间取第一段 (即原文件) 的记录
+func joinChunk(opts DecompressOptions, name, base string, paths []string) error {
+	info, err := os.Stat(paths[0])
+	if err != nil {
+		return err
+	}
+	target, ok, err := opts.conflicts.resolve(name, base, false, info.ModTime())
+	if err != nil {
+		return err
+	}
+	if !ok {
+		for _, path := range paths {
+			_ = os.Remove(path)
+		}
+		return nil
+	}
+
+	if err := os.Rename(paths[0], target); err != nil {
+		return fmt.Errorf("拼接分段文件失败: %s, 错误: %v", name, err)
+	}
+	if err := os.Chmod(target, info.Mode().Perm()|0200); err != nil {
+		return fmt.Errorf("拼接分段文件失败: %s, 错误: %v", name, err)
+	}
+	out, err := os.OpenFile(target, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		return fmt.Errorf("拼接分段文件失败: %s, 错误: %v", name, err)
+	}
+	for _, path := range paths[1:] {
+		if err := appendFile(out, path); err != nil {
+			_ = out.Close()
+			return fmt.Errorf("拼接分段文件失败: %s, 错误: %v", name, err)
+		}
+	}
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("拼接分段文件失败: %s, 错误: %v", name, err)
+	}
+	if err := os.Chmod(target, info.Mode()); err != nil {
+		return err
+	}
+	if err := os.Chtimes(target, time.Time{}, info.ModTime()); err != nil {
+		return err
+	}
+	if utils.VerboseMode() {
+		log.Success("已拼接分段文件:", name, "共", len(paths), "段")
+	}
+	return nil
+}
+
+// appendFile 把 path 的内容追加到 out 后删除 path
+func appendFile(out *os.File, path string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	_ = in.Close()
+	if err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
@@ -0,0 +1,263 @@
+package compress
+
+import (
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// partsTreeFiles 随机数据的源文件, big.bin 超过一个分包的容量, 会被切成若干段
+func partsTreeFiles() map[string][]byte {
+	rnd := rand.New(rand.NewSource(25))
+	files := map[string][]byte{"parts/small.txt": []byte("small file")}
+	for name, size := range map[string]int{"parts/a.bin": 600 << 10, "parts/b.bin": 600 << 10, "parts/big.bin": 2500 << 10} {
+		data := make([]byte, size)
+		rnd.Read(data)
+		files[name] = data
+	}
+	return files
+}
+
+// writeEntriesParts 按条目分卷压缩 files, 返回各分包路径
+func writeEntriesParts(t *testing.T, format string, files map[string][]byte) []string {
+	t.Helper()
+	output := filepath.Join(t.TempDir(), "data."+format)
+	writeTestArchive(t, files, CompressOptions{Format: format, OutputPath: output, SplitSize: minPartSize, SplitMode: SplitModeEntries})
+	parts := OutputParts(output)
+	if len(parts) < 3 {
+		t.Fatalf("分包 = %v, 期望至少 3 个", parts)
+	}
+	return parts
+}
+
+func TestParseSplitMode(t *testing.T) {
+	for input, want := range map[string]string{"": SplitModeBytes, "bytes": SplitModeBytes, "entries": SplitModeEntries} {
+		if got, err := ParseSplitMode(input); err != nil || got != want {
+			t.Errorf("ParseSplitMode(%q) = %q, %v", input, got, err)
+		}
+	}
+	if _, err := ParseSplitMode("files"); err == nil {
+		t.Error("应拒绝未知的分卷方式")
+	}
+}
+
+func TestPartNames(t *testing.T) {
+	dir := filepath.Join("out", "dir")
+	for _, c := range []struct {
+		output string
+		n      int
+		width  int
+		want   string
+	}{
+		{"data.zip", 1, 2, "data.part01.zip"},
+		{"data.tar.gz", 12, 3, "data.part012.tar.gz"},
+		{"data.tar.zst", 3, 2, "data.part03.tar.zst"},
+	} {
+		got := partPath(filepath.Join(dir, c.output), c.n, c.width)
+		if got != filepath.Join(dir, c.want) {
+			t.Errorf("partPath(%s, %d) = %s, 期望 %s", c.output, c.n, got, c.want)
+		}
+		if base := partBase(got); base != filepath.Join(dir, c.output) {
+			t.Errorf("partBase(%s) = %s", got, base)
+		}
+	}
+	if partWidth(9) != 2 || partWidth(100) != 3 {
+		t.Error("分包编号至少 2 位, 按分包数补足位数")
+	}
+
+	name := chunkName("dir/big.bin", 2, 5)
+	if name != "dir/big.bin.gf-chunk-002-of-005" {
+		t.Fatalf("chunkName = %s", name)
+	}
+	if base, index, total, ok := parseChunkName(name); !ok || base != "dir/big.bin" || index != 2 || total != 5 {
+		t.Fatalf("parseChunkName = %s, %d, %d, %t", base, index, total, ok)
+	}
+	for _, bad := range []string{"big.bin", "big.bin.gf-chunk-006-of-005", "big.bin.gf-chunk-000-of-005"} {
+		if _, _, _, ok := parseChunkName(bad); ok {
+			t.Errorf("parseChunkName(%s) 不应识别为分段", bad)
+		}
+	}
+}
+
+func TestPlanParts(t *testing.T) {
+	files := partsTreeFiles()
+	parts, err := planParts(writeTestTree(t, files), minPartSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunked := int64(0)
+	for i, part := range parts {
+		used := int64(0)
+		for _, entry := range part {
+			size, err := plannedSize(entry)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if entry.chunk != nil {
+				size = entry.chunk.size
+				chunked += size
+			}
+			used += entryCost(len(entry.Name), size)
+		}
+		if used > minPartSize-partReserve {
+			t.Errorf("分包 %d 规划了 %d 字节, 超出容量", i+1, used)
+		}
+	}
+	if chunked != int64(len(files["parts/big.bin"])) {
+		t.Fatalf("各段合计 %d 字节, 期望 %d", chunked, len(files["parts/big.bin"]))
+	}
+}
+
+func TestEntriesPartsRoundTrip(t *testing.T) {
+	files := partsTreeFiles()
+	for _, format := range []string{"zip", "tar.gz", "7z"} {
+		t.Run(format, func(t *testing.T) {
+			parts := writeEntriesParts(t, format, files)
+
+			// 每个分包不超过分卷大小, 单独拿出来也能解压; 大文件的各段按文件名顺序拼接即为原文件
+			alone := map[string][]byte{}
+			for _, part := range parts {
+				data, err := os.ReadFile(part)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(data) > minPartSize {
+					t.Errorf("%s 大小 %d 超出分卷大小", part, len(data))
+				}
+				source := filepath.Join(t.TempDir(), "alone."+format)
+				if err := os.WriteFile(source, data, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				output := t.TempDir()
+				if err := RunDecompress(DecompressOptions{SourcePath: source, OutputDir: output}); err != nil {
+					t.Fatalf("单独解压 %s: %v", part, err)
+				}
+				for name, data := range readOutputTree(t, output) {
+					if base, _, _, ok := parseChunkName(name); ok {
+						name = base
+					}
+					alone[name] = append(alone[name], data...)
+				}
+			}
+			assertSameFiles(t, alone, files)
+
+			// 从任意一个分包开始都解压全部分包, 并拼接大文件的各段
+			output := filepath.Join(t.TempDir(), "out")
+			if err := RunDecompress(DecompressOptions{SourcePath: parts[len(parts)-1], OutputDir: output}); err != nil {
+				t.Fatal(err)
+			}
+			assertSameFiles(t, readOutputTree(t, output), files)
+		})
+	}
+}
+
+func TestEntriesPartsManifest(t *testing.T) {
+	parts := writeEntriesParts(t, "zip", partsTreeFiles())
+	base := PartBasePath(parts[1])
+	if base != strings.TrimSuffix(parts[0], ".part01.zip")+".zip" {
+		t.Fatalf("PartBasePath = %s", base)
+	}
+	manifest, err := readPartsManifest(base)
+	if err != nil || manifest == nil {
+		t.Fatalf("readPartsManifest = %v, %v", manifest, err)
+	}
+	if manifest.Archive != filepath.Base(base) || manifest.Format != "zip" || len(manifest.Parts) != len(parts) {
+		t.Fatalf("说明文件 = %+v", manifest)
+	}
+	for i, part := range manifest.Parts {
+		info, err := os.Stat(parts[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if part.Name != filepath.Base(parts[i]) || part.Size != info.Size() {
+			t.Errorf("分包 %d = %+v", i+1, part)
+		}
+	}
+}
+
+func TestEntriesPartsMissingPart(t *testing.T) {
+	parts := writeEntriesParts(t, "zip", partsTreeFiles())
+	if err := os.Remove(parts[1]); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Truncate(parts[2], 100); err != nil {
+		t.Fatal(err)
+	}
+
+	// 缺少或截断的分包全部列出, 其余分包也不解压
+	output := filepath.Join(t.TempDir(), "out")
+	err := RunDecompress(DecompressOptions{SourcePath: parts[0], OutputDir: output})
+	if err == nil || !strings.Contains(err.Error(), parts[1]+" (缺失)") || !strings.Contains(err.Error(), parts[2]+" (大小不符") {
+		t.Fatalf("应列出缺失与大小不符的分包, 得到 %v", err)
+	}
+	if got := readOutputTree(t, output); len(got) > 0 {
+		t.Fatalf("缺少分包时输出了 %d 个文件", len(got))
+	}
+}
+
+func TestPartNameWithoutManifest(t *testing.T) {
+	// 两个无关的压缩包碰巧命名为 .partNN, 没有分包说明文件
+	dir := t.TempDir()
+	for _, name := range []string{"report.part01.zip", "report.part02.zip"} {
+		archive := filepath.Join(dir, name)
+		writeTestArchive(t, map[string][]byte{strings.TrimSuffix(name, ".zip") + ".txt": []byte(name)}, CompressOptions{Format: "zip", OutputPath: archive})
+	}
+
+	source := filepath.Join(dir, "report.part02.zip")
+	if got := PartBasePath(source); got != source {
+		t.Fatalf("PartBasePath = %s, 期望原样返回", got)
+	}
+	// 单独解压, 也支持 --resume
+	output := filepath.Join(t.TempDir(), "out")
+	if err := RunDecompress(DecompressOptions{SourcePath: source, OutputDir: output, Resume: true}); err != nil {
+		t.Fatal(err)
+	}
+	assertSameFiles(t, readOutputTree(t, output), map[string][]byte{"report.part02.txt": []byte("report.part02.zip")})
+}
+
+func TestEntriesPartsRemovesStaleParts(t *testing.T) {
+	files := partsTreeFiles()
+	parts := writeEntriesParts(t, "zip", files)
+	output := PartBasePath(parts[0])
+	// 之前同名压缩包留下的多余分包
+	stale := []string{partPath(output, len(parts)+1, 2), partPath(output, len(parts)+2, 2)}
+	for _, path := range stale {
+		if err := os.WriteFile(path, []byte("stale"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeTestArchive(t, files, CompressOptions{Format: "zip", OutputPath: output, SplitSize: minPartSize, SplitMode: SplitModeEntries})
+	for _, path := range stale {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Fatalf("应删除多余的分包 %s", path)
+		}
+	}
+	if got := OutputParts(output); len(got) != len(parts) {
+		t.Fatalf("分包 = %v, 期望 %d 个", got, len(parts))
+	}
+}
+
+func TestEntriesPartsRejects(t *testing.T) {
+	entries := writeTestTree(t, map[string][]byte{"a.txt": []byte("a")})
+	output := filepath.Join(t.TempDir(), "data.zip")
+	for name, opts := range map[string]CompressOptions{
+		"no-split":  {SplitSize: 0},
+		"too-small": {SplitSize: minPartSize - 1},
+		"parity":    {SplitSize: minPartSize, Parity: 1},
+		"stdin":     {SplitSize: minPartSize, Entries: []SourceEntry{{Path: "-", Name: "stdin"}}},
+		"bad-mode":  {SplitSize: minPartSize, SplitMode: "files"},
+	} {
+		opts.Format, opts.OutputPath, opts.Level = "zip", output, -1
+		if opts.SplitMode == "" {
+			opts.SplitMode = SplitModeEntries
+		}
+		if opts.Entries == nil {
+			opts.Entries = entries
+		}
+		if err := RunCompress(opts); err == nil {
+			t.Errorf("%s: 应拒绝", name)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+//go:build !linux && !darwin
+
+// Package compress /core/compress/special_other.go
+package compress
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+)
+
+// inodeKey 硬链接识别键, 当前平台不识别硬链接
+type inodeKey struct{}
+
+// hardlinkKey 当前平台不识别硬链接, 每个链接都按普通文件归档
+func hardlinkKey(os.FileInfo) (inodeKey, bool) {
+	return inodeKey{}, false
+}
+
+// makeSpecial 当前平台不支持创建 FIFO 与设备文件
+func makeSpecial(string, os.FileMode, int64, int64) error {
+	return fmt.Errorf("%s 不支持创建 FIFO 与设备文件", runtime.GOOS)
+}
@@ -0,0 +1,42 @@
+//go:build linux || darwin
+
+// Package compress /core/compress/special_unix.go
+package compress
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// inodeKey 硬链接识别键: 设备号 + inode
+type inodeKey struct {
+	dev, ino uint64
+}
+
+// hardlinkKey 文件存在多个硬链接时返回识别键
+func hardlinkKey(info os.FileInfo) (inodeKey, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok || stat.Nlink <= 1 {
+		return inodeKey{}, false
+	}
+	return inodeKey{dev: uint64(stat.Dev), ino: uint64(stat.Ino)}, true
+}
+
+// makeSpecial 创建 FIFO 或设备文件, 创建设备文件通常需要 root
+func makeSpecial(path string, mode os.FileMode, major, minor int64) error {
+	perm := uint32(mode.Perm())
+	dev := int(unix.Mkdev(uint32(major), uint32(minor)))
+	switch {
+	case mode&os.ModeNamedPipe != 0:
+		return unix.Mkfifo(path, perm)
+	case mode&os.ModeCharDevice != 0:
+		return unix.Mknod(path, unix.S_IFCHR|perm, dev)
+	case mode&os.ModeDevice != 0:
+		return unix.Mknod(path, unix.S_IFBLK|perm, dev)
+	default:
+		return fmt.Errorf("不支持的文件类型: %v", mode.Type())
+	}
+}
@@ -0,0 +1,184 @@
+// Package compress /core/compress/staging.go
+package compress
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/GoFurry/gf-file-tool/utils/log"
+)
+
+// 解压先写入暂存目录, 全部成功并刷盘后再移动到输出目录, 失败或中断时输出目录保持原样.
+// 暂存目录总是建在输出目录的上级目录中 (.name.gf-staging-*), 不会出现在已存在的输出目录内部 (输出到根目录除外):
+//   - 输出目录不存在: 完成后暂存目录整体改名为输出目录
+//   - 输出目录已存在: 完成后逐个条目改名覆盖, 输出目录中不存在的子目录整体移入, 已存在的子目录递归合并并保留其原有属性
+// 改名要求暂存目录与输出目录在同一文件系统, 输出目录本身是挂载点时合并会失败.
+// 进程被强制结束时暂存目录会留下, 可以直接删除, 或用 --resume 复用其中已解压的条目.
+
+// stagingDir 解压暂存目录
+type stagingDir struct {
+	path   string // 暂存目录
+	target string // 输出目录
+	merge  bool   // 输出目录已存在, 逐个条目合并
+	follow bool   // 合并时跟随输出目录中指向目录的符号链接 (仅 trust 策略)
+}
+
+// newStagingDir 在输出目录的上级目录中创建暂存目录, reuse 非空时复用上次中断留下的暂存目录
+func newStagingDir(target string, policy PathPolicy, reuse string) (*stagingDir, error) {
+	target, err := filepath.Abs(target)
+	if err != nil {
+		return nil, fmt.Errorf("解析输出目录失败: %v", err)
+	}
+	parent := filepath.Dir(target)
+	s := &stagingDir{target: target, follow: policy == PathTrust}
+
+	info, err := os.Stat(target)
+	switch {
+	case err == nil && !info.IsDir():
+		return nil, fmt.Errorf("输出路径不是目录: %s", target)
+	case err == nil:
+		s.merge = true
+	case os.IsNotExist(err):
+		if err := os.MkdirAll(parent, 0755); err != nil {
+			return nil, fmt.Errorf("创建输出目录失败: %v", err)
+		}
+	default:
+		return nil, fmt.Errorf("读取输出目录失败: %v", err)
+	}
+
+	if s.path = reusableStaging(reuse, parent); s.path != "" {
+		return s, nil
+	}
+	prefix := "." + filepath.Base(target)
+	if parent == target {
+		prefix = "" // 根目录没有上级目录, 只能建在其中
+	}
+	if s.path, err = os.MkdirTemp(parent, prefix+".gf-staging-*"); err != nil {
+		return nil, fmt.Errorf("创建暂存目录失败: %v", err)
+	}
+	// MkdirTemp 创建的目录为 0700, 整体改名为输出目录时应与直接创建输出目录时一致
+	if !s.merge {
+		if err := os.Chmod(s.path, 0755&^ProcessUmask()); err != nil {
+			s.discard()
+			return nil, fmt.Errorf("创建暂存目录失败: %v", err)
+		}
+	}
+	return s, nil
+}
+
+// reusableStaging 上次的暂存目录位于本次应在的目录中时返回其路径, 否则返回空
+func reusableStaging(reuse, dir string) string {
+	if reuse == "" {
+		return ""
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil || filepath.Dir(reuse) != abs {
+		return ""
+	}
+	return reuse
+}
+
+// commit 刷盘后把暂存目录中的内容移动到输出目录
+func (s *stagingDir) commit() error {
+	if err := syncTree(s.path); err != nil {
+		return fmt.Errorf("刷盘失败: %v", err)
+	}
+	if !s.merge {
+		if err := os.Rename(s.path, s.target); err != nil {
+			return fmt.Errorf("移动到输出目录失败: %v", err)
+		}
+		return syncPath(filepath.Dir(s.target))
+	}
+	if err := s.mergeDir(s.path, s.target); err != nil {
+		return err
+	}
+	return os.RemoveAll(s.path)
+}
+
+// discard 删除暂存目录, 输出目录不受影响
+func (s *stagingDir) discard() {
+	if s == nil {
+		return
+	}
+	if err := os.RemoveAll(s.path); err != nil {
+		log.Warn("清理暂存目录失败:", s.path, ", 错误:", err)
+	}
+}
+
+// targetPath 暂存目录中的路径对应的输出路径
+func (s *stagingDir) targetPath(path string) string {
+	rel, err := filepath.Rel(s.path, path)
+	if err != nil {
+		return path
+	}
+	return filepath.Join(s.target, rel)
+}
+
+// mergeDir 把 src 中的条目逐个改名到 dst, 同名文件被原子替换
+func (s *stagingDir) mergeDir(src, dst string) error {
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		from, to := filepath.Join(src, entry.Name()), filepath.Join(dst, entry.Name())
+		if !entry.IsDir() {
+			if err := os.Rename(from, to); err != nil {
+				return fmt.Errorf("移动到输出目录失败: %s, 错误: %v", to, err)
+			}
+			continue
+		}
+
+		info, err := os.Lstat(to)
+		if err == nil && info.Mode()&os.ModeSymlink != 0 && s.follow {
+			info, err = os.Stat(to)
+		}
+		switch {
+		case os.IsNotExist(err):
+			if err := os.Rename(from, to); err != nil {
+				return fmt.Errorf("移动到输出目录失败: %s, 错误: %v", to, err)
+			}
+		case err != nil:
+			return err
+		case info.IsDir():
+			if err := s.mergeDir(from, to); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("输出目录中已存在同名的非目录: %s", to)
+		}
+	}
+	return syncPath(dst)
+}
+
+// syncTree 刷盘目录中的所有文件与目录, 不跟随符号链接
+func syncTree(root string) error {
+	return filepath.WalkDir(root, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() && !entry.Type().IsRegular() {
+			return nil
+		}
+		// 按压缩包还原为不可读的文件无法打开, 跳过
+		if err := syncPath(path); err != nil && !os.IsPermission(err) {
+			return err
+		}
+		return nil
+	})
+}
+
+// syncPath 刷盘单个文件或目录, Windows 不支持对目录与只读句柄刷盘, 忽略
+func syncPath(path string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return file.Sync()
+}
@@ -0,0 +1,122 @@
+package compress
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// assertNoStaging 检查目录中没有留下暂存目录
+func assertNoStaging(t *testing.T, dirs ...string) {
+	t.Helper()
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, entry := range entries {
+			if strings.Contains(entry.Name(), ".gf-staging-") {
+				t.Fatalf("留下了暂存目录: %s", filepath.Join(dir, entry.Name()))
+			}
+		}
+	}
+}
+
+// stagingTreeFiles 前一个条目很小, 后一个条目足以超出 1M 的总量上限, 用于制造解压中途失败
+func stagingTreeFiles() map[string][]byte {
+	return map[string][]byte{"a.txt": []byte("first"), "b.bin": make([]byte, 2<<20)}
+}
+
+func TestStagingNewOutputDir(t *testing.T) {
+	files := stagingTreeFiles()
+	for _, format := range []string{"zip", "tar", "7z"} {
+		t.Run(format, func(t *testing.T) {
+			archive := writeTestArchive(t, files, CompressOptions{Format: format})
+			parent := t.TempDir()
+			output := filepath.Join(parent, "nested", "out")
+
+			// 中途失败: 输出目录不会被创建
+			err := RunDecompress(DecompressOptions{SourcePath: archive, OutputDir: output, Limits: ExtractLimits{MaxTotalSize: 1 << 20}})
+			var limitErr *LimitError
+			if !errors.As(err, &limitErr) {
+				t.Fatalf("期望 *LimitError, 得到 %v", err)
+			}
+			if _, err := os.Stat(output); !os.IsNotExist(err) {
+				t.Fatalf("失败后不应创建输出目录: %v", err)
+			}
+			assertNoStaging(t, filepath.Dir(output))
+
+			if err := RunDecompress(DecompressOptions{SourcePath: archive, OutputDir: output}); err != nil {
+				t.Fatal(err)
+			}
+			assertSameFiles(t, readOutputTree(t, output), files)
+			assertNoStaging(t, filepath.Dir(output))
+		})
+	}
+}
+
+func TestStagingExistingOutputDir(t *testing.T) {
+	files := stagingTreeFiles()
+	for _, format := range []string{"zip", "tar", "7z"} {
+		t.Run(format, func(t *testing.T) {
+			archive := writeTestArchive(t, files, CompressOptions{Format: format})
+			output := t.TempDir()
+			existing := map[string][]byte{"a.txt": []byte("old"), "keep.txt": []byte("keep")}
+			for name, data := range existing {
+				if err := os.WriteFile(filepath.Join(output, name), data, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			// 中途失败: 已写入暂存目录的 a.txt 不会覆盖输出目录中的文件
+			err := RunDecompress(DecompressOptions{SourcePath: archive, OutputDir: output, Limits: ExtractLimits{MaxTotalSize: 1 << 20}})
+			if err == nil {
+				t.Fatal("超出上限应失败")
+			}
+			assertSameFiles(t, readOutputTree(t, output), existing)
+			assertNoStaging(t, output, filepath.Dir(output))
+
+			// 成功: 逐个合并, 已有的其他文件保留
+			if err := RunDecompress(DecompressOptions{SourcePath: archive, OutputDir: output}); err != nil {
+				t.Fatal(err)
+			}
+			assertSameFiles(t, readOutputTree(t, output), map[string][]byte{"a.txt": files["a.txt"], "b.bin": files["b.bin"], "keep.txt": []byte("keep")})
+			assertNoStaging(t, output, filepath.Dir(output))
+		})
+	}
+}
+
+func TestStagingOutputNotDirectory(t *testing.T) {
+	archive := writeTestArchive(t, stagingTreeFiles(), CompressOptions{Format: "zip"})
+	output := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(output, []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := RunDecompress(DecompressOptions{SourcePath: archive, OutputDir: output}); err == nil {
+		t.Fatal("输出路径是文件时应报错")
+	}
+	assertNoStaging(t, filepath.Dir(output))
+}
+
+func TestStagingDirBesideOutput(t *testing.T) {
+	parent := t.TempDir()
+	existing := filepath.Join(parent, "existing")
+	if err := os.Mkdir(existing, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, target := range []string{existing, filepath.Join(parent, "missing")} {
+		staging, err := newStagingDir(target, PathSanitize, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		// 已存在的输出目录也不在其内部创建暂存目录
+		name := filepath.Base(staging.path)
+		if filepath.Dir(staging.path) != parent || !strings.HasPrefix(name, "."+filepath.Base(target)+".gf-staging-") {
+			t.Errorf("%s 的暂存目录 = %s, 期望位于 %s", target, staging.path, parent)
+		}
+		staging.discard()
+	}
+	assertNoStaging(t, existing, parent)
+}
@@ -0,0 +1,57 @@
+//go:build linux || darwin
+
+package compress
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStagingMergeKeepsExistingDirAttributes(t *testing.T) {
+	archive := writeTestArchive(t, map[string][]byte{"sub/new.txt": []byte("new")}, CompressOptions{Format: "tar"})
+	output := t.TempDir()
+	sub := filepath.Join(output, "sub")
+	if err := os.Mkdir(sub, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(sub, "old.txt"), []byte("old"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := RunDecompress(DecompressOptions{SourcePath: archive, OutputDir: output}); err != nil {
+		t.Fatal(err)
+	}
+	assertSameFiles(t, readOutputTree(t, output), map[string][]byte{"sub/new.txt": []byte("new"), "sub/old.txt": []byte("old")})
+	info, err := os.Stat(sub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o700 {
+		t.Fatalf("已有目录的权限被改为 %03o", info.Mode().Perm())
+	}
+}
+
+func TestStagingMergeRefusesSymlinkedDir(t *testing.T) {
+	// 输出目录中指向外部的同名目录链接不会被跟随
+	archive := writeTestArchive(t, map[string][]byte{"sub/new.txt": []byte("new")}, CompressOptions{Format: "tar"})
+	parent := t.TempDir()
+	outside := filepath.Join(parent, "outside")
+	output := filepath.Join(parent, "out")
+	for _, dir := range []string{outside, output} {
+		if err := os.Mkdir(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(outside, filepath.Join(output, "sub")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := RunDecompress(DecompressOptions{SourcePath: archive, OutputDir: output}); err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ := os.ReadDir(outside); len(entries) != 0 {
+		t.Fatalf("经过输出目录中的符号链接写到了外部: %v", entries)
+	}
+	assertNoStaging(t, output, parent)
+}
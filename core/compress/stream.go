@@ -0,0 +1,166 @@
+// Package compress /core/compress/stream.go
+package compress
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/GoFurry/gf-file-tool/utils/compress"
+	"github.com/GoFurry/gf-file-tool/utils/log"
+)
+
+// 流式模式: 路径写作 - 时表示标准输入/标准输出, 便于接入管道:
+//   pg_dump db | gf-file-tool compress - -f tar.zst -o - | ssh host ...
+//   ssh host cat backup.tar.zst | gf-file-tool decompress - -o ./restore
+// 管道不可 seek, 因此只有 tar 系列与 zip (数据描述符) 支持流式, 7z 需要随机访问.
+// 解压 tar 时直接从标准输入读取; 压缩时 tar 头部要预先写明大小, 标准输入作为条目时先读入内存, 只有超过上限才缓存到临时文件.
+
+// StdioPath 表示标准输入/标准输出的路径
+const StdioPath = "-"
+
+// DefaultStdinName 标准输入在压缩包内的默认文件名
+const DefaultStdinName = "stdin"
+
+// IsStdio 路径是否表示标准输入/标准输出
+func IsStdio(path string) bool {
+	return path == StdioPath
+}
+
+var (
+	stdinOnce   sync.Once
+	stdinBuffer *bufio.Reader
+)
+
+// stdinReader 带缓冲的标准输入, 全局唯一
+// 识别格式时 Peek 的头部字节仍保留在缓冲区中, 解压时可以继续读取
+func stdinReader() *bufio.Reader {
+	stdinOnce.Do(func() {
+		stdinBuffer = bufio.NewReaderSize(os.Stdin, 64*1024)
+	})
+	return stdinBuffer
+}
+
+// stdinFileInfo 标准输入的文件信息, 大小未知记为 -1
+type stdinFileInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+}
+
+func (s stdinFileInfo) Name() string       { return s.name }
+func (s stdinFileInfo) Size() int64        { return s.size }
+func (s stdinFileInfo) Mode() os.FileMode  { return 0644 }
+func (s stdinFileInfo) ModTime() time.Time { return s.modTime }
+func (s stdinFileInfo) IsDir() bool        { return false }
+func (s stdinFileInfo) Sys() any           { return nil }
+
+// openSource 打开待压缩的源文件, - 表示标准输入
+func openSource(path string) (io.ReadCloser, os.FileInfo, error) {
+	if IsStdio(path) {
+		return io.NopCloser(stdinReader()), stdinFileInfo{name: DefaultStdinName, size: -1, modTime: time.Now()}, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return nil, nil, err
+	}
+	return file, info, nil
+}
+
+// stdinMemoryLimit tar 条目的标准输入在内存中缓冲的上限
+const stdinMemoryLimit = 32 << 20
+
+// openStdinEntry 读入标准输入作为 tar 条目, 返回数据与大小
+// tar 头部必须预先写明大小: 不超过 stdinMemoryLimit 时直接从内存写入 tar, 超过时才缓存到临时文件, Close 时删除
+func openStdinEntry() (io.ReadCloser, int64, error) {
+	head, err := io.ReadAll(io.LimitReader(stdinReader(), stdinMemoryLimit+1))
+	if err != nil {
+		return nil, 0, fmt.Errorf("读取标准输入失败: %v", err)
+	}
+	if len(head) <= stdinMemoryLimit {
+		return io.NopCloser(bytes.NewReader(head)), int64(len(head)), nil
+	}
+	log.Warn("标准输入超过", stdinMemoryLimit>>20, "MB, tar 头部需要预先写明大小, 先缓存到临时文件; 使用 zip 格式可以直接流式写入")
+	return spoolStdin(head)
+}
+
+// spooledStdin 缓存标准输入的临时文件, Close 时删除
+type spooledStdin struct {
+	*os.File
+}
+
+func (s spooledStdin) Close() error {
+	err := s.File.Close()
+	_ = os.Remove(s.Name())
+	return err
+}
+
+// spoolStdin 把已读出的 head 与标准输入的剩余数据写入临时文件并回到开头
+func spoolStdin(head []byte) (io.ReadCloser, int64, error) {
+	tempFile, err := os.CreateTemp(compress.GetSystemTempDir(), "stdin-*.tmp")
+	if err != nil {
+		return nil, 0, fmt.Errorf("创建标准输入缓存文件失败: %v", err)
+	}
+	spooled := spooledStdin{tempFile}
+	if _, err := tempFile.Write(head); err != nil {
+		_ = spooled.Close()
+		return nil, 0, fmt.Errorf("写入标准输入缓存失败: %v", err)
+	}
+	size, err := io.Copy(tempFile, stdinReader())
+	if err != nil {
+		_ = spooled.Close()
+		return nil, 0, fmt.Errorf("读取标准输入失败: %v", err)
+	}
+	if _, err := tempFile.Seek(0, io.SeekStart); err != nil {
+		_ = spooled.Close()
+		return nil, 0, fmt.Errorf("读取标准输入缓存失败: %v", err)
+	}
+	return spooled, int64(len(head)) + size, nil
+}
+
+// createOutput 创建压缩包输出, - 表示标准输出 (Close 不关闭标准输出)
+func createOutput(path string) (io.WriteCloser, error) {
+	if IsStdio(path) {
+		return nopWriteCloser{os.Stdout}, nil
+	}
+	return os.Create(path)
+}
+
+// createArchive 创建压缩包输出: 分卷时为边写边切分的分卷写入器, 否则同 createOutput
+func createArchive(opts *CompressOptions) (io.WriteCloser, error) {
+	if opts.SplitSize > 0 {
+		return createVolumes(opts), nil
+	}
+	return createOutput(opts.OutputPath)
+}
+
+// abortArchive 出错返回时关闭压缩包输出, 已正常关闭时什么也不做
+// 分卷写入器只关闭当前分卷, 不生成恢复卷与说明文件
+func abortArchive(out io.Closer) error {
+	if volumes, ok := out.(*volumeWriter); ok {
+		return volumes.closeCurrent()
+	}
+	if err := out.Close(); err != nil && !errors.Is(err, os.ErrClosed) {
+		return err
+	}
+	return nil
+}
+
+// openInput 打开待解压的压缩包, - 表示标准输入
+func openInput(path string) (io.ReadCloser, error) {
+	if IsStdio(path) {
+		return io.NopCloser(stdinReader()), nil
+	}
+	return os.Open(path)
+}
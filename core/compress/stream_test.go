@@ -0,0 +1,240 @@
+package compress
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/GoFurry/gf-file-tool/utils/log"
+)
+
+// withStdio 用临时文件替换标准输入/标准输出, 返回写入标准输出的内容的读取函数
+func withStdio(t *testing.T, input []byte) func() []byte {
+	t.Helper()
+	dir := t.TempDir()
+	inPath := filepath.Join(dir, "stdin")
+	if err := os.WriteFile(inPath, input, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	in, err := os.Open(inPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	outPath := filepath.Join(dir, "stdout")
+	out, err := os.Create(outPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oldIn, oldOut, oldLog := os.Stdin, os.Stdout, log.Output()
+	os.Stdin, os.Stdout = in, out
+	log.SetOutput(io.Discard)
+	stdinOnce, stdinBuffer = sync.Once{}, nil
+	t.Cleanup(func() {
+		os.Stdin, os.Stdout = oldIn, oldOut
+		log.SetOutput(oldLog)
+		stdinOnce, stdinBuffer = sync.Once{}, nil
+		_ = in.Close()
+		_ = out.Close()
+	})
+
+	return func() []byte {
+		data, err := os.ReadFile(outPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+}
+
+func TestStdioRoundTrip(t *testing.T) {
+	payload := codecSample()
+	for _, format := range append([]string{"zip"}, codecFormats...) {
+		t.Run(format, func(t *testing.T) {
+			// 标准输入 → 压缩 → 标准输出
+			stdout := withStdio(t, payload)
+			err := RunCompress(CompressOptions{
+				Entries:    []SourceEntry{{Path: StdioPath, Name: "dump/data.bin"}},
+				OutputPath: StdioPath,
+				Format:     format,
+				Level:      -1,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			archive := stdout()
+			if len(archive) == 0 {
+				t.Fatal("标准输出没有数据")
+			}
+
+			// 标准输入 → 识别格式 → 解压
+			withStdio(t, archive)
+			output := t.TempDir()
+			if err := RunDecompress(DecompressOptions{SourcePath: StdioPath, OutputDir: output}); err != nil {
+				t.Fatal(err)
+			}
+			assertSameFiles(t, readOutputTree(t, output), map[string][]byte{"dump/data.bin": payload})
+		})
+	}
+}
+
+func TestStdoutMixedSources(t *testing.T) {
+	files := testTreeFiles()
+	sources := writeTestTree(t, files)
+	stdout := withStdio(t, []byte("from stdin"))
+	err := RunCompress(CompressOptions{
+		Entries:    append(sources, SourceEntry{Path: StdioPath}),
+		OutputPath: StdioPath,
+		Format:     "tar.zst",
+		Level:      -1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	archive := filepath.Join(t.TempDir(), "mixed.tar.zst")
+	if err := os.WriteFile(archive, stdout(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	output := t.TempDir()
+	if err := RunDecompress(DecompressOptions{SourcePath: archive, OutputDir: output}); err != nil {
+		t.Fatal(err)
+	}
+	files[DefaultStdinName] = []byte("from stdin")
+	assertSameFiles(t, readOutputTree(t, output), files)
+}
+
+func TestStdoutRejections(t *testing.T) {
+	withStdio(t, nil)
+	sources := writeTestTree(t, map[string][]byte{"a.txt": []byte("a")})
+
+	// 7z 需要随机访问, 不能写入标准输出
+	err := RunCompress(CompressOptions{Entries: sources, OutputPath: StdioPath, Format: "7z"})
+	if err == nil || !strings.Contains(err.Error(), "7z") {
+		t.Fatalf("7z 输出到标准输出应当报错, 实际: %v", err)
+	}
+	// 标准输出不能分卷
+	err = RunCompress(CompressOptions{Entries: sources, OutputPath: StdioPath, Format: "zip", SplitSize: 1024})
+	if err == nil {
+		t.Fatal("标准输出分卷应当报错")
+	}
+}
+
+func TestStdoutZipMethods(t *testing.T) {
+	payload := codecSample()
+	for _, method := range ZipMethodNames() {
+		t.Run(method, func(t *testing.T) {
+			stdout := withStdio(t, payload)
+			err := RunCompress(CompressOptions{
+				Entries:    []SourceEntry{{Path: StdioPath, Name: "dump/data.bin"}},
+				OutputPath: StdioPath,
+				Format:     "zip",
+				Method:     method,
+				Level:      -1,
+			})
+			// 只有 deflate 条目能从管道中流式读回
+			if method != DefaultZipMethod {
+				if err == nil || !strings.Contains(err.Error(), "deflate") {
+					t.Fatalf("--method %s 输出到标准输出应当报错, 实际: %v", method, err)
+				}
+				if len(stdout()) > 0 {
+					t.Fatal("拒绝后不应向标准输出写入数据")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			withStdio(t, stdout())
+			output := t.TempDir()
+			if err := RunDecompress(DecompressOptions{SourcePath: StdioPath, OutputDir: output}); err != nil {
+				t.Fatal(err)
+			}
+			assertSameFiles(t, readOutputTree(t, output), map[string][]byte{"dump/data.bin": payload})
+		})
+	}
+}
+
+// stdinTempFiles 标准输入缓存目录中的临时文件
+func stdinTempFiles(t *testing.T) []string {
+	t.Helper()
+	paths, err := filepath.Glob(filepath.Join(os.TempDir(), "gf-file-tool", "stdin-*.tmp"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return paths
+}
+
+func TestStdinTarEntry(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	big := make([]byte, stdinMemoryLimit+1<<20)
+	rand.New(rand.NewSource(5)).Read(big)
+
+	for name, payload := range map[string][]byte{"memory": codecSample(), "spooled": big} {
+		t.Run(name, func(t *testing.T) {
+			withStdio(t, payload)
+			var logs bytes.Buffer
+			log.SetOutput(&logs)
+
+			reader, size, err := openStdinEntry()
+			if err != nil {
+				t.Fatal(err)
+			}
+			// 不超过上限时只在内存中缓冲, 超过时才缓存到临时文件并警告
+			_, spooled := reader.(spooledStdin)
+			if spooled != (len(payload) > stdinMemoryLimit) || spooled != strings.Contains(logs.String(), "临时文件") {
+				t.Fatalf("%d 字节: 缓存到临时文件 = %t, 日志 %q", len(payload), spooled, logs.String())
+			}
+			if spooled != (len(stdinTempFiles(t)) == 1) {
+				t.Fatalf("临时文件 = %v", stdinTempFiles(t))
+			}
+			data, err := io.ReadAll(reader)
+			if err != nil || size != int64(len(payload)) || !bytes.Equal(data, payload) {
+				t.Fatalf("读出 %d 字节 (大小 %d), %v, 期望 %d 字节", len(data), size, err, len(payload))
+			}
+			if err := reader.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if paths := stdinTempFiles(t); len(paths) > 0 {
+				t.Fatalf("关闭后应删除临时文件: %v", paths)
+			}
+		})
+	}
+}
+
+func TestStdinTarRoundTrip(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	big := make([]byte, stdinMemoryLimit+1<<20)
+	rand.New(rand.NewSource(6)).Read(big)
+
+	for name, payload := range map[string][]byte{"memory": codecSample(), "spooled": big} {
+		t.Run(name, func(t *testing.T) {
+			withStdio(t, payload)
+			archive := filepath.Join(t.TempDir(), "stdin.tar.zst")
+			err := RunCompress(CompressOptions{
+				Entries:    []SourceEntry{{Path: StdioPath, Name: "dump/data.bin"}},
+				OutputPath: archive,
+				Format:     "tar.zst",
+				Level:      -1,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if paths := stdinTempFiles(t); len(paths) > 0 {
+				t.Fatalf("压缩后应删除临时文件: %v", paths)
+			}
+
+			output := t.TempDir()
+			if err := RunDecompress(DecompressOptions{SourcePath: archive, OutputDir: output}); err != nil {
+				t.Fatal(err)
+			}
+			assertSameFiles(t, readOutputTree(t, output), map[string][]byte{"dump/data.bin": payload})
+		})
+	}
+}
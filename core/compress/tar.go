@@ -0,0 +1,773 @@
+// Package compress /core/compress/tar.go
+package compress
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/GoFurry/gf-file-tool/progress"
+	"github.com/GoFurry/gf-file-tool/utils"
+	"github.com/GoFurry/gf-file-tool/utils/compress"
+	"github.com/GoFurry/gf-file-tool/utils/log"
+)
+
+// ============================== tar 压缩部分 ==============================
+
+// TarCompressor tar 系列压缩器 (不支持加密)
+// 外层压缩由 Codec 决定: tar/tar.gz/tar.zst/tar.xz/tar.bz2/tar.lz4
+type TarCompressor struct {
+	Codec TarCodec
+}
+
+// Compress tar 压缩逻辑
+func (t *TarCompressor) Compress(opts *CompressOptions) error {
+	// 禁用加密
+	if opts.Encrypt {
+		return fmt.Errorf("%s 格式不支持加密压缩, 请使用 zip 格式", t.Codec.Name())
+	}
+
+	// 执行压缩, 分卷时边写边切分
+	return t.compressSingleFile(*opts)
+}
+
+// compressSingleFile tar 单文件压缩
+func (t *TarCompressor) compressSingleFile(opts CompressOptions) error {
+	// 校验压缩等级
+	level, err := ResolveLevel(t.Codec, opts.Level)
+	if err != nil {
+		return err
+	}
+
+	// 续传时截断到上次的检查点后追加, 否则创建输出 (- 为标准输出, 分卷时为分卷写入器)
+	resume, _, resuming, err := opts.journal.compressResume()
+	if err != nil {
+		return err
+	}
+	var outFile io.WriteCloser
+	if resuming {
+		outFile, err = openOutputAt(opts.OutputPath, resume.Offset)
+	} else {
+		outFile, err = createArchive(&opts)
+	}
+	if err != nil {
+		return fmt.Errorf("创建 %s 文件失败: %v", t.Codec.Name(), err)
+	}
+	defer abortArchive(outFile)
+
+	// 初始化外层压缩 Writer, 检查点处分帧
+	frames := &tarFrames{codec: t.Codec, level: level, out: &countWriter{w: outFile, n: resume.Offset}, file: outFile}
+	if err := frames.next(); err != nil {
+		return fmt.Errorf("初始化 %s 写入器失败: %v", t.Codec.Name(), err)
+	}
+
+	// 初始化 tar Writer
+	tarWriter := tar.NewWriter(frames)
+
+	if err := t.writeEntries(tarWriter, frames, opts, resume); err != nil {
+		return err
+	}
+
+	// 按 tar → 压缩层 → 文件的顺序关闭, 关闭时才会写出尾部数据, 失败必须返回错误
+	if err := tarWriter.Close(); err != nil {
+		return fmt.Errorf("关闭 tar 写入器失败: %v", err)
+	}
+	if err := frames.Close(); err != nil {
+		return fmt.Errorf("关闭 %s 写入器失败: %v", t.Codec.Name(), err)
+	}
+	if err := outFile.Close(); err != nil {
+		return fmt.Errorf("关闭 %s 文件失败: %v", t.Codec.Name(), err)
+	}
+	return nil
+}
+
+// writeEntries 遍历文件写入 tar, 续传时跳过检查点之前的条目
+func (t *TarCompressor) writeEntries(tarWriter *tar.Writer, frames *tarFrames, opts CompressOptions, resume compressCheckpoint) error {
+	// 批量进度条
+	batchBar := progress.NewBatchProgressBar(len(opts.Entries))
+	defer progress.FinishProgress(batchBar)
+
+	// 已写入的多链接文件, 值为其在压缩包内的路径
+	links := make(map[inodeKey]string)
+
+	// 遍历文件压缩
+	for i, entry := range opts.Entries {
+		progress.UpdateProgress(batchBar, 1)
+		srcPath := entry.Path
+
+		// 续传: 检查点之前的条目已写出, 只登记硬链接; 中断在条目中间时写完剩余的数据
+		if i < resume.Entry {
+			rememberHardlink(links, entry)
+			continue
+		}
+		if i == resume.Entry && resume.Data > 0 {
+			if err := t.resumeEntry(frames, opts, i, resume, links); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// 条目之间记录检查点, 先补齐上一个条目的块
+		if opts.journal != nil && frames.due() {
+			if err := tarWriter.Flush(); err != nil {
+				return fmt.Errorf("写入 tar 失败: %v", err)
+			}
+			if err := frames.checkpoint(opts.journal, i, 0, nil); err != nil {
+				return err
+			}
+		}
+
+		// 目录、符号链接、特殊文件只写入条目头
+		if entry.special() {
+			if err := writeTarHeaderOnly(tarWriter, entry); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// 打开源文件, 标准输入先读入以获得大小, 大文件的一段只读取该段
+		var file io.ReadCloser
+		var fileInfo os.FileInfo
+		var err error
+		switch {
+		case IsStdio(srcPath):
+			var size int64
+			if file, size, err = openStdinEntry(); err == nil {
+				defer file.Close() // 出错返回时也删除缓存的临时文件
+				fileInfo = stdinFileInfo{name: DefaultStdinName, size: size, modTime: time.Now()}
+			}
+		case entry.chunk != nil:
+			file, fileInfo, err = openChunkSource(entry)
+		default:
+			file, fileInfo, err = openSource(srcPath)
+		}
+		if err != nil {
+			return fmt.Errorf("打开文件失败: %s, 错误: %v", srcPath, err)
+		}
+
+		// 压缩包内路径
+		relPath := entry.Name
+
+		// 创建 Tar 头
+		header, err := tar.FileInfoHeader(fileInfo, "")
+		if err != nil {
+			return fmt.Errorf("创建 tar 文件头失败: %s, 错误: %v", srcPath, err)
+		}
+		header.Name = relPath
+		header.Size = fileInfo.Size()
+		if IsStdio(srcPath) {
+			header.Mode = 0644
+			header.ModTime = time.Now()
+			header.AccessTime = header.ModTime
+			header.Format = tar.FormatPAX
+		} else {
+			setTarMetadata(header, srcPath)
+		}
+
+		// 同一文件的其他硬链接只记录链接目标, 不重复写入内容
+		if key, ok := hardlinkKey(fileInfo); ok && !IsStdio(srcPath) && entry.chunk == nil {
+			if target, seen := links[key]; seen {
+				_ = file.Close()
+				header.Typeflag = tar.TypeLink
+				header.Linkname = target
+				header.Size = 0
+				if err := tarWriter.WriteHeader(header); err != nil {
+					return fmt.Errorf("写入 tar 头失败: %s, 错误: %v", srcPath, err)
+				}
+				if utils.VerboseMode() {
+					log.Success("已添加硬链接:", relPath, "→", target)
+				}
+				continue
+			}
+			links[key] = relPath
+		}
+
+		// 写入 Tar 头
+		if err := tarWriter.WriteHeader(header); err != nil {
+			return fmt.Errorf("写入 tar 头失败: %s, 错误: %v", srcPath, err)
+		}
+
+		// 单个文件进度条
+		fileBar := progress.NewFileProgressBar(fileInfo.Size(), relPath)
+
+		// 分块拷贝
+		buf := make([]byte, 4*1024*1024)
+		totalWritten := int64(0)
+		for {
+			n, err := file.Read(buf)
+			if err != nil && err != io.EOF {
+				return fmt.Errorf("读取文件失败: %s, 错误: %v", srcPath, err)
+			}
+			if n == 0 {
+				break
+			}
+
+			if _, err := tarWriter.Write(buf[:n]); err != nil {
+				return fmt.Errorf("写入 tar 失败: %s, 错误: %v", srcPath, err)
+			}
+
+			totalWritten += int64(n)
+			if fileBar != nil {
+				_ = fileBar.Set64(totalWritten)
+			}
+			// 大文件中间也记录检查点
+			if opts.journal != nil && frames.due() {
+				if err := frames.checkpoint(opts.journal, i, totalWritten, fileInfo); err != nil {
+					return err
+				}
+			}
+		}
+
+		// 手动关闭, 防止泄露
+		closeErr := file.Close()
+		if closeErr != nil {
+			log.Warn("文件关闭失败")
+		}
+		progress.FinishProgress(fileBar)
+
+		if utils.VerboseMode() {
+			log.Success("已压缩:", relPath, "总计", totalWritten, "字节")
+		}
+	}
+
+	return nil
+}
+
+// rememberHardlink 续传时登记检查点之前已写出内容的多链接文件
+func rememberHardlink(links map[inodeKey]string, entry SourceEntry) {
+	if entry.special() || IsStdio(entry.Path) {
+		return
+	}
+	info, err := os.Stat(entry.Path)
+	if err != nil {
+		return
+	}
+	if key, ok := hardlinkKey(info); ok {
+		if _, seen := links[key]; !seen {
+			links[key] = entry.Name
+		}
+	}
+}
+
+// resumeEntry 续传时写完上次中断在中间的条目: 条目头与前 resume.Data 字节已写出,
+// 剩余数据直接写入外层压缩流并补齐 tar 块, 之后的条目由新的 tar.Writer 继续
+func (t *TarCompressor) resumeEntry(frames *tarFrames, opts CompressOptions, index int, resume compressCheckpoint, links map[inodeKey]string) error {
+	entry := opts.Entries[index]
+	file, err := os.Open(entry.Path)
+	if err != nil {
+		return fmt.Errorf("打开文件失败: %s, 错误: %v", entry.Path, err)
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("获取文件信息失败: %s, 错误: %v", entry.Path, err)
+	}
+	if info.Size() != resume.Size || info.ModTime().UnixNano() != resume.ModTime {
+		return fmt.Errorf("文件在中断后已改变, 无法续传: %s, 请去掉 --resume 重新开始", entry.Path)
+	}
+	if key, ok := hardlinkKey(info); ok {
+		links[key] = entry.Name
+	}
+	if _, err := file.Seek(resume.Data, io.SeekStart); err != nil {
+		return fmt.Errorf("读取文件失败: %s, 错误: %v", entry.Path, err)
+	}
+
+	buf := make([]byte, 4*1024*1024)
+	totalWritten := resume.Data
+	for totalWritten < info.Size() {
+		n, err := file.Read(buf[:min(int64(len(buf)), info.Size()-totalWritten)])
+		if n > 0 {
+			if _, err := frames.Write(buf[:n]); err != nil {
+				return fmt.Errorf("写入 tar 失败: %s, 错误: %v", entry.Path, err)
+			}
+			totalWritten += int64(n)
+			if frames.due() {
+				if err := frames.checkpoint(opts.journal, index, totalWritten, info); err != nil {
+					return err
+				}
+			}
+		}
+		if err == io.EOF {
+			return fmt.Errorf("文件在中断后已改变, 无法续传: %s, 请去掉 --resume 重新开始", entry.Path)
+		}
+		if err != nil {
+			return fmt.Errorf("读取文件失败: %s, 错误: %v", entry.Path, err)
+		}
+	}
+
+	// 补齐到 512 字节块
+	if pad := -info.Size() & (tarBlockSize - 1); pad > 0 {
+		if _, err := frames.Write(make([]byte, pad)); err != nil {
+			return fmt.Errorf("写入 tar 失败: %s, 错误: %v", entry.Path, err)
+		}
+	}
+	if utils.VerboseMode() {
+		log.Success("已续传:", entry.Name, "总计", totalWritten, "字节")
+	}
+	return nil
+}
+
+// tarBlockSize tar 的块大小, 条目数据按块补齐
+const tarBlockSize = 512
+
+// tarFrames 外层压缩写入器, 检查点处结束当前压缩帧并从帧边界继续.
+// gzip/zstd/xz/bzip2/lz4 都允许多帧直接拼接, 解压时视为一个连续的流
+type tarFrames struct {
+	codec TarCodec
+	level int
+	out   *countWriter // 输出文件, n 为已写出的字节数 (含续传前的部分)
+	file  io.Writer    // 输出文件, 检查点处刷盘
+	w     io.WriteCloser
+	since int64 // 上个检查点之后写入的字节数
+}
+
+// next 开始新的压缩帧
+func (f *tarFrames) next() error {
+	w, err := f.codec.NewWriter(f.out, f.level)
+	if err != nil {
+		return err
+	}
+	f.w = w
+	return nil
+}
+
+// Write 写入当前压缩帧
+func (f *tarFrames) Write(p []byte) (int, error) {
+	n, err := f.w.Write(p)
+	f.since += int64(n)
+	return n, err
+}
+
+// Close 结束最后一帧
+func (f *tarFrames) Close() error {
+	return f.w.Close()
+}
+
+// due 距上个检查点是否已写入足够的数据
+func (f *tarFrames) due() bool {
+	return f.since >= journalInterval
+}
+
+// checkpoint 结束当前压缩帧、刷盘并记录检查点, 之后开始新的一帧
+// index: 当前条目序号, data: 当前条目已写出的字节数, 在条目之间调用时为 0 且 index 为下一个条目
+func (f *tarFrames) checkpoint(j *journal, index int, data int64, info os.FileInfo) error {
+	if err := f.w.Close(); err != nil {
+		return fmt.Errorf("写入检查点失败: %v", err)
+	}
+	if syncer, ok := f.file.(interface{ Sync() error }); ok {
+		if err := syncer.Sync(); err != nil {
+			return fmt.Errorf("写入检查点失败: %v", err)
+		}
+	}
+	f.since = 0
+	record := compressCheckpoint{Entry: index, Data: data, Offset: f.out.n}
+	if data > 0 {
+		record.Size = info.Size()
+		record.ModTime = info.ModTime().UnixNano()
+	}
+	if err := j.checkpoint(record); err != nil {
+		return err
+	}
+	if err := f.next(); err != nil {
+		return fmt.Errorf("初始化 %s 写入器失败: %v", f.codec.Name(), err)
+	}
+	return nil
+}
+
+// writeTarHeaderOnly 写入不含数据的条目: 目录 (名称以 / 结尾)、符号链接、FIFO、设备文件 (含设备号)
+func writeTarHeaderOnly(tarWriter *tar.Writer, entry SourceEntry) error {
+	info, err := statEntry(entry)
+	if err != nil {
+		return fmt.Errorf("获取文件信息失败: %s, 错误: %v", entry.Path, err)
+	}
+	link := ""
+	if info.Mode()&os.ModeSymlink != 0 {
+		if link, err = os.Readlink(entry.Path); err != nil {
+			return fmt.Errorf("读取符号链接失败: %s, 错误: %v", entry.Path, err)
+		}
+	}
+	header, err := tar.FileInfoHeader(info, link)
+	if err != nil {
+		return fmt.Errorf("创建 tar 文件头失败: %s, 错误: %v", entry.Path, err)
+	}
+	header.Name = entry.Name
+	if info.IsDir() {
+		header.Name += "/"
+	}
+	setTarMetadata(header, entry.Path)
+	if err := tarWriter.WriteHeader(header); err != nil {
+		return fmt.Errorf("写入 tar 头失败: %s, 错误: %v", entry.Path, err)
+	}
+	if utils.VerboseMode() {
+		if link != "" {
+			log.Success("已添加符号链接:", header.Name, "→", link)
+		} else {
+			log.Success("已添加:", header.Name)
+		}
+	}
+	return nil
+}
+
+// setTarMetadata 使用 PAX 格式保留纳秒级修改时间与访问时间, 扩展属性写入 SCHILY.xattr.* 记录
+// 属主由 FileInfoHeader 填写; 未指定格式时 tar.Writer 会丢弃访问时间
+func setTarMetadata(header *tar.Header, path string) {
+	header.Format = tar.FormatPAX
+	xattrs, err := readXattrs(path)
+	if err != nil {
+		log.Warn("读取扩展属性失败:", path, ", 错误:", err)
+		return
+	}
+	for name, value := range xattrs {
+		if header.PAXRecords == nil {
+			header.PAXRecords = make(map[string]string)
+		}
+		header.PAXRecords[paxXattrPrefix+name] = value
+	}
+}
+
+// ============================== tar 解压缩部分 ==============================
+
+// TarDecompressor tar 系列解压缩器
+type TarDecompressor struct {
+	Codec TarCodec
+}
+
+// Decompress tar 解压缩逻辑
+func (t *TarDecompressor) Decompress(opts DecompressOptions) error {
+	// 禁用加密
+	if opts.Encrypt {
+		return fmt.Errorf("%s 格式不支持加密解密，请使用 zip 格式", t.Codec.Name())
+	}
+
+	// 打开压缩包 (- 为标准输入), 分卷按顺序原地读取, 解压流程结束再关闭
+	tarFile, closeSource, err := t.openSource(opts)
+	if err != nil {
+		return err
+	}
+	defer closeSource()
+
+	// 初始化外层解压读取器
+	codecReader, err := t.Codec.NewReader(opts.limiter.countInput(tarFile))
+	if err != nil {
+		return fmt.Errorf("初始化 %s 读取器失败：%v", t.Codec.Name(), err)
+	}
+	// 解压流程结束再关闭 codecReader
+	defer func() {
+		if err := codecReader.Close(); err != nil && utils.VerboseMode() {
+			log.Warn("关闭", t.Codec.Name(), "读取器失败:", err)
+		}
+	}()
+
+	// 初始化 tar 读取器
+	tarReader := tar.NewReader(codecReader)
+
+	// 目录的时间与权限在全部条目解压后还原
+	restorer := newMetadataRestorer(opts)
+	defer restorer.finish()
+
+	// 遍历 tar 内文件, index 为条目在压缩包中的序号
+	fileCount := 0
+	for index := 0; ; index++ {
+		// 指定的条目都已解压, 不必读完整个流
+		if opts.Filter.Done() {
+			break
+		}
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("读取 tar 头失败: %v", err)
+		}
+		// 按筛选条件跳过
+		if !opts.Filter.Match(header.Name, header.Typeflag == tar.TypeDir) {
+			continue
+		}
+
+		// 构建输出路径, 不安全的条目按路径策略修正、跳过或中止
+		outputPath, ok, err := opts.guard.resolve(header.Name, header.Typeflag == tar.TypeDir)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		if err := opts.limiter.addEntry(header.Name, header.Size); err != nil {
+			return err
+		}
+		if opts.journal.skip(opts, index, header.Name, outputPath) {
+			continue
+		}
+		if outputPath, ok, err = opts.conflicts.resolve(header.Name, outputPath, header.Typeflag == tar.TypeDir, header.ModTime); err != nil {
+			return err
+		} else if !ok {
+			continue
+		}
+		fileCount++
+		if utils.VerboseMode() {
+			log.Newline()
+			log.Info("解压文件:", header.Name, "→", outputPath)
+		}
+
+		// 按条目类型处理, 只有普通文件需要写出内容
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := compress.MkdirIfNotExist(outputPath); err != nil {
+				return fmt.Errorf("创建目录失败: %s, 错误: %v", outputPath, err)
+			}
+			restorer.deferDir(outputPath, tarMetadata(header))
+			continue
+		case tar.TypeSymlink:
+			if err := extractSymlink(header.Name, header.Linkname, outputPath); err != nil {
+				return err
+			}
+			restorer.apply(outputPath, tarMetadata(header))
+			continue
+		case tar.TypeLink:
+			// 硬链接与目标共用 inode, 元数据已随目标还原
+			if err := extractHardlink(opts, header.Name, header.Linkname, outputPath); err != nil {
+				return err
+			}
+			continue
+		case tar.TypeFifo, tar.TypeChar, tar.TypeBlock:
+			created, err := extractSpecial(opts, header.Name, header.FileInfo().Mode(), header.Devmajor, header.Devminor, outputPath)
+			if err != nil {
+				return err
+			}
+			if created {
+				restorer.apply(outputPath, tarMetadata(header))
+			}
+			continue
+		case tar.TypeReg, tar.TypeCont, tar.TypeGNUSparse:
+		default:
+			log.Warn("跳过不支持的条目类型:", header.Name, "("+string(header.Typeflag)+")")
+			continue
+		}
+
+		// 创建文件目录
+		if err := compress.MkdirIfNotExist(filepath.Dir(outputPath)); err != nil {
+			return fmt.Errorf("创建文件目录失败: %s, 错误: %v", filepath.Dir(outputPath), err)
+		}
+
+		// 创建输出文件
+		dstFile, err := os.Create(outputPath)
+		if err != nil {
+			return fmt.Errorf("创建输出文件失败: %s, 错误: %v", outputPath, err)
+		}
+		// 写入后立即关闭
+		defer dstFile.Close()
+
+		// 单个文件进度条
+		fileBar := progress.NewFileProgressBar(header.Size, header.Name)
+		defer progress.FinishProgress(fileBar)
+
+		// 分块拷贝, 按实际解出的字节检查解压上限
+		data := opts.journal.track(opts.limiter.reader(header.Name, tarReader))
+		buf := make([]byte, 4*1024*1024) // 4MB 缓冲区
+		totalWritten := int64(0)
+		for {
+			n, err := data.Read(buf)
+			if err != nil && err != io.EOF {
+				return fmt.Errorf("读取 tar 内文件失败: %s, 错误: %v", header.Name, err)
+			}
+			if n == 0 {
+				break
+			}
+
+			if _, err := dstFile.Write(buf[:n]); err != nil {
+				return fmt.Errorf("写入文件失败: %s, 错误: %v", outputPath, err)
+			}
+
+			totalWritten += int64(n)
+			if fileBar != nil {
+				_ = fileBar.Set64(totalWritten)
+			}
+		}
+
+		// 主动刷新并关闭当前文件
+		if err := dstFile.Sync(); err != nil && utils.VerboseMode() {
+			log.Warn("刷新文件缓存失败:", outputPath, ", 错误:", err)
+		}
+		if err := dstFile.Close(); err != nil && utils.VerboseMode() {
+			log.Warn("关闭输出文件失败:", outputPath, ", 错误:", err)
+		}
+
+		// 还原权限、时间、属主与扩展属性
+		restorer.apply(outputPath, tarMetadata(header))
+		if err := opts.journal.extracted(index, header.Name, outputPath); err != nil {
+			return err
+		}
+
+		// 完整性校验: tar 条目没有校验和, 核对解出的大小, 压缩流的校验和在结束后统一检查
+		if opts.Verify {
+			if totalWritten != header.Size {
+				return fmt.Errorf("文件 %s 大小不匹配: 预期 %d, 实际 %d", header.Name, header.Size, totalWritten)
+			}
+			if utils.VerboseMode() {
+				log.Newline()
+				log.Success("文件", header.Name, "大小校验通过")
+			}
+		}
+	}
+
+	// tar 结束标记之后继续读到压缩流结尾, 让 gzip/zstd/xz 等校验尾部的校验和
+	if opts.Verify && !opts.Filter.Done() {
+		if _, err := io.Copy(io.Discard, codecReader); err != nil {
+			return fmt.Errorf("%s 压缩流校验失败: %v", t.Codec.Name(), err)
+		}
+	}
+
+	if utils.VerboseMode() {
+		log.Newline()
+		log.Success("共解压", fileCount, "个文件")
+	}
+	return nil
+}
+
+// tarMetadata 从 tar 头读取条目元数据
+func tarMetadata(header *tar.Header) entryMetadata {
+	return entryMetadata{
+		Mode:       header.FileInfo().Mode(),
+		ModTime:    header.ModTime,
+		AccessTime: header.AccessTime,
+		Uid:        header.Uid,
+		Gid:        header.Gid,
+		Uname:      header.Uname,
+		Gname:      header.Gname,
+		Xattrs:     xattrsFromPAX(header.PAXRecords),
+		Link:       header.Typeflag == tar.TypeSymlink,
+	}
+}
+
+// Test 读取完整的 tar 流: 每个条目的数据都流过外层解压器,
+// tar 结束标记之后继续读到压缩流结尾, 让 gzip/zstd/xz 等校验尾部的校验和
+func (t *TarDecompressor) Test(opts DecompressOptions, report func(TestResult)) error {
+	source, closeSource, err := t.openSource(opts)
+	if err != nil {
+		return err
+	}
+	defer closeSource()
+
+	codecReader, err := t.Codec.NewReader(source)
+	if err != nil {
+		return fmt.Errorf("初始化 %s 读取器失败：%v", t.Codec.Name(), err)
+	}
+	defer codecReader.Close()
+
+	tarReader := tar.NewReader(codecReader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("读取 tar 头失败: %v", err)
+		}
+		n, err := io.Copy(io.Discard, tarReader)
+		if err != nil {
+			// tar 流损坏后无法定位下一个条目
+			report(TestResult{Name: header.Name, Size: n, Err: fmt.Errorf("读取失败: %v", err)})
+			return fmt.Errorf("%s 流在 %s 处损坏", t.Codec.Name(), header.Name)
+		}
+		report(TestResult{Name: header.Name, Size: n})
+	}
+
+	if _, err := io.Copy(io.Discard, codecReader); err != nil {
+		return fmt.Errorf("%s 压缩流校验失败: %v", t.Codec.Name(), err)
+	}
+	return nil
+}
+
+// WriteEntry 顺序查找单个条目写入 w, 找到后不再读取后续数据
+func (t *TarDecompressor) WriteEntry(opts DecompressOptions, name string, w io.Writer) error {
+	source, closeSource, err := t.openSource(opts)
+	if err != nil {
+		return err
+	}
+	defer closeSource()
+
+	codecReader, err := t.Codec.NewReader(source)
+	if err != nil {
+		return fmt.Errorf("初始化 %s 读取器失败：%v", t.Codec.Name(), err)
+	}
+	defer codecReader.Close()
+
+	tarReader := tar.NewReader(codecReader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return errEntryNotFound(name)
+		}
+		if err != nil {
+			return fmt.Errorf("读取 tar 头失败: %v", err)
+		}
+		if compress.CleanEntryName(header.Name) != name {
+			continue
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			return errEntryIsDir(name)
+		case tar.TypeSymlink, tar.TypeLink:
+			return errEntryIsLink(name, header.Linkname)
+		}
+		if _, err := io.Copy(w, tarReader); err != nil {
+			return fmt.Errorf("读取压缩包内文件失败: %s, 错误: %v", name, err)
+		}
+		return nil
+	}
+}
+
+// openSource 打开 tar 数据源: 分卷按顺序拼接后原地读取, - 为标准输入
+func (t *TarDecompressor) openSource(opts DecompressOptions) (io.Reader, func(), error) {
+	if IsStdio(opts.SourcePath) {
+		input, err := openInput(opts.SourcePath)
+		if err != nil {
+			return nil, nil, fmt.Errorf("打开 %s 失败：%v", t.Codec.Name(), err)
+		}
+		return input, func() { _ = input.Close() }, nil
+	}
+	volumes, err := openVolumes(opts.SourcePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	return io.NewSectionReader(volumes, 0, volumes.Size()), func() { _ = volumes.Close() }, nil
+}
+
+// List 顺序读取 tar 头, tar 系列整体压缩, 单个文件没有压缩后大小
+func (t *TarDecompressor) List(opts DecompressOptions) ([]ArchiveEntry, error) {
+	source, closeSource, err := t.openSource(opts)
+	if err != nil {
+		return nil, err
+	}
+	defer closeSource()
+
+	codecReader, err := t.Codec.NewReader(source)
+	if err != nil {
+		return nil, fmt.Errorf("初始化 %s 读取器失败：%v", t.Codec.Name(), err)
+	}
+	defer codecReader.Close()
+
+	var entries []ArchiveEntry
+	tarReader := tar.NewReader(codecReader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return entries, fmt.Errorf("读取 tar 头失败: %v", err)
+		}
+		entries = append(entries, ArchiveEntry{
+			Name:           header.Name,
+			Size:           header.Size,
+			CompressedSize: -1,
+			Method:         t.Codec.Name(),
+			Modified:       header.ModTime,
+			Mode:           header.FileInfo().Mode(),
+		})
+	}
+	return entries, nil
+}
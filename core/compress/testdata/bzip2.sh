@@ -0,0 +1,31 @@
+#!/bin/sh
+# Generate the bzip2 known-answer fixtures with the reference tools. gf-file-tool only reads bzip2,
+# so its own output cannot serve as a fixture.
+#
+#   bzip2.tar.bz2  docs/lines.txt + docs/empty.txt, tar compressed with bzip2 -9
+#   bzip2.zip      the same two files, written by Info-ZIP zip -Z bzip2 (method 12)
+#
+# Run from this directory: sh bzip2.sh
+set -e
+work=$(mktemp -d)
+trap 'rm -rf "$work"' EXIT
+here=$(pwd)
+
+cd "$work"
+mkdir docs
+i=0
+while [ $i -lt 2000 ]; do
+	printf 'bzip2 known answer line %04d\n' $i
+	i=$((i + 1))
+done > docs/lines.txt
+: > docs/empty.txt
+touch -d '2024-05-01 12:34:56' docs/lines.txt docs/empty.txt docs
+
+tar --format=ustar --owner=0 --group=0 --numeric-owner -cf bzip2.tar docs/lines.txt docs/empty.txt
+bzip2 -9 bzip2.tar
+zip -q -X -Z bzip2 bzip2.zip docs/lines.txt docs/empty.txt
+
+bzip2 -t bzip2.tar.bz2
+unzip -q -t bzip2.zip
+cp bzip2.tar.bz2 bzip2.zip "$here"
+sha256sum docs/lines.txt
@@ -0,0 +1,239 @@
+#!/usr/bin/env python3
+"""Generate testdata/deflate64.zip, the Deflate64 known-answer fixture.
+
+The stream is encoded by hand (independently of the Go decoder) so that it
+exercises the parts where Deflate64 differs from deflate:
+
+  - block 1: stored, 40000 random bytes
+  - block 2: fixed Huffman
+      * match distance 40000 / length 40000 (distance code 30, length code 285)
+      * literal 'A' + match distance 1 / length 65538 (length code 285, 16 extra bits all set)
+  - block 3: dynamic Huffman, final
+      * match distance 60000 / length 1000 (distance code 31)
+      * match distance 65536 / length 200 (distance code 31, 14 extra bits all set)
+
+The result was cross-checked with Info-ZIP `unzip -t` (built with USE_DEFLATE64).
+Run from this directory: python3 deflate64.py
+"""
+import hashlib
+import heapq
+import random
+import struct
+import zlib
+
+LEN_BASE = [3, 4, 5, 6, 7, 8, 9, 10, 11, 13, 15, 17, 19, 23, 27, 31, 35, 43, 51, 59,
+            67, 83, 99, 115, 131, 163, 195, 227, 3]
+LEN_EXTRA = [0, 0, 0, 0, 0, 0, 0, 0, 1, 1, 1, 1, 2, 2, 2, 2, 3, 3, 3, 3,
+             4, 4, 4, 4, 5, 5, 5, 5, 16]
+DIST_BASE = [1, 2, 3, 4, 5, 7, 9, 13, 17, 25, 33, 49, 65, 97, 129, 193, 257, 385, 513, 769,
+             1025, 1537, 2049, 3073, 4097, 6145, 8193, 12289, 16385, 24577, 32769, 49153]
+DIST_EXTRA = [0, 0, 0, 0, 1, 1, 2, 2, 3, 3, 4, 4, 5, 5, 6, 6, 7, 7, 8, 8,
+              9, 9, 10, 10, 11, 11, 12, 12, 13, 13, 14, 14]
+CL_ORDER = [16, 17, 18, 0, 8, 7, 9, 6, 10, 5, 11, 4, 12, 3, 13, 2, 14, 1, 15]
+
+
+class BitWriter:
+    def __init__(self):
+        self.out = bytearray()
+        self.acc = 0
+        self.n = 0
+
+    def bits(self, value, count):
+        assert 0 <= value < (1 << count) or count == 0
+        self.acc |= value << self.n
+        self.n += count
+        while self.n >= 8:
+            self.out.append(self.acc & 0xFF)
+            self.acc >>= 8
+            self.n -= 8
+
+    def code(self, code, length):
+        # Huffman codes are sent most significant bit first
+        self.bits(int(format(code, "0%db" % length)[::-1], 2), length)
+
+    def align(self):
+        if self.n:
+            self.bits(0, 8 - self.n)
+
+
+def canonical(lengths):
+    count = [0] * 16
+    for l in lengths:
+        count[l] += 1
+    count[0] = 0
+    code, next_code = 0, [0] * 16
+    for l in range(1, 16):
+        code = (code + count[l - 1]) << 1
+        next_code[l] = code
+    codes = [0] * len(lengths)
+    for sym, l in enumerate(lengths):
+        if l:
+            codes[sym] = next_code[l]
+            next_code[l] += 1
+    return codes
+
+
+def huffman_lengths(freqs, size, limit):
+    heap = [(f, i, (s,)) for i, (s, f) in enumerate(sorted(freqs.items()))]
+    heapq.heapify(heap)
+    lengths = [0] * size
+    if len(heap) == 1:
+        lengths[heap[0][2][0]] = 1
+        return lengths
+    uid = len(heap)
+    while len(heap) > 1:
+        f1, _, s1 = heapq.heappop(heap)
+        f2, _, s2 = heapq.heappop(heap)
+        for s in s1 + s2:
+            lengths[s] += 1
+        heapq.heappush(heap, (f1 + f2, uid, s1 + s2))
+        uid += 1
+    assert max(lengths) <= limit
+    return lengths
+
+
+def length_symbol(length):
+    if length > 258 or length == 258:
+        # Deflate64: code 285 = 3 + 16 extra bits
+        return 28, length - 3
+    for i in range(27, -1, -1):
+        if length >= LEN_BASE[i]:
+            assert length - LEN_BASE[i] < (1 << LEN_EXTRA[i])
+            return i, length - LEN_BASE[i]
+
+
+def dist_symbol(dist):
+    for i in range(31, -1, -1):
+        if dist >= DIST_BASE[i]:
+            return i, dist - DIST_BASE[i]
+
+
+def emit(w, tokens, lit_codes, lit_lens, dist_codes, dist_lens, out):
+    for tok in tokens:
+        if isinstance(tok, int):
+            w.code(lit_codes[tok], lit_lens[tok])
+            out.append(tok)
+            continue
+        dist, length = tok
+        ls, le = length_symbol(length)
+        w.code(lit_codes[257 + ls], lit_lens[257 + ls])
+        w.bits(le, LEN_EXTRA[ls])
+        ds, de = dist_symbol(dist)
+        w.code(dist_codes[ds], dist_lens[ds])
+        w.bits(de, DIST_EXTRA[ds])
+        assert dist <= len(out)
+        for _ in range(length):
+            out.append(out[-dist])
+    w.code(lit_codes[256], lit_lens[256])
+
+
+def rle(lengths):
+    """Code-length alphabet run-length encoding (symbols 16/17/18)."""
+    out, i = [], 0
+    while i < len(lengths):
+        l = lengths[i]
+        run = 1
+        while i + run < len(lengths) and lengths[i + run] == l:
+            run += 1
+        if l == 0 and run >= 11:
+            n = min(run, 138)
+            out.append((18, n - 11))
+        elif l == 0 and run >= 3:
+            n = run
+            out.append((17, n - 3))
+        elif l != 0 and run >= 4:
+            out.append((l, None))
+            n = min(run - 1, 6)
+            out.append((16, n - 3))
+            n += 1
+        else:
+            out.append((l, None))
+            n = 1
+        i += n
+    return out
+
+
+def main():
+    rnd = random.Random(64)
+    random_block = bytes(rnd.getrandbits(8) for _ in range(40000))
+    w = BitWriter()
+    out = bytearray()
+
+    # block 1: stored
+    w.bits(0, 1)
+    w.bits(0, 2)
+    w.align()
+    w.bits(len(random_block), 16)
+    w.bits(len(random_block) ^ 0xFFFF, 16)
+    for b in random_block:
+        w.bits(b, 8)
+    out += random_block
+
+    # block 2: fixed Huffman, 32 five-bit distance codes
+    lit_lens = [8] * 144 + [9] * 112 + [7] * 24 + [8] * 8
+    dist_lens = [5] * 32
+    w.bits(0, 1)
+    w.bits(1, 2)
+    tokens = [(40000, 40000), ord("A"), (1, 65538)] + list(b"deflate64")
+    emit(w, tokens, canonical(lit_lens), lit_lens, canonical(dist_lens), dist_lens, out)
+
+    # block 3: dynamic Huffman
+    tokens = list(b"abcdefghijklmnopqrstuvwxyz known answer\n") + [(60000, 1000), (65536, 200)]
+    lit_freq, dist_freq = {256: 1}, {}
+    for tok in tokens:
+        if isinstance(tok, int):
+            lit_freq[tok] = lit_freq.get(tok, 0) + 1
+        else:
+            ls = 257 + length_symbol(tok[1])[0]
+            ds = dist_symbol(tok[0])[0]
+            lit_freq[ls] = lit_freq.get(ls, 0) + 1
+            dist_freq[ds] = dist_freq.get(ds, 0) + 1
+    lit_lens = huffman_lengths(lit_freq, 286, 15)
+    dist_lens = huffman_lengths(dist_freq, 32, 15)
+    # Deflate64 declares 286 literal/length and 32 distance codes
+    hlit, hdist = 286, 32
+    cl_tokens = rle(lit_lens[:hlit] + dist_lens[:hdist])
+    cl_freq = {}
+    for sym, _ in cl_tokens:
+        cl_freq[sym] = cl_freq.get(sym, 0) + 1
+    assert {16, 17, 18} <= set(cl_freq), cl_freq
+    cl_lens = huffman_lengths(cl_freq, 19, 7)
+    cl_codes = canonical(cl_lens)
+    hclen = 19
+    while cl_lens[CL_ORDER[hclen - 1]] == 0:
+        hclen -= 1
+
+    w.bits(1, 1)
+    w.bits(2, 2)
+    w.bits(hlit - 257, 5)
+    w.bits(hdist - 1, 5)
+    w.bits(hclen - 4, 4)
+    for i in range(hclen):
+        w.bits(cl_lens[CL_ORDER[i]], 3)
+    for sym, extra in cl_tokens:
+        w.code(cl_codes[sym], cl_lens[sym])
+        if sym == 16:
+            w.bits(extra, 2)
+        elif sym == 17:
+            w.bits(extra, 3)
+        elif sym == 18:
+            w.bits(extra, 7)
+    emit(w, tokens, canonical(lit_lens), lit_lens, canonical(dist_lens), dist_lens, out)
+    w.align()
+
+    data = bytes(out)
+    comp = bytes(w.out)
+    crc = zlib.crc32(data)
+    name = b"deflate64.bin"
+    local = struct.pack("<IHHHHHIIIHH", 0x04034B50, 21, 0, 9, 0, 0x21,
+                        crc, len(comp), len(data), len(name), 0) + name
+    central = struct.pack("<IHHHHHHIIIHHHHHII", 0x02014B50, 21, 21, 0, 9, 0, 0x21,
+                          crc, len(comp), len(data), len(name), 0, 0, 0, 0, 0, 0) + name
+    eocd = struct.pack("<IHHHHIIH", 0x06054B50, 0, 0, 1, 1, len(central), len(local) + len(comp), 0)
+    with open("deflate64.zip", "wb") as f:
+        f.write(local + comp + central + eocd)
+    print("size %d crc32 %08x sha256 %s compressed %d" % (len(data), crc, hashlib.sha256(data).hexdigest(), len(comp)))
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,32 @@
+#!/bin/sh
+# Generate the ZipCrypto known-answer fixtures with Info-ZIP zip (independent of the Go reader).
+#
+#   zipcrypto.zip         deflated.txt (deflate) + stored.txt (store), written to a seekable file
+#   zipcrypto-stream.zip  deflated.txt read from stdin and written to a pipe, so the entry
+#                         is named "-" and its sizes are only known from the data descriptor
+#
+# Info-ZIP always sets the data descriptor flag on encrypted entries, so the check byte
+# is the high byte of the DOS modification time. Password: "known answer".
+# Run from this directory: sh zipcrypto.sh
+set -e
+work=$(mktemp -d)
+trap 'rm -rf "$work"' EXIT
+here=$(pwd)
+
+cd "$work"
+i=0
+while [ $i -lt 500 ]; do
+	printf 'ZipCrypto known answer line %04d\n' $i
+	i=$((i + 1))
+done > deflated.txt
+printf 'stored entry, no compression\n' > stored.txt
+touch -d '2024-05-01 12:34:56' deflated.txt stored.txt
+
+zip -q -X -P 'known answer' zipcrypto.zip deflated.txt
+zip -q -X -0 -P 'known answer' zipcrypto.zip stored.txt
+zip -q -X -P 'known answer' - - < deflated.txt | cat > zipcrypto-stream.zip
+
+unzip -q -t -P 'known answer' zipcrypto.zip
+unzip -q -t -P 'known answer' zipcrypto-stream.zip
+cp zipcrypto.zip zipcrypto-stream.zip "$here"
+sha256sum deflated.txt stored.txt
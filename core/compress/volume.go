@@ -0,0 +1,396 @@
+// Package compress /core/compress/volume.go
+package compress
+
+import (
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/GoFurry/gf-file-tool/utils"
+	"github.com/GoFurry/gf-file-tool/utils/compress"
+	"github.com/GoFurry/gf-file-tool/utils/log"
+)
+
+// 分卷只是把一个完整的压缩包按字节切开, 把各卷按顺序拼接成一个只读的 io.ReaderAt,
+// zip.NewReader 等需要随机访问的读取器就能直接在原地读取 .001/.002, 不必先合并到临时文件.
+// 写入时反过来: 压缩器写入按大小切换分卷的 io.Writer, 边压缩边切分, 不需要先生成完整的临时包.
+
+// volumeReader 多个分卷拼接成的只读文件, 普通压缩包视为只有一卷
+type volumeReader struct {
+	paths   []string
+	files   []*os.File
+	offsets []int64 // 每卷在拼接后文件中的起始偏移
+	size    int64
+	checked bool // 各卷大小已按说明文件核对
+}
+
+// archiveVolumes 压缩包的全部分卷路径, 非分卷文件返回自身
+// 有说明文件时按其中的顺序返回, 并核对各卷都存在且大小一致
+func archiveVolumes(path string) ([]string, *splitManifest, error) {
+	if !compress.IsSplitFile(path) {
+		return []string{path}, nil, nil
+	}
+	manifest, err := readSplitManifest(compress.SplitBasePath(path))
+	if err != nil {
+		return nil, nil, err
+	}
+	if manifest == nil {
+		paths, err := compress.SplitVolumePaths(path)
+		return paths, nil, err
+	}
+	if err := volumeProblemsError(manifest.checkPresent()); err != nil {
+		return nil, nil, err
+	}
+	return manifest.volumePaths(), manifest, nil
+}
+
+// openVolumes 打开压缩包的全部分卷
+func openVolumes(path string) (*volumeReader, error) {
+	paths, manifest, err := archiveVolumes(path)
+	if err != nil {
+		return nil, err
+	}
+	v := &volumeReader{paths: paths, checked: manifest != nil}
+	for _, volumePath := range paths {
+		file, err := os.Open(volumePath)
+		if err != nil {
+			_ = v.Close()
+			return nil, fmt.Errorf("打开分卷失败: %s, 错误: %v", volumePath, err)
+		}
+		info, err := file.Stat()
+		if err != nil {
+			_ = file.Close()
+			_ = v.Close()
+			return nil, fmt.Errorf("读取分卷信息失败: %s, 错误: %v", volumePath, err)
+		}
+		v.files = append(v.files, file)
+		v.offsets = append(v.offsets, v.size)
+		v.size += info.Size()
+	}
+	if v.checked {
+		return v, nil
+	}
+	if err := v.checkSizes(); err != nil {
+		_ = v.Close()
+		return nil, err
+	}
+	return v, nil
+}
+
+// checkSizes 除最后一卷外各卷大小应相同, 较小的分卷被截断
+// 最后一卷本来就可能较小, 是否完整由各格式读取时发现
+func (v *volumeReader) checkSizes() error {
+	volumeSize := int64(0)
+	for i := range v.offsets {
+		volumeSize = max(volumeSize, v.volumeSize(i))
+	}
+	for i := 0; i < len(v.offsets)-1; i++ {
+		if size := v.volumeSize(i); size < volumeSize {
+			return fmt.Errorf("分卷被截断: %s (%d 字节, 其他分卷为 %d 字节)", v.paths[i], size, volumeSize)
+		}
+	}
+	return nil
+}
+
+// volumeSize 第 i 卷 (从 0 开始) 的大小
+func (v *volumeReader) volumeSize(i int) int64 {
+	if i+1 < len(v.offsets) {
+		return v.offsets[i+1] - v.offsets[i]
+	}
+	return v.size - v.offsets[i]
+}
+
+// Size 拼接后的总大小
+func (v *volumeReader) Size() int64 { return v.size }
+
+// Paths 分卷路径
+func (v *volumeReader) Paths() []string { return v.paths }
+
+// ReadAt 跨卷读取
+func (v *volumeReader) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, fmt.Errorf("无效的读取偏移: %d", off)
+	}
+	if off >= v.size {
+		return 0, io.EOF
+	}
+	// 定位 off 所在的分卷
+	idx := sort.Search(len(v.offsets), func(i int) bool { return v.offsets[i] > off }) - 1
+
+	n := 0
+	for n < len(p) && idx < len(v.files) {
+		volumeEnd := v.offsets[idx] + v.volumeSize(idx)
+		chunk := p[n:]
+		if remain := volumeEnd - off; int64(len(chunk)) > remain {
+			chunk = chunk[:remain]
+		}
+		read, err := v.files[idx].ReadAt(chunk, off-v.offsets[idx])
+		n += read
+		off += int64(read)
+		if err != nil && err != io.EOF {
+			return n, fmt.Errorf("读取分卷失败: %s, 错误: %v", v.paths[idx], err)
+		}
+		if read < len(chunk) {
+			return n, fmt.Errorf("分卷被截断: %s", v.paths[idx])
+		}
+		idx++
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// describe 为读取压缩包结构失败的错误补充分卷提示: 没有说明文件时最后一卷的大小无法核对, 被截断时只能由格式解析发现
+func (v *volumeReader) describe(err error) error {
+	if len(v.paths) < 2 || v.checked {
+		return err
+	}
+	return fmt.Errorf("%v (共 %d 卷, 最后一卷 %s 可能被截断, 或缺少之后的分卷)", err, len(v.paths), v.paths[len(v.paths)-1])
+}
+
+// Close 关闭全部分卷
+func (v *volumeReader) Close() error {
+	var firstErr error
+	for _, file := range v.files {
+		if err := file.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	v.files = nil
+	return firstErr
+}
+
+// archiveCRC32 压缩包的 CRC32, 分卷按拼接后的内容计算
+func archiveCRC32(path string) (string, error) {
+	volumes, err := openVolumes(path)
+	if err != nil {
+		return "", err
+	}
+	defer volumes.Close()
+	hash := crc32.NewIEEE()
+	if _, err := io.Copy(hash, io.NewSectionReader(volumes, 0, volumes.Size())); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%08x", hash.Sum32()), nil
+}
+
+// ============================== 分卷写入 ==============================
+
+// volumeWriter 按大小切换分卷的写入器, 写满一卷后关闭并创建下一卷, 只有最后一卷小于分卷大小
+type volumeWriter struct {
+	base    string   // 压缩包路径, 分卷为 base.001/base.002...
+	suffix  string   // 分卷后缀格式, 空表示 .%03d
+	size    int64    // 分卷大小
+	parity  int      // 恢复卷数量
+	format  string   // 压缩格式, 记录在说明文件中
+	file    *os.File // 当前分卷
+	written int64    // 当前分卷已写入的字节数
+	paths   []string // 已创建的分卷
+
+	// 边写边计算说明文件中的校验值, 回写过已写出的位置后改为关闭时重新读取计算
+	sums      []splitVolume
+	current   *checksum
+	whole     *checksum
+	rewritten bool
+}
+
+// createVolumes 按压缩配置创建分卷写入器, 第一卷在首次写入时创建, 关闭时生成 opts.Parity 个恢复卷
+func createVolumes(opts *CompressOptions) *volumeWriter {
+	return &volumeWriter{
+		base:   opts.OutputPath,
+		suffix: opts.SplitSuffix,
+		size:   opts.SplitSize,
+		parity: opts.Parity,
+		format: opts.Format,
+		whole:  newChecksum(),
+	}
+}
+
+// volumePath 第 n 卷 (从 1 开始) 的路径
+func (v *volumeWriter) volumePath(n int) string {
+	return splitVolumePath(v.base, v.suffix, n)
+}
+
+// splitVolumePath 压缩包 base 第 n 卷的路径, suffix 为空时使用 .%03d
+func splitVolumePath(base, suffix string, n int) string {
+	if suffix == "" {
+		suffix = ".%03d"
+	}
+	return base + fmt.Sprintf(suffix, n)
+}
+
+// Write 顺序写入, 当前分卷写满时切换到下一卷
+func (v *volumeWriter) Write(p []byte) (int, error) {
+	n := 0
+	for len(p) > 0 {
+		if v.file == nil || v.written == v.size {
+			if err := v.next(); err != nil {
+				return n, err
+			}
+		}
+		chunk := p[:min(int64(len(p)), v.size-v.written)]
+		written, err := v.file.Write(chunk)
+		n += written
+		v.written += int64(written)
+		v.current.Write(chunk[:written])
+		v.whole.Write(chunk[:written])
+		if err != nil {
+			return n, fmt.Errorf("写入分卷 %s 失败: %v", v.file.Name(), err)
+		}
+		p = p[written:]
+	}
+	return n, nil
+}
+
+// WriteAt 回写已写出的位置 (如 7z 签名头), 可跨卷
+func (v *volumeWriter) WriteAt(p []byte, off int64) (int, error) {
+	v.rewritten = true
+	n := 0
+	for len(p) > 0 {
+		idx := int(off / v.size)
+		if idx >= len(v.paths) {
+			return n, fmt.Errorf("回写位置超出已写入的分卷: %d", off)
+		}
+		chunk := p[:min(int64(len(p)), v.size-off%v.size)]
+		var written int
+		var err error
+		if idx == len(v.paths)-1 && v.file != nil {
+			written, err = v.file.WriteAt(chunk, off%v.size)
+		} else {
+			written, err = writeFileAt(v.paths[idx], chunk, off%v.size)
+		}
+		n += written
+		if err != nil {
+			return n, fmt.Errorf("回写分卷 %s 失败: %v", v.paths[idx], err)
+		}
+		p = p[written:]
+		off += int64(written)
+	}
+	return n, nil
+}
+
+// writeFileAt 在已关闭的分卷的指定位置写入
+func writeFileAt(path string, p []byte, off int64) (int, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return 0, err
+	}
+	n, err := file.WriteAt(p, off)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return n, err
+}
+
+// next 关闭当前分卷并创建下一卷
+func (v *volumeWriter) next() error {
+	if err := v.closeCurrent(); err != nil {
+		return err
+	}
+	path := v.volumePath(len(v.paths) + 1)
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("创建分卷 %s 失败: %v", path, err)
+	}
+	v.file, v.written, v.current = file, 0, newChecksum()
+	v.paths = append(v.paths, path)
+	return nil
+}
+
+// closeCurrent 关闭当前分卷
+func (v *volumeWriter) closeCurrent() error {
+	if v.file == nil {
+		return nil
+	}
+	path := v.file.Name()
+	err := v.file.Close()
+	v.file = nil
+	if err != nil {
+		return fmt.Errorf("关闭分卷 %s 失败: %v", path, err)
+	}
+	v.sums = append(v.sums, splitVolume{Name: filepath.Base(path), Size: v.written, SHA256: v.current.sha256(), CRC32: v.current.crc32()})
+	if utils.VerboseMode() {
+		log.Success("生成分卷:", path, v.written, "字节")
+	}
+	return nil
+}
+
+// Close 关闭最后一卷, 删除之前同名压缩包留下的多余分卷与恢复卷 (避免读取时被当作后续分卷),
+// 生成恢复卷, 最后写出说明文件
+func (v *volumeWriter) Close() error {
+	if err := v.closeCurrent(); err != nil {
+		return err
+	}
+	if err := v.removeStale(); err != nil {
+		return err
+	}
+	return v.writeManifest()
+}
+
+// removeStale 删除编号在最后一卷之后的旧分卷, 以及超出本次数量的旧恢复卷
+func (v *volumeWriter) removeStale() error {
+	if err := removeNumbered(len(v.paths)+1, v.volumePath, "分卷"); err != nil {
+		return err
+	}
+	return removeNumbered(v.parity+1, func(n int) string { return parityVolumePath(v.base, n) }, "恢复卷")
+}
+
+// removeNumbered 从编号 first 开始依次删除, 遇到第一个不存在的编号为止
+func removeNumbered(first int, path func(int) string, kind string) error {
+	for n := first; ; n++ {
+		path := path(n)
+		if _, err := os.Lstat(path); err != nil {
+			return nil
+		}
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("删除多余的旧%s %s 失败: %v", kind, path, err)
+		}
+		log.Warn("已删除之前留下的多余"+kind+":", path)
+	}
+}
+
+// writeManifest 写出说明文件, 回写过已写出的位置时重新读取各卷计算校验值
+func (v *volumeWriter) writeManifest() error {
+	if v.rewritten {
+		v.whole = newChecksum()
+		for i, path := range v.paths {
+			sum, err := checksumFile(path, v.whole)
+			if err != nil {
+				return fmt.Errorf("计算分卷 %s 校验值失败: %v", path, err)
+			}
+			v.sums[i].SHA256, v.sums[i].CRC32 = sum.sha256(), sum.crc32()
+		}
+	}
+	var parity *splitParity
+	if v.parity > 0 {
+		var err error
+		if parity, err = writeParity(v.base, v.paths, v.sums, v.parity); err != nil {
+			return err
+		}
+	}
+	manifest := &splitManifest{
+		Version:    splitManifestVersion,
+		Archive:    filepath.Base(v.base),
+		Format:     v.format,
+		VolumeSize: v.size,
+		Size:       v.whole.size,
+		SHA256:     v.whole.sha256(),
+		CRC32:      v.whole.crc32(),
+		Volumes:    v.sums,
+		Parity:     parity,
+	}
+	if err := manifest.write(v.base); err != nil {
+		return err
+	}
+	if utils.VerboseMode() {
+		log.Success("生成分卷说明文件:", splitManifestPath(v.base))
+	}
+	return nil
+}
+
+// Paths 已创建的分卷路径
+func (v *volumeWriter) Paths() []string { return v.paths }
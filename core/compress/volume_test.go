@@ -0,0 +1,194 @@
+package compress
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVolumeWriter(t *testing.T) {
+	base := filepath.Join(t.TempDir(), "data.bin")
+	// 之前同名压缩包留下的多余分卷
+	for _, n := range []int{4, 5} {
+		if err := os.WriteFile(splitVolumePath(base, "", n), []byte("stale"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writer := createVolumes(&CompressOptions{OutputPath: base, SplitSize: 10})
+	data := []byte("0123456789abcdefghijklmnopqrstu")
+	for _, chunk := range [][]byte{data[:3], data[3:17], data[17:]} {
+		if n, err := writer.Write(chunk); err != nil || n != len(chunk) {
+			t.Fatalf("Write = %d, %v", n, err)
+		}
+	}
+	// 跨卷回写, 包括已关闭的分卷与当前分卷
+	if _, err := writer.WriteAt([]byte("XYZ"), 9); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := writer.WriteAt([]byte("W"), 30); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := writer.WriteAt([]byte("?"), 40); err == nil {
+		t.Fatal("回写位置超出已写入的分卷应报错")
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := append([]byte(nil), data...)
+	copy(want[9:], "XYZ")
+	want[30] = 'W'
+	if len(writer.Paths()) != 4 {
+		t.Fatalf("分卷 = %v", writer.Paths())
+	}
+	var joined []byte
+	for i, path := range writer.Paths() {
+		part, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i < 3 && len(part) != 10 {
+			t.Fatalf("%s 大小 = %d, 只有最后一卷可以小于分卷大小", path, len(part))
+		}
+		joined = append(joined, part...)
+	}
+	if !bytes.Equal(joined, want) {
+		t.Fatalf("拼接后 = %q, 期望 %q", joined, want)
+	}
+	if _, err := os.Stat(splitVolumePath(base, "", 5)); !os.IsNotExist(err) {
+		t.Fatal("应删除之前留下的多余分卷")
+	}
+}
+
+func TestVolumeReader(t *testing.T) {
+	base := filepath.Join(t.TempDir(), "data.bin")
+	data := []byte("0123456789abcdefghijklmnopqrstu")
+	writer := createVolumes(&CompressOptions{OutputPath: base, SplitSize: 7})
+	if _, err := writer.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	volumes, err := openVolumes(splitVolumePath(base, "", 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer volumes.Close()
+	if volumes.Size() != int64(len(data)) || len(volumes.Paths()) != 5 {
+		t.Fatalf("Size = %d, Paths = %v", volumes.Size(), volumes.Paths())
+	}
+	for _, tc := range []struct{ off, n int }{{0, 31}, {5, 10}, {13, 1}, {27, 4}} {
+		buf := make([]byte, tc.n)
+		if n, err := volumes.ReadAt(buf, int64(tc.off)); err != nil || n != tc.n || !bytes.Equal(buf, data[tc.off:tc.off+tc.n]) {
+			t.Errorf("ReadAt(%d, %d) = %q, %v", tc.off, tc.n, buf[:n], err)
+		}
+	}
+	buf := make([]byte, 8)
+	if n, err := volumes.ReadAt(buf, 28); err != io.EOF || n != 3 {
+		t.Fatalf("读到末尾 = %d, %v, 期望 3, EOF", n, err)
+	}
+
+}
+
+func TestSplitVolumesDamaged(t *testing.T) {
+	files := testTreeFiles()
+	const volumeSize = 16 << 10
+	for _, format := range []string{"zip", "7z", "tar.zst"} {
+		t.Run(format, func(t *testing.T) {
+			output := filepath.Join(t.TempDir(), "split."+format)
+			writeTestArchive(t, files, CompressOptions{Format: format, OutputPath: output, SplitSize: volumeSize})
+			first := splitVolumePath(output, "", 1)
+			paths, _, err := archiveVolumes(first)
+			if err != nil || len(paths) < 3 {
+				t.Fatalf("分卷 = %v, %v", paths, err)
+			}
+
+			// 原地读取, 不会生成合并后的临时文件
+			if err := RunDecompress(DecompressOptions{SourcePath: first, OutputDir: t.TempDir()}); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := os.Stat(first + ".merged"); !os.IsNotExist(err) {
+				t.Fatal("不应生成合并后的临时文件")
+			}
+
+			// 中间的分卷被截断
+			data, err := os.ReadFile(paths[1])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(paths[1], data[:100], 0o644); err != nil {
+				t.Fatal(err)
+			}
+			err = RunDecompress(DecompressOptions{SourcePath: first, OutputDir: t.TempDir()})
+			if err == nil || !strings.Contains(err.Error(), "截断") || !strings.Contains(err.Error(), paths[1]) {
+				t.Fatalf("期望截断错误, 得到 %v", err)
+			}
+
+			// 中间缺卷
+			if err := os.Remove(paths[1]); err != nil {
+				t.Fatal(err)
+			}
+			err = RunDecompress(DecompressOptions{SourcePath: first, OutputDir: t.TempDir()})
+			if err == nil || !strings.Contains(err.Error(), paths[1]) {
+				t.Fatalf("期望缺少 %s 的错误, 得到 %v", paths[1], err)
+			}
+		})
+	}
+}
+
+func TestSplitRoundTrip(t *testing.T) {
+	files := testTreeFiles()
+	const volumeSize = 16 << 10
+	for _, format := range []string{"zip", "7z", "tar", "targz", "tar.zst"} {
+		t.Run(format, func(t *testing.T) {
+			output := filepath.Join(t.TempDir(), "split."+format)
+			writeTestArchive(t, files, CompressOptions{Format: format, OutputPath: output, SplitSize: volumeSize, Verify: true})
+			if _, err := os.Stat(output); !os.IsNotExist(err) {
+				t.Fatal("分卷压缩不应生成完整的压缩包")
+			}
+
+			paths, _, err := archiveVolumes(splitVolumePath(output, "", 1))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(paths) < 2 {
+				t.Fatalf("分卷数 = %d", len(paths))
+			}
+			var joined []byte
+			for i, path := range paths {
+				data, err := os.ReadFile(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if i < len(paths)-1 && len(data) != volumeSize {
+					t.Fatalf("%s 大小 = %d", path, len(data))
+				}
+				joined = append(joined, data...)
+			}
+
+			// 从第一卷原地解压
+			target := t.TempDir()
+			if err := RunDecompress(DecompressOptions{SourcePath: paths[0], OutputDir: target}); err != nil {
+				t.Fatal(err)
+			}
+			assertSameFiles(t, readOutputTree(t, target), files)
+
+			// 分卷只是按字节切开, 拼接后就是完整的压缩包
+			whole := filepath.Join(t.TempDir(), "whole."+format)
+			if err := os.WriteFile(whole, joined, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			target = t.TempDir()
+			if err := RunDecompress(DecompressOptions{SourcePath: whole, OutputDir: target}); err != nil {
+				t.Fatal(err)
+			}
+			assertSameFiles(t, readOutputTree(t, target), files)
+		})
+	}
+}
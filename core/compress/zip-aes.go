@@ -0,0 +1,439 @@
+// Package compress /core/compress/zip-aes.go
+package compress
+
+import (
+	"archive/zip"
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+)
+
+// WinZip AES 是 7-Zip/WinZip/Info-ZIP 等工具通用的 zip 加密标准:
+//   - 压缩方式记为 99, 真实压缩方式写在 0x9901 扩展字段中
+//   - 数据区 = 盐值 + 2 字节密码校验值 + AES-CTR 密文 + 10 字节 HMAC-SHA1 认证码
+//   - 密钥由 PBKDF2-HMAC-SHA1 (1000 次迭代) 从密码派生, 依次切出加密密钥、认证密钥、校验值
+//   - AE-2 不写 CRC32 (置 0), 完整性由认证码保证, 避免 CRC 泄露明文信息
+// 注意 CTR 计数器是从 1 开始的小端序整数, 与 crypto/cipher 的大端序 CTR 不同, 需要自行实现.
+
+const (
+	zipMethodWinZipAES  = 99     // WinZip AES 压缩方式
+	zipExtraWinZipAES   = 0x9901 // WinZip AES 扩展字段
+	winZipAESVersion1   = 1      // AE-1 保留 CRC32
+	winZipAESVersion2   = 2      // AE-2 CRC32 置 0
+	winZipAESIterations = 1000   // PBKDF2 迭代次数
+	winZipAESPVLen      = 2      // 密码校验值长度
+	winZipAESAuthLen    = 10     // 认证码长度
+	winZipAESReaderVer  = 51     // 解压所需版本 5.1
+)
+
+var (
+	// errAuthCodeMismatch WinZip AES 认证码不匹配, 密文被截断或篡改
+	errAuthCodeMismatch = errors.New("认证码不匹配")
+	// errWrongPassword 密码校验值不匹配, WinZip AES 与 ZipCrypto 共用, 调用方用 errors.Is 判断
+	errWrongPassword = errors.New("密码错误")
+)
+
+// winZipAESExtra 0x9901 扩展字段内容
+type winZipAESExtra struct {
+	Version  uint16 // AE-1/AE-2
+	Strength byte   // 1/2/3 对应 AES-128/192/256
+	Method   uint16 // 加密前的真实压缩方式
+}
+
+// winZipAESStrength 密钥长度转换为强度标记
+func winZipAESStrength(keyLength int) (byte, error) {
+	switch keyLength {
+	case 16:
+		return 1, nil
+	case 24:
+		return 2, nil
+	case 32, 0:
+		return 3, nil
+	default:
+		return 0, fmt.Errorf("无效的密钥长度: %d, 仅支持 16/24/32", keyLength)
+	}
+}
+
+// winZipAESKeyLen 强度标记对应的密钥长度, 盐值长度为其一半
+func winZipAESKeyLen(strength byte) (int, error) {
+	if strength < 1 || strength > 3 {
+		return 0, fmt.Errorf("无效的 AES 强度标记: %d", strength)
+	}
+	return 8 + 8*int(strength), nil
+}
+
+// parseWinZipAESExtra 从扩展字段中查找 0x9901
+func parseWinZipAESExtra(extra []byte) (*winZipAESExtra, bool) {
+	for len(extra) >= 4 {
+		tag := binary.LittleEndian.Uint16(extra[0:2])
+		size := int(binary.LittleEndian.Uint16(extra[2:4]))
+		if len(extra) < 4+size {
+			return nil, false
+		}
+		field := extra[4 : 4+size]
+		extra = extra[4+size:]
+		if tag != zipExtraWinZipAES || size < 7 || field[2] != 'A' || field[3] != 'E' {
+			continue
+		}
+		return &winZipAESExtra{
+			Version:  binary.LittleEndian.Uint16(field[0:2]),
+			Strength: field[4],
+			Method:   binary.LittleEndian.Uint16(field[5:7]),
+		}, true
+	}
+	return nil, false
+}
+
+// encode 编码为扩展字段 (含 4 字节头)
+func (e *winZipAESExtra) encode() []byte {
+	buf := make([]byte, 11)
+	binary.LittleEndian.PutUint16(buf[0:2], zipExtraWinZipAES)
+	binary.LittleEndian.PutUint16(buf[2:4], 7)
+	binary.LittleEndian.PutUint16(buf[4:6], e.Version)
+	buf[6], buf[7] = 'A', 'E'
+	buf[8] = e.Strength
+	binary.LittleEndian.PutUint16(buf[9:11], e.Method)
+	return buf
+}
+
+// deriveWinZipAESKeys 派生加密密钥、认证密钥与密码校验值
+func deriveWinZipAESKeys(password string, salt []byte, keyLen int) (encKey, authKey, verifier []byte, err error) {
+	derived, err := pbkdf2.Key(sha1.New, password, salt, winZipAESIterations, 2*keyLen+winZipAESPVLen)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("派生密钥失败: %v", err)
+	}
+	return derived[:keyLen], derived[keyLen : 2*keyLen], derived[2*keyLen:], nil
+}
+
+// winZipAESCTR 小端序计数器的 AES-CTR
+type winZipAESCTR struct {
+	block   cipher.Block
+	counter [aes.BlockSize]byte
+	stream  [aes.BlockSize]byte
+	pos     int
+}
+
+func newWinZipAESCTR(key []byte) (*winZipAESCTR, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("初始化 AES 失败: %v", err)
+	}
+	return &winZipAESCTR{block: block, pos: aes.BlockSize}, nil
+}
+
+// XORKeyStream 加解密相同
+func (c *winZipAESCTR) XORKeyStream(dst, src []byte) {
+	for i := range src {
+		if c.pos == aes.BlockSize {
+			// 计数器 +1 (小端序)
+			for j := range c.counter {
+				c.counter[j]++
+				if c.counter[j] != 0 {
+					break
+				}
+			}
+			c.block.Encrypt(c.stream[:], c.counter[:])
+			c.pos = 0
+		}
+		dst[i] = src[i] ^ c.stream[c.pos]
+		c.pos++
+	}
+}
+
+// ============================== 写入 ==============================
+
+// winZipAESWriter 加密写入器, 创建时写出盐值与校验值, Close 时写出认证码
+type winZipAESWriter struct {
+	w   io.Writer
+	ctr *winZipAESCTR
+	mac hash.Hash
+	buf []byte
+}
+
+func newWinZipAESWriter(w io.Writer, password string, strength byte) (*winZipAESWriter, error) {
+	keyLen, err := winZipAESKeyLen(strength)
+	if err != nil {
+		return nil, err
+	}
+	salt := make([]byte, keyLen/2)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, fmt.Errorf("生成盐值失败: %v", err)
+	}
+	encKey, authKey, verifier, err := deriveWinZipAESKeys(password, salt, keyLen)
+	if err != nil {
+		return nil, err
+	}
+	ctr, err := newWinZipAESCTR(encKey)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(salt); err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(verifier); err != nil {
+		return nil, err
+	}
+	return &winZipAESWriter{w: w, ctr: ctr, mac: hmac.New(sha1.New, authKey)}, nil
+}
+
+func (a *winZipAESWriter) Write(p []byte) (int, error) {
+	if cap(a.buf) < len(p) {
+		a.buf = make([]byte, len(p))
+	}
+	out := a.buf[:len(p)]
+	a.ctr.XORKeyStream(out, p)
+	a.mac.Write(out)
+	return a.w.Write(out)
+}
+
+// Close 写出认证码, 不关闭底层写入器
+func (a *winZipAESWriter) Close() error {
+	_, err := a.w.Write(a.mac.Sum(nil)[:winZipAESAuthLen])
+	return err
+}
+
+// writeWinZipAESEntry 以 WinZip AES (AE-2) 写入一个条目, 先按 header.Method 压缩再加密
+// header 需已填好名称、时间、权限与压缩方式, comp 为该方式的压缩器,
+// src 为明文数据, onCreate 在条目头写入后调用 (可为 nil), onProgress 报告已写入的明文字节数
+func writeWinZipAESEntry(zipWriter *zip.Writer, header *zip.FileHeader, comp zip.Compressor, src io.Reader, password string, keyLength int, onCreate func() error, onProgress func(int64)) (int64, error) {
+	if password == "" {
+		return 0, fmt.Errorf("zip 加密需要指定密码")
+	}
+	strength, err := winZipAESStrength(keyLength)
+	if err != nil {
+		return 0, err
+	}
+
+	// 压缩方式记为 99, 真实方式写入扩展字段; 数据描述符记录写完后的大小
+	aesExtra := &winZipAESExtra{Version: winZipAESVersion2, Strength: strength, Method: header.Method}
+	header.Method = zipMethodWinZipAES
+	header.Flags |= zipFlagEncrypted | zipFlagDataDescriptor
+	header.Extra = append(header.Extra, aesExtra.encode()...)
+	header.CRC32 = 0
+	header.ReaderVersion = winZipAESReaderVer
+	header.CreatorVersion = header.CreatorVersion&0xff00 | winZipAESReaderVer
+	if !header.Modified.IsZero() {
+		header.ModifiedDate, header.ModifiedTime = zipDosTime(header.Modified)
+	}
+	if !isASCII(header.Name) {
+		header.Flags |= 0x800 // 文件名 UTF-8
+	}
+
+	rawWriter, err := zipWriter.CreateRaw(header)
+	if err != nil {
+		return 0, fmt.Errorf("创建 Zip 写入器失败: %v", err)
+	}
+	if onCreate != nil {
+		if err := onCreate(); err != nil {
+			return 0, err
+		}
+	}
+	counter := &countWriter{w: rawWriter}
+	aesWriter, err := newWinZipAESWriter(counter, password, strength)
+	if err != nil {
+		return 0, err
+	}
+	compWriter, err := comp(aesWriter)
+	if err != nil {
+		return 0, err
+	}
+
+	// 分块拷贝
+	buf := make([]byte, 4*1024*1024) // 4MB 缓冲区
+	totalWritten := int64(0)
+	for {
+		n, err := src.Read(buf)
+		if err != nil && err != io.EOF {
+			return totalWritten, fmt.Errorf("读取文件失败: %v", err)
+		}
+		if n == 0 {
+			break
+		}
+		if _, err := compWriter.Write(buf[:n]); err != nil {
+			return totalWritten, fmt.Errorf("加密写入失败: %v", err)
+		}
+		totalWritten += int64(n)
+		if onProgress != nil {
+			onProgress(totalWritten)
+		}
+	}
+	if err := compWriter.Close(); err != nil {
+		return totalWritten, fmt.Errorf("关闭压缩写入器失败: %v", err)
+	}
+	if err := aesWriter.Close(); err != nil {
+		return totalWritten, fmt.Errorf("写入认证码失败: %v", err)
+	}
+
+	// CreateRaw 不统计大小, 需在下一个条目开始前回填, 数据描述符与中央目录使用这些值
+	header.CompressedSize64 = uint64(counter.n)
+	header.UncompressedSize64 = uint64(totalWritten)
+	header.CompressedSize = uint32(min(header.CompressedSize64, 0xFFFFFFFF))
+	header.UncompressedSize = uint32(min(header.UncompressedSize64, 0xFFFFFFFF))
+	return totalWritten, nil
+}
+
+// countWriter 统计写入字节数
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// ============================== 读取 ==============================
+
+// winZipAESReader 解密读取器
+// payload 为密文长度 (不含盐值/校验值/认证码), -1 表示未知 (流式数据描述符),
+// 此时由调用方在压缩数据结束后调用 Authenticate 读取认证码
+type winZipAESReader struct {
+	r         *bufio.Reader
+	ctr       *winZipAESCTR
+	mac       hash.Hash
+	remaining int64
+	verified  bool
+	name      string
+	one       [1]byte
+}
+
+// newWinZipAESReader 读取盐值并校验密码
+func newWinZipAESReader(r *bufio.Reader, name, password string, strength byte, payload int64) (*winZipAESReader, error) {
+	keyLen, err := winZipAESKeyLen(strength)
+	if err != nil {
+		return nil, err
+	}
+	salt := make([]byte, keyLen/2)
+	if _, err := io.ReadFull(r, salt); err != nil {
+		return nil, fmt.Errorf("读取盐值失败: %s, 错误: %v", name, err)
+	}
+	verifier := make([]byte, winZipAESPVLen)
+	if _, err := io.ReadFull(r, verifier); err != nil {
+		return nil, fmt.Errorf("读取密码校验值失败: %s, 错误: %v", name, err)
+	}
+	encKey, authKey, expected, err := deriveWinZipAESKeys(password, salt, keyLen)
+	if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal(verifier, expected) {
+		return nil, fmt.Errorf("%w: %s", errWrongPassword, name)
+	}
+	ctr, err := newWinZipAESCTR(encKey)
+	if err != nil {
+		return nil, err
+	}
+	if payload >= 0 {
+		payload -= int64(len(salt) + winZipAESPVLen + winZipAESAuthLen)
+		if payload < 0 {
+			return nil, fmt.Errorf("加密数据长度无效: %s", name)
+		}
+	}
+	return &winZipAESReader{r: r, ctr: ctr, mac: hmac.New(sha1.New, authKey), remaining: payload, name: name}, nil
+}
+
+func (a *winZipAESReader) Read(p []byte) (int, error) {
+	if a.remaining == 0 {
+		return 0, io.EOF
+	}
+	if a.remaining > 0 && int64(len(p)) > a.remaining {
+		p = p[:a.remaining]
+	}
+	n, err := a.r.Read(p)
+	a.mac.Write(p[:n])
+	a.ctr.XORKeyStream(p[:n], p[:n])
+	if a.remaining > 0 {
+		a.remaining -= int64(n)
+	}
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+// ReadByte 逐字节读取, flate 借此精确停在压缩数据结尾, 不会多读认证码
+func (a *winZipAESReader) ReadByte() (byte, error) {
+	if a.remaining == 0 {
+		return 0, io.EOF
+	}
+	b, err := a.r.ReadByte()
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, err
+	}
+	a.one[0] = b
+	a.mac.Write(a.one[:])
+	a.ctr.XORKeyStream(a.one[:], a.one[:])
+	if a.remaining > 0 {
+		a.remaining--
+	}
+	return a.one[0], nil
+}
+
+// Authenticate 读完剩余密文并核对认证码, 可重复调用
+func (a *winZipAESReader) Authenticate() error {
+	if a.verified {
+		return nil
+	}
+	if a.remaining > 0 {
+		if _, err := io.Copy(io.Discard, a); err != nil {
+			return fmt.Errorf("读取加密数据失败: %s, 错误: %v", a.name, err)
+		}
+	}
+	code := make([]byte, winZipAESAuthLen)
+	if _, err := io.ReadFull(a.r, code); err != nil {
+		return fmt.Errorf("读取认证码失败: %s, 错误: %v", a.name, err)
+	}
+	if !hmac.Equal(code, a.mac.Sum(nil)[:winZipAESAuthLen]) {
+		return fmt.Errorf("%w: %s (数据损坏或被篡改)", errAuthCodeMismatch, a.name)
+	}
+	a.verified = true
+	return nil
+}
+
+// winZipAESEntryReader 解密并解压后的明文读取器, 读到结尾时核对认证码
+type winZipAESEntryReader struct {
+	data io.Reader
+	aes  *winZipAESReader
+}
+
+func (e *winZipAESEntryReader) Read(p []byte) (int, error) {
+	n, err := e.data.Read(p)
+	if err == io.EOF {
+		if authErr := e.aes.Authenticate(); authErr != nil {
+			return n, authErr
+		}
+	}
+	return n, err
+}
+
+// openWinZipAES 解密 WinZip AES 条目
+// raw 为条目的原始数据 (盐值起), payload 为原始数据总长度, -1 表示未知
+func openWinZipAES(raw *bufio.Reader, name, password string, extra *winZipAESExtra, payload int64) (io.Reader, error) {
+	if password == "" {
+		return nil, fmt.Errorf("文件 %s 已加密, 请使用 -e -k 指定密码", name)
+	}
+	aesReader, err := newWinZipAESReader(raw, name, password, extra.Strength, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	// 长度未知时解码器必须逐字节读取, 才能停在认证码之前
+	data, err := newZipMethodReader(aesReader, extra.Method, name, payload >= 0)
+	if err != nil {
+		return nil, err
+	}
+	return &winZipAESEntryReader{data: data, aes: aesReader}, nil
+}
@@ -0,0 +1,185 @@
+package compress
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// 已知答案由 Python hashlib/hmac 与 openssl aes-256-ecb 独立计算:
+// PBKDF2-HMAC-SHA1("password", 00..0f, 1000 次) 依次切出 32 字节加密密钥、32 字节认证密钥、2 字节校验值,
+// 密钥流为 AES(加密密钥, 小端序计数器 1, 2, 3, 4), 认证码为密文 HMAC-SHA1 的前 10 字节
+var winZipAESVector = struct {
+	password, plain                 string
+	salt, encKey, authKey, verifier string
+	keystream, ciphertext, authCode string
+}{
+	password:   "password",
+	plain:      "WinZip AES-256 known answer: the counter starts at 1",
+	salt:       "000102030405060708090a0b0c0d0e0f",
+	encKey:     "0309e2fe4e0bdfe7d0fe4828d41c234416e2d9bfb61cdd8f643a11cfbfdfc119",
+	authKey:    "e78b0eb3d9243415743b2fe4f5e67c6689bd2c3e512d0fda622dd7d1b0565b83",
+	verifier:   "256b",
+	keystream:  "8bd4ca5bd83197a34d479480bba538462d67f1c9ccd9f3e1149aa3ed2cb3c892766c84fbc3b67940ad0457dd1a4e6df135d98fdeee5c0e3f5149c1b702f2382d",
+	ciphertext: "dcbda401b141b7e20814b9b28e93182d430886a7ecb89d9263ffd1d70cc7a0f7560feb8eadc21c328d7723bc683a1ed154adafef",
+	authCode:   "6815a0fce93f8e4a3e0f",
+}
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// winZipAESVectorPayload 条目数据区: 盐值 + 校验值 + 密文 + 认证码
+func winZipAESVectorPayload(t *testing.T) []byte {
+	v := winZipAESVector
+	var payload []byte
+	for _, part := range []string{v.salt, v.verifier, v.ciphertext, v.authCode} {
+		payload = append(payload, mustHex(t, part)...)
+	}
+	return payload
+}
+
+func TestDeriveWinZipAESKeys(t *testing.T) {
+	v := winZipAESVector
+	encKey, authKey, verifier, err := deriveWinZipAESKeys(v.password, mustHex(t, v.salt), 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		name      string
+		got, want []byte
+	}{
+		{"加密密钥", encKey, mustHex(t, v.encKey)},
+		{"认证密钥", authKey, mustHex(t, v.authKey)},
+		{"校验值", verifier, mustHex(t, v.verifier)},
+	} {
+		if !bytes.Equal(c.got, c.want) {
+			t.Errorf("%s = %x, 期望 %x", c.name, c.got, c.want)
+		}
+	}
+}
+
+func TestWinZipAESCTRLittleEndianFromOne(t *testing.T) {
+	v := winZipAESVector
+	ctr, err := newWinZipAESCTR(mustHex(t, v.encKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 分两次调用, 确认跨块时计数器状态延续
+	stream := make([]byte, len(mustHex(t, v.keystream)))
+	ctr.XORKeyStream(stream[:5], stream[:5])
+	ctr.XORKeyStream(stream[5:], stream[5:])
+	if want := mustHex(t, v.keystream); !bytes.Equal(stream, want) {
+		t.Fatalf("密钥流 = %x, 期望 %x", stream, want)
+	}
+}
+
+func TestOpenWinZipAESKnownAnswer(t *testing.T) {
+	v := winZipAESVector
+	payload := winZipAESVectorPayload(t)
+	extra := &winZipAESExtra{Version: winZipAESVersion2, Strength: 3, Method: zip.Store}
+
+	reader, err := openWinZipAES(bufio.NewReader(bytes.NewReader(payload)), "vector.txt", v.password, extra, int64(len(payload)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != v.plain {
+		t.Fatalf("明文 = %q, 期望 %q", got, v.plain)
+	}
+
+	if _, err := openWinZipAES(bufio.NewReader(bytes.NewReader(payload)), "vector.txt", "wrong", extra, int64(len(payload))); !errors.Is(err, errWrongPassword) {
+		t.Fatalf("错误密码应当报错, 实际: %v", err)
+	}
+}
+
+func TestOpenWinZipAESRejectsTampering(t *testing.T) {
+	v := winZipAESVector
+	extra := &winZipAESExtra{Version: winZipAESVersion2, Strength: 3, Method: zip.Store}
+	dataStart := len(mustHex(t, v.salt)) + winZipAESPVLen
+
+	for name, offset := range map[string]int{
+		"密文":  dataStart + 7,
+		"认证码": len(winZipAESVectorPayload(t)) - 1,
+	} {
+		payload := winZipAESVectorPayload(t)
+		payload[offset] ^= 0x01
+		reader, err := openWinZipAES(bufio.NewReader(bytes.NewReader(payload)), "vector.txt", v.password, extra, int64(len(payload)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.ReadAll(reader); !errors.Is(err, errAuthCodeMismatch) {
+			t.Errorf("篡改%s后应返回 errAuthCodeMismatch, 实际: %v", name, err)
+		}
+	}
+}
+
+func TestWinZipAESRoundTrip(t *testing.T) {
+	files := testTreeFiles()
+	for _, keyLength := range []int{16, 24, 32} {
+		t.Run(fmt.Sprintf("aes-%d", keyLength*8), func(t *testing.T) {
+			dir := t.TempDir()
+			archive := filepath.Join(dir, "aes.zip")
+			err := RunCompress(CompressOptions{
+				Entries:    writeTestTree(t, files),
+				OutputPath: archive,
+				Format:     "zip",
+				Encrypt:    true,
+				Password:   "right",
+				KeyLength:  keyLength,
+				Level:      -1,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// 文件条目为 AE-2: 方式 99, 扩展字段记录强度与真实压缩方式, CRC32 置 0
+			reader, err := zip.OpenReader(archive)
+			if err != nil {
+				t.Fatal(err)
+			}
+			strength, _ := winZipAESStrength(keyLength)
+			for _, file := range reader.File {
+				if file.FileInfo().IsDir() {
+					continue
+				}
+				extra, ok := parseWinZipAESExtra(file.Extra)
+				if !ok || file.Method != zipMethodWinZipAES || extra.Version != winZipAESVersion2 || extra.Strength != strength {
+					t.Errorf("%s: AE-2 头部不正确: method=%d extra=%+v", file.Name, file.Method, extra)
+				}
+				if file.CRC32 != 0 {
+					t.Errorf("%s: AE-2 的 CRC32 应为 0, 实际 %08x", file.Name, file.CRC32)
+				}
+			}
+			_ = reader.Close()
+
+			key := []byte("right")
+			output := filepath.Join(dir, "out")
+			err = RunDecompress(DecompressOptions{SourcePath: archive, OutputDir: output, Encrypt: true, Key: key, Password: "right"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertSameFiles(t, readOutputTree(t, output), files)
+
+			err = RunDecompress(DecompressOptions{SourcePath: archive, OutputDir: filepath.Join(dir, "wrong"), Encrypt: true, Key: key, Password: "wrong"})
+			if err == nil || !strings.Contains(err.Error(), "密码错误") {
+				t.Fatalf("错误密码应当报错, 实际: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+// Package compress /core/compress/zip-crypto.go
+package compress
+
+import (
+	"bufio"
+	"fmt"
+	"hash/crc32"
+	"io"
+
+	"github.com/GoFurry/gf-file-tool/utils/log"
+)
+
+// ZipCrypto 是 PKWARE 最早的 zip 加密方式 (通用标志位 bit 0, 没有 0x9901 扩展字段).
+// 它用三个 32 位密钥组成的流密码逐字节异或, 可以被已知明文攻击在很短时间内破解,
+// 这里只提供读取以兼容旧压缩包, 加密写入一律使用 WinZip AES.
+// 数据区开头有 12 字节加密头, 解密后最后一个字节应等于 CRC32 的最高字节
+// (使用数据描述符时 CRC 未知, 改为比对修改时间的高字节), 可据此快速判断密码是否正确.
+
+const zipCryptoHeaderLen = 12
+
+// zipCryptoKeys ZipCrypto 密钥状态
+type zipCryptoKeys [3]uint32
+
+func newZipCryptoKeys(password string) *zipCryptoKeys {
+	keys := &zipCryptoKeys{0x12345678, 0x23456789, 0x34567890}
+	for i := 0; i < len(password); i++ {
+		keys.update(password[i])
+	}
+	return keys
+}
+
+// update 用明文字节更新密钥
+func (k *zipCryptoKeys) update(b byte) {
+	k[0] = crc32Update(k[0], b)
+	k[1] = (k[1]+k[0]&0xff)*134775813 + 1
+	k[2] = crc32Update(k[2], byte(k[1]>>24))
+}
+
+// decryptByte 解密单个字节并更新密钥
+func (k *zipCryptoKeys) decryptByte(c byte) byte {
+	temp := (k[2] | 2) & 0xffff // 乘法需在 32 位下进行, 不能用 uint16 截断
+	plain := c ^ byte((temp*(temp^1))>>8)
+	k.update(plain)
+	return plain
+}
+
+// crc32Update 单字节 CRC32 (不做首尾取反), ZipCrypto 密钥更新使用
+func crc32Update(crc uint32, b byte) uint32 {
+	return crc32.IEEETable[byte(crc)^b] ^ crc>>8
+}
+
+// zipCryptoReader 解密读取器, remaining 为 -1 时长度未知 (流式数据描述符)
+type zipCryptoReader struct {
+	r         *bufio.Reader
+	keys      *zipCryptoKeys
+	remaining int64
+}
+
+// newZipCryptoReader 读取并校验 12 字节加密头
+// check 为期望的校验字节: CRC32 最高字节, 或使用数据描述符时修改时间的高字节
+func newZipCryptoReader(r *bufio.Reader, name, password string, check byte, payload int64) (*zipCryptoReader, error) {
+	keys := newZipCryptoKeys(password)
+	header := make([]byte, zipCryptoHeaderLen)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("读取加密头失败: %s, 错误: %v", name, err)
+	}
+	for i := range header {
+		header[i] = keys.decryptByte(header[i])
+	}
+	if header[zipCryptoHeaderLen-1] != check {
+		return nil, fmt.Errorf("%w: %s", errWrongPassword, name)
+	}
+	if payload >= 0 {
+		payload -= zipCryptoHeaderLen
+		if payload < 0 {
+			return nil, fmt.Errorf("加密数据长度无效: %s", name)
+		}
+	}
+	return &zipCryptoReader{r: r, keys: keys, remaining: payload}, nil
+}
+
+func (z *zipCryptoReader) Read(p []byte) (int, error) {
+	if z.remaining == 0 {
+		return 0, io.EOF
+	}
+	if z.remaining > 0 && int64(len(p)) > z.remaining {
+		p = p[:z.remaining]
+	}
+	n, err := z.r.Read(p)
+	for i := 0; i < n; i++ {
+		p[i] = z.keys.decryptByte(p[i])
+	}
+	if z.remaining > 0 {
+		z.remaining -= int64(n)
+	}
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+// ReadByte 逐字节读取, 流式 deflate 借此停在压缩数据结尾
+func (z *zipCryptoReader) ReadByte() (byte, error) {
+	if z.remaining == 0 {
+		return 0, io.EOF
+	}
+	b, err := z.r.ReadByte()
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, err
+	}
+	if z.remaining > 0 {
+		z.remaining--
+	}
+	return z.keys.decryptByte(b), nil
+}
+
+// openZipCrypto 解密 ZipCrypto 条目并解压
+// raw 为条目原始数据 (加密头起), payload 为原始数据总长度, -1 表示未知
+func openZipCrypto(raw *bufio.Reader, name, password string, method uint16, check byte, payload int64) (io.Reader, error) {
+	if password == "" {
+		return nil, fmt.Errorf("文件 %s 已加密, 请使用 -e -k 指定密码", name)
+	}
+	cryptoReader, err := newZipCryptoReader(raw, name, password, check, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return newZipMethodReader(cryptoReader, method, name, payload >= 0)
+}
+
+// zipCryptoCheckByte 加密头的期望校验字节
+func zipCryptoCheckByte(flags uint16, crc uint32, modifiedTime uint16) byte {
+	if flags&zipFlagDataDescriptor != 0 {
+		return byte(modifiedTime >> 8)
+	}
+	return byte(crc >> 24)
+}
+
+// warnZipCrypto 提示 ZipCrypto 的安全风险
+func warnZipCrypto() {
+	log.Warn("压缩包使用 ZipCrypto 传统加密, 该算法可被已知明文攻击快速破解, 仅适合兼容旧文件, 建议解压后使用 -e 重新压缩 (WinZip AES)")
+}
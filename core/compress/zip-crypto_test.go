@@ -0,0 +1,139 @@
+package compress
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// ZipCrypto 已知答案: testdata/zipcrypto.sh 使用 Info-ZIP zip -P 生成, 密码 "known answer"
+const zipCryptoFixturePassword = "known answer"
+
+// zipCryptoFixturePlain 生成脚本中的明文, 附带 sha256 防止两边不一致
+func zipCryptoFixturePlain(t *testing.T) map[string][]byte {
+	t.Helper()
+	var deflated bytes.Buffer
+	for i := 0; i < 500; i++ {
+		fmt.Fprintf(&deflated, "ZipCrypto known answer line %04d\n", i)
+	}
+	files := map[string][]byte{
+		"deflated.txt": deflated.Bytes(),
+		"stored.txt":   []byte("stored entry, no compression\n"),
+	}
+	for name, want := range map[string]string{
+		"deflated.txt": "3ad781417c26696c518687f9a0a15a0b9fd3ea7b634837290cd20cbf06d8cf33",
+		"stored.txt":   "8cda59724160b68c79923ae77bc233d71cd2921a3e4fc9c624e7c3b4c87d8c9b",
+	} {
+		sum := sha256.Sum256(files[name])
+		if hex.EncodeToString(sum[:]) != want {
+			t.Fatalf("%s 明文与生成脚本不一致", name)
+		}
+	}
+	return files
+}
+
+func TestZipCryptoKnownAnswer(t *testing.T) {
+	want := zipCryptoFixturePlain(t)
+	reader, err := zip.OpenReader(filepath.Join("testdata", "zipcrypto.zip"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+
+	methods := map[string]uint16{"deflated.txt": zip.Deflate, "stored.txt": zip.Store}
+	for _, file := range reader.File {
+		if file.Flags&zipFlagEncrypted == 0 || file.Method != methods[file.Name] {
+			t.Fatalf("%s: 夹具不是预期的 ZipCrypto 条目: flags=%#x method=%d", file.Name, file.Flags, file.Method)
+		}
+		src, err := openZipEntry(file, DecompressOptions{Encrypt: true, Password: zipCryptoFixturePassword}, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := io.ReadAll(src)
+		_ = src.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want[file.Name]) {
+			t.Fatalf("%s 解密结果不一致", file.Name)
+		}
+	}
+}
+
+func TestZipCryptoDecompress(t *testing.T) {
+	want := zipCryptoFixturePlain(t)
+	opts := DecompressOptions{
+		SourcePath: filepath.Join("testdata", "zipcrypto.zip"),
+		OutputDir:  t.TempDir(),
+		Encrypt:    true,
+		Key:        []byte(zipCryptoFixturePassword),
+		Password:   zipCryptoFixturePassword,
+	}
+	if err := RunDecompress(opts); err != nil {
+		t.Fatal(err)
+	}
+	assertSameFiles(t, readOutputTree(t, opts.OutputDir), want)
+
+	opts.OutputDir = t.TempDir()
+	opts.Password = "wrong"
+	if err := RunDecompress(opts); err == nil || !strings.Contains(err.Error(), "密码错误") {
+		t.Fatalf("错误密码应当报错, 实际: %v", err)
+	}
+}
+
+func TestZipCryptoStreamKnownAnswer(t *testing.T) {
+	want := zipCryptoFixturePlain(t)
+	archive, err := os.ReadFile(filepath.Join("testdata", "zipcrypto-stream.zip"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 标准输入按本地文件头顺序读取, 长度与 CRC32 只能从数据描述符获得
+	withStdio(t, archive)
+	output := t.TempDir()
+	opts := DecompressOptions{
+		SourcePath: StdioPath,
+		OutputDir:  output,
+		Encrypt:    true,
+		Key:        []byte(zipCryptoFixturePassword),
+		Password:   zipCryptoFixturePassword,
+	}
+	if err := RunDecompress(opts); err != nil {
+		t.Fatal(err)
+	}
+	assertSameFiles(t, readOutputTree(t, output), map[string][]byte{"-": want["deflated.txt"]})
+}
+
+func TestZipCryptoWrongPassword(t *testing.T) {
+	// 按 ZipCrypto 加密 12 字节加密头, 最后一个字节为校验字节
+	const check = 0xA5
+	plain := make([]byte, zipCryptoHeaderLen)
+	plain[zipCryptoHeaderLen-1] = check
+	keys := newZipCryptoKeys("right")
+	encrypted := make([]byte, len(plain))
+	for i, b := range plain {
+		temp := (keys[2] | 2) & 0xffff
+		encrypted[i] = b ^ byte((temp*(temp^1))>>8)
+		keys.update(b)
+	}
+
+	open := func(password string) error {
+		_, err := newZipCryptoReader(bufio.NewReader(bytes.NewReader(encrypted)), "legacy.txt", password, check, -1)
+		return err
+	}
+	if err := open("right"); err != nil {
+		t.Fatalf("密码正确时不应报错: %v", err)
+	}
+	if err := open("wrong"); !errors.Is(err, errWrongPassword) {
+		t.Fatalf("错误密码应当报错, 实际: %v", err)
+	}
+}
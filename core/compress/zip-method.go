@@ -0,0 +1,253 @@
+// Package compress /core/compress/zip-method.go
+package compress
+
+import (
+	"archive/zip"
+	"compress/flate"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// zip 条目的压缩方式由文件头的 method 字段决定, 标准库只内置 store(0) 与 deflate(8).
+// 这里补充 zstd(93)、xz(95) 的读写与 bzip2(12)、Deflate64(9) 的读取,
+// 压缩器与 tar 系列共用同一套编解码器, --level 的含义也与 tar 系列一致.
+
+// zip 压缩方式编号 (APPNOTE 4.4.5)
+const (
+	zipMethodDeflate64 uint16 = 9
+	zipMethodBzip2     uint16 = 12
+	zipMethodZstd      uint16 = 93
+	zipMethodXz        uint16 = 95
+)
+
+// DefaultZipMethod 默认压缩方式
+const DefaultZipMethod = "deflate"
+
+// zipMethodNames --method 可选值, bzip2 与 Deflate64 只支持读取不在其中
+var zipMethodNames = map[string]uint16{
+	"store":   zip.Store,
+	"deflate": zip.Deflate,
+	"zstd":    zipMethodZstd,
+	"xz":      zipMethodXz,
+}
+
+// ZipMethodNames 可写入的压缩方式名称, 按字母排序
+func ZipMethodNames() []string {
+	names := make([]string, 0, len(zipMethodNames))
+	for name := range zipMethodNames {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseZipMethod 解析 --method, 空字符串表示默认 deflate
+func ParseZipMethod(name string) (uint16, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		name = DefaultZipMethod
+	}
+	method, ok := zipMethodNames[name]
+	if !ok {
+		return 0, fmt.Errorf("不支持的 zip 压缩方式: %s, 仅支持 %s", name, strings.Join(ZipMethodNames(), "/"))
+	}
+	return method, nil
+}
+
+// CheckStdoutZipMethod 输出到标准输出时只允许 deflate: 流式读取 zip 时其余方式的条目无法确定数据结尾
+func CheckStdoutZipMethod(name string) error {
+	method, err := ParseZipMethod(name)
+	if err != nil {
+		return err
+	}
+	if method != zip.Deflate {
+		return fmt.Errorf("输出到标准输出时 zip 只支持 deflate 压缩方式, 不支持 %s", strings.ToLower(strings.TrimSpace(name)))
+	}
+	return nil
+}
+
+// zipMethodName 压缩方式的显示名称
+func zipMethodName(method uint16) string {
+	switch method {
+	case zipMethodDeflate64:
+		return "deflate64"
+	case zipMethodBzip2:
+		return "bzip2"
+	case zipMethodWinZipAES:
+		return "aes"
+	}
+	for name, id := range zipMethodNames {
+		if id == method {
+			return name
+		}
+	}
+	return fmt.Sprintf("method-%d", method)
+}
+
+// zipMethodCodec 压缩方式对应的编解码器, store 与未知方式返回 nil, bzip2 的编解码器只能解压
+func zipMethodCodec(method uint16) TarCodec {
+	switch method {
+	case zip.Deflate:
+		return &zipDeflateCodec{}
+	case zipMethodBzip2:
+		return &Bzip2Codec{}
+	case zipMethodZstd:
+		return &ZstdCodec{}
+	case zipMethodXz:
+		return &XzCodec{}
+	default:
+		return nil
+	}
+}
+
+// newZipMethodCompressor 按压缩等级创建条目压缩器, level 为负数时使用默认等级
+// 返回值可注册到 zip.Writer, 也供 WinZip AES 加密写入使用
+func newZipMethodCompressor(method uint16, level int) (zip.Compressor, error) {
+	if method == zip.Store {
+		return func(w io.Writer) (io.WriteCloser, error) { return nopWriteCloser{w}, nil }, nil
+	}
+	codec := zipMethodCodec(method)
+	if codec == nil || method == zipMethodBzip2 {
+		return nil, fmt.Errorf("不支持写入的 zip 压缩方式: %s", zipMethodName(method))
+	}
+	minLevel, maxLevel, defLevel := codec.Levels()
+	if level < 0 {
+		level = defLevel
+	} else if level < minLevel || level > maxLevel {
+		return nil, fmt.Errorf("zip %s 压缩等级超出范围: %d, 支持 %d-%d", zipMethodName(method), level, minLevel, maxLevel)
+	}
+	return func(w io.Writer) (io.WriteCloser, error) {
+		return &lazyCodecWriter{w: w, codec: codec, level: level}, nil
+	}, nil
+}
+
+// lazyCodecWriter 首次写入时才创建编解码器写入器
+// zip.Writer 先创建压缩器再写本地文件头, 而 xz 等写入器创建时就会输出流头部, 必须推迟
+type lazyCodecWriter struct {
+	w      io.Writer
+	codec  TarCodec
+	level  int
+	writer io.WriteCloser
+}
+
+func (l *lazyCodecWriter) init() error {
+	if l.writer != nil {
+		return nil
+	}
+	writer, err := l.codec.NewWriter(l.w, l.level)
+	if err != nil {
+		return err
+	}
+	l.writer = writer
+	return nil
+}
+
+func (l *lazyCodecWriter) Write(p []byte) (int, error) {
+	if err := l.init(); err != nil {
+		return 0, err
+	}
+	return l.writer.Write(p)
+}
+
+func (l *lazyCodecWriter) Close() error {
+	// 空文件也要输出完整的压缩流
+	if err := l.init(); err != nil {
+		return err
+	}
+	return l.writer.Close()
+}
+
+// newZipMethodReader 按压缩方式包装解压读取器
+// bounded 为 false 表示数据边界未知 (流式读取且使用数据描述符),
+// 此时只能使用逐字节读取、不会越过数据结尾的 deflate/Deflate64 解码器
+func newZipMethodReader(r io.Reader, method uint16, name string, bounded bool) (io.Reader, error) {
+	if bounded {
+		// 长度已知时隐藏 ReadByte, 让解码器自带缓冲批量读取
+		r = struct{ io.Reader }{r}
+	}
+	switch method {
+	case zip.Store:
+		if !bounded {
+			return nil, fmt.Errorf("条目 %s 使用数据描述符且未压缩, 无法在流中确定数据长度", name)
+		}
+		return r, nil
+	case zip.Deflate:
+		return flate.NewReader(r), nil
+	case zipMethodDeflate64:
+		return newDeflate64Reader(r), nil
+	}
+
+	codec := zipMethodCodec(method)
+	if codec == nil {
+		return nil, fmt.Errorf("不支持的压缩方式: %d (%s)", method, name)
+	}
+	if !bounded {
+		return nil, fmt.Errorf("条目 %s 使用数据描述符且压缩方式为 %s, 无法在流中确定数据长度", name, zipMethodName(method))
+	}
+	reader, err := codec.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("初始化 %s 解压失败: %s, 错误: %v", zipMethodName(method), name, err)
+	}
+	return &closeOnEOFReader{ReadCloser: reader}, nil
+}
+
+// closeOnEOFReader 读到结尾时自动关闭, 释放 zstd 解码器的后台协程
+type closeOnEOFReader struct {
+	io.ReadCloser
+	closed bool
+}
+
+func (c *closeOnEOFReader) Read(p []byte) (int, error) {
+	if c.closed {
+		return 0, io.EOF
+	}
+	n, err := c.ReadCloser.Read(p)
+	if err == io.EOF {
+		c.closed = true
+		_ = c.ReadCloser.Close()
+	}
+	return n, err
+}
+
+// errReadCloser 解压器初始化失败时返回的读取器, zip.Decompressor 无法直接返回错误
+type errReadCloser struct {
+	err error
+}
+
+func (e errReadCloser) Read([]byte) (int, error) { return 0, e.err }
+func (e errReadCloser) Close() error             { return nil }
+
+func init() {
+	// 全局注册解压器, 所有 zip.Reader (解压、列表、校验) 都能直接读取
+	zip.RegisterDecompressor(zipMethodDeflate64, func(r io.Reader) io.ReadCloser {
+		return newDeflate64Reader(r)
+	})
+	for _, method := range []uint16{zipMethodBzip2, zipMethodZstd, zipMethodXz} {
+		codec := zipMethodCodec(method)
+		zip.RegisterDecompressor(method, func(r io.Reader) io.ReadCloser {
+			reader, err := codec.NewReader(r)
+			if err != nil {
+				return errReadCloser{err: err}
+			}
+			return reader
+		})
+	}
+}
+
+// ============================== deflate ==============================
+
+// zipDeflateCodec zip 条目使用的裸 deflate 流, 等级 1-9, 默认 6 (与标准库默认一致)
+type zipDeflateCodec struct{}
+
+func (c *zipDeflateCodec) Name() string                { return "deflate" }
+func (c *zipDeflateCodec) Levels() (min, max, def int) { return 1, 9, 6 }
+
+func (c *zipDeflateCodec) NewWriter(w io.Writer, level int) (io.WriteCloser, error) {
+	return flate.NewWriter(w, level)
+}
+
+func (c *zipDeflateCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return flate.NewReader(r), nil
+}
@@ -0,0 +1,103 @@
+package compress
+
+import (
+	"archive/zip"
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseZipMethod(t *testing.T) {
+	for name, want := range map[string]uint16{
+		"":        zip.Deflate,
+		"store":   zip.Store,
+		" ZSTD ":  zipMethodZstd,
+		"xz":      zipMethodXz,
+		"deflate": zip.Deflate,
+	} {
+		got, err := ParseZipMethod(name)
+		if err != nil || got != want {
+			t.Errorf("ParseZipMethod(%q) = %d, %v, 期望 %d", name, got, err, want)
+		}
+	}
+	// bzip2 与 Deflate64 只支持读取
+	for _, name := range []string{"bzip2", "deflate64"} {
+		if _, err := ParseZipMethod(name); err == nil {
+			t.Errorf("%s 不应作为写入方式", name)
+		}
+	}
+}
+
+func TestZipMethodsRoundTrip(t *testing.T) {
+	files := testTreeFiles()
+	cases := []struct {
+		method string
+		levels []int
+	}{
+		{"store", []int{-1}},
+		{"deflate", []int{-1, 1, 9}},
+		{"zstd", []int{-1, 1, 22}},
+		{"xz", []int{-1, 0, 9}},
+	}
+	for _, c := range cases {
+		for _, level := range c.levels {
+			for _, encrypt := range []bool{false, true} {
+				t.Run(fmt.Sprintf("%s/%d/encrypt=%t", c.method, level, encrypt), func(t *testing.T) {
+					dir := t.TempDir()
+					archive := filepath.Join(dir, "out.zip")
+					opts := CompressOptions{
+						Entries:    writeTestTree(t, files),
+						OutputPath: archive,
+						Format:     "zip",
+						Method:     c.method,
+						Level:      level,
+					}
+					if encrypt {
+						opts.Encrypt, opts.Password, opts.KeyLength = true, "secret", 32
+					}
+					if err := RunCompress(opts); err != nil {
+						t.Fatal(err)
+					}
+
+					// 中央目录记录的压缩方式, 加密条目记录在 0x9901 扩展字段中
+					want, _ := ParseZipMethod(c.method)
+					reader, err := zip.OpenReader(archive)
+					if err != nil {
+						t.Fatal(err)
+					}
+					for _, file := range reader.File {
+						if file.FileInfo().IsDir() {
+							continue
+						}
+						method := file.Method
+						if extra, ok := parseWinZipAESExtra(file.Extra); ok {
+							method = extra.Method
+						}
+						if method != want {
+							t.Errorf("%s: 压缩方式 = %s, 期望 %s", file.Name, zipMethodName(method), c.method)
+						}
+					}
+					_ = reader.Close()
+
+					output := filepath.Join(dir, "out")
+					dopts := DecompressOptions{SourcePath: archive, OutputDir: output}
+					if encrypt {
+						dopts.Encrypt, dopts.Key, dopts.Password = true, []byte("secret"), "secret"
+					}
+					if err := RunDecompress(dopts); err != nil {
+						t.Fatal(err)
+					}
+					assertSameFiles(t, readOutputTree(t, output), files)
+				})
+			}
+		}
+	}
+}
+
+func TestZipMethodLevelOutOfRange(t *testing.T) {
+	for method, level := range map[uint16]int{zip.Deflate: 10, zipMethodZstd: 23, zipMethodXz: 10} {
+		if _, err := newZipMethodCompressor(method, level); err == nil {
+			t.Errorf("%s 等级 %d 超出范围应当报错", zipMethodName(method), level)
+		}
+	}
+}
@@ -0,0 +1,248 @@
+// Package compress /core/compress/zip-resume.go
+package compress
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// zip 压缩续传: zip.Writer 只在关闭时写出中央目录, 中断后的输出没有中央目录.
+// 每个检查点把新完成条目的中央目录记录写入续传日志, 续传时截断到检查点追加新条目,
+// 关闭后把日志中的记录补到本次写出的中央目录之前, 再重写目录结尾.
+// 记录与目录结尾按 archive/zip 关闭时的规则生成, 与一次写完的压缩包格式一致.
+
+const (
+	zipLocalHeaderLen = 30
+	zipDirHeaderLen   = 46
+	zipDirEndLen      = 22
+	zip64DirEndLen    = 56
+	zip64DirLocLen    = 20
+	zipUint16Max      = 1<<16 - 1
+	zipUint32Max      = 1<<32 - 1
+	zip64DirLocSig    = 0x07064b50
+	zipVersion45      = 45
+)
+
+// zipDirRecord 已写出条目的中央目录记录
+type zipDirRecord struct {
+	Raw   []byte `json:"raw"`
+	Zip64 bool   `json:"zip64,omitempty"` // 记录中含 zip64 扩展字段, 目录结尾需要 zip64 记录
+}
+
+// zipCheckpoints 跟踪条目在输出文件中的位置并记录检查点, 不记录时为 nil
+type zipCheckpoints struct {
+	journal *journal
+	writer  *zip.Writer
+	out     *countWriter // 输出文件, n 为已写出的字节数 (含续传前的部分)
+	file    *os.File
+	pending *zip.FileHeader // 最近一个条目, 下一个条目开始时才由 zip.Writer 结束
+	offset  int64           // pending 的本地文件头偏移
+	records []zipDirRecord  // 上个检查点之后完成的条目
+	last    int64           // 上个检查点的偏移
+}
+
+// started 在条目头写入之后、数据写入之前调用
+// zip.Writer 写入新条目头之前会结束上一个条目, 此时上一个条目的数据与数据描述符都已写出
+func (z *zipCheckpoints) started(index int, header *zip.FileHeader) error {
+	if z == nil {
+		return nil
+	}
+	if err := z.writer.Flush(); err != nil {
+		return err
+	}
+	start := z.out.n - int64(zipLocalHeaderLen+len(header.Name)+len(header.Extra))
+	if z.pending != nil {
+		z.records = append(z.records, zipDirectoryRecord(z.pending, z.offset))
+	}
+	z.pending, z.offset = header, start
+	if start-z.last < journalInterval {
+		return nil
+	}
+
+	// 检查点: 当前条目之前的数据都已写出
+	if err := z.file.Sync(); err != nil {
+		return fmt.Errorf("写入检查点失败: %v", err)
+	}
+	if err := z.journal.checkpoint(compressCheckpoint{Entry: index, Offset: start, Zip: z.records}); err != nil {
+		return err
+	}
+	z.records = nil
+	z.last = start
+	return nil
+}
+
+// zipDirectoryRecord 按 archive/zip 关闭时的规则生成中央目录记录, 不修改 header
+func zipDirectoryRecord(h *zip.FileHeader, offset int64) zipDirRecord {
+	extra := append([]byte(nil), h.Extra...)
+	readerVersion := h.ReaderVersion
+	zip64 := false
+	if h.CompressedSize64 >= zipUint32Max || h.UncompressedSize64 >= zipUint32Max || offset >= zipUint32Max {
+		zip64 = true
+		readerVersion = max(readerVersion, zipVersion45)
+		field := binary.LittleEndian.AppendUint16(nil, zipExtraZip64)
+		field = binary.LittleEndian.AppendUint16(field, 0)
+		if h.UncompressedSize64 >= zipUint32Max {
+			field = binary.LittleEndian.AppendUint64(field, h.UncompressedSize64)
+		}
+		if h.CompressedSize64 >= zipUint32Max {
+			field = binary.LittleEndian.AppendUint64(field, h.CompressedSize64)
+		}
+		if offset >= zipUint32Max {
+			field = binary.LittleEndian.AppendUint64(field, uint64(offset))
+		}
+		binary.LittleEndian.PutUint16(field[2:], uint16(len(field)-4))
+		extra = append(extra, field...)
+	}
+
+	le := binary.LittleEndian
+	raw := make([]byte, 0, zipDirHeaderLen+len(h.Name)+len(extra)+len(h.Comment))
+	raw = le.AppendUint32(raw, zipCentralHeaderSig)
+	raw = le.AppendUint16(raw, h.CreatorVersion)
+	raw = le.AppendUint16(raw, readerVersion)
+	raw = le.AppendUint16(raw, h.Flags)
+	raw = le.AppendUint16(raw, h.Method)
+	raw = le.AppendUint16(raw, h.ModifiedTime)
+	raw = le.AppendUint16(raw, h.ModifiedDate)
+	raw = le.AppendUint32(raw, h.CRC32)
+	raw = le.AppendUint32(raw, uint32(min(h.CompressedSize64, zipUint32Max)))
+	raw = le.AppendUint32(raw, uint32(min(h.UncompressedSize64, zipUint32Max)))
+	raw = le.AppendUint16(raw, uint16(len(h.Name)))
+	raw = le.AppendUint16(raw, uint16(len(extra)))
+	raw = le.AppendUint16(raw, uint16(len(h.Comment)))
+	raw = le.AppendUint16(raw, 0) // 起始磁盘号
+	raw = le.AppendUint16(raw, 0) // 内部属性
+	raw = le.AppendUint32(raw, h.ExternalAttrs)
+	raw = le.AppendUint32(raw, uint32(min(uint64(offset), zipUint32Max)))
+	raw = append(raw, h.Name...)
+	raw = append(raw, extra...)
+	raw = append(raw, h.Comment...)
+	return zipDirRecord{Raw: raw, Zip64: zip64}
+}
+
+// rebuildZipDirectory 把上次记录的中央目录补到本次写出的中央目录之前, 重写目录结尾
+func rebuildZipDirectory(file *os.File, previous []zipDirRecord) error {
+	end, err := file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	start, size, records, zip64, err := readZipDirectoryEnd(file, end)
+	if err != nil {
+		return fmt.Errorf("读取中央目录失败: %v", err)
+	}
+	current := make([]byte, size)
+	if _, err := file.ReadAt(current, start); err != nil {
+		return fmt.Errorf("读取中央目录失败: %v", err)
+	}
+
+	if err := file.Truncate(start); err != nil {
+		return err
+	}
+	if _, err := file.Seek(start, io.SeekStart); err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(file)
+	for _, record := range previous {
+		_, _ = writer.Write(record.Raw)
+		size += int64(len(record.Raw))
+		zip64 = zip64 || record.Zip64
+	}
+	_, _ = writer.Write(current)
+	writeZipDirectoryEnd(writer, start, size, records+uint64(len(previous)), zip64)
+	return writer.Flush()
+}
+
+// readZipDirectoryEnd 读取 archive/zip 写出的目录结尾 (无注释), 返回中央目录的位置、大小、条目数与是否使用 zip64
+func readZipDirectoryEnd(file *os.File, end int64) (start, size int64, records uint64, zip64 bool, err error) {
+	le := binary.LittleEndian
+	buf := make([]byte, zip64DirEndLen+zip64DirLocLen+zipDirEndLen)
+	offset := max(end-int64(len(buf)), 0)
+	n, err := file.ReadAt(buf[:end-offset], offset)
+	if err != nil && err != io.EOF {
+		return 0, 0, 0, false, err
+	}
+	buf = buf[:n]
+	if len(buf) < zipDirEndLen || le.Uint32(buf[len(buf)-zipDirEndLen:]) != zipEndOfCentralSig {
+		return 0, 0, 0, false, fmt.Errorf("未找到目录结尾")
+	}
+	eocd := buf[len(buf)-zipDirEndLen:]
+	records = uint64(le.Uint16(eocd[10:12]))
+	size = int64(le.Uint32(eocd[12:16]))
+	start = int64(le.Uint32(eocd[16:20]))
+
+	// 存在 zip64 定位记录时以 zip64 目录结尾为准
+	if len(buf) == zip64DirEndLen+zip64DirLocLen+zipDirEndLen && le.Uint32(buf[zip64DirEndLen:]) == zip64DirLocSig {
+		record := buf[:zip64DirEndLen]
+		if le.Uint32(record) != zip64EndOfCentralSig {
+			return 0, 0, 0, false, fmt.Errorf("zip64 目录结尾无效")
+		}
+		records = le.Uint64(record[32:40])
+		size = int64(le.Uint64(record[40:48]))
+		start = int64(le.Uint64(record[48:56]))
+		zip64 = true
+	}
+	return start, size, records, zip64, nil
+}
+
+// writeZipDirectoryEnd 按 archive/zip 的规则写出目录结尾
+func writeZipDirectoryEnd(w io.Writer, start, size int64, records uint64, zip64 bool) {
+	le := binary.LittleEndian
+	end := start + size
+	var buf []byte
+	if zip64 || records >= zipUint16Max || size >= zipUint32Max || start >= zipUint32Max {
+		buf = le.AppendUint32(buf, zip64EndOfCentralSig)
+		buf = le.AppendUint64(buf, zip64DirEndLen-12)
+		buf = le.AppendUint16(buf, zipVersion45)
+		buf = le.AppendUint16(buf, zipVersion45)
+		buf = le.AppendUint32(buf, 0)
+		buf = le.AppendUint32(buf, 0)
+		buf = le.AppendUint64(buf, records)
+		buf = le.AppendUint64(buf, records)
+		buf = le.AppendUint64(buf, uint64(size))
+		buf = le.AppendUint64(buf, uint64(start))
+
+		buf = le.AppendUint32(buf, zip64DirLocSig)
+		buf = le.AppendUint32(buf, 0)
+		buf = le.AppendUint64(buf, uint64(end))
+		buf = le.AppendUint32(buf, 1)
+	}
+	buf = le.AppendUint32(buf, zipEndOfCentralSig)
+	buf = le.AppendUint16(buf, 0)
+	buf = le.AppendUint16(buf, 0)
+	buf = le.AppendUint16(buf, uint16(min(records, zipUint16Max)))
+	buf = le.AppendUint16(buf, uint16(min(records, zipUint16Max)))
+	buf = le.AppendUint32(buf, uint32(min(uint64(size), zipUint32Max)))
+	buf = le.AppendUint32(buf, uint32(min(uint64(start), zipUint32Max)))
+	buf = le.AppendUint16(buf, 0) // 注释长度
+	_, _ = w.Write(buf)
+}
+
+// checkResumePassword 续传加密压缩包前用已写出的第一个加密条目校验密码, 避免同一压缩包混用两个密码
+func checkResumePassword(path string, offset int64, password string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader := newZipStreamReader(bufio.NewReader(io.LimitReader(file, offset)), password)
+	for {
+		entry, _, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			if errors.Is(err, errWrongPassword) {
+				return fmt.Errorf("密码与中断前的压缩包不一致: %w", err)
+			}
+			return nil // 检查点之前的数据不完整, 由续传覆盖
+		}
+		if entry.AES != nil || entry.ZipCrypto {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package compress
+
+import (
+	"archive/zip"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeAESTestZip 写入只含一个 WinZip AES 条目的压缩包, 返回文件大小
+func writeAESTestZip(t *testing.T, path, password string) int64 {
+	t.Helper()
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	zipWriter := zip.NewWriter(file)
+	comp, err := newZipMethodCompressor(zip.Deflate, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := &zip.FileHeader{Name: "secret.txt", Method: zip.Deflate, Modified: time.Now()}
+	if _, err := writeWinZipAESEntry(zipWriter, header, comp, strings.NewReader("resume me"), password, 32, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := zipWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return info.Size()
+}
+
+func TestCheckResumePassword(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "resume.zip")
+	size := writeAESTestZip(t, path, "right")
+
+	if err := checkResumePassword(path, size, "right"); err != nil {
+		t.Fatalf("密码一致时不应报错: %v", err)
+	}
+	err := checkResumePassword(path, size, "wrong")
+	if err == nil {
+		t.Fatal("密码不一致时应报错")
+	}
+	if !errors.Is(err, errWrongPassword) {
+		t.Fatalf("错误应包装 errWrongPassword, 实际: %v", err)
+	}
+}
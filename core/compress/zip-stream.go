@@ -0,0 +1,408 @@
+// Package compress /core/compress/zip-stream.go
+package compress
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/GoFurry/gf-file-tool/utils"
+	"github.com/GoFurry/gf-file-tool/utils/compress"
+	"github.com/GoFurry/gf-file-tool/utils/log"
+)
+
+// zip 的中央目录位于文件末尾, 标准读取方式需要随机访问.
+// 管道输入无法 seek, 只能顺序解析每个条目前的本地文件头 (local file header):
+// 大小已知的条目直接按大小读取; 使用数据描述符 (bit 3) 的条目大小写在数据之后,
+// 只有 deflate 这种自带结束标记的压缩方式才能确定边界, 读完后再核对描述符中的 CRC32 与大小.
+
+const (
+	zipLocalHeaderSig    = 0x04034b50
+	zipCentralHeaderSig  = 0x02014b50
+	zipEndOfCentralSig   = 0x06054b50
+	zipDataDescriptorSig = 0x08074b50
+	zip64EndOfCentralSig = 0x06064b50
+
+	zipFlagEncrypted      = 0x1
+	zipFlagDataDescriptor = 0x8
+	zipExtraZip64         = 0x0001
+)
+
+// zipStreamEntry 本地文件头中的条目信息
+type zipStreamEntry struct {
+	Name             string
+	Flags            uint16
+	Method           uint16
+	ModifiedTime     uint16 // MS-DOS 时间, ZipCrypto 使用数据描述符时用于校验密码
+	CRC32            uint32
+	CompressedSize   uint64
+	UncompressedSize uint64
+	Extra            []byte          // 本地文件头扩展字段
+	AES              *winZipAESExtra // WinZip AES 加密信息, 未加密为 nil
+	ZipCrypto        bool            // 是否为 ZipCrypto 传统加密
+}
+
+// IsDir 目录条目以 / 结尾
+func (e *zipStreamEntry) IsDir() bool {
+	return strings.HasSuffix(e.Name, "/")
+}
+
+// zipStreamReader 顺序读取 zip 条目
+type zipStreamReader struct {
+	r        *bufio.Reader
+	cur      io.Reader // 当前条目数据, 读取下一个条目前需读完
+	password string    // WinZip AES 密码
+}
+
+// newZipStreamReader 创建流式读取器, r 需实现 io.ByteReader 以便 deflate 不多读数据
+func newZipStreamReader(r *bufio.Reader, password string) *zipStreamReader {
+	return &zipStreamReader{r: r, password: password}
+}
+
+// Next 读取下一个条目, 遇到中央目录时返回 io.EOF
+func (z *zipStreamReader) Next() (*zipStreamEntry, io.Reader, error) {
+	// 跳过上一个条目未读完的数据, 同时完成其 CRC32 校验
+	if z.cur != nil {
+		if _, err := io.Copy(io.Discard, z.cur); err != nil {
+			return nil, nil, err
+		}
+		z.cur = nil
+	}
+
+	var sigBuf [4]byte
+	if _, err := io.ReadFull(z.r, sigBuf[:]); err != nil {
+		if err == io.EOF {
+			return nil, nil, io.EOF
+		}
+		return nil, nil, fmt.Errorf("读取条目签名失败: %v", err)
+	}
+	switch binary.LittleEndian.Uint32(sigBuf[:]) {
+	case zipLocalHeaderSig:
+	case zipCentralHeaderSig, zipEndOfCentralSig, zip64EndOfCentralSig:
+		// 条目结束, 剩余的中央目录不再需要
+		_, _ = io.Copy(io.Discard, z.r)
+		return nil, nil, io.EOF
+	default:
+		return nil, nil, fmt.Errorf("无效的本地文件头签名: %x", sigBuf)
+	}
+
+	// 固定部分 26 字节
+	var buf [26]byte
+	if _, err := io.ReadFull(z.r, buf[:]); err != nil {
+		return nil, nil, fmt.Errorf("读取本地文件头失败: %v", err)
+	}
+	entry := &zipStreamEntry{
+		Flags:            binary.LittleEndian.Uint16(buf[2:4]),
+		Method:           binary.LittleEndian.Uint16(buf[4:6]),
+		ModifiedTime:     binary.LittleEndian.Uint16(buf[6:8]),
+		CRC32:            binary.LittleEndian.Uint32(buf[10:14]),
+		CompressedSize:   uint64(binary.LittleEndian.Uint32(buf[14:18])),
+		UncompressedSize: uint64(binary.LittleEndian.Uint32(buf[18:22])),
+	}
+	nameLen := int(binary.LittleEndian.Uint16(buf[22:24]))
+	extraLen := int(binary.LittleEndian.Uint16(buf[24:26]))
+
+	name := make([]byte, nameLen)
+	if _, err := io.ReadFull(z.r, name); err != nil {
+		return nil, nil, fmt.Errorf("读取文件名失败: %v", err)
+	}
+	entry.Name = string(name)
+
+	extra := make([]byte, extraLen)
+	if _, err := io.ReadFull(z.r, extra); err != nil {
+		return nil, nil, fmt.Errorf("读取扩展字段失败: %s, 错误: %v", entry.Name, err)
+	}
+	entry.Extra = extra
+	parseZip64Extra(entry, extra)
+
+	descriptor := entry.Flags&zipFlagDataDescriptor != 0
+
+	// 确定压缩数据边界: 使用数据描述符时本地文件头的大小通常为 0,
+	// 但 Info-ZIP 等工具写入可 seek 的文件时仍会填写, 非 0 即可按大小读取
+	var raw io.Reader = z.r
+	var bounded *io.LimitedReader
+	payload := int64(-1)
+	if !descriptor || entry.CompressedSize > 0 {
+		payload = int64(entry.CompressedSize)
+		bounded = &io.LimitedReader{R: z.r, N: payload}
+		raw = bounded
+	}
+
+	// 解压, bufio.Reader 实现了 io.ByteReader, deflate/Deflate64 逐字节读取, 不会越过压缩数据的结尾
+	var data io.Reader
+	var err error
+	switch {
+	case entry.Flags&zipFlagEncrypted != 0:
+		source := z.r
+		if bounded != nil {
+			source = bufio.NewReader(bounded)
+		}
+		if aesExtra, ok := parseWinZipAESExtra(extra); ok {
+			entry.AES = aesExtra
+			data, err = openWinZipAES(source, entry.Name, z.password, aesExtra, payload)
+		} else {
+			// 没有 0x9901 扩展字段的是 ZipCrypto 传统加密
+			entry.ZipCrypto = true
+			check := zipCryptoCheckByte(entry.Flags, entry.CRC32, entry.ModifiedTime)
+			data, err = openZipCrypto(source, entry.Name, z.password, entry.Method, check, payload)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+	default:
+		data, err = newZipMethodReader(raw, entry.Method, entry.Name, bounded != nil)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	z.cur = &zipStreamEntryReader{
+		z:          z,
+		entry:      entry,
+		data:       data,
+		bounded:    bounded,
+		descriptor: descriptor,
+		crc:        crc32.NewIEEE(),
+	}
+	return entry, z.cur, nil
+}
+
+// parseZip64Extra 从 zip64 扩展字段中读取 64 位大小
+func parseZip64Extra(entry *zipStreamEntry, extra []byte) {
+	for len(extra) >= 4 {
+		tag := binary.LittleEndian.Uint16(extra[0:2])
+		size := int(binary.LittleEndian.Uint16(extra[2:4]))
+		if len(extra) < 4+size {
+			return
+		}
+		field := extra[4 : 4+size]
+		extra = extra[4+size:]
+		if tag != zipExtraZip64 {
+			continue
+		}
+		if entry.UncompressedSize == 0xFFFFFFFF && len(field) >= 8 {
+			entry.UncompressedSize = binary.LittleEndian.Uint64(field[0:8])
+			field = field[8:]
+		}
+		if entry.CompressedSize == 0xFFFFFFFF && len(field) >= 8 {
+			entry.CompressedSize = binary.LittleEndian.Uint64(field[0:8])
+		}
+	}
+}
+
+// zipStreamEntryReader 条目数据读取器, 读到结尾时校验 CRC32
+type zipStreamEntryReader struct {
+	z          *zipStreamReader
+	entry      *zipStreamEntry
+	data       io.Reader
+	bounded    *io.LimitedReader // 按大小读取时的原始数据, 读描述符前需跳过剩余字节
+	descriptor bool
+	crc        hash.Hash32
+	size       uint64
+	done       bool
+}
+
+func (r *zipStreamEntryReader) Read(p []byte) (int, error) {
+	if r.done {
+		return 0, io.EOF
+	}
+	n, err := r.data.Read(p)
+	r.crc.Write(p[:n])
+	r.size += uint64(n)
+	if err == io.EOF {
+		r.done = true
+		if verifyErr := r.finish(); verifyErr != nil {
+			return n, verifyErr
+		}
+	}
+	return n, err
+}
+
+// finish 条目读取完毕: 读取数据描述符并校验 CRC32 与大小
+func (r *zipStreamEntryReader) finish() error {
+	if r.bounded != nil {
+		if _, err := io.Copy(io.Discard, r.bounded); err != nil {
+			return fmt.Errorf("读取压缩包内文件失败: %s, 错误: %v", r.entry.Name, err)
+		}
+	}
+	if r.descriptor {
+		if err := r.readDescriptor(); err != nil {
+			return err
+		}
+	}
+	if r.size != r.entry.UncompressedSize {
+		return fmt.Errorf("文件 %s 大小不匹配: 预期 %d, 实际 %d", r.entry.Name, r.entry.UncompressedSize, r.size)
+	}
+	// AE-2 不记录 CRC32, 由认证码保证完整性
+	if r.entry.AES != nil && r.entry.AES.Version == winZipAESVersion2 {
+		return nil
+	}
+	if r.crc.Sum32() != r.entry.CRC32 {
+		return fmt.Errorf("文件 %s CRC32 不匹配: 预期 %08x, 实际 %08x", r.entry.Name, r.entry.CRC32, r.crc.Sum32())
+	}
+	return nil
+}
+
+// readDescriptor 读取数据描述符, 签名可选, 大小字段可能为 32 位或 64 位 (zip64)
+func (r *zipStreamEntryReader) readDescriptor() error {
+	br := r.z.r
+	head, err := br.Peek(4)
+	if err != nil {
+		return fmt.Errorf("读取数据描述符失败: %s, 错误: %v", r.entry.Name, err)
+	}
+	if binary.LittleEndian.Uint32(head) == zipDataDescriptorSig {
+		_, _ = br.Discard(4)
+	}
+
+	var crcBuf [4]byte
+	if _, err := io.ReadFull(br, crcBuf[:]); err != nil {
+		return fmt.Errorf("读取数据描述符失败: %s, 错误: %v", r.entry.Name, err)
+	}
+	r.entry.CRC32 = binary.LittleEndian.Uint32(crcBuf[:])
+
+	// 32 位大小与实际解压大小一致时按 32 位处理, 否则按 zip64 的 64 位处理
+	sizes, err := br.Peek(8)
+	if err == nil && r.size <= 0xFFFFFFFF && uint64(binary.LittleEndian.Uint32(sizes[4:8])) == r.size {
+		_, _ = br.Discard(8)
+		r.entry.CompressedSize = uint64(binary.LittleEndian.Uint32(sizes[0:4]))
+		r.entry.UncompressedSize = uint64(binary.LittleEndian.Uint32(sizes[4:8]))
+		return nil
+	}
+	var sizes64 [16]byte
+	if _, err := io.ReadFull(br, sizes64[:]); err != nil {
+		return fmt.Errorf("读取数据描述符失败: %s, 错误: %v", r.entry.Name, err)
+	}
+	r.entry.CompressedSize = binary.LittleEndian.Uint64(sizes64[0:8])
+	r.entry.UncompressedSize = binary.LittleEndian.Uint64(sizes64[8:16])
+	return nil
+}
+
+// decompressStream 从不可 seek 的输入 (标准输入) 顺序解压 zip
+func (z *ZipDecompressor) decompressStream(opts DecompressOptions) error {
+	restorer := newMetadataRestorer(opts)
+	defer restorer.finish()
+
+	fileCount := 0
+	err := walkZipStream(opts, func(entry *zipStreamEntry, data io.Reader) error {
+		// 按筛选条件跳过, 指定的条目都已解压时不必读完整个流
+		if opts.Filter.Done() {
+			return errStopWalk
+		}
+		if !opts.Filter.Match(entry.Name, entry.IsDir()) {
+			return nil
+		}
+
+		// 构建输出路径, 不安全的条目按路径策略修正、跳过或中止
+		outputPath, ok, err := opts.guard.resolve(entry.Name, entry.IsDir())
+		if err != nil || !ok {
+			return err
+		}
+		if err := opts.limiter.addEntry(entry.Name, int64(entry.UncompressedSize)); err != nil {
+			return err
+		}
+
+		// 本地文件头不含权限信息, 文件使用默认权限, 时间与属主取自扩展字段
+		meta := newEntryMetadata(0644, time.Time{})
+		parseZipMetadataExtra(entry.Extra, &meta)
+		if outputPath, ok, err = opts.conflicts.resolve(entry.Name, outputPath, entry.IsDir(), meta.ModTime); err != nil || !ok {
+			return err
+		}
+		fileCount++
+		if utils.VerboseMode() {
+			log.Newline()
+			log.Info("解压文件:", entry.Name, "→", outputPath)
+		}
+
+		// 处理目录
+		if entry.IsDir() {
+			if err := compress.MkdirIfNotExist(outputPath); err != nil {
+				return fmt.Errorf("创建目录失败: %s, 错误: %v", outputPath, err)
+			}
+			meta.Mode = 0
+			restorer.deferDir(outputPath, meta)
+			return nil
+		}
+
+		// 创建文件目录
+		if err := compress.MkdirIfNotExist(filepath.Dir(outputPath)); err != nil {
+			return fmt.Errorf("创建文件目录失败: %s, 错误: %v", filepath.Dir(outputPath), err)
+		}
+
+		// 大小未知时进度条显示为不定长
+		size := int64(entry.UncompressedSize)
+		if entry.Flags&zipFlagDataDescriptor != 0 {
+			size = -1
+		}
+		if _, err := extractZipEntry(opts.limiter.reader(entry.Name, data), entry.Name, size, outputPath); err != nil {
+			return err
+		}
+		restorer.apply(outputPath, meta)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if utils.VerboseMode() {
+		log.Newline()
+		log.Success("共解压", fileCount, "个文件")
+	}
+	return nil
+}
+
+// errStopWalk fn 返回该错误表示正常结束遍历
+var errStopWalk = errors.New("stop walk")
+
+// walkZipStream 顺序读取 zip 流中的条目, fn 收到的 data 已解密、解压并在读完时校验 CRC32
+// fn 返回错误时停止遍历
+func walkZipStream(opts DecompressOptions, fn func(entry *zipStreamEntry, data io.Reader) error) error {
+	input, err := openInput(opts.SourcePath)
+	if err != nil {
+		return fmt.Errorf("打开压缩包失败: %v", err)
+	}
+	defer input.Close()
+
+	password := ""
+	if opts.Encrypt {
+		password = opts.Password
+	}
+	reader := newZipStreamReader(bufio.NewReader(opts.limiter.countInput(input)), password)
+	standard := false // 是否已出现标准加密条目
+	warned := false   // 是否已提示 ZipCrypto 风险
+	for {
+		entry, data, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		// 未标记加密但指定了 -e, 且尚未出现标准加密条目: 旧版本的 AES-GCM 自定义格式
+		if entry.AES != nil || entry.ZipCrypto {
+			standard = true
+		}
+		if entry.ZipCrypto && !warned {
+			warnZipCrypto()
+			warned = true
+		}
+		if opts.Encrypt && !standard && !entry.IsDir() {
+			if data, err = newZipGCMReader(data, entry.Name, opts); err != nil {
+				return err
+			}
+		}
+		if err := fn(entry, data); err != nil {
+			if err == errStopWalk {
+				return nil
+			}
+			return err
+		}
+	}
+}
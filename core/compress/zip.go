@@ -3,14 +3,17 @@ package compress
 
 import (
 	"archive/zip"
+	"bufio"
 	"crypto/aes"
 	"crypto/cipher"
-	"crypto/rand"
 	"encoding/binary"
 	"fmt"
+	"hash"
+	"hash/crc32"
 	"io"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/GoFurry/gf-file-tool/progress"
 	"github.com/GoFurry/gf-file-tool/utils"
@@ -19,181 +22,176 @@ import (
 )
 
 // 加密会破坏冗余数据导致压缩失效, 所以加密正确的逻辑应该放在压缩之后而不是压缩之前.
-// 此处故意留作为不影响工具正常功能的缺陷, 大家可以思考优化方式.
+// 加密条目采用 WinZip AES 标准 (见 zip-aes.go): 先压缩再 AES-CTR, 与 7-Zip/WinZip 互通.
+// 压缩方式由 --method 指定, 默认 deflate, 其余方式见 zip-method.go.
 
 // ============================== Zip 压缩部分 ==============================
 
 // ZipCompressor Zip 压缩器
 type ZipCompressor struct{}
 
-// Compress Zip 压缩逻辑
+// Compress Zip 压缩逻辑, 分卷时边写边切分, 生成的 .zip.001 可被 7-Zip 直接识别
 func (z *ZipCompressor) Compress(opts *CompressOptions) error {
-	// 不分卷逻辑
-	if opts.SplitSize <= 0 {
-		return z.compressSingleFile(*opts)
-	}
-
-	// 分卷压缩逻辑
-	return z.compressSplitFiles(opts)
+	return z.compressSingleFile(*opts)
 }
 
-// compressSingleFile 单文件压缩
+// compressSingleFile 压缩为单个压缩包
 func (z *ZipCompressor) compressSingleFile(opts CompressOptions) error {
-	// 创建输出文件
-	outFile, err := os.Create(opts.OutputPath)
+	// 续传时截断到上次的检查点后追加, 否则创建输出 (- 为标准输出, 分卷时为分卷写入器, 条目使用数据描述符, 无需回写文件头)
+	resume, previous, resuming, err := opts.journal.compressResume()
+	if err != nil {
+		return err
+	}
+	if resuming && opts.Encrypt {
+		if err := checkResumePassword(opts.OutputPath, resume.Offset, opts.Password); err != nil {
+			return err
+		}
+	}
+	var outFile io.WriteCloser
+	var resumeFile *os.File
+	if resuming {
+		resumeFile, err = openOutputAt(opts.OutputPath, resume.Offset)
+		outFile = resumeFile
+	} else {
+		outFile, err = createArchive(&opts)
+	}
 	if err != nil {
 		return fmt.Errorf("创建压缩包失败: %v", err)
 	}
 	defer func() {
-		if err := outFile.Close(); err != nil {
-			fmt.Printf("关闭文件写入器失败: %v\n", err)
+		if err := abortArchive(outFile); err != nil {
+			log.Warn("关闭文件写入器失败:", err)
 		}
 	}()
 
-	// 初始化 Zip Writer
-	zipWriter := zip.NewWriter(outFile)
+	// 解析压缩方式与等级
+	method, err := ParseZipMethod(opts.Method)
+	if err != nil {
+		return err
+	}
+	comp, err := newZipMethodCompressor(method, opts.Level)
+	if err != nil {
+		return err
+	}
+
+	// 初始化 Zip Writer, 按压缩等级注册本写入器专用的压缩器
+	counter := &countWriter{w: outFile, n: resume.Offset}
+	zipWriter := zip.NewWriter(counter)
+	zipWriter.SetOffset(resume.Offset)
+	if method != zip.Store {
+		zipWriter.RegisterCompressor(method, comp)
+	}
+	closed := false
 	defer func() {
+		if closed {
+			return
+		}
 		if err := zipWriter.Close(); err != nil {
-			fmt.Printf("关闭 Zip 写入器失败: %v\n", err)
+			log.Warn("关闭 Zip 写入器失败:", err)
 		}
 	}()
 
+	// 检查点只在条目之间, 记录已完成条目的中央目录
+	var checkpoints *zipCheckpoints
+	if file, ok := outFile.(*os.File); ok && opts.journal != nil {
+		checkpoints = &zipCheckpoints{journal: opts.journal, writer: zipWriter, out: counter, file: file, last: resume.Offset}
+	}
+
 	// 批量进度条
-	batchBar := progress.NewBatchProgressBar(len(opts.SourcePaths))
+	batchBar := progress.NewBatchProgressBar(len(opts.Entries))
 	defer progress.FinishProgress(batchBar)
 
 	// 遍历文件压缩
-	for _, srcPath := range opts.SourcePaths {
+	for i, entry := range opts.Entries {
 		progress.UpdateProgress(batchBar, 1)
+		srcPath := entry.Path
 
-		// 打开源文件
-		file, err := os.Open(srcPath)
-		if err != nil {
-			return fmt.Errorf("打开文件失败: %s, 错误: %v", srcPath, err)
+		// 续传: 检查点之前的条目已写出
+		if i < resume.Entry {
+			continue
 		}
 
-		// 获取文件信息
-		fileInfo, err := file.Stat()
-		if err != nil {
-			return fmt.Errorf("获取文件信息失败:%s, 错误: %v", srcPath, err)
+		// 目录与 FIFO 只写入条目头, zip 无法记录设备号
+		if entry.Dir || entry.Mode&os.ModeNamedPipe != 0 {
+			header, err := writeZipHeaderOnly(zipWriter, entry)
+			if err != nil {
+				return err
+			}
+			if err := checkpoints.started(i, header); err != nil {
+				return err
+			}
+			continue
+		}
+		if entry.Mode&os.ModeDevice != 0 {
+			log.Warn("zip 无法记录设备号, 已跳过:", srcPath, "(可使用 tar 格式)")
+			continue
 		}
 
-		// 计算相对路径
-		relPath := srcPath
-		if len(opts.SourcePaths) > 1 {
-			// 取相对于第一个文件目录的路径
-			baseDir := filepath.Dir(opts.SourcePaths[0])
-			rel, err := filepath.Rel(baseDir, srcPath)
-			if err == nil {
-				relPath = rel
-			} else {
-				relPath = filepath.Base(srcPath)
-			}
+		// 打开源文件 (- 为标准输入), 符号链接的内容为链接目标, 大文件的一段只读取该段
+		var file io.ReadCloser
+		var fileInfo os.FileInfo
+		var err error
+		if entry.Mode&os.ModeSymlink != 0 {
+			file, fileInfo, err = openSymlinkSource(srcPath)
+		} else if entry.chunk != nil {
+			file, fileInfo, err = openChunkSource(entry)
 		} else {
-			relPath = filepath.Base(srcPath)
+			file, fileInfo, err = openSource(srcPath)
+		}
+		if err != nil {
+			return fmt.Errorf("打开文件失败: %s, 错误: %v", srcPath, err)
 		}
 
+		// 压缩包内路径
+		relPath := entry.Name
+
 		// 创建 Zip 文件头
 		header, err := zip.FileInfoHeader(fileInfo)
 		if err != nil {
 			return fmt.Errorf("创建文件头失败: %s, 错误: %v", srcPath, err)
 		}
 		header.Name = relPath
-		header.Method = zip.Deflate // 启用压缩
+		if fileInfo.Size() < 0 {
+			// 标准输入大小未知, 写完后由数据描述符记录
+			header.UncompressedSize64 = 0
+		}
+		header.Method = method
 		header.SetMode(fileInfo.Mode())
-
-		// 创建 Zip 写入器
-		writer, err := zipWriter.CreateHeader(header)
-		if err != nil {
-			return fmt.Errorf("创建 Zip 写入器失败: %s, 错误: %v", srcPath, err)
+		setZipMetadata(header, srcPath, fileInfo)
+		// 符号链接的链接目标只存储不加密, 不支持 AES 的解压工具也能还原链接
+		link := entry.Mode&os.ModeSymlink != 0
+		if link {
+			header.Method = zip.Store
 		}
 
 		// 单个文件进度条
 		fileBar := progress.NewFileProgressBar(fileInfo.Size(), relPath)
 
-		// 分块拷贝
-		buf := make([]byte, 4*1024*1024) // 4MB 缓冲区
 		totalWritten := int64(0)
-
-		if opts.Encrypt {
-			// AES-GCM 自定义加密写入
-			block, err := aes.NewCipher(opts.Key)
+		if opts.Encrypt && !link {
+			// WinZip AES (AE-2) 加密写入, 7-Zip/WinZip 可直接打开
+			totalWritten, err = writeWinZipAESEntry(zipWriter, header, comp, file, opts.Password, opts.KeyLength, func() error {
+				return checkpoints.started(i, header)
+			}, func(n int64) {
+				if fileBar != nil {
+					_ = fileBar.Set64(n)
+				}
+			})
 			if err != nil {
-				return fmt.Errorf("初始化 AES 加密失败: %v", err)
+				return fmt.Errorf("加密写入失败: %s, 错误: %v", srcPath, err)
 			}
-
-			gcm, err := cipher.NewGCM(block)
+		} else {
+			// 创建 Zip 写入器
+			writer, err := zipWriter.CreateHeader(header)
 			if err != nil {
-				return fmt.Errorf("初始化 GCM 模式失败: %v", err)
+				return fmt.Errorf("创建 Zip 写入器失败: %s, 错误: %v", srcPath, err)
 			}
-
-			// 生成随机 nonce
-			nonce := make([]byte, gcm.NonceSize())
-			if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
-				return fmt.Errorf("生成 nonce 失败: %v", err)
-			}
-
-			// 先写长度, 再写盐值
-			// 写入 nonce
-			if _, err := writer.Write(nonce); err != nil {
-				return fmt.Errorf("写入 nonce 失败: %v", err)
-			}
-
-			// 写入盐值
-			saltBytes := []byte(opts.EncryptSalt)
-			saltLenBuf := make([]byte, 4)
-			binary.BigEndian.PutUint32(saltLenBuf, uint32(len(saltBytes)))
-			// 写入盐值长度
-			if _, err := writer.Write(saltLenBuf); err != nil {
-				return fmt.Errorf("写入盐值长度失败: %v", err)
-			}
-			// 写入盐值内容
-			if _, err := writer.Write(saltBytes); err != nil {
-				return fmt.Errorf("写入盐值失败: %v", err)
+			if err := checkpoints.started(i, header); err != nil {
+				return err
 			}
 
-			// 分块加密写入
-			buf = make([]byte, 4*1024*1024) // 4MB 缓冲区
-			totalWritten = int64(0)
-			blockIndex := uint64(0) // 固定块索引
-
-			for {
-				n, err := file.Read(buf)
-				if err != nil && err != io.EOF {
-					return fmt.Errorf("读取文件失败: %s, 错误: %v", srcPath, err)
-				}
-				if n == 0 {
-					break
-				}
-
-				// 标准库 GCM 加密: Seal (nil, nonce, 明文, 附加数据)
-				subNonce := make([]byte, len(nonce))
-				copy(subNonce, nonce)
-				binary.BigEndian.PutUint64(subNonce[4:], blockIndex) // 用固定块索引
-
-				// 加密当前块
-				cipherText := gcm.Seal(nil, subNonce, buf[:n], nil)
-
-				// 先写入块长度
-				lenBuf := make([]byte, 8)
-				binary.BigEndian.PutUint64(lenBuf, uint64(len(cipherText)))
-				if _, err := writer.Write(lenBuf); err != nil {
-					return fmt.Errorf("写入加密块长度失败: %v", err)
-				}
-
-				// 写入加密数据
-				if _, err := writer.Write(cipherText); err != nil {
-					return fmt.Errorf("加密写入失败: %s, 错误: %v", srcPath, err)
-				}
-
-				totalWritten += int64(n)
-				blockIndex++ // 块索引递增
-				if fileBar != nil {
-					_ = fileBar.Set64(totalWritten)
-				}
-			}
-		} else {
-			// 非加密写入
+			// 分块拷贝
+			buf := make([]byte, 4*1024*1024) // 4MB 缓冲区
 			for {
 				n, err := file.Read(buf)
 				if err != nil && err != io.EOF {
@@ -227,147 +225,60 @@ func (z *ZipCompressor) compressSingleFile(opts CompressOptions) error {
 		}
 	}
 
-	return nil
-}
-
-// compressSplitFiles 分卷压缩
-func (z *ZipCompressor) compressSplitFiles(opts *CompressOptions) error {
-	// 创建临时压缩包
-	tempZip := opts.OutputPath + ".tmp"
-	tempOpts := *opts // 浅拷贝, 不修改指针内容
-	tempOpts.OutputPath = tempZip
-	tempOpts.SplitSize = 0 // 临时包不分卷
-
-	// 压缩为完整包
-	if err := z.compressSingleFile(tempOpts); err != nil {
-		removeErr := os.Remove(tempZip)
-		if removeErr != nil {
-			log.Warn("tempZip 清理临时压缩包失败", removeErr)
+	// 关闭时写出本次条目的中央目录, 续传时再补上之前的条目
+	closed = true
+	if err := zipWriter.Close(); err != nil {
+		return fmt.Errorf("关闭 Zip 写入器失败: %v", err)
+	}
+	if len(previous) > 0 {
+		if err := rebuildZipDirectory(resumeFile, previous); err != nil {
+			return fmt.Errorf("重建中央目录失败: %v", err)
 		}
-		return fmt.Errorf("创建临时压缩包失败: %v", removeErr)
 	}
+	// 分卷在关闭时生成恢复卷与说明文件, 失败必须返回错误
+	if err := outFile.Close(); err != nil {
+		return fmt.Errorf("关闭压缩包失败: %v", err)
+	}
+	return nil
+}
 
-	// 打开临时包
-	tempFile, err := os.Open(tempZip)
+// writeZipHeaderOnly 写入不含数据的条目: 目录 (名称以 / 结尾) 与 FIFO, 类型记录在 Unix 权限位中
+func writeZipHeaderOnly(zipWriter *zip.Writer, entry SourceEntry) (*zip.FileHeader, error) {
+	info, err := statEntry(entry)
 	if err != nil {
-		removeErr := os.Remove(tempZip)
-		if removeErr != nil {
-			log.Warn("tempZip 清理临时压缩包失败", removeErr)
-		}
-		return fmt.Errorf("打开临时压缩包失败：%v", err)
+		return nil, fmt.Errorf("获取文件信息失败: %s, 错误: %v", entry.Path, err)
 	}
-
-	// 获取临时包大小
-	tempInfo, err := tempFile.Stat()
+	header, err := zip.FileInfoHeader(info)
 	if err != nil {
-		closeErr := tempFile.Close()
-		if closeErr != nil {
-			log.Warn("tempFile 文件关闭失败", err)
-		}
-		removeErr := os.Remove(tempZip)
-		if removeErr != nil {
-			log.Warn("tempZip 清理临时压缩包失败", removeErr)
-		}
-		return fmt.Errorf("获取临时包信息失败: %v", closeErr)
+		return nil, fmt.Errorf("创建文件头失败: %s, 错误: %v", entry.Path, err)
 	}
-	tempSize := tempInfo.Size()
-
-	// 计算分卷数
-	splitCount := (tempSize + opts.SplitSize - 1) / opts.SplitSize
-	if utils.VerboseMode() {
-		log.Info("开始分卷: 总大小", tempSize, "字节, 分卷大小", opts.SplitSize, "字节, 共", splitCount, "卷")
+	header.Name = entry.Name
+	if info.IsDir() {
+		header.Name += "/"
 	}
-
-	// 分卷切割
-	remaining := tempSize
-	volumeNum := int64(1)
-	splitBar := progress.NewBatchProgressBar(int(splitCount))
-	defer progress.FinishProgress(splitBar)
-
-	for remaining > 0 {
-		progress.UpdateProgress(splitBar, 1)
-		currentSize := opts.SplitSize
-		if remaining < currentSize {
-			currentSize = remaining
-		}
-
-		// 分卷文件名
-		splitPath := fmt.Sprintf("%s.%03d", opts.OutputPath, volumeNum)
-		if opts.SplitSuffix != "" {
-			splitPath = fmt.Sprintf("%s%s", opts.OutputPath, fmt.Sprintf(opts.SplitSuffix, volumeNum))
-		}
-
-		// 创建分卷文件
-		splitFile, err := os.Create(splitPath)
-		if err != nil {
-			closeErr := tempFile.Close()
-			if closeErr != nil {
-				log.Warn("tempFile 文件关闭失败", err)
-			}
-			removeErr := os.Remove(tempZip)
-			if removeErr != nil {
-				log.Warn("tempZip 清理临时压缩包失败", removeErr)
-			}
-			return fmt.Errorf("创建分卷 %s 失败：%v", splitPath, err)
-		}
-
-		// 写入分卷数据
-		written, err := io.CopyN(splitFile, tempFile, currentSize)
-		closeErr := splitFile.Close() // 立即关闭分卷文件句柄
-		if closeErr != nil {
-			log.Warn("splitFile 文件关闭失败", err)
-		}
-
-		if err != nil && err != io.EOF {
-			removeErr := os.Remove(splitPath)
-			if removeErr != nil {
-				log.Warn("splitPath 清理临时压缩包失败", removeErr)
-			}
-			closeErr = tempFile.Close()
-			if closeErr != nil {
-				log.Warn("tempFile 文件关闭失败", err)
-			}
-			removeErr = os.Remove(tempZip)
-			if removeErr != nil {
-				log.Warn("tempZip 清理临时压缩包失败", removeErr)
-			}
-			return fmt.Errorf("写入分卷 %s 失败: %v", splitPath, err)
-		}
-
-		if utils.VerboseMode() {
-			log.Success("生成分卷:", splitPath, written, "字节")
-		}
-
-		remaining -= written
-		volumeNum++
+	header.Method = zip.Store
+	header.UncompressedSize64 = 0
+	setZipMetadata(header, entry.Path, info)
+	if _, err := zipWriter.CreateHeader(header); err != nil {
+		return nil, fmt.Errorf("写入条目失败: %s, 错误: %v", entry.Path, err)
 	}
-
-	// 切割完成后立即关闭临时文件句柄
-	closeErr := tempFile.Close()
-	if closeErr != nil {
-		log.Warn("tempFile 文件关闭失败:", closeErr)
+	if utils.VerboseMode() {
+		log.Success("已添加:", header.Name)
 	}
+	return header, nil
+}
 
-	// 修改指针, 用于外层兜底清除临时文件
-	opts.TempFilePath = tempZip
-
-	// 生成分卷说明文件 TODO:新增一个可选参数
-	//manifestPath := opts.OutputPath + ".split"
-	//manifest, err := os.Create(manifestPath)
-	//if err != nil {
-	//	log.Warn("创建分卷说明文件失败:", err)
-	//} else {
-	//	_, _ = manifest.WriteString(fmt.Sprintf("Source: %s\n", opts.OutputPath))
-	//	_, _ = manifest.WriteString(fmt.Sprintf("TotalSize: %d\n", tempSize))
-	//	_, _ = manifest.WriteString(fmt.Sprintf("SplitSize: %d\n", opts.SplitSize))
-	//	_, _ = manifest.WriteString(fmt.Sprintf("VolumeCount: %d\n", splitCount))
-	//	_ = manifest.Close()
-	//	if utils.VerboseMode() {
-	//		log.Success("生成分卷说明:", manifestPath)
-	//	}
-	//}
-
-	return nil
+// setZipMetadata 写入扩展时间戳 (修改/访问时间) 与 Unix 属主扩展字段
+// Modified 非零时 zip.Writer 会再追加一份只含修改时间的 0x5455, 因此自行填写 MS-DOS 时间后清空 Modified
+func setZipMetadata(header *zip.FileHeader, path string, info os.FileInfo) {
+	mtime := header.Modified
+	header.ModifiedDate, header.ModifiedTime = zipDosTime(mtime)
+	uid, gid, atime := -1, -1, time.Time{}
+	if !IsStdio(path) {
+		uid, gid, atime, _ = statOwnerTime(path, info)
+	}
+	header.Extra = append(header.Extra, zipMetadataExtra(mtime, atime, uid, gid)...)
+	header.Modified = time.Time{}
 }
 
 // ============================== Zip 解压缩部分 ==============================
@@ -377,53 +288,32 @@ type ZipDecompressor struct{}
 
 // Decompress 解压缩逻辑
 func (z *ZipDecompressor) Decompress(opts DecompressOptions) error {
-	// 检测分卷并合并
-	if compress.IsSplitFile(opts.SourcePath) {
-		// 合并分卷为完整压缩包
-		mergedPath := opts.SourcePath + ".merged"
-		if err := compress.MergeSplitFiles(opts.SourcePath, mergedPath); err != nil {
-			return fmt.Errorf("合并分卷失败: %v", err)
-		}
-		// 替换为合并后的路径, 解压完成后删除临时文件
-		oldSourcePath := opts.SourcePath
-		opts.SourcePath = mergedPath
-		defer func() {
-			// 兜底删除临时文件
-			if err := os.Remove(mergedPath); err != nil && utils.VerboseMode() {
-				log.Warn("清理合并临时文件失败:", err)
-			}
-		}()
-		if utils.VerboseMode() {
-			log.Success("分卷合并完成:", oldSourcePath, "→", mergedPath)
-		}
+	// 标准输入不可 seek, 按本地文件头顺序解压
+	if IsStdio(opts.SourcePath) {
+		return z.decompressStream(opts)
 	}
 
-	// 执行解压
+	// 分卷原地读取, 不需要先合并
 	return z.decompressSingleFile(opts)
 }
 
 // decompressSingleFile Zip 解压缩
 func (z *ZipDecompressor) decompressSingleFile(opts DecompressOptions) error {
-	// 打开压缩包
-	zipFile, err := os.Open(opts.SourcePath)
+	// 打开压缩包, 分卷原地读取
+	volumes, err := openVolumes(opts.SourcePath)
 	if err != nil {
-		return fmt.Errorf("打开压缩包失败: %v", err)
+		return err
 	}
-	// 解压流程结束再关闭 zipFile
+	// 解压流程结束再关闭分卷
 	defer func() {
-		if err := zipFile.Close(); err != nil && utils.VerboseMode() {
+		if err := volumes.Close(); err != nil && utils.VerboseMode() {
 			log.Warn("关闭压缩包文件失败:", err)
 		}
 	}()
 
-	fileInfo, err := zipFile.Stat()
-	if err != nil {
-		return fmt.Errorf("获取压缩包信息失败: %v", err)
-	}
-
-	zipReader, err := zip.NewReader(zipFile, fileInfo.Size())
+	zipReader, err := zip.NewReader(volumes, volumes.Size())
 	if err != nil {
-		return fmt.Errorf("初始化 Zip 读取器失败: %v", err)
+		return fmt.Errorf("初始化 Zip 读取器失败: %v", volumes.describe(err))
 	}
 
 	// 临时文件列表
@@ -441,13 +331,45 @@ func (z *ZipDecompressor) decompressSingleFile(opts DecompressOptions) error {
 	batchBar := progress.NewBatchProgressBar(len(zipReader.File))
 	defer progress.FinishProgress(batchBar)
 
-	for _, file := range zipReader.File {
+	// 旧版本 AES-GCM 自定义格式的条目没有加密标记, 只有整个压缩包都没有标准加密条目时才按旧格式解密
+	legacy := opts.Encrypt && !hasEncryptedEntry(zipReader.File)
+	if hasZipCryptoEntry(zipReader.File) {
+		warnZipCrypto()
+	}
+
+	// 目录的时间与权限在全部条目解压后还原
+	restorer := newMetadataRestorer(opts)
+	defer restorer.finish()
+
+	for index, file := range zipReader.File {
 		progress.UpdateProgress(batchBar, 1)
 
-		// 构建输出路径
-		outputPath := filepath.Join(opts.OutputDir, file.Name)
+		// 按筛选条件跳过
+		if !opts.Filter.Match(file.Name, file.FileInfo().IsDir()) {
+			continue
+		}
+
+		// 构建输出路径, 不安全的条目按路径策略修正、跳过或中止
+		outputPath, ok, err := opts.guard.resolve(file.Name, file.FileInfo().IsDir())
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		if err := opts.limiter.addEntry(file.Name, int64(file.UncompressedSize64)); err != nil {
+			return err
+		}
+		if opts.journal.skip(opts, index, file.Name, outputPath) {
+			continue
+		}
+		if outputPath, ok, err = opts.conflicts.resolve(file.Name, outputPath, file.FileInfo().IsDir(), zipMetadata(file).ModTime); err != nil {
+			return err
+		} else if !ok {
+			continue
+		}
 		if utils.VerboseMode() {
-			fmt.Println()
+			log.Newline()
 			log.Info("解压文件:", file.Name, "→", outputPath)
 		}
 
@@ -456,6 +378,26 @@ func (z *ZipDecompressor) decompressSingleFile(opts DecompressOptions) error {
 			if err = compress.MkdirIfNotExist(outputPath); err != nil {
 				return fmt.Errorf("创建目录失败: %s, 错误: %v", outputPath, err)
 			}
+			restorer.deferDir(outputPath, zipMetadata(file))
+			continue
+		}
+
+		// 符号链接与 FIFO/设备文件, 类型记录在 Unix 权限位中
+		if mode := file.Mode(); mode&(os.ModeSymlink|os.ModeNamedPipe|os.ModeDevice) != 0 {
+			created := true
+			if mode&os.ModeSymlink == 0 {
+				created, err = extractSpecial(opts, file.Name, mode, 0, 0, outputPath)
+			} else if target, readErr := readZipSymlink(file, opts); readErr != nil {
+				err = readErr
+			} else {
+				err = extractSymlink(file.Name, target, outputPath)
+			}
+			if err != nil {
+				return err
+			}
+			if created {
+				restorer.apply(outputPath, zipMetadata(file))
+			}
 			continue
 		}
 
@@ -464,174 +406,466 @@ func (z *ZipDecompressor) decompressSingleFile(opts DecompressOptions) error {
 			return fmt.Errorf("创建文件目录失败: %s, 错误: %v", filepath.Dir(outputPath), err)
 		}
 
-		// 打开压缩包内文件
-		srcFile, err := file.Open()
+		// 打开压缩包内文件 (按条目标记自动解密)
+		srcFile, err := openZipEntry(file, opts, legacy)
 		if err != nil {
-			return fmt.Errorf("打开压缩包内文件失败: %s, 错误: %v", file.Name, err)
+			return err
 		}
+		written, err := extractZipEntry(opts.journal.track(opts.limiter.reader(file.Name, srcFile)), file.Name, int64(file.UncompressedSize64), outputPath)
 		// 每个文件读取完立即关闭 srcFile
-		defer func() {
-			if err := srcFile.Close(); err != nil && utils.VerboseMode() {
-				log.Warn("关闭压缩包内文件失败:", file.Name, ", 错误:", err)
-			}
-		}()
+		if closeErr := srcFile.Close(); closeErr != nil && utils.VerboseMode() {
+			log.Warn("关闭压缩包内文件失败:", file.Name, ", 错误:", closeErr)
+		}
+		if err != nil {
+			return err
+		}
 
-		// 处理加密文件
-		if opts.Encrypt {
-			// 初始化 AES-GCM
-			block, err := aes.NewCipher(opts.Key)
-			if err != nil {
-				return fmt.Errorf("初始化 AES 解密失败: %v", err)
-			}
+		// 还原权限、时间与属主
+		restorer.apply(outputPath, zipMetadata(file))
+		if err := opts.journal.extracted(index, file.Name, outputPath); err != nil {
+			return err
+		}
 
-			gcm, err := cipher.NewGCM(block)
-			if err != nil {
-				return fmt.Errorf("初始化 GCM 模式失败: %v", err)
+		// 完整性校验: 条目的 CRC32 (AES 条目为认证码) 在读到结尾时已校验, 不匹配会中止解压, 这里再核对解出的大小
+		if opts.Verify {
+			if !legacy && uint64(written) != file.UncompressedSize64 {
+				return fmt.Errorf("文件 %s 大小不匹配: 预期 %d, 实际 %d", file.Name, file.UncompressedSize64, written)
 			}
-
-			// 读取 nonce
-			nonce := make([]byte, gcm.NonceSize())
-			if _, err := io.ReadFull(srcFile, nonce); err != nil {
-				return fmt.Errorf("读取 Nonce 失败: %s, 错误: %v", file.Name, err)
+			if utils.VerboseMode() {
+				log.Newline()
+				log.Success("文件", file.Name, "校验通过")
 			}
+		}
+	}
 
-			// 读取盐值长度
-			saltLenBuf := make([]byte, 4)
-			if _, err := io.ReadFull(srcFile, saltLenBuf); err != nil {
-				return fmt.Errorf("读取盐值长度失败: %s, 错误: %v", file.Name, err)
-			}
-			saltLen := binary.BigEndian.Uint32(saltLenBuf)
+	return nil
+}
+
+// List 读取中央目录, 分卷原地读取
+func (z *ZipDecompressor) List(opts DecompressOptions) ([]ArchiveEntry, error) {
+	if IsStdio(opts.SourcePath) {
+		return nil, fmt.Errorf("zip 列表需要读取中央目录, 不支持标准输入")
+	}
+	volumes, err := openVolumes(opts.SourcePath)
+	if err != nil {
+		return nil, err
+	}
+	defer volumes.Close()
 
-			// 读取盐值内容
-			saltBytes := make([]byte, saltLen)
-			if _, err := io.ReadFull(srcFile, saltBytes); err != nil {
-				return fmt.Errorf("读取盐值失败: %s, 错误: %v", file.Name, err)
+	reader, err := zip.NewReader(volumes, volumes.Size())
+	if err != nil {
+		return nil, fmt.Errorf("读取 zip 目录失败: %v", volumes.describe(err))
+	}
+
+	entries := make([]ArchiveEntry, 0, len(reader.File))
+	for _, file := range reader.File {
+		mode := file.Mode()
+		entry := ArchiveEntry{
+			Name:           file.Name,
+			Size:           int64(file.UncompressedSize64),
+			CompressedSize: int64(file.CompressedSize64),
+			Method:         zipMethodName(file.Method),
+			Modified:       file.Modified,
+			Mode:           mode,
+			CRC32:          file.CRC32,
+			HasCRC32:       !mode.IsDir(),
+			Encrypted:      file.Flags&zipFlagEncrypted != 0,
+		}
+		// WinZip AES 的真实压缩方式在扩展字段中, AE-2 不记录 CRC32
+		if aesExtra, ok := parseWinZipAESExtra(file.Extra); ok && entry.Encrypted {
+			entry.Method = zipMethodName(aesExtra.Method)
+			entry.HasCRC32 = entry.HasCRC32 && aesExtra.Version == winZipAESVersion1
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// Test 逐个条目解密、解压并校验 CRC32/认证码, 不写入磁盘
+// 分卷原地读取, - 为标准输入 (按本地文件头顺序校验)
+func (z *ZipDecompressor) Test(opts DecompressOptions, report func(TestResult)) error {
+	if IsStdio(opts.SourcePath) {
+		return walkZipStream(opts, func(entry *zipStreamEntry, data io.Reader) error {
+			n, err := io.Copy(io.Discard, data)
+			report(TestResult{Name: entry.Name, Size: n, Err: err})
+			return nil // 数据损坏导致无法定位下一个条目时, Next 会返回错误终止遍历
+		})
+	}
+
+	volumes, err := openVolumes(opts.SourcePath)
+	if err != nil {
+		return err
+	}
+	defer volumes.Close()
+
+	zipReader, err := zip.NewReader(volumes, volumes.Size())
+	if err != nil {
+		return fmt.Errorf("读取 zip 目录失败: %v", volumes.describe(err))
+	}
+
+	legacy := opts.Encrypt && !hasEncryptedEntry(zipReader.File)
+	if hasZipCryptoEntry(zipReader.File) {
+		warnZipCrypto()
+	}
+
+	for _, file := range zipReader.File {
+		if file.FileInfo().IsDir() {
+			report(TestResult{Name: file.Name})
+			continue
+		}
+		report(testZipEntry(file, opts, legacy))
+	}
+	return nil
+}
+
+// WriteEntry 解密、解压单个条目写入 w, 读完时校验 CRC32/认证码
+func (z *ZipDecompressor) WriteEntry(opts DecompressOptions, name string, w io.Writer) error {
+	if IsStdio(opts.SourcePath) {
+		found := false
+		err := walkZipStream(opts, func(entry *zipStreamEntry, data io.Reader) error {
+			if compress.CleanEntryName(entry.Name) != name {
+				return nil
 			}
+			if entry.IsDir() {
+				return errEntryIsDir(name)
+			}
+			found = true
+			if _, err := io.Copy(w, data); err != nil {
+				return fmt.Errorf("读取压缩包内文件失败: %s, 错误: %v", name, err)
+			}
+			return errStopWalk
+		})
+		if err == nil && !found {
+			return errEntryNotFound(name)
+		}
+		return err
+	}
+
+	volumes, err := openVolumes(opts.SourcePath)
+	if err != nil {
+		return err
+	}
+	defer volumes.Close()
+	zipReader, err := zip.NewReader(volumes, volumes.Size())
+	if err != nil {
+		return fmt.Errorf("读取 zip 目录失败: %v", volumes.describe(err))
+	}
 
-			// 验证盐值
-			if opts.EncryptSalt != "" && string(saltBytes) != opts.EncryptSalt {
-				return fmt.Errorf("盐值不匹配: 预期 %s, 实际 %s", opts.EncryptSalt, string(saltBytes))
+	for _, file := range zipReader.File {
+		if compress.CleanEntryName(file.Name) != name {
+			continue
+		}
+		if file.FileInfo().IsDir() {
+			return errEntryIsDir(name)
+		}
+		if _, ok := parseWinZipAESExtra(file.Extra); file.Flags&zipFlagEncrypted != 0 && !ok {
+			warnZipCrypto()
+		}
+		legacy := opts.Encrypt && !hasEncryptedEntry(zipReader.File)
+		if file.Mode()&os.ModeSymlink != 0 {
+			target, err := readZipSymlink(file, opts)
+			if err != nil {
+				return err
 			}
+			return errEntryIsLink(name, target)
+		}
+		src, err := openZipEntry(file, opts, legacy)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		if _, err := io.Copy(w, src); err != nil {
+			return fmt.Errorf("读取压缩包内文件失败: %s, 错误: %v", name, err)
+		}
+		return nil
+	}
+	return errEntryNotFound(name)
+}
+
+// testZipEntry 校验单个条目, 旧版本 AES-GCM 条目记录的是密文大小, 不比较明文大小
+func testZipEntry(file *zip.File, opts DecompressOptions, legacy bool) TestResult {
+	result := TestResult{Name: file.Name}
+	src, err := openZipEntry(file, opts, legacy)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	defer src.Close()
 
-			// 创建输出文件
-			dstFile, err := os.Create(outputPath)
+	result.Size, err = io.Copy(io.Discard, src)
+	if err != nil {
+		result.Err = fmt.Errorf("读取失败: %v", err)
+	} else if !legacy && uint64(result.Size) != file.UncompressedSize64 {
+		result.Err = fmt.Errorf("大小不匹配: 预期 %d, 实际 %d", file.UncompressedSize64, result.Size)
+	}
+	return result
+}
+
+// openZipEntry 打开压缩包内文件, 返回解密、解压后的明文
+//   - 带 0x9901 扩展字段: WinZip AES 标准加密
+//   - 带加密标记但无 0x9901: ZipCrypto 传统加密, 只读
+//   - legacy 为 true 时: 旧版本工具写入的 AES-GCM 自定义格式, 仅保留读取兼容
+func openZipEntry(file *zip.File, opts DecompressOptions, legacy bool) (io.ReadCloser, error) {
+	if file.Flags&zipFlagEncrypted != 0 {
+		raw, err := file.OpenRaw()
+		if err != nil {
+			return nil, fmt.Errorf("打开压缩包内文件失败: %s, 错误: %v", file.Name, err)
+		}
+		password := ""
+		if opts.Encrypt {
+			password = opts.Password
+		}
+
+		// 没有 0x9901 扩展字段的是 ZipCrypto 传统加密
+		aesExtra, ok := parseWinZipAESExtra(file.Extra)
+		if !ok {
+			check := zipCryptoCheckByte(file.Flags, file.CRC32, file.ModifiedTime)
+			reader, err := openZipCrypto(bufio.NewReader(raw), file.Name, password, file.Method, check, int64(file.CompressedSize64))
 			if err != nil {
-				return fmt.Errorf("创建输出文件失败: %s, 错误: %v", outputPath, err)
+				return nil, err
 			}
-			defer func() {
-				if err := dstFile.Close(); err != nil && utils.VerboseMode() {
-					log.Warn("关闭输出文件失败:", outputPath, ", 错误:", err)
-				}
-			}()
+			return io.NopCloser(newCRC32CheckReader(reader, file.Name, file.CRC32)), nil
+		}
 
-			// 分块解密读取
-			fileBar := progress.NewFileProgressBar(int64(file.UncompressedSize64), file.Name)
-			defer progress.FinishProgress(fileBar)
+		reader, err := openWinZipAES(bufio.NewReader(raw), file.Name, password, aesExtra, int64(file.CompressedSize64))
+		if err != nil {
+			return nil, err
+		}
+		// AE-1 仍记录 CRC32, 额外校验
+		if aesExtra.Version == winZipAESVersion1 {
+			reader = newCRC32CheckReader(reader, file.Name, file.CRC32)
+		}
+		return io.NopCloser(reader), nil
+	}
 
-			totalWritten := int64(0)
-			blockIndex := uint64(0) // 固定块索引
+	srcFile, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("打开压缩包内文件失败: %s, 错误: %v", file.Name, err)
+	}
+	if !legacy {
+		return srcFile, nil
+	}
+	gcmReader, err := newZipGCMReader(srcFile, file.Name, opts)
+	if err != nil {
+		_ = srcFile.Close()
+		return nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{gcmReader, srcFile}, nil
+}
 
-			for {
-				lenBuf := make([]byte, 8)
-				n, err := io.ReadFull(srcFile, lenBuf)
-				if err == io.EOF {
-					break
-				}
-				if err != nil {
-					return fmt.Errorf("读取加密块长度失败: %s, 错误: %v", file.Name, err)
-				}
-				if n != 8 {
-					return fmt.Errorf("无效的加密块长度: %s", file.Name)
-				}
-				cipherLen := binary.BigEndian.Uint64(lenBuf)
+// hasEncryptedEntry 是否存在带加密标记的条目
+func hasEncryptedEntry(files []*zip.File) bool {
+	for _, file := range files {
+		if file.Flags&zipFlagEncrypted != 0 {
+			return true
+		}
+	}
+	return false
+}
 
-				// 读取加密块数据
-				cipherText := make([]byte, cipherLen)
-				if _, err := io.ReadFull(srcFile, cipherText); err != nil {
-					return fmt.Errorf("读取加密块数据失败: %s, 错误: %v", file.Name, err)
-				}
+// hasZipCryptoEntry 是否存在 ZipCrypto 传统加密条目
+func hasZipCryptoEntry(files []*zip.File) bool {
+	for _, file := range files {
+		if _, ok := parseWinZipAESExtra(file.Extra); file.Flags&zipFlagEncrypted != 0 && !ok {
+			return true
+		}
+	}
+	return false
+}
 
-				// 生成子 Nonce
-				subNonce := make([]byte, len(nonce))
-				copy(subNonce, nonce)
-				binary.BigEndian.PutUint64(subNonce[4:], blockIndex) // 用固定块索引
+// readZipSymlink 读取符号链接条目的链接目标 (条目数据, 旧版本写入的可能已加密)
+// 旧版 AES-GCM 格式没有符号链接条目, 未加密的链接条目按明文读取, 避免只含链接的加密包被误判为旧版格式
+func readZipSymlink(file *zip.File, opts DecompressOptions) (string, error) {
+	srcFile, err := openZipEntry(file, opts, false)
+	if err != nil {
+		return "", err
+	}
+	defer srcFile.Close()
+	return readSymlinkTarget(srcFile, file.Name)
+}
 
-				// 解密当前块
-				plainText, err := gcm.Open(nil, subNonce, cipherText, nil)
-				if err != nil {
-					return fmt.Errorf("解密块失败: %s, 错误: %v (块索引: %d, 密码/盐值错误或文件损坏)", file.Name, err, blockIndex)
-				}
+// extractZipEntry 把压缩包内单个文件的明文写出到 outputPath, 随机访问与流式读取共用
+// 返回写出的字节数, 权限与时间由调用方在写完后还原
+func extractZipEntry(srcFile io.Reader, name string, size int64, outputPath string) (int64, error) {
+	dstFile, err := os.Create(outputPath)
+	if err != nil {
+		return 0, fmt.Errorf("创建输出文件失败: %s, 错误: %v", outputPath, err)
+	}
+	defer func() {
+		if err := dstFile.Close(); err != nil && utils.VerboseMode() {
+			log.Warn("关闭输出文件失败:", outputPath, ", 错误:", err)
+		}
+	}()
 
-				// 写入明文
-				if _, err := dstFile.Write(plainText); err != nil {
-					return fmt.Errorf("写入解密文件失败: %s, 错误: %v", outputPath, err)
-				}
+	// 单个文件进度条
+	fileBar := progress.NewFileProgressBar(size, name)
+	defer progress.FinishProgress(fileBar)
 
-				totalWritten += int64(len(plainText))
-				blockIndex++ // 块索引递增
-				if fileBar != nil {
-					_ = fileBar.Set64(totalWritten)
-				}
-			}
+	// 分块拷贝
+	buf := make([]byte, 4*1024*1024) // 4MB 缓冲区
+	totalWritten := int64(0)
+	for {
+		n, err := srcFile.Read(buf)
+		if err != nil && err != io.EOF {
+			return totalWritten, fmt.Errorf("读取压缩包内文件失败: %s, 错误: %v", name, err)
+		}
+		if n == 0 {
+			break
+		}
 
-			// 保留权限
-			if err := os.Chmod(outputPath, file.Mode()); err != nil && utils.VerboseMode() {
-				log.Warn("设置文件权限失败:", outputPath, ", 错误:", err)
-			}
-		} else {
-			dstFile, err := os.Create(outputPath)
-			if err != nil {
-				return fmt.Errorf("创建输出文件失败: %s, 错误: %v", outputPath, err)
-			}
-			defer func() {
-				if err := dstFile.Close(); err != nil && utils.VerboseMode() {
-					log.Warn("关闭输出文件失败:", outputPath, ", 错误:", err)
-				}
-			}()
+		if _, err := dstFile.Write(buf[:n]); err != nil {
+			return totalWritten, fmt.Errorf("写入文件失败: %s, 错误: %v", outputPath, err)
+		}
 
-			// 分块拷贝非加密文件
-			fileBar := progress.NewFileProgressBar(int64(file.UncompressedSize64), file.Name)
-			defer progress.FinishProgress(fileBar)
+		totalWritten += int64(n)
+		if fileBar != nil {
+			_ = fileBar.Set64(totalWritten)
+		}
+	}
+	return totalWritten, nil
+}
 
-			buf := make([]byte, 4*1024*1024) // 4MB 缓冲区
-			totalWritten := int64(0)
-			for {
-				n, err := srcFile.Read(buf)
-				if err != nil && err != io.EOF {
-					return fmt.Errorf("读取压缩包内文件失败: %s, 错误: %v", file.Name, err)
-				}
-				if n == 0 {
-					break
-				}
+// zipMetadata 读取条目元数据, 扩展字段中的时间与属主优先于 MS-DOS 时间
+func zipMetadata(file *zip.File) entryMetadata {
+	meta := newEntryMetadata(file.Mode(), file.Modified)
+	parseZipMetadataExtra(file.Extra, &meta)
+	meta.Link = file.Mode()&os.ModeSymlink != 0
+	return meta
+}
 
-				if _, err := dstFile.Write(buf[:n]); err != nil {
-					return fmt.Errorf("写入文件失败: %s, 错误: %v", outputPath, err)
-				}
+// zipGCMReader 旧版本 AES-GCM 自定义格式的解密读取器
+// 格式: nonce + 盐值长度 + 盐值 + 若干 (8 字节长度 + GCM 密文块)
+type zipGCMReader struct {
+	r          io.Reader
+	gcm        cipher.AEAD
+	nonce      []byte
+	blockIndex uint64
+	plain      []byte
+	name       string
+	done       bool
+}
 
-				totalWritten += int64(n)
-				if fileBar != nil {
-					_ = fileBar.Set64(totalWritten)
-				}
-			}
+// newZipGCMReader 读取 nonce 与盐值
+func newZipGCMReader(r io.Reader, name string, opts DecompressOptions) (*zipGCMReader, error) {
+	// 初始化 AES-GCM
+	block, err := aes.NewCipher(opts.Key)
+	if err != nil {
+		return nil, fmt.Errorf("初始化 AES 解密失败: %v", err)
+	}
 
-			// 保留权限
-			if err := os.Chmod(outputPath, file.Mode()); err != nil && utils.VerboseMode() {
-				log.Warn("设置文件权限失败:", outputPath, ", 错误:", err)
-			}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("初始化 GCM 模式失败: %v", err)
+	}
+
+	// 读取 nonce, 旧格式即使空文件也带 nonce, 完全没有数据说明是未加密的空文件
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(r, nonce); err != nil {
+		if err == io.EOF {
+			return &zipGCMReader{r: r, done: true}, nil
 		}
+		return nil, fmt.Errorf("读取 Nonce 失败: %s, 错误: %v", name, err)
+	}
 
-		// 完整性校验
-		if opts.Verify {
-			fmt.Println()
-			if ok, err := compress.VerifyFileCRC32(outputPath, opts.ExpectedCRC); err != nil {
-				log.Warn("校验文件", outputPath, "失败:", err)
-			} else if !ok {
-				log.Error("文件", outputPath, "CRC32 不匹配")
-			} else if utils.VerboseMode() {
-				log.Success("文件", outputPath, "CRC32 校验通过")
+	// 读取盐值长度
+	saltLenBuf := make([]byte, 4)
+	if _, err := io.ReadFull(r, saltLenBuf); err != nil {
+		return nil, fmt.Errorf("读取盐值长度失败: %s, 错误: %v", name, err)
+	}
+	saltLen := binary.BigEndian.Uint32(saltLenBuf)
+
+	// 读取盐值内容
+	saltBytes := make([]byte, saltLen)
+	if _, err := io.ReadFull(r, saltBytes); err != nil {
+		return nil, fmt.Errorf("读取盐值失败: %s, 错误: %v", name, err)
+	}
+
+	// 验证盐值
+	if opts.EncryptSalt != "" && string(saltBytes) != opts.EncryptSalt {
+		return nil, fmt.Errorf("盐值不匹配: 预期 %s, 实际 %s", opts.EncryptSalt, string(saltBytes))
+	}
+
+	return &zipGCMReader{r: r, gcm: gcm, nonce: nonce, name: name}, nil
+}
+
+func (g *zipGCMReader) Read(p []byte) (int, error) {
+	if g.done {
+		return 0, io.EOF
+	}
+	if len(g.plain) == 0 {
+		lenBuf := make([]byte, 8)
+		if _, err := io.ReadFull(g.r, lenBuf); err != nil {
+			if err == io.EOF {
+				return 0, io.EOF
 			}
+			return 0, fmt.Errorf("读取加密块长度失败: %s, 错误: %v", g.name, err)
 		}
+		cipherLen := binary.BigEndian.Uint64(lenBuf)
+
+		// 读取加密块数据
+		cipherText := make([]byte, cipherLen)
+		if _, err := io.ReadFull(g.r, cipherText); err != nil {
+			return 0, fmt.Errorf("读取加密块数据失败: %s, 错误: %v", g.name, err)
+		}
+
+		// 生成子 Nonce
+		subNonce := make([]byte, len(g.nonce))
+		copy(subNonce, g.nonce)
+		binary.BigEndian.PutUint64(subNonce[4:], g.blockIndex) // 用固定块索引
+
+		// 解密当前块
+		plainText, err := g.gcm.Open(nil, subNonce, cipherText, nil)
+		if err != nil {
+			return 0, fmt.Errorf("解密块失败: %s, 错误: %v (块索引: %d, 密码/盐值错误或文件损坏)", g.name, err, g.blockIndex)
+		}
+		g.plain = plainText
+		g.blockIndex++ // 块索引递增
 	}
+	n := copy(p, g.plain)
+	g.plain = g.plain[n:]
+	return n, nil
+}
 
-	return nil
+// crc32CheckReader 读到结尾时核对 CRC32
+type crc32CheckReader struct {
+	r        io.Reader
+	hash     hash.Hash32
+	name     string
+	expected uint32
+}
+
+func newCRC32CheckReader(r io.Reader, name string, expected uint32) io.Reader {
+	return &crc32CheckReader{r: r, hash: crc32.NewIEEE(), name: name, expected: expected}
+}
+
+func (c *crc32CheckReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.hash.Write(p[:n])
+	if err == io.EOF && c.hash.Sum32() != c.expected {
+		return n, fmt.Errorf("文件 %s CRC32 不匹配: 预期 %08x, 实际 %08x", c.name, c.expected, c.hash.Sum32())
+	}
+	return n, err
+}
+
+// zipDosTime 转换为 MS-DOS 日期时间, CreateRaw 不会自动填充
+func zipDosTime(t time.Time) (date, clock uint16) {
+	if t.IsZero() || t.Year() < 1980 {
+		return 1<<5 | 1, 0 // 1980-01-01
+	}
+	date = uint16(t.Day() + int(t.Month())<<5 + (t.Year()-1980)<<9)
+	clock = uint16(t.Second()/2 + t.Minute()<<5 + t.Hour()<<11)
+	return date, clock
+}
+
+// isASCII 文件名是否只含 ASCII 字符
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
 }
@@ -3,12 +3,15 @@ package init
 
 import (
 	"github.com/GoFurry/gf-file-tool/cmd"
+	"github.com/GoFurry/gf-file-tool/cmd/cat"
 	"github.com/GoFurry/gf-file-tool/cmd/compress"
 	"github.com/GoFurry/gf-file-tool/cmd/decompress"
 	"github.com/GoFurry/gf-file-tool/cmd/decrypt"
 	"github.com/GoFurry/gf-file-tool/cmd/encrypt"
 	"github.com/GoFurry/gf-file-tool/cmd/function/crc32"
 	"github.com/GoFurry/gf-file-tool/cmd/function/merge"
+	"github.com/GoFurry/gf-file-tool/cmd/list"
+	"github.com/GoFurry/gf-file-tool/cmd/test"
 )
 
 // PerformInitOnStart 开始前的初始化函数, 在 Web 项目中常用于初始化数据库以及各种中间件服务.
@@ -21,6 +24,9 @@ func PerformInitOnStart() {
 	// 初始化子命令
 	compress.InitCompress()     // 压缩
 	decompress.InitDecompress() // 解压缩
+	list.InitList()             // 查看压缩包内容
+	test.InitTest()             // 完整性校验
+	cat.InitCat()               // 输出单个条目
 	encrypt.InitEncrypt()       // 加密
 	decrypt.InitDecrypt()       // 解密
 	crc32.InitCRC32()           // CRC32 校验
@@ -3,10 +3,10 @@ package progress
 
 import (
 	"fmt"
-	"os"
 	"time"
 
 	"github.com/GoFurry/gf-file-tool/utils"
+	"github.com/GoFurry/gf-file-tool/utils/log"
 	"github.com/schollz/progressbar/v3"
 )
 
@@ -23,10 +23,10 @@ func NewFileProgressBar(totalSize int64, fileName string) *progressbar.ProgressB
 	// 进度条配置
 	bar := progressbar.NewOptions64(
 		totalSize,
-		progressbar.OptionSetWriter(os.Stdout),   // 输出到标准输出
-		progressbar.OptionEnableColorCodes(true), // 启用颜色
-		progressbar.OptionShowBytes(true),        // 显示字节数
-		progressbar.OptionSetWidth(50),           // 进度条宽度
+		progressbar.OptionSetWriter(log.Output()), // 与日志输出一致
+		progressbar.OptionEnableColorCodes(true),  // 启用颜色
+		progressbar.OptionShowBytes(true),         // 显示字节数
+		progressbar.OptionSetWidth(50),            // 进度条宽度
 		progressbar.OptionSetDescription( // 左侧描述
 			fmt.Sprintf("处理文件: [red]%s [reset]", fileName),
 		),
@@ -53,7 +53,7 @@ func NewBatchProgressBar(totalCount int) *progressbar.ProgressBar {
 
 	bar := progressbar.NewOptions(
 		totalCount,
-		progressbar.OptionSetWriter(os.Stdout),
+		progressbar.OptionSetWriter(log.Output()),
 		progressbar.OptionEnableColorCodes(true),
 		progressbar.OptionSetWidth(50),
 		progressbar.OptionSetDescription("[cyan]批量处理中[reset]"),
@@ -84,6 +84,6 @@ func UpdateProgress(bar *progressbar.ProgressBar, n int) {
 func FinishProgress(bar *progressbar.ProgressBar) {
 	if bar != nil {
 		_ = bar.Finish()
-		fmt.Println()
+		log.Newline()
 	}
 }
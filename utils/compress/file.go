@@ -2,13 +2,10 @@ package compress
 
 import (
 	"fmt"
-	"io"
 	"os"
 	"path/filepath"
-
-	"github.com/GoFurry/gf-file-tool/progress"
-	"github.com/GoFurry/gf-file-tool/utils"
-	"github.com/GoFurry/gf-file-tool/utils/log"
+	"strconv"
+	"strings"
 )
 
 // GetSystemTempDir 获取程序专属系统临时目录
@@ -99,20 +96,18 @@ func IsSplitFile(path string) bool {
 	return false
 }
 
-// MergeSplitFiles 优化分卷合并
-func MergeSplitFiles(firstSplitPath string, outputPath string) error {
-	// 解析分卷基础名
-	var base string
-	if IsSplitFile(firstSplitPath) {
-		ext := filepath.Ext(firstSplitPath)
-		if len(ext) == 4 && ext[0] == '.' {
-			base = firstSplitPath[:len(firstSplitPath)-4]
-		} else if ext == ".split" {
-			base = firstSplitPath[:len(firstSplitPath)-6] // 去掉 .split
-		}
-	} else {
-		base = firstSplitPath
+// SplitBasePath 去掉分卷后缀 (.001/.split) 的基础名, 不是分卷文件时原样返回
+func SplitBasePath(path string) string {
+	if !IsSplitFile(path) {
+		return path
 	}
+	return strings.TrimSuffix(path, filepath.Ext(path))
+}
+
+// SplitVolumePaths 按 .001/.002 顺序查找全部分卷
+// firstSplitPath: 任一分卷、.split 说明文件或去掉分卷后缀的基础名
+func SplitVolumePaths(firstSplitPath string) ([]string, error) {
+	base := SplitBasePath(firstSplitPath)
 
 	// 查找所有分卷
 	var splitPaths []string
@@ -124,39 +119,31 @@ func MergeSplitFiles(firstSplitPath string, outputPath string) error {
 		splitPaths = append(splitPaths, splitPath)
 	}
 	if len(splitPaths) == 0 {
-		return fmt.Errorf("未找到分卷文件: %s", base)
+		return nil, fmt.Errorf("未找到分卷文件: %s", base)
 	}
-
-	if utils.VerboseMode() {
-		log.Info("合并", len(splitPaths), "个分卷:", splitPaths)
+	// 中间缺卷时后面的分卷仍然存在, 不能当作最后一卷
+	if last := lastVolumeNumber(base); last > len(splitPaths) {
+		return nil, fmt.Errorf("缺少分卷: %s.%03d (共找到 %d 卷, 最大编号 %03d)", base, len(splitPaths)+1, len(splitPaths), last)
 	}
+	return splitPaths, nil
+}
 
-	// 合并分卷
-	outFile, err := os.Create(outputPath)
+// lastVolumeNumber 目录中 base.NNN 分卷的最大编号, 没有时返回 0
+func lastVolumeNumber(base string) int {
+	entries, err := os.ReadDir(filepath.Dir(base))
 	if err != nil {
-		return fmt.Errorf("创建合并文件失败: %v", err)
+		return 0
 	}
-	defer outFile.Close()
-
-	// 批量进度条
-	batchBar := progress.NewBatchProgressBar(len(splitPaths))
-	defer progress.FinishProgress(batchBar)
-
-	buf := make([]byte, 4*1024*1024) // 4MB 缓冲区
-	for _, splitPath := range splitPaths {
-		progress.UpdateProgress(batchBar, 1)
-
-		inFile, err := os.Open(splitPath)
-		if err != nil {
-			return fmt.Errorf("打开分卷 %s 失败: %v", splitPath, err)
+	prefix := filepath.Base(base) + "."
+	last := 0
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) || !IsSplitFile(name) || len(name) != len(prefix)+3 {
+			continue
 		}
-		defer inFile.Close()
-
-		_, err = io.CopyBuffer(outFile, inFile, buf)
-		if err != nil {
-			return fmt.Errorf("拷贝分卷 %s 失败: %v", splitPath, err)
+		if n, err := strconv.Atoi(name[len(prefix):]); err == nil && n > last {
+			last = n
 		}
 	}
-
-	return nil
+	return last
 }
@@ -0,0 +1,69 @@
+package compress
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeVolumes 创建指定编号的空分卷
+func writeVolumes(t *testing.T, base string, numbers ...int) {
+	t.Helper()
+	for _, n := range numbers {
+		if err := os.WriteFile(fmt.Sprintf("%s.%03d", base, n), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSplitVolumePaths(t *testing.T) {
+	base := filepath.Join(t.TempDir(), "data.zip")
+	writeVolumes(t, base, 1, 2, 3)
+	want := []string{base + ".001", base + ".002", base + ".003"}
+	// 任一分卷、.split 说明文件或基础名都能找到全部分卷
+	for _, path := range []string{base + ".001", base + ".003", base + ".split", base} {
+		got, err := SplitVolumePaths(path)
+		if err != nil || !reflect.DeepEqual(got, want) {
+			t.Errorf("SplitVolumePaths(%s) = %v, %v", filepath.Base(path), got, err)
+		}
+	}
+	// 名字相近的其他文件不算分卷
+	if err := os.WriteFile(base+".0004", nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := SplitVolumePaths(base); err != nil || len(got) != 3 {
+		t.Fatalf("SplitVolumePaths = %v, %v", got, err)
+	}
+}
+
+func TestSplitVolumePathsMissing(t *testing.T) {
+	base := filepath.Join(t.TempDir(), "data.tar")
+	if _, err := SplitVolumePaths(base + ".001"); err == nil {
+		t.Fatal("没有分卷时应报错")
+	}
+
+	// 中间缺卷: 报告缺少的分卷, 而不是把 .002 当作最后一卷
+	writeVolumes(t, base, 1, 2, 4)
+	_, err := SplitVolumePaths(base + ".001")
+	if err == nil || !strings.Contains(err.Error(), base+".003") {
+		t.Fatalf("期望缺少 .003 的错误, 得到 %v", err)
+	}
+}
+
+func TestIsSplitFile(t *testing.T) {
+	for path, want := range map[string]bool{
+		"a.zip.001": true,
+		"a.7z.123":  true,
+		"a.split":   true,
+		"a.zip":     false,
+		"a.0001":    false,
+		"a.00x":     false,
+	} {
+		if got := IsSplitFile(path); got != want {
+			t.Errorf("IsSplitFile(%s) = %t", path, got)
+		}
+	}
+}
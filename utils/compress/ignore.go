@@ -0,0 +1,108 @@
+package compress
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/GoFurry/gf-file-tool/utils/log"
+)
+
+// IgnoreFileName 目录内的忽略规则文件, 语法与 .gitignore 相同:
+//   - 空行与 # 开头的行忽略, \# 表示以 # 开头的模式
+//   - ! 开头表示重新包含之前被忽略的路径, 父目录已被忽略时无法重新包含
+//   - / 结尾只匹配目录
+//   - 开头或中间含 / 的模式相对 .gfignore 所在目录匹配, 否则匹配其下任意层级
+//   - 多条规则匹配同一路径时以最后一条为准, 子目录的 .gfignore 优先于父目录
+const IgnoreFileName = ".gfignore"
+
+// ignoreRule 单条忽略规则
+type ignoreRule struct {
+	base     string   // .gfignore 所在目录, 相对遍历根目录, 根目录为空
+	segments []string // 模式路径段
+	negate   bool     // ! 重新包含
+	dirOnly  bool     // 只匹配目录
+}
+
+// match 判断相对遍历根目录的路径是否匹配规则
+func (r ignoreRule) match(rel string, dir bool) bool {
+	if r.dirOnly && !dir {
+		return false
+	}
+	if r.base != "" {
+		if !strings.HasPrefix(rel, r.base+"/") {
+			return false
+		}
+		rel = rel[len(r.base)+1:]
+	}
+	return matchSegments(r.segments, strings.Split(rel, "/"))
+}
+
+// loadIgnoreFile 读取目录下的 .gfignore, 文件不存在时返回 nil
+// dir: 目录实际路径, base: 目录相对遍历根目录的路径
+func loadIgnoreFile(dir, base string) ([]ignoreRule, error) {
+	file, err := os.Open(filepath.Join(dir, IgnoreFileName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var rules []ignoreRule
+	scanner := bufio.NewScanner(file)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		rule, ok := parseIgnoreLine(scanner.Text())
+		if !ok {
+			continue
+		}
+		if err := ValidatePattern(strings.Join(rule.segments, "/")); err != nil {
+			log.Warn("忽略无效的规则:", filepath.Join(dir, IgnoreFileName)+":", lineNum, ", 错误:", err)
+			continue
+		}
+		rule.base = base
+		rules = append(rules, rule)
+	}
+	return rules, scanner.Err()
+}
+
+// parseIgnoreLine 解析单行规则, 空行与注释返回 false
+func parseIgnoreLine(line string) (ignoreRule, bool) {
+	line = strings.TrimSuffix(line, "\r")
+	// 去掉行尾未转义的空格
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, "\\ ") {
+		line = line[:len(line)-1]
+	}
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignoreRule{}, false
+	}
+
+	var rule ignoreRule
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, "\\!") || strings.HasPrefix(line, "\\#") {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+	}
+	rule.segments = patternSegments(line)
+	if len(rule.segments) == 0 {
+		return ignoreRule{}, false
+	}
+	return rule, true
+}
+
+// ignored 按规则判断路径是否被忽略, 最后一条匹配的规则生效
+func ignored(rules []ignoreRule, rel string, dir bool) bool {
+	result := false
+	for _, rule := range rules {
+		if rule.match(rel, dir) {
+			result = !rule.negate
+		}
+	}
+	return result
+}
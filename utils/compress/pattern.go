@@ -0,0 +1,86 @@
+package compress
+
+import (
+	"path"
+	"strings"
+)
+
+// 路径通配规则 (与 .gitignore 一致):
+//   - *、?、[...] 与 path.Match 相同, 不跨越 /
+//   - ** 匹配任意层目录 (包括 0 层), 如 **/*.conf、logs/**、a/**/b
+//   - 不含 / 的模式匹配任意层级的文件名, 如 *.log 同时匹配 a.log 与 x/y/a.log
+//   - 以 / 开头的模式只从根开始匹配, 如 /build 只匹配顶层的 build
+//   - 模式匹配某个目录时, 目录下的所有文件都视为匹配
+
+// ValidatePattern 校验通配模式语法
+func ValidatePattern(pattern string) error {
+	for _, segment := range strings.Split(pattern, "/") {
+		if segment == "**" {
+			continue
+		}
+		if _, err := path.Match(segment, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MatchPattern 判断 / 分隔的相对路径 name 是否匹配通配模式
+func MatchPattern(pattern, name string) bool {
+	segments := patternSegments(pattern)
+	if len(segments) == 0 {
+		return false
+	}
+	nameSegments := strings.Split(CleanEntryName(name), "/")
+	// 依次尝试 name 本身及其各级父目录
+	for i := len(nameSegments); i > 0; i-- {
+		if matchSegments(segments, nameSegments[:i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// CleanEntryName 规范化压缩包内路径: 统一 /, 去掉开头的 ./ 与 /、结尾的 /
+func CleanEntryName(name string) string {
+	name = strings.ReplaceAll(name, "\\", "/")
+	name = path.Clean("/" + name)
+	return strings.TrimPrefix(name, "/")
+}
+
+// patternSegments 把模式拆分为路径段, 未锚定且不含 / 的模式前补 **
+func patternSegments(pattern string) []string {
+	pattern = strings.TrimSuffix(strings.TrimPrefix(strings.ReplaceAll(pattern, "\\", "/"), "./"), "/")
+	if pattern == "" {
+		return nil
+	}
+	anchored := strings.HasPrefix(pattern, "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+	if !anchored && !strings.Contains(pattern, "/") {
+		pattern = "**/" + pattern
+	}
+	return strings.Split(pattern, "/")
+}
+
+// matchSegments 逐段匹配, ** 可吞掉任意多段
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			rest := pattern[1:]
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(rest, name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
@@ -0,0 +1,73 @@
+package compress
+
+import "testing"
+
+func TestMatchPattern(t *testing.T) {
+	cases := []struct {
+		pattern, name string
+		want          bool
+	}{
+		// 不含 / 的模式匹配任意层级的文件名
+		{"*.log", "a.log", true},
+		{"*.log", "x/y/a.log", true},
+		{"*.log", "a.log.gz", false},
+		{"a?.txt", "dir/ab.txt", true},
+		{"[ab].txt", "c.txt", false},
+		// 以 / 开头只从根开始匹配
+		{"/build", "build", true},
+		{"/build", "build/out.bin", true},
+		{"/build", "src/build", false},
+		// 含 / 的模式从根开始匹配, * 不跨越 /
+		{"docs/*.md", "docs/a.md", true},
+		{"docs/*.md", "docs/api/a.md", false},
+		// ** 匹配任意层目录, 包括 0 层
+		{"docs/**/*.md", "docs/a.md", true},
+		{"docs/**/*.md", "docs/api/v1/a.md", true},
+		{"**/*.conf", "etc/nginx/nginx.conf", true},
+		{"logs/**", "logs/2024/app.log", true},
+		{"a/**/b", "a/b", true},
+		{"a/**/b", "a/x/y/b", true},
+		{"a/**/b", "a/x/y/c", false},
+		// 匹配目录时目录下的所有文件都视为匹配
+		{"node_modules", "web/node_modules/react/index.js", true},
+		{"src/vendor", "src/vendor/lib/a.go", true},
+		// 模式与路径的分隔符、./ 前缀
+		{"./docs/*.md", "docs/a.md", true},
+		{"docs\\*.md", "docs\\a.md", true},
+		{"", "a", false},
+	}
+	for _, c := range cases {
+		if got := MatchPattern(c.pattern, c.name); got != c.want {
+			t.Errorf("MatchPattern(%q, %q) = %t, 期望 %t", c.pattern, c.name, got, c.want)
+		}
+	}
+}
+
+func TestValidatePattern(t *testing.T) {
+	for _, pattern := range []string{"*.log", "a/**/b", "[a-z]*", "**"} {
+		if err := ValidatePattern(pattern); err != nil {
+			t.Errorf("ValidatePattern(%q) = %v", pattern, err)
+		}
+	}
+	for _, pattern := range []string{"[", "a/[b", "\\"} {
+		if err := ValidatePattern(pattern); err == nil {
+			t.Errorf("ValidatePattern(%q) 应当报错", pattern)
+		}
+	}
+}
+
+func TestCleanEntryName(t *testing.T) {
+	for name, want := range map[string]string{
+		"a/b":        "a/b",
+		"./a/b/":     "a/b",
+		"/a//b":      "a/b",
+		"a\\b\\c":    "a/b/c",
+		"a/./b/../c": "a/c",
+		"../../etc":  "etc",
+		"":           "",
+	} {
+		if got := CleanEntryName(name); got != want {
+			t.Errorf("CleanEntryName(%q) = %q, 期望 %q", name, got, want)
+		}
+	}
+}
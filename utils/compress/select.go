@@ -0,0 +1,381 @@
+package compress
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/GoFurry/gf-file-tool/utils"
+	"github.com/GoFurry/gf-file-tool/utils/log"
+)
+
+// SelectOptions 待处理文件的筛选条件
+// 通配模式相对源目录匹配, 语法见 pattern.go
+type SelectOptions struct {
+	Include   []string  // 只保留匹配的文件, 为空表示全部
+	Exclude   []string  // 跳过匹配的文件/目录, 优先于 Include
+	NoIgnore  bool      // 不读取 .gfignore
+	NewerThan time.Time // 只保留修改时间晚于该时间的文件, 零值不限制
+	OlderThan time.Time // 只保留修改时间早于该时间的文件, 零值不限制
+	MaxSize   int64     // 只保留不超过该大小的文件 (字节), 0 不限制
+
+	FollowSymlinks bool // 跟随目录内的符号链接, 归档链接目标而不是链接本身
+	SpecialFiles   bool // 保留 FIFO 与设备文件
+}
+
+// FileSelector 文件筛选器, 替代 GetFileList 供 compress/encrypt/decrypt 共用
+type FileSelector struct {
+	opts    SelectOptions
+	skipped int // 被筛掉的文件/目录数
+}
+
+// NewFileSelector 创建筛选器并校验通配模式
+func NewFileSelector(opts SelectOptions) (*FileSelector, error) {
+	for _, pattern := range append(append([]string{}, opts.Include...), opts.Exclude...) {
+		if err := ValidatePattern(pattern); err != nil {
+			return nil, fmt.Errorf("无效的通配模式: %s, 错误: %v", pattern, err)
+		}
+	}
+	if opts.MaxSize < 0 {
+		return nil, fmt.Errorf("无效的大小限制: %d", opts.MaxSize)
+	}
+	return &FileSelector{opts: opts}, nil
+}
+
+// Skipped 被筛掉的文件/目录数
+func (s *FileSelector) Skipped() int {
+	return s.skipped
+}
+
+// TreeEntry 遍历结果中的文件或目录
+type TreeEntry struct {
+	Path string      // 绝对路径
+	Rel  string      // 相对源路径, / 分隔, 源本身为 .
+	Dir  bool        // 是否为目录
+	Mode os.FileMode // 文件类型与权限, 跟随符号链接时为链接目标的
+}
+
+// Collect 批量获取 src 下通过筛选的普通文件
+// return: 所有文件的绝对路径列表、错误
+func (s *FileSelector) Collect(src string) ([]string, error) {
+	tree, err := s.CollectTree(src)
+	if err != nil {
+		return nil, err
+	}
+	var fileList []string
+	for _, entry := range tree {
+		if entry.Mode.IsRegular() {
+			fileList = append(fileList, entry.Path)
+		}
+	}
+	return fileList, nil
+}
+
+// CollectTree 获取 src 下通过筛选的文件、目录 (含空目录)、符号链接与特殊文件, 父目录排在子项之前
+// 命令行上的 src 本身是符号链接时始终跟随; 目录内的符号链接默认原样保留, FollowSymlinks 时跟随
+// 被排除或忽略的目录整体跳过, 不再遍历; 指定 --include 时只保留含有匹配文件的目录
+func (s *FileSelector) CollectTree(src string) ([]TreeEntry, error) {
+	info, err := os.Stat(src)
+	if err != nil {
+		return nil, fmt.Errorf("路径不存在: %s, 错误: %v", src, err)
+	}
+	absPath, _ := filepath.Abs(src)
+
+	// 单文件按文件名匹配通配模式, 不读取 .gfignore
+	if !info.IsDir() {
+		if !s.keepType(absPath, info) || !s.keepFile(absPath, filepath.Base(src), info) {
+			return nil, nil
+		}
+		return []TreeEntry{{Path: absPath, Rel: ".", Mode: info.Mode()}}, nil
+	}
+
+	walker := &treeWalker{selector: s, rules: make(map[string][]ignoreRule), active: make(map[string]bool)}
+	if err := walker.walk(absPath, ".", info); err != nil {
+		return nil, fmt.Errorf("遍历目录失败: %s, 错误: %v", src, err)
+	}
+	tree := walker.tree
+	if len(s.opts.Include) > 0 {
+		tree = pruneEmptyDirs(tree)
+	}
+	return tree, nil
+}
+
+// treeWalker 目录遍历状态
+type treeWalker struct {
+	selector *FileSelector
+	rules    map[string][]ignoreRule // 各目录的 .gfignore 规则, 键为相对源路径的目录, 根目录为空
+	active   map[string]bool         // 正在遍历的真实目录, 跟随符号链接时用于发现循环
+	tree     []TreeEntry
+}
+
+// walk 按文件名顺序递归遍历目录, 父目录先于子项加入结果
+func (w *treeWalker) walk(dir, rel string, info os.FileInfo) error {
+	s := w.selector
+	w.tree = append(w.tree, TreeEntry{Path: dir, Rel: rel, Dir: true, Mode: info.Mode()})
+
+	// 跟随符号链接时防止目录循环
+	if s.opts.FollowSymlinks {
+		realDir, err := filepath.EvalSymlinks(dir)
+		if err != nil {
+			return fmt.Errorf("解析路径失败: %s, 错误: %v", dir, err)
+		}
+		if w.active[realDir] {
+			s.skip(dir, "符号链接形成循环")
+			return nil
+		}
+		w.active[realDir] = true
+		defer delete(w.active, realDir)
+	}
+
+	base := ""
+	if rel != "." {
+		base = rel
+	}
+	if !s.opts.NoIgnore {
+		dirRules, err := loadIgnoreFile(dir, base)
+		if err != nil {
+			return fmt.Errorf("读取 %s 失败: %v", filepath.Join(dir, IgnoreFileName), err)
+		}
+		w.rules[base] = dirRules
+	}
+
+	children, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("读取目录失败: %s, 错误: %v", dir, err)
+	}
+	for _, child := range children {
+		childPath := filepath.Join(dir, child.Name())
+		childRel := path.Join(base, child.Name())
+		childInfo, err := os.Lstat(childPath)
+		if err != nil {
+			return fmt.Errorf("获取文件信息失败: %s, 错误: %v", childPath, err)
+		}
+		if childInfo.Mode()&os.ModeSymlink != 0 && s.opts.FollowSymlinks {
+			target, err := os.Stat(childPath)
+			if err != nil {
+				s.skip(childPath, "符号链接目标不存在")
+				continue
+			}
+			childInfo = target
+		}
+
+		isDir := childInfo.IsDir()
+		if isDir && s.excluded(childRel) {
+			s.skip(childPath, "已排除")
+			continue
+		}
+		if !s.opts.NoIgnore && ignored(ancestorRules(w.rules, childRel), childRel, isDir) {
+			s.skip(childPath, "已忽略")
+			continue
+		}
+		if isDir {
+			if err := w.walk(childPath, childRel, childInfo); err != nil {
+				return err
+			}
+			continue
+		}
+		if s.keepType(childPath, childInfo) && s.keepFile(childPath, childRel, childInfo) {
+			w.tree = append(w.tree, TreeEntry{Path: childPath, Rel: childRel, Mode: childInfo.Mode()})
+		}
+	}
+	return nil
+}
+
+// keepType 按文件类型筛选: 普通文件与符号链接保留, FIFO/设备文件需要 SpecialFiles, socket 等不支持
+func (s *FileSelector) keepType(path string, info os.FileInfo) bool {
+	mode := info.Mode()
+	switch {
+	case mode.IsRegular(), mode&os.ModeSymlink != 0:
+		return true
+	case mode&(os.ModeNamedPipe|os.ModeDevice) != 0:
+		if !s.opts.SpecialFiles {
+			s.skip(path, "特殊文件, 需 --special-files")
+			return false
+		}
+		return true
+	default:
+		s.skip(path, "不支持的文件类型")
+		return false
+	}
+}
+
+// pruneEmptyDirs 去掉不含任何文件的目录
+func pruneEmptyDirs(tree []TreeEntry) []TreeEntry {
+	used := make(map[string]bool)
+	for _, entry := range tree {
+		if entry.Dir {
+			continue
+		}
+		for dir := path.Dir(entry.Rel); ; dir = path.Dir(dir) {
+			used[dir] = true
+			if dir == "." {
+				break
+			}
+		}
+	}
+	pruned := tree[:0]
+	for _, entry := range tree {
+		if !entry.Dir || used[entry.Rel] {
+			pruned = append(pruned, entry)
+		}
+	}
+	return pruned
+}
+
+// keepFile 按通配模式、修改时间、大小判断文件是否保留
+func (s *FileSelector) keepFile(path, rel string, info os.FileInfo) bool {
+	switch {
+	case s.excluded(rel):
+		s.skip(path, "已排除")
+	case !s.included(rel):
+		s.skip(path, "不匹配 --include")
+	case !s.opts.NewerThan.IsZero() && !info.ModTime().After(s.opts.NewerThan):
+		s.skip(path, "修改时间早于 --newer-than")
+	case !s.opts.OlderThan.IsZero() && !info.ModTime().Before(s.opts.OlderThan):
+		s.skip(path, "修改时间晚于 --older-than")
+	case s.opts.MaxSize > 0 && info.Size() > s.opts.MaxSize:
+		s.skip(path, "超过 --max-size")
+	default:
+		return true
+	}
+	return false
+}
+
+// excluded 是否匹配 --exclude
+func (s *FileSelector) excluded(rel string) bool {
+	for _, pattern := range s.opts.Exclude {
+		if MatchPattern(pattern, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// included 是否匹配 --include, 未指定时全部保留
+func (s *FileSelector) included(rel string) bool {
+	if len(s.opts.Include) == 0 {
+		return true
+	}
+	for _, pattern := range s.opts.Include {
+		if MatchPattern(pattern, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// skip 记录被筛掉的路径, 详细模式下输出原因
+func (s *FileSelector) skip(path, reason string) {
+	s.skipped++
+	if utils.VerboseMode() {
+		log.Info("跳过:", path, "("+reason+")")
+	}
+}
+
+// ancestorRules 按从根到父目录的顺序合并各级 .gfignore 规则
+func ancestorRules(rules map[string][]ignoreRule, rel string) []ignoreRule {
+	merged := rules[""]
+	segments := strings.Split(rel, "/")
+	for i := 1; i < len(segments); i++ {
+		merged = append(merged[:len(merged):len(merged)], rules[strings.Join(segments[:i], "/")]...)
+	}
+	return merged
+}
+
+// ReadFilesFrom 读取源路径列表, path 为 - 时读取标准输入
+// 内容含 \0 时按 \0 分隔 (find -print0), 否则按行分隔, 忽略空项
+func ReadFilesFrom(path string) ([]string, error) {
+	var reader io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("打开文件列表失败: %v", err)
+		}
+		defer file.Close()
+		reader = file
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("读取文件列表失败: %v", err)
+	}
+
+	separator := []byte("\n")
+	if bytes.IndexByte(data, 0) >= 0 {
+		separator = []byte{0}
+	}
+	var paths []string
+	for _, item := range bytes.Split(data, separator) {
+		item = bytes.TrimSuffix(item, []byte("\r"))
+		if len(item) > 0 {
+			paths = append(paths, string(item))
+		}
+	}
+	return paths, nil
+}
+
+// ParseSize 解析大小, 支持 K/M/G/T 后缀 (1024 进制, 可带 B/iB), 无后缀为字节
+func ParseSize(value string) (int64, error) {
+	text := strings.ToUpper(strings.TrimSpace(value))
+	text = strings.TrimSuffix(strings.TrimSuffix(text, "IB"), "B")
+	multiplier := int64(1)
+	if text != "" {
+		switch text[len(text)-1] {
+		case 'K':
+			multiplier = 1 << 10
+		case 'M':
+			multiplier = 1 << 20
+		case 'G':
+			multiplier = 1 << 30
+		case 'T':
+			multiplier = 1 << 40
+		}
+		if multiplier > 1 {
+			text = text[:len(text)-1]
+		}
+	}
+	number, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+	if err != nil || number < 0 {
+		return 0, fmt.Errorf("无效的大小: %s", value)
+	}
+	return int64(number * float64(multiplier)), nil
+}
+
+// ParseTimeBound 解析时间条件, 支持:
+//   - 相对时长: 30m/12h/7d/2w, 表示 now 之前
+//   - 日期时间: 2006-01-02、2006-01-02 15:04:05、RFC3339
+//   - 已存在的文件路径: 取其修改时间 (同 find -newer)
+func ParseTimeBound(value string, now time.Time) (time.Time, error) {
+	text := strings.TrimSpace(value)
+	if text == "" {
+		return time.Time{}, nil
+	}
+
+	// 相对时长, time.ParseDuration 不支持天与周
+	if unit := text[len(text)-1]; unit == 'd' || unit == 'w' {
+		if days, err := strconv.ParseFloat(text[:len(text)-1], 64); err == nil {
+			if unit == 'w' {
+				days *= 7
+			}
+			return now.Add(-time.Duration(days * float64(24*time.Hour))), nil
+		}
+	}
+	if duration, err := time.ParseDuration(text); err == nil {
+		return now.Add(-duration), nil
+	}
+
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, text, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	if info, err := os.Stat(text); err == nil {
+		return info.ModTime(), nil
+	}
+	return time.Time{}, fmt.Errorf("无效的时间: %s (支持 7d/12h、2006-01-02 或参照文件路径)", value)
+}
@@ -0,0 +1,269 @@
+package compress
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeSelectTree 写出测试目录, 返回根目录
+func writeSelectTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+// collectRel 筛选并返回相对根目录的路径, 按字母排序
+func collectRel(t *testing.T, root string, opts SelectOptions) []string {
+	t.Helper()
+	selector, err := NewFileSelector(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths, err := selector.Collect(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	absRoot, _ := filepath.Abs(root)
+	var rels []string
+	for _, path := range paths {
+		rel, _ := filepath.Rel(absRoot, path)
+		rels = append(rels, filepath.ToSlash(rel))
+	}
+	sort.Strings(rels)
+	return rels
+}
+
+func TestFileSelectorPatterns(t *testing.T) {
+	root := writeSelectTree(t, map[string]string{
+		"main.go":          "",
+		"main_test.go":     "",
+		"docs/a.md":        "",
+		"build/out.bin":    "",
+		"src/build/gen.go": "",
+		"logs/app.log":     "",
+	})
+
+	cases := []struct {
+		name string
+		opts SelectOptions
+		want []string
+	}{
+		{"all", SelectOptions{}, []string{"build/out.bin", "docs/a.md", "logs/app.log", "main.go", "main_test.go", "src/build/gen.go"}},
+		{"include", SelectOptions{Include: []string{"*.go"}}, []string{"main.go", "main_test.go", "src/build/gen.go"}},
+		{"exclude-wins", SelectOptions{Include: []string{"*.go"}, Exclude: []string{"*_test.go"}}, []string{"main.go", "src/build/gen.go"}},
+		{"anchored", SelectOptions{Exclude: []string{"/build", "logs/"}}, []string{"docs/a.md", "main.go", "main_test.go", "src/build/gen.go"}},
+		{"any-depth-dir", SelectOptions{Exclude: []string{"build"}}, []string{"docs/a.md", "logs/app.log", "main.go", "main_test.go"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := collectRel(t, root, c.opts); !reflect.DeepEqual(got, c.want) {
+				t.Fatalf("筛选结果 = %v, 期望 %v", got, c.want)
+			}
+		})
+	}
+
+	if _, err := NewFileSelector(SelectOptions{Include: []string{"[a-"}}); err == nil {
+		t.Fatal("无效的通配模式应当报错")
+	}
+	if _, err := NewFileSelector(SelectOptions{MaxSize: -1}); err == nil {
+		t.Fatal("负数大小限制应当报错")
+	}
+}
+
+func TestFileSelectorIgnoreFile(t *testing.T) {
+	root := writeSelectTree(t, map[string]string{
+		IgnoreFileName: strings.Join([]string{
+			"# 注释与空行忽略",
+			"",
+			"*.log",
+			"!keep.log",
+			"tmp/",
+			"/secret.txt",
+			"\\#hash.txt",
+		}, "\n"),
+		"a.log":                 "",
+		"keep.log":              "",
+		"secret.txt":            "",
+		"#hash.txt":             "",
+		"tmp/x.txt":             "",
+		"tmp.txt":               "",
+		"sub/secret.txt":        "",
+		"sub/b.log":             "",
+		"sub/" + IgnoreFileName: "!b.log\ngen/\n",
+		"sub/gen/out.go":        "",
+		"sub/keep.go":           "",
+	})
+	want := []string{IgnoreFileName, "keep.log", "sub/" + IgnoreFileName, "sub/b.log", "sub/keep.go", "sub/secret.txt", "tmp.txt"}
+	if got := collectRel(t, root, SelectOptions{}); !reflect.DeepEqual(got, want) {
+		t.Fatalf("筛选结果 = %v, 期望 %v", got, want)
+	}
+
+	// --no-ignore 不读取 .gfignore
+	if got := collectRel(t, root, SelectOptions{NoIgnore: true}); len(got) != 12 {
+		t.Fatalf("--no-ignore 筛选出 %d 个文件, 期望 12: %v", len(got), got)
+	}
+}
+
+func TestFileSelectorTimeAndSize(t *testing.T) {
+	root := writeSelectTree(t, map[string]string{
+		"old.txt":   "old",
+		"new.txt":   "new",
+		"large.bin": strings.Repeat("x", 2048),
+	})
+	now := time.Now()
+	old := now.Add(-48 * time.Hour)
+	if err := os.Chtimes(filepath.Join(root, "old.txt"), old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	day := now.Add(-24 * time.Hour)
+	if got := collectRel(t, root, SelectOptions{NewerThan: day}); !reflect.DeepEqual(got, []string{"large.bin", "new.txt"}) {
+		t.Fatalf("--newer-than = %v", got)
+	}
+	if got := collectRel(t, root, SelectOptions{OlderThan: day}); !reflect.DeepEqual(got, []string{"old.txt"}) {
+		t.Fatalf("--older-than = %v", got)
+	}
+	if got := collectRel(t, root, SelectOptions{MaxSize: 1024}); !reflect.DeepEqual(got, []string{"new.txt", "old.txt"}) {
+		t.Fatalf("--max-size = %v", got)
+	}
+
+	// 单文件按文件名匹配
+	selector, _ := NewFileSelector(SelectOptions{Exclude: []string{"*.bin"}})
+	if paths, err := selector.Collect(filepath.Join(root, "large.bin")); err != nil || len(paths) != 0 || selector.Skipped() != 1 {
+		t.Fatalf("单文件筛选 = %v, %v, 跳过 %d", paths, err, selector.Skipped())
+	}
+}
+
+func TestReadFilesFrom(t *testing.T) {
+	dir := t.TempDir()
+	cases := map[string][]string{
+		"a.txt\r\nb dir/c.txt\n\n":        {"a.txt", "b dir/c.txt"},
+		"a.txt\x00name\nwith newline\x00": {"a.txt", "name\nwith newline"},
+	}
+	for content, want := range cases {
+		path := filepath.Join(dir, "list")
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		got, err := ReadFilesFrom(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("ReadFilesFrom(%q) = %q, 期望 %q", content, got, want)
+		}
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	for text, want := range map[string]int64{
+		"100":    100,
+		"1k":     1024,
+		"1.5M":   1536 << 10,
+		"2GiB":   2 << 30,
+		"1 TB":   1 << 40,
+		" 512B ": 512,
+	} {
+		got, err := ParseSize(text)
+		if err != nil || got != want {
+			t.Errorf("ParseSize(%q) = %d, %v, 期望 %d", text, got, err, want)
+		}
+	}
+	for _, text := range []string{"", "abc", "-1K", "1X"} {
+		if _, err := ParseSize(text); err == nil {
+			t.Errorf("ParseSize(%q) 应当报错", text)
+		}
+	}
+}
+
+func TestParseTimeBound(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.Local)
+	for text, want := range map[string]time.Time{
+		"7d":                   now.AddDate(0, 0, -7),
+		"2w":                   now.AddDate(0, 0, -14),
+		"36h":                  now.Add(-36 * time.Hour),
+		"2024-05-01":           time.Date(2024, 5, 1, 0, 0, 0, 0, time.Local),
+		"2024-05-01 08:30:00":  time.Date(2024, 5, 1, 8, 30, 0, 0, time.Local),
+		"2024-05-01T08:30:00Z": time.Date(2024, 5, 1, 8, 30, 0, 0, time.UTC),
+	} {
+		got, err := ParseTimeBound(text, now)
+		if err != nil || !got.Equal(want) {
+			t.Errorf("ParseTimeBound(%q) = %v, %v, 期望 %v", text, got, err, want)
+		}
+	}
+
+	// 参照文件的修改时间
+	ref := filepath.Join(t.TempDir(), "ref")
+	if err := os.WriteFile(ref, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Date(2023, 1, 2, 3, 4, 5, 0, time.Local)
+	if err := os.Chtimes(ref, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := ParseTimeBound(ref, now); err != nil || !got.Equal(mtime) {
+		t.Errorf("ParseTimeBound(参照文件) = %v, %v, 期望 %v", got, err, mtime)
+	}
+	if got, err := ParseTimeBound("", now); err != nil || !got.IsZero() {
+		t.Errorf("空字符串应当返回零值: %v, %v", got, err)
+	}
+	if _, err := ParseTimeBound("yesterday", now); err == nil {
+		t.Error("无效的时间应当报错")
+	}
+}
+
+func TestCollectTree(t *testing.T) {
+	root := writeSelectTree(t, map[string]string{
+		"a.go":          "",
+		"docs/guide.md": "",
+	})
+	if err := os.MkdirAll(filepath.Join(root, "empty", "deeper"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	collect := func(opts SelectOptions) []string {
+		t.Helper()
+		selector, err := NewFileSelector(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tree, err := selector.CollectTree(root)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var items []string
+		for _, entry := range tree {
+			item := entry.Rel
+			if entry.Dir {
+				item += "/"
+			}
+			items = append(items, item)
+		}
+		return items
+	}
+
+	// 空目录保留, 父目录排在子项之前
+	want := []string{"./", "a.go", "docs/", "docs/guide.md", "empty/", "empty/deeper/"}
+	if got := collect(SelectOptions{}); !reflect.DeepEqual(got, want) {
+		t.Fatalf("CollectTree = %v, 期望 %v", got, want)
+	}
+	// 指定 --include 时只保留含有匹配文件的目录
+	want = []string{"./", "docs/", "docs/guide.md"}
+	if got := collect(SelectOptions{Include: []string{"*.md"}}); !reflect.DeepEqual(got, want) {
+		t.Fatalf("CollectTree(--include) = %v, 期望 %v", got, want)
+	}
+}
@@ -0,0 +1,73 @@
+//go:build linux || darwin
+
+package compress
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCollectTreeSymlinks(t *testing.T) {
+	root := writeSelectTree(t, map[string]string{
+		"real/a.txt": "a",
+	})
+	if err := os.Symlink("real", filepath.Join(root, "alias")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("a.txt", filepath.Join(root, "real", "link")); err != nil {
+		t.Fatal(err)
+	}
+	// 指向祖先目录的链接, 跟随时形成循环
+	if err := os.Symlink("..", filepath.Join(root, "real", "up")); err != nil {
+		t.Fatal(err)
+	}
+
+	collect := func(opts SelectOptions) map[string]os.FileMode {
+		t.Helper()
+		selector, err := NewFileSelector(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tree, err := selector.CollectTree(root)
+		if err != nil {
+			t.Fatal(err)
+		}
+		types := map[string]os.FileMode{}
+		for _, entry := range tree {
+			types[entry.Rel] = entry.Mode.Type()
+		}
+		return types
+	}
+
+	// 默认原样保留符号链接, 不进入链接指向的目录
+	types := collect(SelectOptions{})
+	for rel, want := range map[string]os.FileMode{
+		"alias":      os.ModeSymlink,
+		"real/link":  os.ModeSymlink,
+		"real/up":    os.ModeSymlink,
+		"real/a.txt": 0,
+	} {
+		if got, ok := types[rel]; !ok || got != want {
+			t.Errorf("%s: 类型 = %v (%t), 期望 %v", rel, got, ok, want)
+		}
+	}
+	if _, ok := types["alias/a.txt"]; ok {
+		t.Error("未开启 --follow-symlinks 时不应遍历链接目录")
+	}
+
+	// 跟随符号链接: 链接目录展开, 循环的链接被跳过
+	types = collect(SelectOptions{FollowSymlinks: true})
+	for rel, want := range map[string]os.FileMode{
+		"alias":       os.ModeDir,
+		"alias/a.txt": 0,
+		"real/link":   0,
+	} {
+		if got, ok := types[rel]; !ok || got != want {
+			t.Errorf("跟随 %s: 类型 = %v (%t), 期望 %v", rel, got, ok, want)
+		}
+	}
+	if _, ok := types["real/up/real/a.txt"]; ok {
+		t.Error("循环的符号链接应当跳过")
+	}
+}
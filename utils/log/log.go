@@ -2,19 +2,40 @@ package log
 
 import (
 	"fmt"
+	"io"
+	"os"
 
 	"github.com/gookit/color"
 )
 
 // 简易的日志封装, 利于学生快速实践开发工具包的封装
 
+// output 日志输出目标, 默认标准输出
+// 流式模式下标准输出用于传输数据, 需要切换到标准错误
+var output io.Writer = os.Stdout
+
+// SetOutput 设置日志 (以及进度条) 的输出目标
+func SetOutput(w io.Writer) {
+	output = w
+}
+
+// Output 获取当前日志输出目标
+func Output() io.Writer {
+	return output
+}
+
+// Newline 输出空行, 用于分隔进度条与日志
+func Newline() {
+	fmt.Fprintln(output)
+}
+
 // Error 红色[Error]开头的错误日志
 func Error(args ...any) {
 	text := color.FgRed.Render("[Error]")
 	for _, arg := range args {
 		text += " " + fmt.Sprint(arg)
 	}
-	fmt.Println(text)
+	fmt.Fprintln(output, text)
 }
 
 // Info 蓝色[Info]开头的普通日志
@@ -23,7 +44,7 @@ func Info(args ...any) {
 	for _, arg := range args {
 		text += " " + fmt.Sprint(arg)
 	}
-	fmt.Println(text)
+	fmt.Fprintln(output, text)
 }
 
 // Success 绿色[Success]开头的成功日志
@@ -32,7 +53,7 @@ func Success(args ...any) {
 	for _, arg := range args {
 		text += " " + fmt.Sprint(arg)
 	}
-	fmt.Println(text)
+	fmt.Fprintln(output, text)
 }
 
 // Warn 黄色[Warn]开头的警告日志
@@ -41,5 +62,5 @@ func Warn(args ...any) {
 	for _, arg := range args {
 		text += " " + fmt.Sprint(arg)
 	}
-	fmt.Println(text)
+	fmt.Fprintln(output, text)
 }